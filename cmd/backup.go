@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/spf13/cobra"
+)
+
+type backupCMD struct {
+	ctx  *WuKongIMContext
+	dir  string // 备份目录（运行中节点所在机器上的目录）
+	addr string // 管理服务地址
+}
+
+func newBackupCMD(ctx *WuKongIMContext) *backupCMD {
+	return &backupCMD{
+		ctx: ctx,
+	}
+}
+
+func (b *backupCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "take an online backup of the running WuKongIM node",
+		RunE:  b.run,
+	}
+	cmd.Flags().StringVar(&b.dir, "dir", "", "backup dir, must not exist or be empty (default: {dataDir}/backup/{time})")
+	cmd.Flags().StringVar(&b.addr, "addr", "", "manager server address (default: manager.addr in config)")
+	return cmd
+}
+
+func (b *backupCMD) run(cmd *cobra.Command, args []string) error {
+	if !initialed {
+		return nil
+	}
+	if strings.TrimSpace(serverOpts.ManagerToken) == "" {
+		return errors.New("managerToken is not configured")
+	}
+	addr := strings.TrimSpace(b.addr)
+	if addr == "" {
+		addr = strings.Replace(serverOpts.Manager.Addr, "0.0.0.0", "127.0.0.1", 1)
+	}
+	if !strings.HasPrefix(addr, "http") {
+		addr = fmt.Sprintf("http://%s", addr)
+	}
+
+	resp, err := network.Post(fmt.Sprintf("%s/manager/backup", addr), []byte(wkutil.ToJSON(map[string]string{
+		"dir": b.dir,
+	})), map[string]string{
+		"token": serverOpts.ManagerToken,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backup failed: %s", resp.Body)
+	}
+	fmt.Println("backup done:", resp.Body)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/spf13/cobra"
+)
+
+type indexCMD struct {
+	ctx *WuKongIMContext
+}
+
+func newIndexCMD(ctx *WuKongIMContext) *indexCMD {
+	return &indexCMD{
+		ctx: ctx,
+	}
+}
+
+func (i *indexCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "manage the message full-text index",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rebuild",
+		Short: "rebuild the message full-text index (the server must be stopped)",
+		RunE:  i.rebuild,
+	})
+	return cmd
+}
+
+func (i *indexCMD) rebuild(cmd *cobra.Command, args []string) error {
+	if !initialed {
+		return nil
+	}
+	trace.SetGlobalTrace(trace.New(context.Background(), trace.NewOptions()))
+
+	db := wkdb.NewWukongDB(
+		wkdb.NewOptions(
+			wkdb.WithDir(filepath.Join(serverOpts.DataDir, "db")),
+			wkdb.WithNodeId(serverOpts.Cluster.NodeId),
+			wkdb.WithShardNum(serverOpts.Db.ShardNum),
+			wkdb.WithMemTableSize(serverOpts.Db.MemTableSize),
+			wkdb.WithSlotCount(serverOpts.Cluster.SlotCount),
+		),
+	)
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	start := time.Now()
+	fmt.Println("rebuilding message full-text index...")
+	if err := db.RebuildMessageSearchIndex(); err != nil {
+		return err
+	}
+	fmt.Printf("message full-text index rebuilt, cost: %s\n", time.Since(start))
+	return nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/WuKongIM/WuKongIM/internal/server"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/spf13/cobra"
+)
+
+type restoreCMD struct {
+	ctx   *WuKongIMContext
+	from  string // 备份目录
+	force bool   // 数据目录不为空时是否覆盖（旧数据会被重命名保留）
+}
+
+func newRestoreCMD(ctx *WuKongIMContext) *restoreCMD {
+	return &restoreCMD{
+		ctx: ctx,
+	}
+}
+
+func (r *restoreCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "restore a backup into the data dir (the server must be stopped)",
+		RunE:  r.run,
+	}
+	cmd.Flags().StringVar(&r.from, "from", "", "backup dir")
+	cmd.Flags().BoolVar(&r.force, "force", false, "move the existing data aside and restore")
+	return cmd
+}
+
+func (r *restoreCMD) run(cmd *cobra.Command, args []string) error {
+	if !initialed {
+		return nil
+	}
+	if strings.TrimSpace(r.from) == "" {
+		return errors.New("--from is required")
+	}
+	if serverRunning() {
+		return errors.New("WuKongIM server is running, please stop it first")
+	}
+	manifest, err := server.Restore(serverOpts, r.from, r.force)
+	if err != nil {
+		return err
+	}
+	fmt.Println("restore done:", wkutil.ToJSON(manifest))
+	return nil
+}
+
+// 通过pid文件判断服务是否在运行
+func serverRunning() bool {
+	strb, err := os.ReadFile(path.Join(".", pidfile))
+	if err != nil {
+		return false
+	}
+	pid := wkutil.ParseInt(strings.TrimSpace(string(strb)))
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil || process == nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -39,14 +39,21 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	//################### 频道 ###################
 	r.POST("/channel", ch.channelCreateOrUpdate)       // 创建或修改频道
 	r.POST("/channel/info", ch.updateOrAddChannelInfo) // 更新或添加频道基础信息
+	r.GET("/channel/info", ch.channelInfoGet)          // 获取频道基础信息（包含置顶消息）
 	r.POST("/channel/delete", ch.channelDelete)        // 删除频道
 
 	//################### 订阅者 ###################// 删除频道
 	r.POST("/channel/subscriber_add", ch.addSubscriber)       // 添加订阅者
 	r.POST("/channel/subscriber_remove", ch.removeSubscriber) // 移除订阅者
+	r.POST("/channel/subscriber_update", ch.updateSubscriber) // 更新订阅者（角色、禁言、扩展属性）
+	r.POST("/channel/subscribers", ch.subscriberList)         // 分页获取订阅者
 
 	r.POST("/tmpchannel/subscriber_set", ch.setTmpSubscriber) // 临时频道设置订阅者
 
+	//################### 置顶消息 ###################
+	r.POST("/channel/pin", ch.pin)     // 置顶消息
+	r.POST("/channel/unpin", ch.unpin) // 取消置顶消息
+
 	//################### 黑名单 ###################// 删除频道
 	r.POST("/channel/blacklist_add", ch.blacklistAdd)       // 添加黑名单
 	r.POST("/channel/blacklist_set", ch.blacklistSet)       // 设置黑名单（覆盖原来的黑名单数据）
@@ -120,6 +127,7 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 				Uid:       subscriber,
 				CreatedAt: &createdAt,
 				UpdatedAt: &updatedAt,
+				JoinedAt:  createdAt.Unix(),
 			})
 		}
 		err = ch.s.store.AddSubscribers(req.ChannelID, req.ChannelType, members)
@@ -243,6 +251,125 @@ func (ch *ChannelAPI) addSubscriber(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 更新订阅者的角色、禁言和扩展属性
+func (ch *ChannelAPI) updateSubscriber(c *wkhttp.Context) {
+	var req subscriberUpdateReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if req.ChannelType == 0 {
+		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelId, req.ChannelType) // 获取频道的领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	members := make([]wkdb.Member, 0, len(req.Subscribers))
+	updatedAt := time.Now()
+	for _, subscriber := range req.Subscribers {
+		member, err := ch.s.store.GetSubscriber(req.ChannelId, req.ChannelType, subscriber.Uid)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				c.ResponseError(fmt.Errorf("订阅者[%s]不存在！", subscriber.Uid))
+				return
+			}
+			ch.Error("获取订阅者失败！", zap.Error(err), zap.String("uid", subscriber.Uid))
+			c.ResponseError(errors.New("获取订阅者失败！"))
+			return
+		}
+		if subscriber.Role != nil {
+			member.Role = wkdb.MemberRole(*subscriber.Role)
+		}
+		if subscriber.MuteUntil != nil {
+			member.MuteUntil = *subscriber.MuteUntil
+		}
+		if subscriber.Attributes != nil {
+			member.Attributes = subscriber.Attributes
+		}
+		member.UpdatedAt = &updatedAt
+		members = append(members, member)
+	}
+	err = ch.s.store.UpdateSubscribers(req.ChannelId, req.ChannelType, members)
+	if err != nil {
+		ch.Error("更新订阅者失败！", zap.Error(err))
+		c.ResponseError(errors.New("更新订阅者失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 分页获取订阅者
+func (ch *ChannelAPI) subscriberList(c *wkhttp.Context) {
+	var req subscriberListReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.Wrap(err, "数据格式有误！"))
+		return
+	}
+	if req.ChannelType == 0 {
+		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Limit == 0 || req.Limit > 1000 {
+		req.Limit = 1000
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelId, req.ChannelType) // 获取频道的领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == ch.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	// 多查一条用来判断是否还有下一页
+	members, err := ch.s.store.GetSubscribersByOffset(req.ChannelId, req.ChannelType, req.OffsetId, req.Limit+1)
+	if err != nil {
+		ch.Error("获取订阅者失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取订阅者失败！"))
+		return
+	}
+	resp := subscriberListResp{
+		Subscribers: members,
+	}
+	if len(members) > req.Limit {
+		resp.Subscribers = members[:req.Limit]
+		resp.More = 1
+	}
+	if len(resp.Subscribers) > 0 {
+		resp.NextOffsetId = resp.Subscribers[len(resp.Subscribers)-1].Id
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 	var err error
 	existSubscribers := make([]string, 0)
@@ -287,6 +414,7 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 				Uid:       subscriber,
 				CreatedAt: &createdAt,
 				UpdatedAt: &updatedAt,
+				JoinedAt:  createdAt.Unix(),
 			})
 		}
 		err = ch.s.store.AddSubscribers(req.ChannelId, req.ChannelType, members)
@@ -884,6 +1012,19 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			return
 		}
 	}
+
+	// 用户清空聊天记录的位置，小于等于此位置的消息不返回（获取失败不影响消息同步）
+	var clearedToMsgSeq uint64
+	clearedToMsgSeqs, err := ch.s.getClearedToMsgSeqs(req.LoginUID, []wkdb.Channel{{ChannelId: fakeChannelID, ChannelType: req.ChannelType}})
+	if err != nil {
+		ch.Warn("获取清空聊天记录位置失败！", zap.Error(err), zap.String("loginUid", req.LoginUID), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	} else {
+		clearedToMsgSeq = clearedToMsgSeqs[0]
+	}
+	if req.PullMode == PullModeUp && req.StartMessageSeq != 0 && req.StartMessageSeq <= clearedToMsgSeq {
+		req.StartMessageSeq = clearedToMsgSeq + 1
+	}
+
 	if req.StartMessageSeq == 0 && req.EndMessageSeq == 0 {
 		messages, err = ch.s.store.LoadLastMsgs(fakeChannelID, req.ChannelType, limit)
 	} else if req.PullMode == PullModeUp { // 向上拉取
@@ -897,15 +1038,20 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 		return
 	}
 	messageResps := make([]*MessageResp, 0, len(messages))
+	hasCleared := false // 是否有消息被清空
 	if len(messages) > 0 {
 		for _, message := range messages {
+			if uint64(message.MessageSeq) <= clearedToMsgSeq {
+				hasCleared = true
+				continue
+			}
 			messageResp := &MessageResp{}
 			messageResp.from(message, ch.s)
 			messageResps = append(messageResps, messageResp)
 		}
 	}
 	var more bool = true // 是否有更多数据
-	if len(messageResps) < limit {
+	if len(messageResps) < limit || (hasCleared && req.PullMode == PullModeDown) {
 		more = false
 	}
 	if len(messageResps) > 0 {
@@ -926,11 +1072,20 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			}
 		}
 	}
+
+	// 频道的置顶消息（获取失败不影响消息同步）
+	pins, err := ch.s.getPinnedMessages(fakeChannelID, req.ChannelType)
+	if err != nil {
+		ch.Warn("获取置顶消息失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+	fillPinsChannelId(pins, req.ChannelID)
+
 	c.JSON(http.StatusOK, syncMessageResp{
 		StartMessageSeq: req.StartMessageSeq,
 		EndMessageSeq:   req.EndMessageSeq,
 		More:            wkutil.BoolToInt(more),
 		Messages:        messageResps,
+		Pins:            pins,
 	})
 }
 
@@ -988,5 +1143,6 @@ func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
 			return err
 		}
 	}
+	ch.s.webhook.removeChannelWebhookCache(channelInfo.ChannelId, channelInfo.ChannelType)
 	return nil
 }
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 置顶消息
+func (ch *ChannelAPI) pin(c *wkhttp.Context) {
+	ch.handlePin(c, true)
+}
+
+// 取消置顶消息
+func (ch *ChannelAPI) unpin(c *wkhttp.Context) {
+	ch.handlePin(c, false)
+}
+
+// 置顶消息保存在频道所在的槽上，请求在频道的领导节点上处理，以便校验消息
+// 超过MaxPinnedMessages时由存储层移除最早置顶的消息
+func (ch *ChannelAPI) handlePin(c *wkhttp.Context, pin bool) {
+	var req channelPinReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ch.s.ctx, time.Second*5)
+	leaderInfo, err := ch.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	cancel()
+	if err != nil {
+		ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+		ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	// 非个人频道只有订阅者才能置顶
+	if req.ChannelType != wkproto.ChannelTypePerson {
+		isSubscriber, err := ch.s.store.ExistSubscriber(req.ChannelID, req.ChannelType, req.LoginUID)
+		if err != nil {
+			ch.Error("查询订阅者失败！", zap.Error(err), zap.String("channelID", req.ChannelID), zap.String("uid", req.LoginUID))
+			c.ResponseError(errors.New("查询订阅者失败！"))
+			return
+		}
+		if !isSubscriber {
+			c.ResponseError(errors.New("不是频道的订阅者！"))
+			return
+		}
+	}
+
+	pins, err := ch.s.getPinnedMessages(fakeChannelId, req.ChannelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取置顶消息失败！"))
+		return
+	}
+	var existPin *wkdb.PinnedMessage
+	for i := range pins {
+		if pins[i].MessageSeq == req.MessageSeq {
+			existPin = &pins[i]
+			break
+		}
+	}
+
+	messageId := req.MessageID
+	if pin {
+		if existPin != nil {
+			c.ResponseOK()
+			return
+		}
+		msg, err := ch.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				c.ResponseError(errors.New("消息不存在！"))
+				return
+			}
+			ch.Error("获取消息失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+			c.ResponseError(errors.New("获取消息失败！"))
+			return
+		}
+		if msg.MessageID != req.MessageID || msg.Modify != nil || msg.IsExpired(time.Now().Unix()) {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		if msg.Revoke {
+			c.ResponseError(errors.New("消息已撤回！"))
+			return
+		}
+		err = ch.s.store.AddPinnedMessage(wkdb.PinnedMessage{
+			ChannelId:   fakeChannelId,
+			ChannelType: req.ChannelType,
+			MessageSeq:  req.MessageSeq,
+			MessageId:   req.MessageID,
+			PinnedBy:    req.LoginUID,
+			PinnedAt:    time.Now().Unix(),
+		})
+	} else {
+		if existPin == nil {
+			c.ResponseOK()
+			return
+		}
+		messageId = existPin.MessageId
+		err = ch.s.store.RemovePinnedMessage(fakeChannelId, req.ChannelType, req.MessageSeq)
+	}
+	if err != nil {
+		ch.Error("更新置顶消息失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("更新置顶消息失败！"))
+		return
+	}
+
+	ch.notifyPin(req, fakeChannelId, messageId, pin)
+	c.ResponseOK()
+}
+
+// 通知在线的订阅者置顶消息发生变化
+func (ch *ChannelAPI) notifyPin(req channelPinReq, fakeChannelId string, messageId int64, pin bool) {
+	action := "pin"
+	if !pin {
+		action = "unpin"
+	}
+	param := map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"message_id":   messageId,
+		"message_seq":  req.MessageSeq,
+		"operator":     req.LoginUID,
+		"action":       action,
+	}
+	var err error
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		// 个人频道双方看到的频道ID不同，分别给双方发送
+		err = sendPersonCMD(ch.s, fakeChannelId, "channelPinUpdate", param)
+	} else {
+		_, err = sendMessageToChannel(ch.s, MessageSendReq{
+			Header: MessageHeader{
+				NoPersist: 1,
+				SyncOnce:  1,
+			},
+			FromUID:     ch.s.opts.SystemUID,
+			ChannelID:   req.ChannelID,
+			ChannelType: req.ChannelType,
+			Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+				"cmd":   "channelPinUpdate",
+				"param": param,
+			})),
+		}, req.ChannelID, req.ChannelType, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+	}
+	if err != nil {
+		ch.Warn("通知置顶消息变化失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+	}
+}
+
+// 获取频道基础信息和置顶消息
+func (ch *ChannelAPI) channelInfoGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	loginUid := c.Query("login_uid")
+
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if channelType == wkproto.ChannelTypePerson && strings.TrimSpace(loginUid) == "" {
+		c.ResponseError(errors.New("个人频道login_uid不能为空！"))
+		return
+	}
+
+	if ch.s.opts.ClusterOn() {
+		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(channelId, channelType) // 获取频道的领导节点
+		if err != nil {
+			ch.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != ch.s.opts.Cluster.NodeId {
+			ch.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), nil)
+			return
+		}
+	}
+
+	channelInfo, err := ch.s.store.GetChannel(channelId, channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		ch.Error("获取频道信息失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道信息失败！"))
+		return
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) {
+		channelInfo = wkdb.NewChannelInfo(channelId, channelType)
+	}
+
+	fakeChannelId := channelId
+	if channelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(loginUid, channelId)
+	}
+	pins, err := ch.s.getPinnedMessages(fakeChannelId, channelType)
+	if err != nil {
+		ch.Error("获取置顶消息失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取置顶消息失败！"))
+		return
+	}
+	fillPinsChannelId(pins, channelId)
+
+	c.JSON(http.StatusOK, &channelInfoResp{
+		ChannelInfo: channelInfo,
+		Pins:        pins,
+	})
+}
+
+// 返回给客户端的置顶消息使用客户端视角的频道
+func fillPinsChannelId(pins []wkdb.PinnedMessage, channelId string) {
+	for i := range pins {
+		pins[i].ChannelId = channelId
+	}
+}
+
+// 获取频道的置顶消息，置顶消息保存在频道所在槽的节点上
+func (s *Server) getPinnedMessages(channelId string, channelType uint8) ([]wkdb.PinnedMessage, error) {
+	leaderNode, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode.Id == s.opts.Cluster.NodeId {
+		return s.store.GetPinnedMessages(channelId, channelType)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &channelReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+	}
+	bodyBytes, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/getPinnedMessages", bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("getPinnedMessages failed, status: %d body: %s", resp.Status, string(resp.Body))
+	}
+	pins := make([]wkdb.PinnedMessage, 0)
+	if err = json.Unmarshal(resp.Body, &pins); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/sendgrid/rest"
@@ -36,11 +38,13 @@ func NewConversationAPI(s *Server) *ConversationAPI {
 // Route 路由
 func (s *ConversationAPI) Route(r *wkhttp.WKHttp) {
 	// r.GET("/conversations", s.conversationsList)                    // 获取会话列表 （此接口作废，使用/conversation/sync）
-	r.POST("/conversations/clearUnread", s.clearConversationUnread) // 清空会话未读数量
-	r.POST("/conversations/setUnread", s.setConversationUnread)     // 设置会话未读数量
-	r.POST("/conversations/delete", s.deleteConversation)           // 删除会话
-	r.POST("/conversation/sync", s.syncUserConversation)            // 同步会话
-	r.POST("/conversation/syncMessages", s.syncRecentMessages)      // 同步会话最近消息
+	r.POST("/conversations/clearUnread", s.clearConversationUnread)   // 清空会话未读数量
+	r.POST("/conversations/setUnread", s.setConversationUnread)       // 设置会话未读数量
+	r.POST("/conversations/delete", s.deleteConversation)             // 删除会话
+	r.POST("/conversations/clearHistory", s.clearConversationHistory) // 清空会话聊天记录（只对当前用户生效）
+	r.POST("/conversations/setAttrs", s.setConversationAttrs)         // 设置会话的置顶、免打扰、分组和草稿
+	r.POST("/conversation/sync", s.syncUserConversation)              // 同步会话
+	r.POST("/conversation/syncMessages", s.syncRecentMessages)        // 同步会话最近消息
 }
 
 // // Get a list of recent conversations
@@ -157,12 +161,15 @@ func (s *ConversationAPI) clearConversationUnread(c *wkhttp.Context) {
 
 	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
 
+	s.s.notifyConversationReadState(req.UID, req.DeviceId, fakeChannelId, req.ChannelType, conversation.ReadToMsgSeq, 0)
+
 	c.ResponseOK()
 }
 
 func (s *ConversationAPI) setConversationUnread(c *wkhttp.Context) {
 	var req struct {
 		UID         string `json:"uid"`
+		DeviceId    string `json:"device_id"` // 发起操作的设备，不会收到已读状态同步通知
 		ChannelID   string `json:"channel_id"`
 		ChannelType uint8  `json:"channel_type"`
 		Unread      int    `json:"unread"`
@@ -255,6 +262,8 @@ func (s *ConversationAPI) setConversationUnread(c *wkhttp.Context) {
 
 	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
 
+	s.s.notifyConversationReadState(req.UID, req.DeviceId, fakeChannelId, req.ChannelType, readedMsgSeq, unread)
+
 	c.ResponseOK()
 }
 
@@ -304,12 +313,226 @@ func (s *ConversationAPI) deleteConversation(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 会话草稿的最大长度
+const maxConversationDraftLen = 4096
+
+// 设置会话属性，属性保存在用户所在的槽，每次修改都会递增用户的会话版本
+func (s *ConversationAPI) setConversationAttrs(c *wkhttp.Context) {
+	var req setConversationAttrsReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	err = s.s.store.UpdateConversationAttrs(req.UID, wkdb.ConversationAttrs{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		Pinned:      req.Pinned,
+		MutedUntil:  req.MutedUntil,
+		Folder:      req.Folder,
+		Draft:       req.Draft,
+	})
+	if err != nil {
+		s.Error("修改会话属性失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("修改会话属性失败！"))
+		return
+	}
+	version, err := s.s.store.GetConversationVersion(req.UID)
+	if err != nil {
+		s.Error("获取会话版本失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("获取会话版本失败！"))
+		return
+	}
+
+	s.notifyConversationAttrsUpdated(req, version)
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"conversation_version": version,
+	})
+}
+
+// 通过cmd消息通知用户的所有设备会话属性已修改（不存储）
+func (s *ConversationAPI) notifyConversationAttrsUpdated(req setConversationAttrsReq, version uint64) {
+	param := map[string]interface{}{
+		"channel_id":           req.ChannelID,
+		"channel_type":         req.ChannelType,
+		"conversation_version": version,
+	}
+	if req.Pinned != nil {
+		param["pinned"] = *req.Pinned
+	}
+	if req.MutedUntil != nil {
+		param["muted_until"] = *req.MutedUntil
+	}
+	if req.Folder != nil {
+		param["folder"] = *req.Folder
+	}
+	if req.Draft != nil {
+		param["draft"] = *req.Draft
+	}
+	_, err := sendMessageToChannel(s.s, MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     s.s.opts.SystemUID,
+		ChannelID:   req.UID,
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd":   "conversationAttrsUpdate",
+			"param": param,
+		})),
+	}, req.UID, wkproto.ChannelTypePerson, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+	if err != nil {
+		s.Warn("通知会话属性修改失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+}
+
+// 清空会话的聊天记录，只记录用户的清空位置，频道里的消息不会被删除
+func (s *ConversationAPI) clearConversationHistory(c *wkhttp.Context) {
+	var req clearConversationHistoryReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(req.UID, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", req.UID), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		leaderIsSelf := leaderInfo.Id == s.s.opts.Cluster.NodeId
+		if !leaderIsSelf {
+			s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
+	}
+
+	clearedToMsgSeq := req.MessageSeq
+	if clearedToMsgSeq == 0 {
+		// 获取此频道最新的消息
+		clearedToMsgSeq, err = s.s.store.GetLastMsgSeq(fakeChannelId, req.ChannelType)
+		if err != nil {
+			s.Error("Failed to query last message", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+	}
+
+	conversation, err := s.s.store.GetConversation(req.UID, fakeChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		s.Error("Failed to query conversation", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if wkdb.IsEmptyConversation(conversation) {
+		createdAt := time.Now()
+		updatedAt := time.Now()
+		conversation = wkdb.Conversation{
+			Uid:         req.UID,
+			ChannelId:   fakeChannelId,
+			ChannelType: req.ChannelType,
+			CreatedAt:   &createdAt,
+			UpdatedAt:   &updatedAt,
+		}
+	}
+	if conversation.ClearedToMsgSeq >= clearedToMsgSeq {
+		c.ResponseOK()
+		return
+	}
+	conversation.ClearedToMsgSeq = clearedToMsgSeq
+	// 清空的消息视为已读
+	if conversation.ReadToMsgSeq < clearedToMsgSeq {
+		conversation.ReadToMsgSeq = clearedToMsgSeq
+		conversation.UnreadCount = 0
+	}
+
+	err = s.s.store.AddOrUpdateConversations(req.UID, []wkdb.Conversation{conversation})
+	if err != nil {
+		s.Error("Failed to add conversation", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	s.s.conversationManager.DeleteUserConversationFromCache(req.UID, fakeChannelId, req.ChannelType)
+
+	s.notifyConversationHistoryCleared(req, clearedToMsgSeq)
+
+	c.ResponseOK()
+}
+
+// 通过cmd消息通知用户的所有设备聊天记录已清空（不存储）
+func (s *ConversationAPI) notifyConversationHistoryCleared(req clearConversationHistoryReq, clearedToMsgSeq uint64) {
+	_, err := sendMessageToChannel(s.s, MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     s.s.opts.SystemUID,
+		ChannelID:   req.UID,
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd": "conversationHistoryCleared",
+			"param": map[string]interface{}{
+				"channel_id":         req.ChannelID,
+				"channel_type":       req.ChannelType,
+				"cleared_to_msg_seq": clearedToMsgSeq,
+			},
+		})),
+	}, req.UID, wkproto.ChannelTypePerson, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+	if err != nil {
+		s.Warn("通知清空聊天记录失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+}
+
 func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 	var req struct {
 		UID         string `json:"uid"`
 		Version     int64  `json:"version"`       // 当前客户端的会话最大版本号(客户端最新会话的时间戳)
 		LastMsgSeqs string `json:"last_msg_seqs"` // 客户端所有会话的最后一条消息序列号 格式： channelID:channelType:last_msg_seq|channelID:channelType:last_msg_seq
 		MsgCount    int64  `json:"msg_count"`     // 每个会话消息数量
+
+		ConversationVersion uint64 `json:"conversation_version"` // 客户端已同步到的会话版本，会话属性在此版本之后修改过的会话即使没有新消息也会返回
 	}
 	bodyBytes, err := BindJSON(&req, c)
 	if err != nil {
@@ -351,6 +574,37 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 		return
 	}
 
+	// 属性有变更的会话可能不在活跃的最近会话里
+	conversationVersion, err := s.s.store.GetConversationVersion(req.UID)
+	if err != nil {
+		s.Error("获取会话版本失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("获取会话版本失败！"))
+		return
+	}
+	if conversationVersion > req.ConversationVersion {
+		allConversations, err := s.s.store.GetConversationsByType(req.UID, wkdb.ConversationTypeChat)
+		if err != nil {
+			s.Error("获取conversation失败！", zap.Error(err), zap.String("uid", req.UID))
+			c.ResponseError(errors.New("获取conversation失败！"))
+			return
+		}
+		for _, conversation := range allConversations {
+			if conversation.Version <= req.ConversationVersion {
+				continue
+			}
+			exist := false
+			for _, cn := range conversations {
+				if cn.ChannelId == conversation.ChannelId && cn.ChannelType == conversation.ChannelType {
+					exist = true
+					break
+				}
+			}
+			if !exist {
+				conversations = append(conversations, conversation)
+			}
+		}
+	}
+
 	// 获取用户缓存的最近会话
 	cacheConversations := s.s.conversationManager.GetUserConversationFromCache(req.UID, wkdb.ConversationTypeChat)
 
@@ -396,9 +650,10 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 		}
 
 		channelRecentMessageReqs = append(channelRecentMessageReqs, &channelRecentMessageReq{
-			ChannelId:   conversation.ChannelId,
-			ChannelType: conversation.ChannelType,
-			LastMsgSeq:  msgSeq,
+			ChannelId:       conversation.ChannelId,
+			ChannelType:     conversation.ChannelType,
+			LastMsgSeq:      msgSeq,
+			ClearedToMsgSeq: conversation.ClearedToMsgSeq,
 		})
 		// syncUserConversationR := newSyncUserConversationResp(conversation)
 		// resps = append(resps, syncUserConversationR)
@@ -423,6 +678,8 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 				continue
 			}
 			resp := newSyncUserConversationResp(conversation)
+			resp.ConversationVersion = conversationVersion
+			attrChanged := conversation.Version > req.ConversationVersion
 
 			for _, channelRecentMessage := range channelRecentMessages {
 				if conversation.ChannelId == channelRecentMessage.ChannelId && conversation.ChannelType == channelRecentMessage.ChannelType {
@@ -445,11 +702,11 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 
 			msgSeq := channelLastMsgMap[fmt.Sprintf("%s-%d", conversation.ChannelId, conversation.ChannelType)]
 
-			if msgSeq != 0 && msgSeq >= uint64(resp.LastMsgSeq) {
+			if msgSeq != 0 && msgSeq >= uint64(resp.LastMsgSeq) && !attrChanged {
 				continue
 			}
 
-			if len(resp.Recents) > 0 {
+			if len(resp.Recents) > 0 || attrChanged {
 				resps = append(resps, resp)
 			}
 		}
@@ -490,6 +747,9 @@ func (s *ConversationAPI) syncRecentMessages(c *wkhttp.Context) {
 	if msgCount <= 0 {
 		msgCount = 15
 	}
+	if err := s.s.fillClearedToMsgSeqs(req.UID, req.Channels); err != nil { // 获取失败不影响消息同步
+		s.Warn("获取清空聊天记录位置失败！", zap.Error(err), zap.String("uid", req.UID))
+	}
 	channelRecentMessages, err := s.s.getRecentMessages(req.UID, msgCount, req.Channels, wkutil.IntToBool(req.OrderByLast))
 	if err != nil {
 		s.Error("获取最近消息失败！", zap.Error(err))
@@ -626,6 +886,9 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 				}
 				if len(recentMessages) > 0 {
 					for _, recentMessage := range recentMessages {
+						if uint64(recentMessage.MessageSeq) <= channel.ClearedToMsgSeq {
+							continue
+						}
 						messageResp := &MessageResp{}
 						messageResp.from(recentMessage, s)
 						messageResps = append(messageResps, messageResp)
@@ -640,6 +903,9 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 				}
 				if len(recentMessages) > 0 {
 					for _, recentMessage := range recentMessages {
+						if uint64(recentMessage.MessageSeq) <= channel.ClearedToMsgSeq {
+							continue
+						}
 						messageResp := &MessageResp{}
 						messageResp.from(recentMessage, s)
 						messageResps = append(messageResps, messageResp)
@@ -656,3 +922,154 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 	}
 	return channelRecentMessages, nil
 }
+
+// fillClearedToMsgSeqs 填充用户在这些频道里清空聊天记录的位置
+func (s *Server) fillClearedToMsgSeqs(uid string, channels []*channelRecentMessageReq) error {
+	if uid == "" || len(channels) == 0 {
+		return nil
+	}
+	reqChannels := make([]wkdb.Channel, 0, len(channels))
+	for _, channel := range channels {
+		reqChannels = append(reqChannels, wkdb.Channel{
+			ChannelId:   channel.ChannelId,
+			ChannelType: channel.ChannelType,
+		})
+	}
+	seqs, err := s.getClearedToMsgSeqs(uid, reqChannels)
+	if err != nil {
+		return err
+	}
+	for i, channel := range channels {
+		if seqs[i] > channel.ClearedToMsgSeq {
+			channel.ClearedToMsgSeq = seqs[i]
+		}
+	}
+	return nil
+}
+
+// getClearedToMsgSeqs 获取用户在频道里清空聊天记录的位置，结果与channels一一对应
+// 最近会话存储在用户所在的槽上，如果当前节点不是槽领导则去槽领导节点获取
+func (s *Server) getClearedToMsgSeqs(uid string, channels []wkdb.Channel) ([]uint64, error) {
+	leaderNode, err := s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode.Id == s.opts.Cluster.NodeId {
+		return s.getClearedToMsgSeqsFromLocal(uid, channels)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &clearedToMsgSeqReq{
+		Uid:      uid,
+		Channels: channels,
+	}
+	bodyBytes, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/getClearedToMsgSeqs", bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("getClearedToMsgSeqs failed, status: %d body: %s", resp.Status, string(resp.Body))
+	}
+	dec := wkproto.NewDecoder(resp.Body)
+	seqs := make([]uint64, len(channels))
+	for i := range seqs {
+		if seqs[i], err = dec.Uint64(); err != nil {
+			return nil, err
+		}
+	}
+	return seqs, nil
+}
+
+func (s *Server) getClearedToMsgSeqsFromLocal(uid string, channels []wkdb.Channel) ([]uint64, error) {
+	seqs := make([]uint64, len(channels))
+	for i, channel := range channels {
+		conversation, err := s.store.GetConversation(uid, channel.ChannelId, channel.ChannelType)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		seqs[i] = conversation.ClearedToMsgSeq
+	}
+	return seqs, nil
+}
+
+// 获取uids中对频道开启了免打扰的用户，会话保存在用户所在的槽，按槽的领导节点分组查询
+func (s *Server) getMutedUids(channelId string, channelType uint8, uids []string) ([]string, error) {
+	uidsOfNode := make(map[uint64][]string)
+	for _, uid := range uids {
+		leaderNode, err := s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			return nil, err
+		}
+		uidsOfNode[leaderNode.Id] = append(uidsOfNode[leaderNode.Id], uid)
+	}
+
+	mutedUids := make([]string, 0)
+	for nodeId, nodeUids := range uidsOfNode {
+		if nodeId == s.opts.Cluster.NodeId {
+			localMutedUids, err := s.getMutedUidsFromLocal(channelId, channelType, nodeUids)
+			if err != nil {
+				return nil, err
+			}
+			mutedUids = append(mutedUids, localMutedUids...)
+			continue
+		}
+		req := &mutedUidsReq{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			Uids:        nodeUids,
+		}
+		bodyBytes, err := req.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+		resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/getMutedUids", bodyBytes)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status != proto.Status_OK {
+			return nil, fmt.Errorf("getMutedUids failed, status: %d body: %s", resp.Status, string(resp.Body))
+		}
+		dec := wkproto.NewDecoder(resp.Body)
+		count, err := dec.Uint32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			uid, err := dec.String()
+			if err != nil {
+				return nil, err
+			}
+			mutedUids = append(mutedUids, uid)
+		}
+	}
+	return mutedUids, nil
+}
+
+func (s *Server) getMutedUidsFromLocal(channelId string, channelType uint8, uids []string) ([]string, error) {
+	now := time.Now().Unix()
+	mutedUids := make([]string, 0)
+	for _, uid := range uids {
+		conversation, err := s.store.GetConversation(uid, channelId, channelType)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if conversation.IsMuted(now) {
+			mutedUids = append(mutedUids, uid)
+		}
+	}
+	return mutedUids, nil
+}
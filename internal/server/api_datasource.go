@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// DatasourceAPI 数据源相关API
+type DatasourceAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewDatasourceAPI NewDatasourceAPI
+func NewDatasourceAPI(s *Server) *DatasourceAPI {
+	return &DatasourceAPI{
+		s:   s,
+		Log: wklog.NewWKLog("DatasourceAPI"),
+	}
+}
+
+// Route route
+func (d *DatasourceAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/datasource/invalidate", d.invalidate)            // 让数据源缓存失效（所有节点）
+	r.POST("/datasource/invalidate_local", d.invalidateLocal) // 仅仅让当前节点的数据源缓存失效
+}
+
+type datasourceInvalidateReq struct {
+	Channels []struct {
+		ChannelId   string `json:"channel_id"`
+		ChannelType uint8  `json:"channel_type"`
+	} `json:"channels"` // 需要失效的频道（订阅者、黑白名单、频道信息）
+	SystemUIDs bool `json:"system_uids"` // 是否让系统账号失效
+	All        bool `json:"all"`         // 是否让所有缓存失效
+}
+
+func (r datasourceInvalidateReq) check() error {
+	if !r.All && !r.SystemUIDs && len(r.Channels) == 0 {
+		return errors.New("channels、system_uids、all不能都为空！")
+	}
+	for _, channel := range r.Channels {
+		if strings.TrimSpace(channel.ChannelId) == "" {
+			return errors.New("channel_id不能为空！")
+		}
+		if channel.ChannelType == 0 {
+			return errors.New("channel_type不能为0！")
+		}
+	}
+	return nil
+}
+
+// 让数据源缓存失效，并通知其他在线节点
+func (d *DatasourceAPI) invalidate(c *wkhttp.Context) {
+	var req datasourceInvalidateReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		d.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	d.invalidateCache(req)
+
+	nodes := d.s.clusterServer.GetConfig().Nodes
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), d.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, ctx := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == d.s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				return d.requestInvalidateLocal(ctx, n, bodyBytes)
+			}
+		}(node))
+	}
+	if err := requestGroup.Wait(); err != nil {
+		d.Error("通知节点数据源缓存失效失败！", zap.Error(err))
+		c.ResponseError(errors.New("通知节点数据源缓存失效失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (d *DatasourceAPI) invalidateLocal(c *wkhttp.Context) {
+	var req datasourceInvalidateReq
+	if err := c.BindJSON(&req); err != nil {
+		d.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	d.invalidateCache(req)
+	c.ResponseOK()
+}
+
+func (d *DatasourceAPI) invalidateCache(req datasourceInvalidateReq) {
+	if cache := d.s.datasourceCache; cache != nil {
+		if req.All {
+			cache.invalidateAll()
+		} else {
+			if req.SystemUIDs {
+				cache.invalidateSystemUIDs()
+			}
+			for _, channel := range req.Channels {
+				cache.invalidateChannel(channel.ChannelId, channel.ChannelType)
+			}
+		}
+	}
+	// 订阅者以数据源为准时，接收者tag也要释放，下次投递时按新的订阅者重新生成
+	if d.s.opts.HasDatasource() {
+		if req.All {
+			for _, sub := range d.s.channelReactor.subs {
+				sub.channelQueue.iter(func(ch *channel) {
+					ch.releaseReceiverTag()
+				})
+			}
+		} else {
+			for _, channel := range req.Channels {
+				d.releaseReceiverTag(channel.ChannelId, channel.ChannelType)
+				d.releaseReceiverTag(d.s.opts.OrginalConvertCmdChannel(channel.ChannelId), channel.ChannelType)
+			}
+		}
+	}
+	// 系统账号管理有自己的缓存，需要在数据源缓存失效后再重置，否则可能重新加载到旧数据
+	if (req.All || req.SystemUIDs) && d.s.opts.HasDatasource() {
+		d.s.systemUIDManager.Invalidate()
+	}
+}
+
+// releaseReceiverTag 释放频道的接收者tag，频道不在当前节点则忽略
+func (d *DatasourceAPI) releaseReceiverTag(channelId string, channelType uint8) {
+	key := wkutil.ChannelToKey(channelId, channelType)
+	if ch := d.s.channelReactor.reactorSub(key).channel(key); ch != nil {
+		ch.releaseReceiverTag()
+	}
+}
+
+func (d *DatasourceAPI) requestInvalidateLocal(ctx context.Context, nodeInfo *pb.Node, bodyBytes []byte) error {
+	reqURL := fmt.Sprintf("%s/datasource/invalidate_local", nodeInfo.ApiServerAddr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		d.Error("通知节点数据源缓存失效失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("通知节点数据源缓存失效请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
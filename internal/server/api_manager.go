@@ -3,6 +3,7 @@ package server
 import (
 	"errors"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
@@ -28,7 +29,17 @@ func NewManagerAPI(s *Server) *ManagerAPI {
 // Route Route
 func (m *ManagerAPI) Route(r *wkhttp.WKHttp) {
 
-	r.POST("/manager/login", m.login) // 登录
+	r.POST("/manager/login", m.login)   // 登录
+	r.POST("/manager/backup", m.backup) // 备份当前节点数据
+
+	r.POST("/manager/export", m.export)             // 导出当前节点数据
+	r.GET("/manager/export/status", m.exportStatus) // 导出状态
+	r.POST("/manager/import", m.importData)         // 导入数据
+	r.GET("/manager/import/status", m.importStatus) // 导入状态
+
+	r.GET("/manager/webhook/deadletters", m.webhookDeadLetters)               // 查询当前节点的webhook死信
+	r.POST("/manager/webhook/deadletters/replay", m.webhookDeadLettersReplay) // 重新推送webhook死信
+	r.POST("/manager/webhook/deadletters/purge", m.webhookDeadLettersPurge)   // 清除webhook死信
 }
 
 func (m *ManagerAPI) login(c *wkhttp.Context) {
@@ -92,3 +103,78 @@ func (m *ManagerAPI) login(c *wkhttp.Context) {
 	})
 
 }
+
+func (m *ManagerAPI) backup(c *wkhttp.Context) {
+	var req struct {
+		Dir string `json:"dir"` // 备份目录，不填则备份到数据目录下的backup目录
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	dir := strings.TrimSpace(req.Dir)
+	if dir == "" {
+		dir = path.Join(m.s.opts.DataDir, "backup", time.Now().Format("20060102150405"))
+	}
+	manifest, err := m.s.Backup(dir)
+	if err != nil {
+		m.Error("备份失败！", zap.Error(err), zap.String("dir", dir))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dir":      dir,
+		"manifest": manifest,
+	})
+}
+
+func (m *ManagerAPI) export(c *wkhttp.Context) {
+	var req struct {
+		Dir string `json:"dir"` // 导出目录，不填则导出到数据目录下的export目录
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	dir := strings.TrimSpace(req.Dir)
+	if dir == "" {
+		dir = path.Join(m.s.opts.DataDir, "export", time.Now().Format("20060102150405"))
+	}
+	if err := m.s.exportTask.Start(dir); err != nil {
+		m.Error("导出失败！", zap.Error(err), zap.String("dir", dir))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dir": dir,
+	})
+}
+
+func (m *ManagerAPI) exportStatus(c *wkhttp.Context) {
+	c.JSON(http.StatusOK, m.s.exportTask.Result())
+}
+
+func (m *ManagerAPI) importData(c *wkhttp.Context) {
+	var req struct {
+		Dir string `json:"dir"` // 导出文件所在目录
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	dir := strings.TrimSpace(req.Dir)
+	if dir == "" {
+		c.ResponseError(errors.New("dir不能为空"))
+		return
+	}
+	if err := m.s.importTask.Start(dir); err != nil {
+		m.Error("导入失败！", zap.Error(err), zap.String("dir", dir))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+func (m *ManagerAPI) importStatus(c *wkhttp.Context) {
+	c.JSON(http.StatusOK, m.s.importTask.Result())
+}
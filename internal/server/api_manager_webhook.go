@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"go.uber.org/zap"
+)
+
+const webhookDeadLetterMaxLimit = 1000 // 每次最多查询或重放的死信数量
+
+// 查询当前节点的webhook死信
+func (m *ManagerAPI) webhookDeadLetters(c *wkhttp.Context) {
+	startId, _ := strconv.ParseUint(c.Query("start_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > webhookDeadLetterMaxLimit {
+		limit = 100
+	}
+	deadLetters, err := m.s.store.DB().GetWebhookDeadLetters(startId, limit)
+	if err != nil {
+		m.Error("获取webhook死信失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*webhookDeadLetterResp, 0, len(deadLetters))
+	for _, deadLetter := range deadLetters {
+		resps = append(resps, newWebhookDeadLetterResp(deadLetter))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 重新推送当前节点的webhook死信，推送成功的从死信队列移除
+func (m *ManagerAPI) webhookDeadLettersReplay(c *wkhttp.Context) {
+	var req webhookDeadLetterReq
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if len(req.Ids) == 0 {
+		c.ResponseError(errors.New("ids不能为空！"))
+		return
+	}
+	if len(req.Ids) > webhookDeadLetterMaxLimit {
+		c.ResponseError(errors.New("ids数量不能超过1000！"))
+		return
+	}
+	results := make([]*webhookReplayResult, 0, len(req.Ids))
+	for _, id := range req.Ids {
+		results = append(results, m.s.webhook.replayDeadLetter(id))
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// 清除当前节点的webhook死信，ids为空时清空全部
+func (m *ManagerAPI) webhookDeadLettersPurge(c *wkhttp.Context) {
+	var req webhookDeadLetterReq
+	if err := c.BindJSON(&req); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	var err error
+	if len(req.Ids) == 0 {
+		err = m.s.store.DB().RemoveAllWebhookDeadLetters()
+	} else {
+		err = m.s.store.DB().RemoveWebhookDeadLetters(req.Ids)
+	}
+	if err != nil {
+		m.Error("清除webhook死信失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// replayDeadLetter 重新推送死信，成功则移除，失败则更新重试次数和失败原因
+func (w *webhook) replayDeadLetter(id uint64) *webhookReplayResult {
+	result := &webhookReplayResult{Id: id}
+	deadLetter, err := w.s.store.DB().GetWebhookDeadLetter(id)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	target, err := w.deadLetterTarget(deadLetter)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err = w.sendWebhook(target, deadLetter.Event, deadLetter.Data); err != nil {
+		deadLetter.RetryCount++
+		deadLetter.Error = err.Error()
+		if err := w.s.store.DB().AddWebhookDeadLetter(deadLetter); err != nil {
+			w.Error("更新webhook死信失败！", zap.Error(err), zap.Uint64("id", id))
+		}
+		result.Error = err.Error()
+		return result
+	}
+	if err = w.s.store.DB().RemoveWebhookDeadLetters([]uint64{id}); err != nil {
+		w.Warn("移除webhook死信失败！", zap.Error(err), zap.Uint64("id", id))
+	}
+	result.Success = true
+	return result
+}
+
+// deadLetterTarget 死信原来的推送目标
+func (w *webhook) deadLetterTarget(deadLetter wkdb.WebhookDeadLetter) (webhookTarget, error) {
+	if deadLetter.SubscriptionId != 0 {
+		w.subscriptionLock.RLock()
+		defer w.subscriptionLock.RUnlock()
+		for _, subscription := range w.subscriptions {
+			if subscription.Id == deadLetter.SubscriptionId {
+				return webhookTarget{
+					subscriptionId: subscription.Id,
+					httpAddr:       subscription.URL,
+					grpcAddr:       subscription.GRPCAddr,
+					secret:         subscription.Secret,
+				}, nil
+			}
+		}
+		return webhookTarget{}, errors.New("webhook订阅不存在！")
+	}
+	if deadLetter.Target == EventSinkFile {
+		return webhookTarget{isFile: true}, nil
+	}
+	if deadLetter.Target != "" {
+		return webhookTarget{
+			httpAddr:  deadLetter.Target,
+			secret:    w.s.opts.Webhook.Secret,
+			isChannel: true,
+		}, nil
+	}
+	target, ok := w.globalTarget()
+	if !ok {
+		return webhookTarget{}, errors.New("没有配置webhook！")
+	}
+	return target, nil
+}
+
+type webhookDeadLetterReq struct {
+	Ids []uint64 `json:"ids"`
+}
+
+type webhookDeadLetterResp struct {
+	Id         uint64          `json:"id"`
+	IdStr      string          `json:"id_str"`
+	Event      string          `json:"event"`       // 事件类型
+	Data       json.RawMessage `json:"data"`        // 事件数据
+	RetryCount uint32          `json:"retry_count"` // 已重试次数
+	Error      string          `json:"error"`       // 最后一次推送失败的原因
+	CreatedAt  int64           `json:"created_at"`  // 进入死信队列的时间（单位秒）
+	// 推送目标，都为空表示全局配置的webhook
+	SubscriptionId uint64 `json:"subscription_id,omitempty"` // webhook订阅id
+	Target         string `json:"target,omitempty"`          // 频道webhook地址
+}
+
+func newWebhookDeadLetterResp(deadLetter wkdb.WebhookDeadLetter) *webhookDeadLetterResp {
+	return &webhookDeadLetterResp{
+		Id:         deadLetter.Id,
+		IdStr:      strconv.FormatUint(deadLetter.Id, 10),
+		Event:      deadLetter.Event,
+		Data:       json.RawMessage(deadLetter.Data),
+		RetryCount: deadLetter.RetryCount,
+		Error:      deadLetter.Error,
+		CreatedAt:  deadLetter.CreatedAt,
+
+		SubscriptionId: deadLetter.SubscriptionId,
+		Target:         deadLetter.Target,
+	}
+}
+
+type webhookReplayResult struct {
+	Id      uint64 `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
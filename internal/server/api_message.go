@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
@@ -49,6 +50,24 @@ func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/message", m.searchMessage) // 搜索单条消息
 
+	r.POST("/messages/search", m.keywordSearch) // 按关键字搜索频道消息
+
+	r.POST("/message/revoke", m.revoke) // 撤回消息
+	r.POST("/message/edit", m.edit)     // 编辑消息
+
+	r.POST("/message/reaction/add", m.reactionAdd)       // 添加消息回应
+	r.POST("/message/reaction/remove", m.reactionRemove) // 取消消息回应
+	r.POST("/message/reaction/sync", m.reactionSync)     // 增量同步频道的消息回应
+
+	r.POST("/message/thread/sync", m.threadSync)       // 同步父消息的回复
+	r.POST("/message/thread/summary", m.threadSummary) // 获取父消息的回复数量
+
+	r.POST("/message/receipt", m.receipt)   // 上报已读回执
+	r.POST("/message/receipts", m.receipts) // 查询消息的已读用户
+
+	r.POST("/message/scheduled", m.scheduledList)          // 查询频道待发送的定时消息
+	r.POST("/message/scheduled/cancel", m.scheduledCancel) // 取消定时消息
+
 }
 
 func (m *MessageAPI) send(c *wkhttp.Context) {
@@ -120,6 +139,12 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
 	}
 
+	// 定时消息
+	if req.SendAt > time.Now().Unix() {
+		m.schedule(c, req, clientMsgNo)
+		return
+	}
+
 	// 发送消息
 	messageId, err := sendMessageToChannel(m.s, req, channelId, channelType, clientMsgNo, wkproto.StreamFlagIng)
 	if err != nil {
@@ -200,19 +225,27 @@ func sendMessageToChannel(s *Server, req MessageSendReq, channelId string, chann
 	// 将消息提交到频道
 	messageId := s.channelReactor.messageIDGen.Generate().Int64()
 	systemDeviceId := s.opts.SystemDeviceId
-	err := channel.proposeSend(messageId, req.FromUID, systemDeviceId, SystemConnId, s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
-		Framer: wkproto.Framer{
-			RedDot:    wkutil.IntToBool(req.Header.RedDot),
-			SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
-			NoPersist: wkutil.IntToBool(req.Header.NoPersist),
+	err := channel.proposeMessage(ReactorChannelMessage{
+		FromConnId:   SystemConnId,
+		FromUid:      req.FromUID,
+		FromDeviceId: systemDeviceId,
+		FromNodeId:   s.opts.Cluster.NodeId,
+		MessageId:    messageId,
+		IsEphemeral:  req.Ephemeral == 1, // 临时事件由服务端标记，不占用消息的setting位
+		SendPacket: &wkproto.SendPacket{
+			Framer: wkproto.Framer{
+				RedDot:    wkutil.IntToBool(req.Header.RedDot),
+				SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
+				NoPersist: wkutil.IntToBool(req.Header.NoPersist),
+			},
+			Setting:     setting,
+			Expire:      req.Expire,
+			StreamNo:    req.StreamNo,
+			ClientMsgNo: clientMsgNo,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			Payload:     req.Payload,
 		},
-		Setting:     setting,
-		Expire:      req.Expire,
-		StreamNo:    req.StreamNo,
-		ClientMsgNo: clientMsgNo,
-		ChannelID:   channelId,
-		ChannelType: channelType,
-		Payload:     req.Payload,
 	})
 	if err != nil {
 		return messageId, err
@@ -221,6 +254,48 @@ func sendMessageToChannel(s *Server, req MessageSendReq, channelId string, chann
 	return messageId, nil
 }
 
+// sendPersonCMD 以系统账号分别给个人频道的两个参与者发送命令消息
+// 个人频道在双方视角下的频道ID不同（都是对方的uid），所以param里的channel_id按接收者分别设置
+func sendPersonCMD(s *Server, fakeChannelId string, cmd string, param map[string]interface{}) error {
+	uid1, uid2 := GetFromUIDAndToUIDWith(fakeChannelId)
+	uids := []string{uid1}
+	if uid2 != uid1 {
+		uids = append(uids, uid2)
+	}
+	for _, uid := range uids {
+		if uid == "" || uid == s.opts.SystemUID {
+			continue
+		}
+		_, err := sendMessageToChannel(s, MessageSendReq{
+			Header: MessageHeader{
+				NoPersist: 1,
+				SyncOnce:  1,
+			},
+			FromUID:     s.opts.SystemUID,
+			ChannelID:   uid,
+			ChannelType: wkproto.ChannelTypePerson,
+			Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+				"cmd":   cmd,
+				"param": personCMDParam(fakeChannelId, uid, param),
+			})),
+		}, uid, wkproto.ChannelTypePerson, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// personCMDParam 复制一份param，channel_id换成uid视角下的频道ID
+func personCMDParam(fakeChannelId string, uid string, param map[string]interface{}) map[string]interface{} {
+	recvParam := make(map[string]interface{}, len(param)+1)
+	for k, v := range param {
+		recvParam[k] = v
+	}
+	recvParam["channel_id"] = GetPeerUIDWith(fakeChannelId, uid)
+	return recvParam
+}
+
 func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 	var req struct {
 		Header      MessageHeader `json:"header"`      // 消息头
@@ -679,3 +754,239 @@ func (m *MessageAPI) searchMessage(c *wkhttp.Context) {
 	resp.from(messages[0], m.s)
 	c.JSON(http.StatusOK, resp)
 }
+
+// 按关键字搜索频道消息
+func (m *MessageAPI) keywordSearch(c *wkhttp.Context) {
+	var req messageKeywordSearchReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+	if req.Limit > 1000 {
+		req.Limit = 1000
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	leaderInfo, err := m.s.cluster.LeaderOfChannelForRead(fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		c.JSON(http.StatusOK, &syncMessageResp{Messages: make([]*MessageResp, 0)})
+		return
+	}
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	messages, err := m.s.store.SearchMessages(wkdb.MessageSearchReq{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		FromUid:     req.FromUID,
+		Keyword:     req.Keyword,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		m.Error("搜索消息失败！", zap.Error(err), zap.String("req", wkutil.ToJSON(req)))
+		c.ResponseError(errors.New("搜索消息失败！"))
+		return
+	}
+
+	resps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		resp := &MessageResp{}
+		resp.from(message, m.s)
+		resps = append(resps, resp)
+	}
+	c.JSON(http.StatusOK, &syncMessageResp{
+		Messages: resps,
+	})
+}
+
+// 撤回消息
+func (m *MessageAPI) revoke(c *wkhttp.Context) {
+	var req messageRevokeReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	m.modifyMessage(c, req, bodyBytes, wkdb.MessageModifyTypeRevoke, nil)
+}
+
+// 编辑消息
+func (m *MessageAPI) edit(c *wkhttp.Context) {
+	var req messageEditReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	m.modifyMessage(c, req.messageRevokeReq, bodyBytes, wkdb.MessageModifyTypeEdit, req.Payload)
+}
+
+// 修改消息（撤回/编辑），修改作为一条日志提交到频道，跟随频道日志复制到各个副本
+func (m *MessageAPI) modifyMessage(c *wkhttp.Context, req messageRevokeReq, bodyBytes []byte, modifyType wkdb.MessageModifyType, payload []byte) {
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*5)
+	leaderInfo, err := m.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	cancel()
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	msg, err := m.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("获取消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息失败！"))
+		return
+	}
+	if msg.Modify != nil || msg.IsExpired(time.Now().Unix()) {
+		c.ResponseError(errors.New("消息不存在！"))
+		return
+	}
+
+	extra, err := m.s.store.GetMessageExtra(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		m.Error("获取消息扩展数据失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息扩展数据失败！"))
+		return
+	}
+	if extra.Revoke {
+		c.ResponseError(errors.New("消息已撤回！"))
+		return
+	}
+
+	operator := req.LoginUID
+	if strings.TrimSpace(operator) == "" {
+		operator = m.s.opts.SystemUID
+	}
+	modify := &wkdb.MessageModify{
+		Type:       modifyType,
+		MessageSeq: req.MessageSeq,
+		Operator:   operator,
+	}
+	if modifyType == wkdb.MessageModifyTypeEdit {
+		modify.Version = extra.EditVersion + 1
+		modify.Payload = payload
+	}
+
+	// 提交到频道日志
+	modifyMsg := wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			Framer: wkproto.Framer{
+				SyncOnce: true,
+			},
+			MessageID:   m.s.channelReactor.messageIDGen.Generate().Int64(),
+			ClientMsgNo: fmt.Sprintf("%s0", wkutil.GenUUID()),
+			FromUID:     operator,
+			ChannelID:   fakeChannelId,
+			ChannelType: req.ChannelType,
+			Timestamp:   int32(time.Now().Unix()),
+		},
+		Modify: modify,
+	}
+	timeoutCtx, cancel = context.WithTimeout(m.s.ctx, time.Second*10)
+	_, err = m.s.store.AppendMessages(timeoutCtx, fakeChannelId, req.ChannelType, []wkdb.Message{modifyMsg})
+	cancel()
+	if err != nil {
+		m.Error("提交消息修改失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("提交消息修改失败！"))
+		return
+	}
+
+	// 通知在线的订阅者
+	m.notifyMessageModify(req, msg, modify)
+
+	if modifyType == wkdb.MessageModifyTypeEdit {
+		c.ResponseOKWithData(map[string]interface{}{
+			"edit_version": modify.Version,
+		})
+		return
+	}
+	c.ResponseOK()
+}
+
+// 通过cmd消息通知在线的订阅者消息被修改（不存储）
+func (m *MessageAPI) notifyMessageModify(req messageRevokeReq, msg wkdb.Message, modify *wkdb.MessageModify) {
+	cmd := "messageRevoke"
+	param := map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"message_id":   msg.MessageID,
+		"message_seq":  req.MessageSeq,
+	}
+	if modify.Type == wkdb.MessageModifyTypeRevoke {
+		param["revoker"] = modify.Operator
+	} else {
+		cmd = "messageEdit"
+		param["edit_version"] = modify.Version
+		param["payload"] = modify.Payload
+	}
+
+	// 个人频道需要以操作者身份发送，这样才能定位到同一个频道
+	fromUid := m.s.opts.SystemUID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fromUid = req.LoginUID
+	}
+	_, err := sendMessageToChannel(m.s, MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     fromUid,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd":   cmd,
+			"param": param,
+		})),
+	}, req.ChannelID, req.ChannelType, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+	if err != nil {
+		m.Warn("通知消息修改失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+	}
+}
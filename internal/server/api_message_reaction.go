@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 添加消息回应
+func (m *MessageAPI) reactionAdd(c *wkhttp.Context) {
+	m.handleReaction(c, false)
+}
+
+// 取消消息回应
+func (m *MessageAPI) reactionRemove(c *wkhttp.Context) {
+	m.handleReaction(c, true)
+}
+
+// 在频道领导节点上校验消息后，回应提案到频道所在的槽保存，不写入频道的消息日志，槽日志的下标即为回应版本
+func (m *MessageAPI) handleReaction(c *wkhttp.Context, remove bool) {
+	var req messageReactionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*5)
+	leaderInfo, err := m.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	cancel()
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	// 非个人频道只有订阅者才能回应
+	if req.ChannelType != wkproto.ChannelTypePerson {
+		isSubscriber, err := m.s.store.ExistSubscriber(req.ChannelID, req.ChannelType, req.LoginUID)
+		if err != nil {
+			m.Error("查询订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.String("uid", req.LoginUID))
+			c.ResponseError(errors.New("查询订阅者失败！"))
+			return
+		}
+		if !isSubscriber {
+			c.ResponseError(errors.New("不是频道的订阅者！"))
+			return
+		}
+	}
+
+	msg, err := m.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("获取消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息失败！"))
+		return
+	}
+	if msg.Modify != nil || msg.IsExpired(time.Now().Unix()) {
+		c.ResponseError(errors.New("消息不存在！"))
+		return
+	}
+	if msg.Revoke {
+		c.ResponseError(errors.New("消息已撤回！"))
+		return
+	}
+
+	// 回应保存在槽的副本上，频道领导节点上不一定有，所以不判断是否已经是期望的状态，由存储层按版本覆盖
+	seq, err := m.s.store.SetReaction(wkdb.Reaction{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		MessageSeq:  req.MessageSeq,
+		Uid:         req.LoginUID,
+		Emoji:       req.Emoji,
+		IsDeleted:   remove,
+		CreatedAt:   time.Now().Unix(),
+	})
+	if err != nil {
+		m.Error("提交消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("提交消息回应失败！"))
+		return
+	}
+
+	// 通知在线的订阅者
+	action := "add"
+	if remove {
+		action = "remove"
+	}
+	param := map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"message_id":   msg.MessageID,
+		"message_seq":  req.MessageSeq,
+		"uid":          req.LoginUID,
+		"emoji":        req.Emoji,
+		"action":       action,
+		"seq":          seq,
+	}
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		// 个人频道双方看到的频道ID不同，分别给双方发送
+		err = sendPersonCMD(m.s, fakeChannelId, "messageReaction", param)
+	} else {
+		payload := []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd":   "messageReaction",
+			"param": param,
+		}))
+		err = m.s.deliverManager.deliverCMD(fakeChannelId, req.ChannelType, req.LoginUID, req.ChannelID, payload)
+	}
+	if err != nil {
+		m.Warn("通知消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("messageSeq", req.MessageSeq))
+	}
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"seq": seq,
+	})
+}
+
+// 增量同步频道的消息回应
+func (m *MessageAPI) reactionSync(c *wkhttp.Context) {
+	var req messageReactionSyncReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 1000 {
+		req.Limit = 1000
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	// 回应保存在频道所在的槽上
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取槽的领导节点
+		if err != nil {
+			m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	// 多查一条用来判断是否还有更多
+	reactions, err := m.s.store.SyncReactions(fakeChannelId, req.ChannelType, req.Seq, req.Limit+1)
+	if err != nil {
+		m.Error("同步消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("同步消息回应失败！"))
+		return
+	}
+	version, err := m.s.store.GetReactionVersion(fakeChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("获取回应版本失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取回应版本失败！"))
+		return
+	}
+	resp := &messageReactionSyncResp{
+		Reactions: reactions,
+		Version:   version,
+	}
+	if len(reactions) > req.Limit {
+		resp.Reactions = reactions[:req.Limit]
+		resp.More = 1
+	}
+	// 返回客户端视角的频道
+	for i := range resp.Reactions {
+		resp.Reactions[i].ChannelId = req.ChannelID
+	}
+	c.JSON(http.StatusOK, resp)
+}
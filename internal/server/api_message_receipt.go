@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 上报回执时每页扫描的消息数量
+const receiptScanLimit = 1000
+
+// 上报已读回执，只有开启了回执（SettingReceiptEnabled）的消息才会被统计
+// 领导节点分页计算出需要回执的消息后，提案到频道所在的槽保存，不写入频道的消息日志
+func (m *MessageAPI) receipt(c *wkhttp.Context) {
+	var req messageReceiptReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, time.Second*5)
+	leaderInfo, err := m.s.cluster.LeaderOfChannel(timeoutCtx, fakeChannelId, req.ChannelType) // 获取频道的领导节点
+	cancel()
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return
+	}
+
+	// 非个人频道只有订阅者才能回执
+	if req.ChannelType != wkproto.ChannelTypePerson {
+		isSubscriber, err := m.s.store.ExistSubscriber(req.ChannelID, req.ChannelType, req.LoginUID)
+		if err != nil {
+			m.Error("查询订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.String("uid", req.LoginUID))
+			c.ResponseError(errors.New("查询订阅者失败！"))
+			return
+		}
+		if !isSubscriber {
+			c.ResponseError(errors.New("不是频道的订阅者！"))
+			return
+		}
+	}
+
+	state, err := m.s.getReceiptState(fakeChannelId, req.ChannelType, req.LoginUID, nil)
+	if err != nil {
+		m.Error("获取已回执位置失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.String("uid", req.LoginUID))
+		c.ResponseError(errors.New("获取已回执位置失败！"))
+		return
+	}
+	readSeq := state.ReadSeq
+	lastSeq, err := m.s.store.GetChannelLastMessageSeq(fakeChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("获取频道最新消息seq失败！", zap.Error(err), zap.String("channelId", fakeChannelId))
+		c.ResponseError(errors.New("获取频道最新消息seq失败！"))
+		return
+	}
+	readToSeq := req.ReadToSeq
+	if readToSeq > lastSeq {
+		readToSeq = lastSeq
+	}
+	if readToSeq <= readSeq {
+		c.ResponseOK()
+		return
+	}
+
+	// 分页扫描未回执的消息，每页提交一次回执，回执位置只推进到已扫描的位置
+	now := time.Now().Unix()
+	receiptsOfUser := make(map[string][]map[string]interface{}) // 发送者 -> 已读数量变化的消息
+	for startSeq := readSeq + 1; startSeq <= readToSeq; {
+		messages, err := m.s.store.LoadNextRangeMsgs(fakeChannelId, req.ChannelType, startSeq, readToSeq+1, receiptScanLimit)
+		if err != nil {
+			m.Error("获取消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("startSeq", startSeq), zap.Uint64("endSeq", readToSeq))
+			c.ResponseError(errors.New("获取消息失败！"))
+			return
+		}
+		pageEndSeq := readToSeq
+		if len(messages) >= receiptScanLimit {
+			pageEndSeq = uint64(messages[len(messages)-1].MessageSeq)
+		}
+
+		receiptMessages := make([]wkdb.Message, 0, len(messages))
+		seqs := make([]uint64, 0, len(messages))
+		for _, msg := range messages {
+			if msg.Modify != nil || msg.Revoke || msg.IsExpired(now) {
+				continue
+			}
+			if !msg.Setting.IsSet(wkproto.SettingReceiptEnabled) || msg.FromUID == req.LoginUID {
+				continue
+			}
+			receiptMessages = append(receiptMessages, msg)
+			seqs = append(seqs, uint64(msg.MessageSeq))
+		}
+
+		var counts []uint32
+		if len(seqs) > 0 {
+			pageState, err := m.s.getReceiptState(fakeChannelId, req.ChannelType, req.LoginUID, seqs)
+			if err != nil {
+				m.Error("获取消息已读数量失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("startSeq", startSeq))
+				c.ResponseError(errors.New("获取消息已读数量失败！"))
+				return
+			}
+			counts = pageState.Counts
+		}
+
+		err = m.s.store.AddReceipt(wkdb.Receipt{
+			ChannelId:   fakeChannelId,
+			ChannelType: req.ChannelType,
+			Uid:         req.LoginUID,
+			ReadToSeq:   pageEndSeq,
+			Seqs:        seqs,
+			ReadAt:      now,
+		})
+		if err != nil {
+			m.Error("提交已读回执失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.String("uid", req.LoginUID))
+			c.ResponseError(errors.New("提交已读回执失败！"))
+			return
+		}
+
+		for i, msg := range receiptMessages {
+			var count uint32
+			if i < len(counts) {
+				count = counts[i]
+			}
+			receiptsOfUser[msg.FromUID] = append(receiptsOfUser[msg.FromUID], map[string]interface{}{
+				"message_id":  msg.MessageID,
+				"message_seq": msg.MessageSeq,
+				"read_count":  count + 1,
+			})
+		}
+		startSeq = pageEndSeq + 1
+	}
+
+	// 通知消息发送者已读数量的变化
+	for fromUid, receipts := range receiptsOfUser {
+		m.notifyReceipt(req, fakeChannelId, fromUid, receipts)
+	}
+	c.ResponseOK()
+}
+
+// 获取用户在频道内已回执的位置和消息的已读数量，回执保存在频道所在槽的节点上
+func (s *Server) getReceiptState(channelId string, channelType uint8, uid string, seqs []uint64) (*receiptStateResp, error) {
+	leaderNode, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderNode.Id == s.opts.Cluster.NodeId {
+		return s.getReceiptStateFromLocal(channelId, channelType, uid, seqs)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+
+	req := &receiptStateReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         uid,
+		Seqs:        seqs,
+	}
+	bodyBytes, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderNode.Id, "/wk/getReceiptState", bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("getReceiptState failed, status: %d body: %s", resp.Status, string(resp.Body))
+	}
+	state := &receiptStateResp{}
+	if err = json.Unmarshal(resp.Body, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *Server) getReceiptStateFromLocal(channelId string, channelType uint8, uid string, seqs []uint64) (*receiptStateResp, error) {
+	readSeq, err := s.store.GetReceiptReadSeq(channelId, channelType, uid)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]uint32, 0, len(seqs))
+	for _, seq := range seqs {
+		count, err := s.store.GetMessageReceiptCount(channelId, channelType, seq)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return &receiptStateResp{
+		ReadSeq: readSeq,
+		Counts:  counts,
+	}, nil
+}
+
+// 以系统账号向消息发送者的所有设备发送已读数量变化的命令消息
+func (m *MessageAPI) notifyReceipt(req messageReceiptReq, fakeChannelId string, fromUid string, receipts []map[string]interface{}) {
+	if fromUid == "" || fromUid == m.s.opts.SystemUID {
+		return
+	}
+	// 个人频道在接收者（消息发送者）视角下的频道ID是对方的uid
+	channelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		channelId = GetPeerUIDWith(fakeChannelId, fromUid)
+	}
+	_, err := sendMessageToChannel(m.s, MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+			SyncOnce:  1,
+		},
+		FromUID:     m.s.opts.SystemUID,
+		ChannelID:   fromUid,
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload: []byte(wkutil.ToJSON(map[string]interface{}{
+			"cmd": "messageReceipt",
+			"param": map[string]interface{}{
+				"channel_id":   channelId,
+				"channel_type": req.ChannelType,
+				"receipts":     receipts,
+			},
+		})),
+	}, fromUid, wkproto.ChannelTypePerson, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng)
+	if err != nil {
+		m.Warn("通知已读回执失败！", zap.Error(err), zap.String("uid", fromUid), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+	}
+}
+
+// 查询消息的已读数量和已读用户
+func (m *MessageAPI) receipts(c *wkhttp.Context) {
+	var req messageReceiptsReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > wkdb.MessageReceiptMaxReaders {
+		req.Limit = wkdb.MessageReceiptMaxReaders
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+
+	// 回执保存在频道所在的槽上
+	if m.s.opts.ClusterOn() {
+		leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(fakeChannelId, req.ChannelType) // 获取槽的领导节点
+		if err != nil {
+			m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+			m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+			c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+			return
+		}
+	}
+
+	count, err := m.s.store.GetMessageReceiptCount(fakeChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		m.Error("获取消息已读数量失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息已读数量失败！"))
+		return
+	}
+	readers, err := m.s.store.GetMessageReceiptReaders(fakeChannelId, req.ChannelType, req.MessageSeq, req.Limit)
+	if err != nil {
+		m.Error("获取消息已读用户失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息已读用户失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, &messageReceiptsResp{
+		MessageSeq: req.MessageSeq,
+		ReadCount:  count,
+		Readers:    readers,
+	})
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 保存定时消息，定时消息属于频道所在的槽，由槽的领导节点到期发送
+func (m *MessageAPI) schedule(c *wkhttp.Context, req MessageSendReq, clientMsgNo string) {
+	if IsSpecialChar(req.ChannelID) {
+		c.ResponseError(errors.New("频道ID不合法！"))
+		return
+	}
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+	scheduled := wkdb.ScheduledMessage{
+		Id:          uint64(m.s.channelReactor.messageIDGen.Generate().Int64()),
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		FromUid:     req.FromUID,
+		ClientMsgNo: clientMsgNo,
+		RedDot:      wkutil.IntToBool(req.Header.RedDot),
+		NoPersist:   wkutil.IntToBool(req.Header.NoPersist),
+		SyncOnce:    wkutil.IntToBool(req.Header.SyncOnce),
+		Expire:      req.Expire,
+		Payload:     req.Payload,
+		SendAt:      req.SendAt,
+		CreatedAt:   time.Now().Unix(),
+	}
+	if err := m.s.store.AddScheduledMessage(scheduled); err != nil {
+		m.Error("保存定时消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("保存定时消息失败！"))
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"scheduled_id":  scheduled.Id,
+		"client_msg_no": clientMsgNo,
+		"send_at":       scheduled.SendAt,
+	})
+}
+
+// 查询频道待发送的定时消息
+func (m *MessageAPI) scheduledList(c *wkhttp.Context) {
+	var req messageScheduledReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+	if m.forwardToChannelSlotLeader(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	messages, err := m.s.store.GetScheduledMessages(fakeChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("获取定时消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取定时消息失败！"))
+		return
+	}
+	// 返回客户端视角的频道
+	for i := range messages {
+		messages[i].ChannelId = req.ChannelID
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// 取消定时消息，只有发送者或频道的管理员、创建者可以取消
+func (m *MessageAPI) scheduledCancel(c *wkhttp.Context) {
+	var req messageScheduledCancelReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+	if m.forwardToChannelSlotLeader(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	scheduled, err := m.s.store.GetScheduledMessage(fakeChannelId, req.ChannelType, req.Id)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("定时消息不存在或已发送！"))
+			return
+		}
+		m.Error("获取定时消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("id", req.Id))
+		c.ResponseError(errors.New("获取定时消息失败！"))
+		return
+	}
+	if scheduled.FromUid != req.LoginUID {
+		allow := false
+		if req.ChannelType != wkproto.ChannelTypePerson {
+			subscriber, err := m.s.store.GetSubscriber(req.ChannelID, req.ChannelType, req.LoginUID)
+			if err != nil && err != wkdb.ErrNotFound {
+				m.Error("查询订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.String("uid", req.LoginUID))
+				c.ResponseError(errors.New("查询订阅者失败！"))
+				return
+			}
+			allow = err == nil && (subscriber.Role == wkdb.MemberRoleAdmin || subscriber.Role == wkdb.MemberRoleOwner)
+		}
+		if !allow {
+			c.ResponseError(errors.New("只有发送者或管理员可以取消定时消息！"))
+			return
+		}
+	}
+	if err = m.s.store.RemoveScheduledMessage(fakeChannelId, req.ChannelType, req.Id); err != nil {
+		m.Error("取消定时消息失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("id", req.Id))
+		c.ResponseError(errors.New("取消定时消息失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 转发请求到频道所在槽的领导节点，已转发或已响应返回true
+func (m *MessageAPI) forwardToChannelSlotLeader(c *wkhttp.Context, channelId string, channelType uint8, bodyBytes []byte) bool {
+	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(channelId, channelType) // 获取频道所在槽的领导节点
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
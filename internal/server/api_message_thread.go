@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 消息内容中声明父消息seq的字段
+var threadParentField = []byte(`"parent_message_seq"`)
+
+// threadParentOfPayload 从json消息内容的parent_message_seq字段解析回复的父消息seq，不是回复返回0
+func threadParentOfPayload(payload []byte) uint64 {
+	if len(payload) == 0 || payload[0] != '{' || !bytes.Contains(payload, threadParentField) {
+		return 0
+	}
+	var v struct {
+		ParentMessageSeq uint64 `json:"parent_message_seq"`
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return 0
+	}
+	return v.ParentMessageSeq
+}
+
+// 同步父消息的回复，回复通过消息内容的parent_message_seq字段声明
+func (m *MessageAPI) threadSync(c *wkhttp.Context) {
+	var req messageThreadSyncReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 100
+	}
+	if req.Limit > 1000 {
+		req.Limit = 1000
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+	if m.forwardToChannelLeaderForRead(c, fakeChannelId, req.ChannelType, bodyBytes, emptySyncMessageResp) {
+		return
+	}
+
+	// 多查一条用来判断是否还有更多
+	messages, err := m.s.store.LoadThreadMsgs(fakeChannelId, req.ChannelType, req.ParentMessageSeq, req.StartMessageSeq, req.Limit+1)
+	if err != nil {
+		m.Error("获取回复失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("parentMessageSeq", req.ParentMessageSeq))
+		c.ResponseError(errors.New("获取回复失败！"))
+		return
+	}
+	more := len(messages) > req.Limit
+	if more {
+		messages = messages[:req.Limit]
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, m.s)
+		messageResps = append(messageResps, messageResp)
+	}
+	c.JSON(http.StatusOK, &syncMessageResp{
+		StartMessageSeq: req.StartMessageSeq,
+		More:            wkutil.BoolToInt(more),
+		Messages:        messageResps,
+	})
+}
+
+// 获取父消息的回复数量和最新回复，用于在频道消息上展示“N条回复”
+func (m *MessageAPI) threadSummary(c *wkhttp.Context) {
+	var req messageThreadSummaryReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
+	if m.forwardToChannelLeaderForRead(c, fakeChannelId, req.ChannelType, bodyBytes, make([]wkdb.ThreadSummary, 0)) {
+		return
+	}
+
+	summaries, err := m.s.store.GetThreadSummaries(fakeChannelId, req.ChannelType, req.ParentMessageSeqs)
+	if err != nil {
+		m.Error("获取回复概要失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取回复概要失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// 转发读请求到频道的领导节点，已转发或已响应返回true
+// 频道集群从未初始化时直接返回empty
+func (m *MessageAPI) forwardToChannelLeaderForRead(c *wkhttp.Context, channelId string, channelType uint8, bodyBytes []byte, empty interface{}) bool {
+	if !m.s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := m.s.cluster.LeaderOfChannelForRead(channelId, channelType) // 获取频道的领导节点
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		c.JSON(http.StatusOK, empty)
+		return true
+	}
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
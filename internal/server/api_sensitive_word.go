@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	sensitiveWordMaxLen    = 100  // 单个敏感词最大长度（字符数）
+	sensitiveWordMaxPerReq = 1000 // 每次请求最多添加或移除的敏感词数量
+)
+
+// SensitiveWordAPI 敏感词相关API
+type SensitiveWordAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewSensitiveWordAPI NewSensitiveWordAPI
+func NewSensitiveWordAPI(s *Server) *SensitiveWordAPI {
+	return &SensitiveWordAPI{
+		s:   s,
+		Log: wklog.NewWKLog("SensitiveWordAPI"),
+	}
+}
+
+// Route route
+func (a *SensitiveWordAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/sensitive_words_add", a.wordsAdd)       // 添加敏感词
+	r.POST("/sensitive_words_remove", a.wordsRemove) // 移除敏感词
+	r.GET("/sensitive_words", a.words)               // 获取敏感词
+	r.POST("/sensitive_words_reload", a.wordsReload) // 仅仅重新加载当前节点的敏感词
+}
+
+type sensitiveWordsReq struct {
+	Words []string `json:"words"`
+}
+
+func (r *sensitiveWordsReq) check() error {
+	if len(r.Words) == 0 {
+		return errors.New("words不能为空！")
+	}
+	if len(r.Words) > sensitiveWordMaxPerReq {
+		return fmt.Errorf("每次最多提交%d个敏感词！", sensitiveWordMaxPerReq)
+	}
+	words := make([]string, 0, len(r.Words))
+	for _, word := range r.Words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		if utf8.RuneCountInString(word) > sensitiveWordMaxLen {
+			return fmt.Errorf("敏感词[%s]长度不能超过%d！", word, sensitiveWordMaxLen)
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return errors.New("words不能为空！")
+	}
+	r.Words = words
+	return nil
+}
+
+// 添加敏感词
+func (a *SensitiveWordAPI) wordsAdd(c *wkhttp.Context) {
+	var req sensitiveWordsReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if a.forwardToSlotLeaderIfNeed(c, bodyBytes) {
+		return
+	}
+
+	err = a.s.store.AddSensitiveWords(req.Words)
+	if err != nil {
+		a.Error("添加敏感词失败！", zap.Error(err))
+		c.ResponseError(errors.New("添加敏感词失败！"))
+		return
+	}
+
+	if err = a.reloadWords(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 移除敏感词
+func (a *SensitiveWordAPI) wordsRemove(c *wkhttp.Context) {
+	var req sensitiveWordsReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if a.forwardToSlotLeaderIfNeed(c, bodyBytes) {
+		return
+	}
+
+	err = a.s.store.RemoveSensitiveWords(req.Words)
+	if err != nil {
+		a.Error("移除敏感词失败！", zap.Error(err))
+		c.ResponseError(errors.New("移除敏感词失败！"))
+		return
+	}
+
+	if err = a.reloadWords(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 获取敏感词
+func (a *SensitiveWordAPI) words(c *wkhttp.Context) {
+	var slotId uint32 = 0 // 敏感词默认存储在slot 0上
+	nodeInfo, err := a.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		a.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return
+	}
+	if nodeInfo.Id != a.s.opts.Cluster.NodeId {
+		a.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.Forward(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	words, err := a.s.store.GetSensitiveWords()
+	if err != nil {
+		a.Error("获取敏感词失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取敏感词失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, words)
+}
+
+func (a *SensitiveWordAPI) wordsReload(c *wkhttp.Context) {
+	if !a.s.opts.SensitiveWord.On {
+		c.ResponseOK()
+		return
+	}
+	if err := a.s.sensitiveWordManager.load(); err != nil {
+		a.Error("加载敏感词失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// forwardToSlotLeaderIfNeed 当前节点不是slot 0的领导节点则转发请求，返回true表示已转发
+func (a *SensitiveWordAPI) forwardToSlotLeaderIfNeed(c *wkhttp.Context, bodyBytes []byte) bool {
+	var slotId uint32 = 0 // 敏感词默认存储在slot 0上
+	nodeInfo, err := a.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		a.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return true
+	}
+	if nodeInfo.Id != a.s.opts.Cluster.NodeId {
+		a.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
+
+// reloadWords 重新加载当前节点的敏感词，并通知其他在线节点重新加载
+func (a *SensitiveWordAPI) reloadWords() error {
+	if a.s.opts.SensitiveWord.On {
+		words, err := a.s.store.GetSensitiveWords()
+		if err != nil {
+			a.Error("获取敏感词失败！", zap.Error(err))
+			return errors.New("获取敏感词失败！")
+		}
+		a.s.sensitiveWordManager.setWords(words)
+	}
+
+	nodes := a.s.clusterServer.GetConfig().Nodes
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), a.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == a.s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				return a.requestWordsReload(n)
+			}
+		}(node))
+	}
+	if err := requestGroup.Wait(); err != nil {
+		a.Error("通知节点重新加载敏感词失败！", zap.Error(err))
+		return errors.New("通知节点重新加载敏感词失败！")
+	}
+	return nil
+}
+
+func (a *SensitiveWordAPI) requestWordsReload(nodeInfo *pb.Node) error {
+	reqURL := fmt.Sprintf("%s/sensitive_words_reload", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, nil, nil)
+	if err != nil {
+		a.Error("通知节点重新加载敏感词失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("通知节点重新加载敏感词请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
@@ -39,10 +39,15 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/token", u.updateToken)                  // 更新用户token
 	r.POST("/user/device_quit", u.deviceQuit)             // 强制设备退出
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
+	r.POST("/user/lastseen", u.getLastSeen)               // 批量获取用户最后上线/离线时间
 	r.POST("/user/systemuids_add", u.systemUidsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUidsRemove) // 移除系统uid
 	r.GET("/user/systemuids", u.getSystemUids)            // 获取系统uid
 
+	r.POST("/user/presence/subscribe", u.presenceSubscribe)         // 订阅用户在线状态
+	r.POST("/user/presence/unsubscribe", u.presenceUnsubscribe)     // 取消订阅用户在线状态
+	r.POST("/user/presence/subscriptions", u.presenceSubscriptions) // 获取订阅的用户
+
 	r.POST("/user/systemuids_add_to_cache", u.systemUidsAddToCache)           // 仅仅添加系统账号至缓存
 	r.POST("/user/systemuids_remove_from_cache", u.systemUidsRemoveFromCache) // 仅仅从缓存中移除系统账号
 
@@ -236,18 +241,171 @@ func (u *UserAPI) getOnlineConns(uids []string) []*OnlinestatusResp {
 
 	onlineStatusResps := make([]*OnlinestatusResp, 0)
 	for _, uid := range uids {
-		conns := u.s.userReactor.getConns(uid)
-		for _, conn := range conns {
-			onlineStatusResps = append(onlineStatusResps, &OnlinestatusResp{
+		userHandler := u.s.userReactor.getUserHandler(uid)
+		if userHandler == nil {
+			continue
+		}
+		lastSeen := userHandler.getLastSeen()
+		for _, conn := range userHandler.getConns() {
+			resp := &OnlinestatusResp{
 				UID:        conn.uid,
 				DeviceFlag: uint8(conn.deviceFlag),
 				Online:     1,
-			})
+			}
+			for _, d := range lastSeen.Devices {
+				if d.DeviceFlag == uint8(conn.deviceFlag) {
+					resp.LastOnline = d.LastOnline
+					break
+				}
+			}
+			onlineStatusResps = append(onlineStatusResps, resp)
 		}
 	}
 	return onlineStatusResps
 }
 
+// 批量获取用户最后上线/离线时间，数据保存在用户所在槽，由用户的领导节点查询
+func (u *UserAPI) getLastSeen(c *wkhttp.Context) {
+	var uids []string
+	err := c.BindJSON(&uids)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if len(uids) == 0 {
+		c.JSON(http.StatusOK, make([]*LastSeenResp, 0))
+		return
+	}
+	if len(uids) > 1000 {
+		c.ResponseError(errors.New("uids数量不能超过1000个！"))
+		return
+	}
+
+	if !u.s.opts.ClusterOn() {
+		resps, err := u.getLocalLastSeens(uids)
+		if err != nil {
+			u.Error("获取用户最后上线/离线时间失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.JSON(http.StatusOK, resps)
+		return
+	}
+
+	uidInPeerMap := make(map[uint64][]string)
+	localUids := make([]string, 0)
+	for _, uid := range uids {
+		leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取用户的领导节点
+		if err != nil {
+			u.Error("获取用户所在节点失败！", zap.Error(err), zap.String("uid", uid))
+			c.ResponseError(errors.New("获取用户所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id == u.s.opts.Cluster.NodeId {
+			localUids = append(localUids, uid)
+			continue
+		}
+		uidInPeerMap[leaderInfo.Id] = append(uidInPeerMap[leaderInfo.Id], uid)
+	}
+	resps := make([]*LastSeenResp, 0, len(uids))
+	if len(localUids) > 0 {
+		localResps, err := u.getLocalLastSeens(localUids)
+		if err != nil {
+			u.Error("获取用户最后上线/离线时间失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		resps = append(resps, localResps...)
+	}
+	if len(uidInPeerMap) > 0 {
+		var (
+			reqErr error
+			lock   sync.Mutex
+		)
+		wg := &sync.WaitGroup{}
+		for nodeId, uidList := range uidInPeerMap {
+			wg.Add(1)
+			go func(pid uint64, uidArr []string) {
+				defer wg.Done()
+				results, err := u.requestLastSeen(pid, uidArr)
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					reqErr = err
+					return
+				}
+				resps = append(resps, results...)
+			}(nodeId, uidList)
+		}
+		wg.Wait()
+		if reqErr != nil {
+			c.ResponseError(reqErr)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+func (u *UserAPI) requestLastSeen(nodeID uint64, uids []string) ([]*LastSeenResp, error) {
+	nodeInfo, err := u.s.cluster.NodeInfoById(nodeID)
+	if err != nil {
+		u.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeID", nodeID))
+		return nil, errors.New("获取节点信息失败！")
+	}
+	reqURL := fmt.Sprintf("%s/user/lastseen", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(uids)), nil)
+	if err != nil {
+		u.Error("获取用户最后上线/离线时间失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取用户最后上线/离线时间请求状态错误！[%d]", resp.StatusCode)
+	}
+	var resps []*LastSeenResp
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &resps)
+	if err != nil {
+		u.Error("解析用户最后上线/离线时间失败！", zap.Error(err))
+		return nil, err
+	}
+	return resps, nil
+}
+
+// 获取本节点负责的用户的最后上线/离线时间，在线用户合并内存中尚未持久化的记录
+func (u *UserAPI) getLocalLastSeens(uids []string) ([]*LastSeenResp, error) {
+	lastSeens, err := u.s.store.GetUserLastSeens(uids)
+	if err != nil {
+		return nil, err
+	}
+	lastSeenMap := make(map[string]wkdb.UserLastSeen, len(lastSeens))
+	for _, lastSeen := range lastSeens {
+		lastSeenMap[lastSeen.Uid] = lastSeen
+	}
+	resps := make([]*LastSeenResp, 0, len(uids))
+	for _, uid := range uids {
+		lastSeen, ok := lastSeenMap[uid]
+		online := 0
+		if userHandler := u.s.userReactor.getUserHandler(uid); userHandler != nil && userHandler.role == userRoleLeader {
+			if !ok {
+				lastSeen = wkdb.UserLastSeen{Uid: uid}
+				ok = true
+			}
+			lastSeen.Merge(userHandler.getLastSeen())
+			if userHandler.getConnCount() > 0 {
+				online = 1
+			}
+		}
+		if !ok {
+			continue
+		}
+		resps = append(resps, &LastSeenResp{
+			UserLastSeen: lastSeen,
+			Online:       online,
+		})
+	}
+	return resps, nil
+}
+
 // 更新用户的token
 func (u *UserAPI) updateToken(c *wkhttp.Context) {
 	var req UpdateTokenReq
@@ -644,4 +802,11 @@ type OnlinestatusResp struct {
 	UID        string `json:"uid"`         // 在线用户uid
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
 	Online     int    `json:"online"`      // 是否在线
+	LastOnline int64  `json:"last_online"` // 设备最后上线时间（单位秒）
+}
+
+// LastSeenResp 用户最后上线/离线时间
+type LastSeenResp struct {
+	wkdb.UserLastSeen
+	Online int `json:"online"` // 是否在线
 }
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// 订阅用户的在线状态，被订阅用户上线或离线时通过订阅者的长连接推送presence cmd
+func (u *UserAPI) presenceSubscribe(c *wkhttp.Context) {
+	var req presenceSubscribeReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if len(req.UIDs) == 0 {
+		c.ResponseError(errors.New("uids不能为空！"))
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+
+	if err = u.s.presenceManager.subscribe(req.UID, req.UIDs); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 取消订阅用户的在线状态
+func (u *UserAPI) presenceUnsubscribe(c *wkhttp.Context) {
+	var req presenceSubscribeReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+
+	u.s.presenceManager.unsubscribe(req.UID, req.UIDs)
+	c.ResponseOK()
+}
+
+// 获取用户订阅了在线状态的用户
+func (u *UserAPI) presenceSubscriptions(c *wkhttp.Context) {
+	var req presenceSubscribeReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if u.forwardToUserLeader(c, req.UID, bodyBytes) {
+		return
+	}
+	c.JSON(http.StatusOK, u.s.presenceManager.getSubscriptions(req.UID))
+}
+
+// 转发请求到用户的领导节点，已转发或已响应返回true
+func (u *UserAPI) forwardToUserLeader(c *wkhttp.Context, uid string, bodyBytes []byte) bool {
+	leaderInfo, err := u.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取用户的领导节点
+	if err != nil {
+		u.Error("获取用户所在节点失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取用户所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id != u.s.opts.Cluster.NodeId {
+		u.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
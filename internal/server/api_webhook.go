@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// WebhookAPI webhook订阅相关API
+type WebhookAPI struct {
+	s *Server
+	wklog.Log
+}
+
+// NewWebhookAPI NewWebhookAPI
+func NewWebhookAPI(s *Server) *WebhookAPI {
+	return &WebhookAPI{
+		s:   s,
+		Log: wklog.NewWKLog("WebhookAPI"),
+	}
+}
+
+// Route route
+func (w *WebhookAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/webhook/subscription", w.subscriptionAddOrUpdate)   // 添加或更新webhook订阅
+	r.POST("/webhook/subscription/remove", w.subscriptionRemove) // 移除webhook订阅
+	r.GET("/webhook/subscriptions", w.subscriptions)             // 获取webhook订阅（不返回签名密钥）
+}
+
+// 添加或更新webhook订阅，id为0表示添加
+func (w *WebhookAPI) subscriptionAddOrUpdate(c *wkhttp.Context) {
+	var req webhookSubscriptionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if w.forwardToSlotLeaderIfNeed(c, bodyBytes) {
+		return
+	}
+
+	now := time.Now().Unix()
+	subscription := req.toSubscription()
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	if req.Id == 0 {
+		subscription.Id = w.s.store.DB().NextPrimaryKey()
+	} else {
+		exist, err := w.getSubscription(req.Id)
+		if err != nil {
+			w.Error("获取webhook订阅失败！", zap.Error(err), zap.Uint64("id", req.Id))
+			c.ResponseError(err)
+			return
+		}
+		subscription.CreatedAt = exist.CreatedAt
+		if subscription.Secret == "" { // 接口不返回签名密钥，更新时没有传则保留原密钥
+			subscription.Secret = exist.Secret
+		}
+	}
+
+	err = w.s.store.AddOrUpdateWebhookSubscription(subscription)
+	if err != nil {
+		w.Error("添加或更新webhook订阅失败！", zap.Error(err))
+		c.ResponseError(errors.New("添加或更新webhook订阅失败！"))
+		return
+	}
+
+	if err = w.reloadSubscriptions(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookSubscriptionResp(subscription))
+}
+
+// 移除webhook订阅
+func (w *WebhookAPI) subscriptionRemove(c *wkhttp.Context) {
+	var req struct {
+		Id uint64 `json:"id"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if req.Id == 0 {
+		c.ResponseError(errors.New("id不能为空！"))
+		return
+	}
+
+	if w.forwardToSlotLeaderIfNeed(c, bodyBytes) {
+		return
+	}
+
+	err = w.s.store.RemoveWebhookSubscription(req.Id)
+	if err != nil {
+		w.Error("移除webhook订阅失败！", zap.Error(err), zap.Uint64("id", req.Id))
+		c.ResponseError(errors.New("移除webhook订阅失败！"))
+		return
+	}
+
+	if err = w.reloadSubscriptions(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 获取webhook订阅
+func (w *WebhookAPI) subscriptions(c *wkhttp.Context) {
+	var slotId uint32 = 0 // webhook订阅默认存储在slot 0上
+	nodeInfo, err := w.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		w.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return
+	}
+	if nodeInfo.Id != w.s.opts.Cluster.NodeId {
+		w.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.Forward(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+
+	subscriptions, err := w.s.store.GetWebhookSubscriptions()
+	if err != nil {
+		w.Error("获取webhook订阅失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取webhook订阅失败！"))
+		return
+	}
+	resps := make([]*webhookSubscriptionResp, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resps = append(resps, newWebhookSubscriptionResp(subscription))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// forwardToSlotLeaderIfNeed 当前节点不是slot 0的领导节点则转发请求，返回true表示已转发
+func (w *WebhookAPI) forwardToSlotLeaderIfNeed(c *wkhttp.Context, bodyBytes []byte) bool {
+	var slotId uint32 = 0 // webhook订阅默认存储在slot 0上
+	nodeInfo, err := w.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		w.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return true
+	}
+	if nodeInfo.Id != w.s.opts.Cluster.NodeId {
+		w.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
+
+func (w *WebhookAPI) getSubscription(id uint64) (wkdb.WebhookSubscription, error) {
+	subscriptions, err := w.s.store.GetWebhookSubscriptions()
+	if err != nil {
+		return wkdb.WebhookSubscription{}, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Id == id {
+			return subscription, nil
+		}
+	}
+	return wkdb.WebhookSubscription{}, errors.New("webhook订阅不存在！")
+}
+
+// reloadSubscriptions 重新加载当前节点的订阅，并通知其他在线节点重新加载
+func (w *WebhookAPI) reloadSubscriptions() error {
+	subscriptions, err := w.s.store.GetWebhookSubscriptions()
+	if err != nil {
+		w.Error("获取webhook订阅失败！", zap.Error(err))
+		return errors.New("获取webhook订阅失败！")
+	}
+	w.s.webhook.setSubscriptions(subscriptions)
+
+	nodes := w.s.clusterServer.GetConfig().Nodes
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), w.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == w.s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			continue
+		}
+		nodeId := node.Id
+		requestGroup.Go(func() error {
+			return w.requestSubscriptionsReload(timeoutCtx, nodeId)
+		})
+	}
+	if err = requestGroup.Wait(); err != nil {
+		w.Error("通知节点重新加载webhook订阅失败！", zap.Error(err))
+		return errors.New("通知节点重新加载webhook订阅失败！")
+	}
+	return nil
+}
+
+// requestSubscriptionsReload 通过集群内部接口通知节点重新加载webhook订阅
+func (w *WebhookAPI) requestSubscriptionsReload(ctx context.Context, nodeId uint64) error {
+	resp, err := w.s.cluster.RequestWithContext(ctx, nodeId, "/wk/webhookSubscriptionsReload", nil)
+	if err != nil {
+		w.Error("通知节点重新加载webhook订阅失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return fmt.Errorf("通知节点重新加载webhook订阅请求状态错误！[%d]", resp.Status)
+	}
+	return nil
+}
+
+var webhookEvents = []string{EventMsgOffline, EventMsgNotify, EventOnlineStatus}
+
+type webhookSubscriptionReq struct {
+	Id           uint64   `json:"id"`            // 订阅id，为0表示添加新的订阅
+	URL          string   `json:"url"`           // http推送地址
+	GRPCAddr     string   `json:"grpc_addr"`     // grpc推送地址（与url二选一）
+	Events       []string `json:"events"`        // 订阅的事件类型，为空表示订阅所有事件
+	ChannelTypes []int    `json:"channel_types"` // 只推送指定频道类型的消息事件，为空表示不限制
+	ChannelIds   []string `json:"channel_ids"`   // 只推送指定频道的消息事件，为空表示不限制
+	Secret       string   `json:"secret"`        // 签名密钥（更新时为空表示不修改）
+}
+
+func (r webhookSubscriptionReq) Check() error {
+	url := strings.TrimSpace(r.URL)
+	grpcAddr := strings.TrimSpace(r.GRPCAddr)
+	if url == "" && grpcAddr == "" {
+		return errors.New("url和grpc_addr不能都为空！")
+	}
+	if url != "" && grpcAddr != "" {
+		return errors.New("url和grpc_addr只能填写一个！")
+	}
+	if url != "" && !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return errors.New("url格式有误！")
+	}
+	for _, event := range r.Events {
+		if !wkutil.ArrayContains(webhookEvents, event) {
+			return fmt.Errorf("不支持的事件类型[%s]！", event)
+		}
+	}
+	for _, channelType := range r.ChannelTypes {
+		if channelType <= 0 || channelType > 255 {
+			return fmt.Errorf("频道类型[%d]有误！", channelType)
+		}
+	}
+	return nil
+}
+
+func (r webhookSubscriptionReq) toSubscription() wkdb.WebhookSubscription {
+	channelTypes := make([]uint8, 0, len(r.ChannelTypes))
+	for _, channelType := range r.ChannelTypes {
+		channelTypes = append(channelTypes, uint8(channelType))
+	}
+	return wkdb.WebhookSubscription{
+		Id:           r.Id,
+		URL:          strings.TrimSpace(r.URL),
+		GRPCAddr:     strings.TrimSpace(r.GRPCAddr),
+		Events:       r.Events,
+		ChannelTypes: channelTypes,
+		ChannelIds:   r.ChannelIds,
+		Secret:       r.Secret,
+	}
+}
+
+type webhookSubscriptionResp struct {
+	Id           uint64   `json:"id"`
+	IdStr        string   `json:"id_str"`
+	URL          string   `json:"url"`
+	GRPCAddr     string   `json:"grpc_addr"`
+	Events       []string `json:"events"`
+	ChannelTypes []int    `json:"channel_types"`
+	ChannelIds   []string `json:"channel_ids"`
+	HasSecret    bool     `json:"has_secret"` // 是否设置了签名密钥（密钥本身不返回）
+	CreatedAt    int64    `json:"created_at"` // 创建时间（单位秒）
+	UpdatedAt    int64    `json:"updated_at"` // 更新时间（单位秒）
+}
+
+func newWebhookSubscriptionResp(subscription wkdb.WebhookSubscription) *webhookSubscriptionResp {
+	channelTypes := make([]int, 0, len(subscription.ChannelTypes))
+	for _, channelType := range subscription.ChannelTypes {
+		channelTypes = append(channelTypes, int(channelType))
+	}
+	return &webhookSubscriptionResp{
+		Id:           subscription.Id,
+		IdStr:        strconv.FormatUint(subscription.Id, 10),
+		URL:          subscription.URL,
+		GRPCAddr:     subscription.GRPCAddr,
+		Events:       subscription.Events,
+		ChannelTypes: channelTypes,
+		ChannelIds:   subscription.ChannelIds,
+		HasSecret:    subscription.Secret != "",
+		CreatedAt:    subscription.CreatedAt,
+		UpdatedAt:    subscription.UpdatedAt,
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscriptionRespRedactSecret(t *testing.T) {
+	resp := newWebhookSubscriptionResp(wkdb.WebhookSubscription{
+		Id:     1,
+		URL:    "http://127.0.0.1:8080/webhook",
+		Events: []string{EventMsgOffline},
+		Secret: "secret123",
+	})
+	assert.True(t, resp.HasSecret)
+
+	data := wkutil.ToJSON(resp)
+	assert.NotContains(t, data, "secret123")
+	assert.Contains(t, data, `"has_secret":true`)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+const (
+	backupVersion      = 1
+	backupManifestFile = "manifest.json"
+)
+
+// BackupManifest 备份清单
+type BackupManifest struct {
+	Version            int               `json:"version"`              // 备份格式版本
+	NodeId             uint64            `json:"node_id"`              // 节点id
+	DbShardNum         int               `json:"db_shard_num"`         // 频道db分片数量
+	SlotDbShardNum     int               `json:"slot_db_shard_num"`    // 槽db分片数量
+	SlotCount          int               `json:"slot_count"`           // 槽数量
+	SlotAppliedIndexes map[uint32]uint64 `json:"slot_applied_indexes"` // 本节点上槽的已应用日志下标
+	ConfigAppliedIndex uint64            `json:"config_applied_index"` // 集群配置的已应用日志下标
+	CreatedAt          int64             `json:"created_at"`           // 备份时间（单位秒）
+}
+
+// Backup 在线备份本节点的所有数据到dir目录，dir必须不存在或者为空
+// 先备份槽日志（包含已应用下标）再备份数据，这样恢复后数据不会落后于已应用下标，之后的日志重新应用即可
+func (s *Server) Backup(dir string) (*BackupManifest, error) {
+	if !s.backupLock.TryLock() {
+		return nil, errors.New("backup is running")
+	}
+	defer s.backupLock.Unlock()
+
+	if !isEmptyDir(dir) {
+		return nil, fmt.Errorf("backup dir[%s] is not empty", dir)
+	}
+
+	start := time.Now()
+	manifest, err := s.backup(dir)
+	if err != nil {
+		s.Error("backup failed", zap.Error(err), zap.String("dir", dir))
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	s.Info("backup done", zap.String("dir", dir), zap.Duration("cost", time.Since(start)))
+	return manifest, nil
+}
+
+func (s *Server) backup(dir string) (*BackupManifest, error) {
+	result, err := s.clusterServer.Checkpoint(path.Join(dir, "cluster"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.store.DB().Checkpoint(path.Join(dir, "db")); err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Version:            backupVersion,
+		NodeId:             s.opts.Cluster.NodeId,
+		DbShardNum:         s.opts.Db.ShardNum,
+		SlotDbShardNum:     s.opts.Db.SlotShardNum,
+		SlotCount:          s.opts.Cluster.SlotCount,
+		SlotAppliedIndexes: result.SlotAppliedIndexes,
+		ConfigAppliedIndex: result.ConfigAppliedIndex,
+		CreatedAt:          time.Now().Unix(),
+	}
+	// 清单最后写入，有清单的备份才是完整的
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = wkutil.WriteFile(path.Join(dir, backupManifestFile), data); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ReadBackupManifest 读取备份清单
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	data, err := wkutil.ReadFile(path.Join(dir, backupManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("backup dir[%s] has no manifest, the backup is incomplete", dir)
+		}
+		return nil, err
+	}
+	manifest := &BackupManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != backupVersion {
+		return nil, fmt.Errorf("unsupported backup version: %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// Restore 将backupDir的备份恢复到opts.DataDir，服务必须是停止状态
+// force为true时，已存在的数据目录会被重命名保留（xxx.bak.时间戳）
+func Restore(opts *Options, backupDir string, force bool) (*BackupManifest, error) {
+	manifest, err := ReadBackupManifest(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.NodeId != opts.Cluster.NodeId {
+		return nil, fmt.Errorf("node id mismatch, backup: %d, config: %d", manifest.NodeId, opts.Cluster.NodeId)
+	}
+	if manifest.DbShardNum != opts.Db.ShardNum {
+		return nil, fmt.Errorf("db.shardNum mismatch, backup: %d, config: %d", manifest.DbShardNum, opts.Db.ShardNum)
+	}
+	if manifest.SlotDbShardNum != opts.Db.SlotShardNum {
+		return nil, fmt.Errorf("db.slotShardNum mismatch, backup: %d, config: %d", manifest.SlotDbShardNum, opts.Db.SlotShardNum)
+	}
+	if manifest.SlotCount != opts.Cluster.SlotCount {
+		return nil, fmt.Errorf("cluster.slotCount mismatch, backup: %d, config: %d", manifest.SlotCount, opts.Cluster.SlotCount)
+	}
+
+	subDirs := []string{"db", "cluster"}
+	for _, subDir := range subDirs {
+		dataDir := path.Join(opts.DataDir, subDir)
+		if isEmptyDir(dataDir) {
+			continue
+		}
+		if !force {
+			return nil, fmt.Errorf("data dir[%s] is not empty", dataDir)
+		}
+		if err = os.Rename(dataDir, fmt.Sprintf("%s.bak.%d", dataDir, time.Now().Unix())); err != nil {
+			return nil, err
+		}
+	}
+	for _, subDir := range subDirs {
+		if err = wkutil.CopyDir(path.Join(opts.DataDir, subDir), path.Join(backupDir, subDir)); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// 目录不存在或者为空
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return len(entries) == 0
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -25,6 +26,10 @@ type channel struct {
 	msgQueue *channelMsgQueue // 消息队列
 	streams  *streamList      // 流消息集合
 
+	ephemerals            []ReactorChannelMessage // 待处理的临时事件
+	ephemeralWindowStart  time.Time               // 临时事件限流窗口的开始时间
+	ephemeralWindowCounts map[string]int          // 当前限流窗口内每个发送者的临时事件数量
+
 	actions []*ChannelAction
 
 	tmpSubscribers     []string // 临时订阅者
@@ -112,6 +117,10 @@ func (c *channel) hasReady() bool {
 		return true
 	}
 
+	if c.hasEphemeral() { // 有未处理的临时事件
+		return true
+	}
+
 	if c.role == channelRoleLeader { // 领导者
 		if c.hasPermissionUnCheck() { // 是否有未检查权限的消息
 			return true
@@ -234,6 +243,12 @@ func (c *channel) ready() ready {
 			}
 		}
 
+		// 临时事件
+		if c.hasEphemeral() {
+			c.exec(&ChannelAction{ActionType: ChannelActionEphemeral, LeaderId: c.leaderId, Messages: c.ephemerals})
+			c.ephemerals = nil
+		}
+
 	}
 
 	actions := c.actions
@@ -374,8 +389,13 @@ func (c *channel) proposeSend(messageId int64, fromUid string, fromDeviceId stri
 		SendPacket:   sendPacket,
 		MessageId:    messageId,
 		IsEncrypt:    isEncrypt,
-		ReasonCode:   wkproto.ReasonSuccess, // 初始状态为成功
 	}
+	return c.proposeMessage(message)
+}
+
+// proposeMessage 提案频道消息
+func (c *channel) proposeMessage(message ReactorChannelMessage) error {
+	message.ReasonCode = wkproto.ReasonSuccess // 初始状态为成功
 
 	c.sub.step(c, &ChannelAction{
 		UniqueNo:   c.uniqueNo,
@@ -452,6 +472,16 @@ type ready struct {
 	actions []*ChannelAction
 }
 
+// releaseReceiverTag 释放接收者标签，下次投递时会重新生成
+func (c *channel) releaseReceiverTag() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.receiverTagKey.Load() != "" {
+		c.r.s.tagManager.releaseReceiverTagNow(c.receiverTagKey.Load())
+		c.receiverTagKey.Store("")
+	}
+}
+
 // makeReceiverTag 创建接收者标签
 // 该方法用于为频道创建一个接收者标签，用于标识频道的订阅者及其所在的节点
 // 返回创建的标签和可能的错误
@@ -490,12 +520,20 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 		if c.r.s.opts.IsCmdChannel(c.channelId) {
 			realChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
 		}
-		members, err := c.r.s.store.GetSubscribers(realChannelId, c.channelType)
-		if err != nil {
-			return nil, err
-		}
-		for _, member := range members {
-			subscribers = append(subscribers, member.Uid)
+		if c.r.s.opts.HasDatasource() { // 配置了数据源，订阅者以数据源为准
+			uids, err := c.r.s.datasource.GetSubscribers(realChannelId, c.channelType)
+			if err != nil {
+				return nil, err
+			}
+			subscribers = append(subscribers, uids...)
+		} else {
+			members, err := c.r.s.store.GetSubscribers(realChannelId, c.channelType)
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				subscribers = append(subscribers, member.Uid)
+			}
 		}
 
 		// 如果是客服频道，获取访客的uid作为订阅者
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const ephemeralMaxPending = 1000 // 每个频道最多积压的临时事件数量，超过将丢弃
+
+// 是否是临时事件（正在输入等），由服务端通过ReactorChannelMessage.IsEphemeral标记
+// 临时事件不存储、不更新最近会话、不触发离线webhook，只投递给在线的订阅者
+func isEphemeral(message ReactorChannelMessage) bool {
+	return message.IsEphemeral
+}
+
+// excludeEphemerals 过滤掉临时事件，没有临时事件时直接返回原消息集合
+func excludeEphemerals(messages []ReactorChannelMessage) []ReactorChannelMessage {
+	for i, message := range messages {
+		if !isEphemeral(message) {
+			continue
+		}
+		filtered := make([]ReactorChannelMessage, 0, len(messages)-1)
+		filtered = append(filtered, messages[:i]...)
+		for _, m := range messages[i+1:] {
+			if !isEphemeral(m) {
+				filtered = append(filtered, m)
+			}
+		}
+		return filtered
+	}
+	return messages
+}
+
+// 是否有未处理的临时事件
+func (c *channel) hasEphemeral() bool {
+	return len(c.ephemerals) > 0
+}
+
+// appendEphemeral 添加临时事件，超过发送频率的事件标记为ReasonRateLimit（只回执不投递）
+func (c *channel) appendEphemeral(message ReactorChannelMessage) {
+	if len(c.ephemerals) >= ephemeralMaxPending {
+		c.Warn("too many pending ephemerals, drop", zap.String("fromUid", message.FromUid), zap.Int64("messageId", message.MessageId))
+		return
+	}
+	message.ReasonCode = wkproto.ReasonSuccess
+	if !c.allowEphemeral(message.FromUid) {
+		message.ReasonCode = wkproto.ReasonRateLimit
+	}
+	c.ephemerals = append(c.ephemerals, message)
+}
+
+// allowEphemeral 按发送者限制临时事件的发送频率（每秒固定窗口）
+func (c *channel) allowEphemeral(fromUid string) bool {
+	limit := c.opts.Channel.EphemeralRateLimit
+	if limit <= 0 || fromUid == c.opts.SystemUID {
+		return true
+	}
+	now := time.Now()
+	if c.ephemeralWindowCounts == nil || now.Sub(c.ephemeralWindowStart) >= time.Second {
+		c.ephemeralWindowStart = now
+		c.ephemeralWindowCounts = make(map[string]int)
+	}
+	count := c.ephemeralWindowCounts[fromUid]
+	if count >= limit {
+		return false
+	}
+	c.ephemeralWindowCounts[fromUid] = count + 1
+	return true
+}
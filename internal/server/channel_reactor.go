@@ -21,12 +21,14 @@ type channelReactor struct {
 	processInitC           chan *initReq           // 处理频道初始化
 	processPayloadDecryptC chan *payloadDecryptReq // 处理消息解密
 	processPermissionC     chan *permissionReq     // 权限请求
+	processBeforeSendC     chan *permissionReq     // 发送前审核
 	processStorageC        chan *storageReq        // 存储请求
 	processDeliverC        chan *deliverReq        // 投递请求
 	processSendackC        chan *sendackReq        // 发送回执请求
 	processForwardC        chan *forwardReq        // 转发请求
 	processCloseC          chan *closeReq          // 关闭请求
 	processCheckTagC       chan *checkTagReq       // 检查tag请求
+	processEphemeralC      chan *ephemeralReq      // 临时事件请求
 
 	stopper *syncutil.Stopper
 	opts    *Options
@@ -49,12 +51,14 @@ func newChannelReactor(s *Server, opts *Options) *channelReactor {
 		processInitC:           make(chan *initReq, 2048),
 		processPayloadDecryptC: make(chan *payloadDecryptReq, 2048),
 		processPermissionC:     make(chan *permissionReq, 2048),
+		processBeforeSendC:     make(chan *permissionReq, 2048),
 		processStorageC:        make(chan *storageReq, 2048),
 		processDeliverC:        make(chan *deliverReq, 2048),
 		processSendackC:        make(chan *sendackReq, 2048),
 		processForwardC:        make(chan *forwardReq, 2048),
 		processCloseC:          make(chan *closeReq, 10),
 		processCheckTagC:       make(chan *checkTagReq, 100),
+		processEphemeralC:      make(chan *ephemeralReq, 2048),
 		stopper:                syncutil.NewStopper(),
 		opts:                   opts,
 		Log:                    wklog.NewWKLog(fmt.Sprintf("ChannelReactor[%d]", opts.Cluster.NodeId)),
@@ -75,6 +79,7 @@ func (r *channelReactor) start() error {
 	for i := 0; i < 50; i++ {
 
 		r.stopper.RunWorker(r.processPayloadDecryptLoop)
+		r.stopper.RunWorker(r.processBeforeSendLoop)
 
 	}
 
@@ -85,6 +90,7 @@ func (r *channelReactor) start() error {
 
 		r.stopper.RunWorker(r.processCheckTagLoop)
 		r.stopper.RunWorker(r.processForwardLoop)
+		r.stopper.RunWorker(r.processEphemeralLoop)
 
 	}
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -167,6 +168,11 @@ func (r *channelReactor) processPayloadDecrypt(req *payloadDecryptReq) {
 		req.messages[i] = msg
 	}
 
+	// 敏感词过滤只在领导节点上做（代理节点会把解密后的消息转发给领导节点）
+	if req.isLeader && !req.isStream && r.opts.SensitiveWord.On {
+		r.processSensitiveWord(req)
+	}
+
 	actionType := ChannelActionPayloadDecryptResp
 	if req.isStream {
 		actionType = ChannelActionStreamPayloadDecryptResp
@@ -186,6 +192,7 @@ type payloadDecryptReq struct {
 	ch       *channel
 	messages []ReactorChannelMessage
 	isStream bool // 是流消息
+	isLeader bool // 是否是领导节点
 }
 
 // =================================== 转发 ===================================
@@ -454,7 +461,6 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 
 	fromUidMap := map[string]wkproto.ReasonCode{}
 	// 权限判断
-	sub := r.reactorSub(req.ch.key)
 	for i, msg := range req.messages {
 
 		if msg.ReasonCode != wkproto.ReasonSuccess {
@@ -495,7 +501,19 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 		req.messages[i].ReasonCode = reasonCode
 		fromUidMap[msg.FromUid] = reasonCode
 	}
-	// 返回成功
+
+	// 发送前审核会同步调用外部服务，交给单独的协程处理，不阻塞权限校验
+	if r.s.webhook.beforeSendOn() && needBeforeSend(req.messages) {
+		r.addBeforeSendReq(req)
+		return
+	}
+
+	r.stepPermissionResp(req)
+}
+
+// stepPermissionResp 返回权限校验结果
+func (r *channelReactor) stepPermissionResp(req *permissionReq) {
+	sub := r.reactorSub(req.ch.key)
 	lastMsg := req.messages[len(req.messages)-1]
 	sub.step(req.ch, &ChannelAction{
 		UniqueNo:   req.ch.uniqueNo,
@@ -547,7 +565,21 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		return reasonCode, nil
 	}
 
+	realChannelId := channelId
+
+	if r.opts.IsCmdChannel(channelId) {
+		realChannelId = r.opts.CmdChannelConvertOrginalChannel(channelId)
+	}
+
 	channelInfo := ch.info
+	if r.opts.Datasource.ChannelInfoOn && r.opts.HasDatasource() {
+		info, err := r.s.datasource.GetChannelInfo(realChannelId, channelType)
+		if err != nil {
+			r.Error("从数据源获取频道信息失败！", zap.Error(err), zap.String("channelId", realChannelId))
+			return wkproto.ReasonSystemError, err
+		}
+		channelInfo = info
+	}
 
 	if channelInfo.Ban { // 频道被封禁
 		return wkproto.ReasonBan, nil
@@ -557,10 +589,9 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		return wkproto.ReasonDisband, nil
 	}
 
-	realChannelId := channelId
-
-	if r.opts.IsCmdChannel(channelId) {
-		realChannelId = r.opts.CmdChannelConvertOrginalChannel(channelId)
+	// 配置了数据源，订阅者和黑白名单以数据源为准
+	if r.opts.HasDatasource() {
+		return r.hasPermissionFromDatasource(realChannelId, channelType, fromUid, channelInfo)
 	}
 
 	// 判断是否是黑名单内
@@ -574,13 +605,27 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 	}
 
 	// 判断是否是订阅者
-	isSubscriber, err := r.s.store.ExistSubscriber(realChannelId, channelType, fromUid)
+	subscriber, err := r.s.store.GetSubscriber(realChannelId, channelType, fromUid)
 	if err != nil {
-		r.Error("ExistSubscriber error", zap.Error(err))
+		if err == wkdb.ErrNotFound {
+			return wkproto.ReasonSubscriberNotExist, nil
+		}
+		r.Error("GetSubscriber error", zap.Error(err))
 		return wkproto.ReasonSystemError, err
 	}
-	if !isSubscriber {
-		return wkproto.ReasonSubscriberNotExist, nil
+
+	// 创建者不受禁言限制，管理员不受全员禁言限制，白名单对所有角色都生效
+	now := time.Now().Unix()
+	if subscriber.Role != wkdb.MemberRoleOwner {
+		// 判断频道是否全员禁言（和成员禁言区分开，返回单独的原因码）
+		if subscriber.Role != wkdb.MemberRoleAdmin && channelInfo.IsMuted(now) {
+			return ReasonChannelMuted, nil
+		}
+
+		// 判断是否被禁言
+		if subscriber.IsMuted(now) {
+			return wkproto.ReasonNotAllowSend, nil
+		}
 	}
 
 	// 判断是否在白名单内
@@ -606,6 +651,46 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 	return wkproto.ReasonSuccess, nil
 }
 
+// hasPermissionFromDatasource 通过数据源判断是否有发送权限
+// 数据源没有成员角色，频道全员禁言对所有成员生效
+func (r *channelReactor) hasPermissionFromDatasource(channelId string, channelType uint8, fromUid string, channelInfo wkdb.ChannelInfo) (wkproto.ReasonCode, error) {
+	// 判断是否是黑名单内
+	blacklist, err := r.s.datasource.GetBlacklist(channelId, channelType)
+	if err != nil {
+		r.Error("从数据源获取黑名单失败！", zap.Error(err), zap.String("channelId", channelId))
+		return wkproto.ReasonSystemError, err
+	}
+	if wkutil.ArrayContains(blacklist, fromUid) {
+		return wkproto.ReasonInBlacklist, nil
+	}
+
+	// 判断是否是订阅者
+	subscribers, err := r.s.datasource.GetSubscribers(channelId, channelType)
+	if err != nil {
+		r.Error("从数据源获取订阅者失败！", zap.Error(err), zap.String("channelId", channelId))
+		return wkproto.ReasonSystemError, err
+	}
+	if !wkutil.ArrayContains(subscribers, fromUid) {
+		return wkproto.ReasonSubscriberNotExist, nil
+	}
+
+	// 判断频道是否全员禁言
+	if channelInfo.IsMuted(time.Now().Unix()) {
+		return ReasonChannelMuted, nil
+	}
+
+	// 判断是否在白名单内
+	whitelist, err := r.s.datasource.GetWhitelist(channelId, channelType)
+	if err != nil {
+		r.Error("从数据源获取白名单失败！", zap.Error(err), zap.String("channelId", channelId))
+		return wkproto.ReasonSystemError, err
+	}
+	if len(whitelist) > 0 && !wkutil.ArrayContains(whitelist, fromUid) {
+		return wkproto.ReasonNotInWhitelist, nil
+	}
+	return wkproto.ReasonSuccess, nil
+}
+
 func (r *channelReactor) requestAllowSend(from, to string) (wkproto.ReasonCode, error) {
 
 	leaderNode, err := r.s.cluster.SlotLeaderOfChannel(to, wkproto.ChannelTypePerson)
@@ -642,6 +727,11 @@ func (r *channelReactor) requestAllowSend(from, to string) (wkproto.ReasonCode,
 }
 
 func (r *channelReactor) allowSend(from, to string) (wkproto.ReasonCode, error) {
+	// 配置了数据源，个人的黑白名单以数据源为准
+	if r.opts.HasDatasource() {
+		return r.allowSendFromDatasource(from, to)
+	}
+
 	// 判断是否是黑名单内
 	isDenylist, err := r.s.store.ExistDenylist(to, wkproto.ChannelTypePerson, from)
 	if err != nil {
@@ -667,9 +757,33 @@ func (r *channelReactor) allowSend(from, to string) (wkproto.ReasonCode, error)
 	return wkproto.ReasonSuccess, nil
 }
 
+func (r *channelReactor) allowSendFromDatasource(from, to string) (wkproto.ReasonCode, error) {
+	blacklist, err := r.s.datasource.GetBlacklist(to, wkproto.ChannelTypePerson)
+	if err != nil {
+		r.Error("从数据源获取黑名单失败！", zap.String("from", from), zap.String("to", to), zap.Error(err))
+		return wkproto.ReasonSystemError, err
+	}
+	if wkutil.ArrayContains(blacklist, from) {
+		return wkproto.ReasonInBlacklist, nil
+	}
+
+	if !r.opts.WhitelistOffOfPerson {
+		whitelist, err := r.s.datasource.GetWhitelist(to, wkproto.ChannelTypePerson)
+		if err != nil {
+			r.Error("从数据源获取白名单失败！", zap.String("from", from), zap.String("to", to), zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		if !wkutil.ArrayContains(whitelist, from) {
+			return wkproto.ReasonNotInWhitelist, nil
+		}
+	}
+	return wkproto.ReasonSuccess, nil
+}
+
 type permissionReq struct {
-	ch       *channel
-	messages []ReactorChannelMessage
+	ch                 *channel
+	messages           []ReactorChannelMessage
+	beforeSendDeadline time.Time // 发送前审核的截止时间，加入审核队列时设置
 }
 
 // =================================== 消息存储 ===================================
@@ -777,6 +891,10 @@ func (r *channelReactor) processStorage(req *storageReq) {
 				StreamNo:    reactorMsg.SendPacket.StreamNo,
 				Payload:     reactorMsg.SendPacket.Payload,
 			},
+			Sensitive: reactorMsg.IsSensitive,
+		}
+		if !reactorMsg.IsEncrypt {
+			msg.ParentMessageSeq = threadParentOfPayload(msg.Payload)
 		}
 		messages = append(messages, msg)
 
@@ -846,7 +964,7 @@ func (r *channelReactor) processStorage(req *storageReq) {
 		}
 	}
 
-	if r.opts.WebhookOn() && reason == ReasonSuccess {
+	if r.s.webhook.on() && reason == ReasonSuccess {
 		// 赋值messageeq
 		for i, msg := range messages {
 			for _, cmsg := range req.messages {
@@ -941,16 +1059,31 @@ func (r *channelReactor) processSendacks(reqs []*sendackReq) {
 }
 
 func (r *channelReactor) processSendack(req *sendackReq) {
+	if req.ch.channelId == "g1" {
+		fmt.Println("processSendack......")
+	}
+
+	r.writeSendacks(req.messages)
+
+	lastMsg := req.messages[len(req.messages)-1]
+	sub := r.reactorSub(req.ch.key)
+	sub.step(req.ch, &ChannelAction{
+		UniqueNo:   req.ch.uniqueNo,
+		ActionType: ChannelActionSendackResp,
+		Index:      lastMsg.Index,
+		Reason:     ReasonSuccess,
+	})
+}
+
+// writeSendacks 给发送者回执，发送者连接不在本节点的转发给对应节点
+func (r *channelReactor) writeSendacks(messages []ReactorChannelMessage) {
 	nodeFowardSendackPacketMap := map[uint64][]*ForwardSendackPacket{}
-	for _, msg := range req.messages {
+	for _, msg := range messages {
 
 		if msg.FromUid == r.opts.SystemUID { // 如果是系统消息，不需要发送ack
 			continue
 		}
 		r.MessageTrace("发送ack", msg.SendPacket.ClientMsgNo, "processSendack")
-		if req.ch.channelId == "g1" {
-			fmt.Println("processSendack......")
-		}
 
 		sendack := &wkproto.SendackPacket{
 			Framer:      msg.SendPacket.Framer,
@@ -981,15 +1114,6 @@ func (r *channelReactor) processSendack(req *sendackReq) {
 			r.Error("requestForwardSendack error", zap.Error(err), zap.Uint64("nodeId", nodeId))
 		}
 	}
-
-	lastMsg := req.messages[len(req.messages)-1]
-	sub := r.reactorSub(req.ch.key)
-	sub.step(req.ch, &ChannelAction{
-		UniqueNo:   req.ch.uniqueNo,
-		ActionType: ChannelActionSendackResp,
-		Index:      lastMsg.Index,
-		Reason:     ReasonSuccess,
-	})
 }
 
 func (r *channelReactor) requestForwardSendack(nodeId uint64, packets []*ForwardSendackPacket) error {
@@ -1249,3 +1373,121 @@ func (r *channelReactor) processCheckTag(req *checkTagReq) {
 type checkTagReq struct {
 	ch *channel
 }
+
+// =================================== 临时事件 ===================================
+
+func (r *channelReactor) addEphemeralReq(req *ephemeralReq) {
+	select {
+	case r.processEphemeralC <- req:
+	default:
+		r.Warn("processEphemeralC is full, ignore", zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+	}
+}
+
+func (r *channelReactor) processEphemeralLoop() {
+	for {
+		select {
+		case req := <-r.processEphemeralC:
+			r.processEphemeral(req)
+		case <-r.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// processEphemeral 处理临时事件，不存储、不更新最近会话、不触发离线webhook
+// 领导节点判断权限后回执并投递给在线订阅者，代理节点将解密后的事件转发给领导节点
+func (r *channelReactor) processEphemeral(req *ephemeralReq) {
+	fromUidMap := map[string]wkproto.ReasonCode{}
+	for i, msg := range req.messages {
+		if msg.ReasonCode == wkproto.ReasonSuccess && msg.IsEncrypt {
+			msg.IsEncrypt = false
+			conn := r.s.userReactor.getConnById(msg.FromUid, msg.FromConnId)
+			if conn == nil {
+				msg.ReasonCode = wkproto.ReasonSenderOffline
+			} else if len(msg.SendPacket.Payload) > 0 {
+				decryptPayload, err := r.s.checkAndDecodePayload(msg.SendPacket, conn)
+				if err != nil {
+					r.Warn("decrypt ephemeral payload error", zap.String("uid", msg.FromUid), zap.String("deviceId", msg.FromDeviceId), zap.Int64("connId", msg.FromConnId), zap.Error(err))
+					msg.ReasonCode = wkproto.ReasonPayloadDecodeError
+				} else {
+					msg.SendPacket.Payload = decryptPayload
+				}
+			}
+		}
+
+		if req.isLeader && msg.ReasonCode == wkproto.ReasonSuccess && !msg.IsSystem {
+			reasonCode, ok := fromUidMap[msg.FromUid]
+			if !ok {
+				var err error
+				reasonCode, err = r.hasPermission(req.ch.channelId, req.ch.channelType, msg.FromUid, req.ch)
+				if err != nil {
+					r.Error("ephemeral hasPermission error", zap.Error(err))
+					reasonCode = wkproto.ReasonSystemError
+				}
+				fromUidMap[msg.FromUid] = reasonCode
+			}
+			msg.ReasonCode = reasonCode
+		}
+		req.messages[i] = msg
+	}
+
+	successMessages := make([]ReactorChannelMessage, 0, len(req.messages))
+	failMessages := make([]ReactorChannelMessage, 0)
+	for _, msg := range req.messages {
+		if msg.ReasonCode == wkproto.ReasonSuccess {
+			successMessages = append(successMessages, msg)
+		} else {
+			failMessages = append(failMessages, msg)
+		}
+	}
+
+	if !req.isLeader {
+		// 失败的事件在本节点直接回执，成功的由领导节点回执
+		if len(failMessages) > 0 {
+			r.writeSendacks(failMessages)
+		}
+		if len(successMessages) == 0 {
+			return
+		}
+		// 临时事件转发失败不重试
+		newLeaderId, err := r.handleForward(&forwardReq{
+			ch:       req.ch,
+			leaderId: req.leaderId,
+			messages: successMessages,
+		})
+		if err != nil {
+			r.Warn("forward ephemeral error", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+		}
+		if newLeaderId > 0 {
+			sub := r.reactorSub(req.ch.key)
+			sub.step(req.ch, &ChannelAction{
+				UniqueNo:   req.ch.uniqueNo,
+				ActionType: ChannelActionLeaderChange,
+				LeaderId:   newLeaderId,
+			})
+		}
+		return
+	}
+
+	r.writeSendacks(req.messages)
+
+	if len(successMessages) > 0 {
+		r.s.deliverManager.deliver(&deliverReq{
+			ch:          req.ch,
+			channelId:   req.ch.channelId,
+			channelType: req.ch.channelType,
+			channelKey:  req.ch.key,
+			tagKey:      req.tagKey,
+			messages:    successMessages,
+		})
+	}
+}
+
+type ephemeralReq struct {
+	ch       *channel
+	isLeader bool   // 当前节点是否是频道领导
+	leaderId uint64 // 频道领导节点（代理节点时有效）
+	tagKey   string
+	messages []ReactorChannelMessage
+}
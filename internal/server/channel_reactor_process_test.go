@@ -0,0 +1,48 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasPermissionChannelMuted(t *testing.T) {
+	configureTestLog(t)
+	s := NewTestServer(t)
+	assert.NoError(t, s.store.Open())
+	defer s.store.Close()
+
+	now := time.Now().Unix()
+	err := s.store.DB().AddSubscribers("g1", wkproto.ChannelTypeGroup, []wkdb.Member{
+		{Uid: "owner", Role: wkdb.MemberRoleOwner},
+		{Uid: "admin", Role: wkdb.MemberRoleAdmin},
+		{Uid: "u1"},
+		{Uid: "u2", MuteUntil: now + 3600},
+	})
+	assert.NoError(t, err)
+
+	ch := newChannel(s.channelReactor.subs[0], "g1", wkproto.ChannelTypeGroup)
+	check := func(fromUid string) wkproto.ReasonCode {
+		reasonCode, err := s.channelReactor.hasPermission("g1", wkproto.ChannelTypeGroup, fromUid, ch)
+		assert.NoError(t, err)
+		return reasonCode
+	}
+
+	assert.Equal(t, wkproto.ReasonSuccess, check("u1"))
+	assert.Equal(t, wkproto.ReasonNotAllowSend, check("u2"))
+
+	// 全员禁言返回单独的原因码，创建者和管理员不受影响
+	ch.info = wkdb.ChannelInfo{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, Mute: true}
+	assert.Equal(t, ReasonChannelMuted, check("u1"))
+	assert.Equal(t, ReasonChannelMuted, check("u2"))
+	assert.Equal(t, wkproto.ReasonSuccess, check("admin"))
+	assert.Equal(t, wkproto.ReasonSuccess, check("owner"))
+
+	// 全员禁言已过期，成员禁言仍然生效
+	ch.info.MuteUntil = now - 1
+	assert.Equal(t, wkproto.ReasonSuccess, check("u1"))
+	assert.Equal(t, wkproto.ReasonNotAllowSend, check("u2"))
+}
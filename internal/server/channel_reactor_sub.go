@@ -141,6 +141,7 @@ func (r *channelReactorSub) handleReady(ch *channel) {
 				ch:       ch,
 				messages: action.Messages,
 				isStream: action.ActionType == ChannelActionStreamPayloadDecrypt,
+				isLeader: ch.role == channelRoleLeader,
 			})
 		case ChannelActionPermissionCheck: // 权限校验
 			r.r.addPermissionReq(&permissionReq{
@@ -180,6 +181,14 @@ func (r *channelReactorSub) handleReady(ch *channel) {
 			r.r.addCheckTagReq(&checkTagReq{
 				ch: ch,
 			})
+		case ChannelActionEphemeral: // 临时事件
+			r.r.addEphemeralReq(&ephemeralReq{
+				ch:       ch,
+				isLeader: ch.role == channelRoleLeader,
+				leaderId: action.LeaderId,
+				tagKey:   ch.receiverTagKey.Load(),
+				messages: action.Messages,
+			})
 		}
 	}
 
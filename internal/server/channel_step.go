@@ -52,6 +52,12 @@ func (c *channel) step(a *ChannelAction) error {
 				continue
 			}
 
+			// 临时事件不进入消息队列
+			if isEphemeral(message) {
+				c.appendEphemeral(message)
+				continue
+			}
+
 			// 如果是流消息，则加入到流消息的队列里
 			streamNo := message.SendPacket.StreamNo
 			if strings.TrimSpace(streamNo) != "" {
@@ -104,6 +110,7 @@ func (c *channel) step(a *ChannelAction) error {
 				decryptMsg := a.Messages[j]
 				if msg.MessageId == decryptMsg.MessageId {
 					msg.SendPacket.Payload = decryptMsg.SendPacket.Payload
+					msg.SendPacket.Setting = decryptMsg.SendPacket.Setting
 					msg.IsEncrypt = decryptMsg.IsEncrypt
 					msg.ReasonCode = decryptMsg.ReasonCode
 					c.msgQueue.messages[i] = msg
@@ -167,6 +174,9 @@ func (c *channel) stepLeader(a *ChannelAction) error {
 				permMsg := a.Messages[j]
 				if msg.MessageId == permMsg.MessageId {
 					msg.ReasonCode = permMsg.ReasonCode
+					if permMsg.SendPacket != nil { // 发送前审核可能替换了消息内容
+						msg.SendPacket = permMsg.SendPacket
+					}
 					c.msgQueue.messages[i] = msg
 					break
 				}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/sendgrid/rest"
 	"go.uber.org/zap"
 )
@@ -20,6 +21,10 @@ const (
 	ClusterMsgTypeNodePing ClusterMsgType = 1001
 	// 节点Pong
 	ClusterMsgTypeNodePong ClusterMsgType = 1002
+	// 用户在线状态事件
+	ClusterMsgTypePresence ClusterMsgType = 1003
+	// 会话已读状态变化
+	ClusterMsgTypeConversationReadState ClusterMsgType = 1004
 )
 
 type channelRole int
@@ -81,6 +86,7 @@ const (
 	ChannelActionLeave        // 离开频道
 	ChannelActionClose        // 关闭频道
 	ChannelActionCheckTag     // 定时检查tag的有效性
+	ChannelActionEphemeral    // 临时事件（正在输入等，不存储只投递给在线订阅者）
 
 )
 
@@ -126,6 +132,8 @@ func (c ChannelActionType) String() string {
 		return "ChannelActionClose"
 	case ChannelActionCheckTag:
 		return "ChannelActionCheckTag"
+	case ChannelActionEphemeral:
+		return "ChannelActionEphemeral"
 
 	}
 	return fmt.Sprintf("Unknow(%d)", c)
@@ -244,6 +252,15 @@ func GetFromUIDAndToUIDWith(channelId string) (string, string) {
 	return "", ""
 }
 
+// GetPeerUIDWith 个人频道在uid视角下的频道ID（即对方的uid）
+func GetPeerUIDWith(fakeChannelId string, uid string) string {
+	uid1, uid2 := GetFromUIDAndToUIDWith(fakeChannelId)
+	if uid1 == uid {
+		return uid2
+	}
+	return uid1
+}
+
 // GetCommunityTopicParentChannelID 获取社区话题频道的父频道ID
 func GetCommunityTopicParentChannelID(channelID string) string {
 	channelIDs := strings.Split(channelID, "@")
@@ -253,6 +270,10 @@ func GetCommunityTopicParentChannelID(channelID string) string {
 	return ""
 }
 
+// ReasonChannelMuted 频道全员禁言中，发送者收到的sendack原因码
+// 协议库目前没有对应的原因码，这里固定取值100，和协议库后续新增的原因码（从ReasonDisband往后递增）错开
+const ReasonChannelMuted wkproto.ReasonCode = 100
+
 type Reason int
 
 const (
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPeerUIDWith(t *testing.T) {
+	fakeChannelId := GetFakeChannelIDWith("u1", "u2")
+	assert.Equal(t, "u2", GetPeerUIDWith(fakeChannelId, "u1"))
+	assert.Equal(t, "u1", GetPeerUIDWith(fakeChannelId, "u2"))
+
+	// 自己和自己的个人频道
+	assert.Equal(t, "u1", GetPeerUIDWith(GetFakeChannelIDWith("u1", "u1"), "u1"))
+}
+
+func TestPersonCMDParam(t *testing.T) {
+	fakeChannelId := GetFakeChannelIDWith("u1", "u2")
+	param := map[string]interface{}{
+		"channel_id":   "u2",
+		"channel_type": 1,
+		"message_seq":  10,
+	}
+
+	// 每个接收者看到的频道ID都是对方的uid，原param不变
+	assert.Equal(t, map[string]interface{}{"channel_id": "u2", "channel_type": 1, "message_seq": 10}, personCMDParam(fakeChannelId, "u1", param))
+	assert.Equal(t, map[string]interface{}{"channel_id": "u1", "channel_type": 1, "message_seq": 10}, personCMDParam(fakeChannelId, "u2", param))
+	assert.Equal(t, "u2", param["channel_id"])
+}
@@ -58,6 +58,8 @@ type connContext struct {
 
 	lastActivity atomic.Time // 最后活动时间
 
+	mqtt *mqttSession // mqtt连接的会话，不为nil表示是mqtt连接
+
 	wklog.Log
 }
 
@@ -225,6 +227,14 @@ func (c *connContext) writeDirectlyPacket(packet wkproto.Frame) error {
 
 // 直接写入连接
 func (c *connContext) writeDirectly(data []byte, recvFrameCount uint32) error {
+	if c.mqtt != nil { // mqtt连接需要把协议包翻译成mqtt报文
+		return c.writeMQTTOutbound(data, recvFrameCount)
+	}
+	return c.writeToConn(data, recvFrameCount)
+}
+
+// writeToConn 把数据写入连接
+func (c *connContext) writeToConn(data []byte, recvFrameCount uint32) error {
 
 	dataSize := int64(len(data))
 	if recvFrameCount > 0 {
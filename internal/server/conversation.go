@@ -11,6 +11,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/lni/goutils/syncutil"
@@ -65,7 +66,12 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 		}
 
 		worker := c.worker(message.FromUid)
-		worker.getOrCreateUserConversation(message.FromUid).updateOrAddConversation(fakeChannelId, channelType, message.MessageSeq)
+		if worker.getOrCreateUserConversation(message.FromUid).updateOrAddConversation(fakeChannelId, channelType, message.MessageSeq) {
+			// 发送者发了消息相当于已读到这条消息，同步给发送者的其他设备
+			if !c.s.opts.IsCmdChannel(fakeChannelId) {
+				c.s.notifyConversationReadState(message.FromUid, message.FromDeviceId, fakeChannelId, channelType, uint64(message.MessageSeq), 0)
+			}
+		}
 	}
 
 	// 处理接受者的最近会话
@@ -479,7 +485,8 @@ func (c *userConversation) addConversationIfNotExist(conversationId uint64, chan
 	return nil
 }
 
-func (c *userConversation) updateOrAddConversation(channelId string, channelType uint8, readedMsgSeq uint32) {
+// 更新或添加会话，已读位置有变化返回true
+func (c *userConversation) updateOrAddConversation(channelId string, channelType uint8, readedMsgSeq uint32) bool {
 
 	c.Lock()
 	defer c.Unlock()
@@ -489,8 +496,9 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 		if conversation.ReadedMsgSeq < readedMsgSeq {
 			conversation.ReadedMsgSeq = readedMsgSeq
 			conversation.NeedUpdate = true
+			return true
 		}
-		return
+		return false
 	}
 
 	var conversationType wkdb.ConversationType
@@ -509,6 +517,7 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	})
+	return true
 }
 
 func (c *userConversation) addConversationNotLock(conversationId uint64, channelId string, channelType uint8, readedMsgSeq uint32) *channelConversation {
@@ -545,3 +554,101 @@ type channelConversation struct {
 	CreatedAt        time.Time             `json:"created_at"`
 	UpdatedAt        time.Time             `json:"updated_at"`
 }
+
+// notifyConversationReadState 通知用户的其他设备会话已读状态发生变化（不存储）
+// excludeDeviceId为发起变化的设备，用户的连接都在用户所在槽的领导节点上，不是领导节点时转发过去
+func (s *Server) notifyConversationReadState(uid string, excludeDeviceId string, fakeChannelId string, channelType uint8, readToMsgSeq uint64, unread uint32) {
+	req := &conversationReadStateReq{
+		Uid:             uid,
+		ExcludeDeviceId: excludeDeviceId,
+		ChannelId:       fakeChannelId,
+		ChannelType:     channelType,
+		ReadToMsgSeq:    readToMsgSeq,
+		Unread:          unread,
+	}
+	leaderId, err := s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		s.Warn("获取用户所在槽的领导节点失败！", zap.Error(err), zap.String("uid", uid))
+		return
+	}
+	if leaderId == s.opts.Cluster.NodeId {
+		s.notifyConversationReadStateToLocal(req)
+		return
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		s.Error("conversationReadStateReq.Marshal error", zap.Error(err))
+		return
+	}
+	err = s.cluster.Send(leaderId, &proto.Message{
+		MsgType: uint32(ClusterMsgTypeConversationReadState),
+		Content: data,
+	})
+	if err != nil {
+		s.Warn("转发会话已读状态失败！", zap.Error(err), zap.String("uid", uid), zap.Uint64("leaderId", leaderId))
+	}
+}
+
+// notifyConversationReadStateToLocal 写入本节点上用户的连接
+func (s *Server) notifyConversationReadStateToLocal(req *conversationReadStateReq) {
+	// 返回客户端视角的频道
+	channelId := req.ChannelId
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		from, to := GetFromUIDAndToUIDWith(req.ChannelId)
+		channelId = to
+		if to == req.Uid {
+			channelId = from
+		}
+	}
+	s.writeCMDToUser(req.Uid, req.ExcludeDeviceId, "conversationReadStateUpdate", map[string]interface{}{
+		"channel_id":      channelId,
+		"channel_type":    req.ChannelType,
+		"read_to_msg_seq": req.ReadToMsgSeq,
+		"unread":          req.Unread,
+	})
+}
+
+type conversationReadStateReq struct {
+	Uid             string
+	ExcludeDeviceId string // 发起变化的设备，不通知
+	ChannelId       string // 频道ID（个人频道为fakeChannelId）
+	ChannelType     uint8
+	ReadToMsgSeq    uint64
+	Unread          uint32
+}
+
+func (c *conversationReadStateReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.Uid)
+	enc.WriteString(c.ExcludeDeviceId)
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteUint64(c.ReadToMsgSeq)
+	enc.WriteUint32(c.Unread)
+	return enc.Bytes(), nil
+}
+
+func (c *conversationReadStateReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ExcludeDeviceId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.ReadToMsgSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.Unread, err = dec.Uint32(); err != nil {
+		return err
+	}
+	return nil
+}
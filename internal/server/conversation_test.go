@@ -3,7 +3,9 @@ package server
 import (
 	"testing"
 
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +41,67 @@ func TestConversationGet(t *testing.T) {
 	assert.Equal(t, uint64(0), conversations2[0].ReadToMsgSeq)
 
 }
+
+func TestConversationReadStateReqMarshal(t *testing.T) {
+	req := &conversationReadStateReq{
+		Uid:             "u1",
+		ExcludeDeviceId: "d1",
+		ChannelId:       "u1@u2",
+		ChannelType:     wkproto.ChannelTypePerson,
+		ReadToMsgSeq:    100,
+		Unread:          2,
+	}
+	data, err := req.Marshal()
+	assert.NoError(t, err)
+
+	result := &conversationReadStateReq{}
+	err = result.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, req, result)
+}
+
+// readStateTestCluster 记录转发的分布式消息，用户所在槽的领导节点固定为leaderId
+type readStateTestCluster struct {
+	icluster.Cluster
+	leaderId uint64
+	sends    map[uint64][]*proto.Message
+}
+
+func (c *readStateTestCluster) SlotLeaderIdOfChannel(channelId string, channelType uint8) (uint64, error) {
+	return c.leaderId, nil
+}
+
+func (c *readStateTestCluster) Send(toNodeId uint64, msg *proto.Message) error {
+	c.sends[toNodeId] = append(c.sends[toNodeId], msg)
+	return nil
+}
+
+func TestNotifyConversationReadStateForwardToLeader(t *testing.T) {
+	s := NewTestServer(t)
+	cluster := &readStateTestCluster{
+		leaderId: 1002,
+		sends:    make(map[uint64][]*proto.Message),
+	}
+	s.cluster = cluster
+
+	// 不是用户所在槽的领导节点，转发给领导节点
+	s.notifyConversationReadState("u1", "d1", "u1@u2", wkproto.ChannelTypePerson, 100, 2)
+	assert.Len(t, cluster.sends[1002], 1)
+	msg := cluster.sends[1002][0]
+	assert.Equal(t, uint32(ClusterMsgTypeConversationReadState), msg.MsgType)
+
+	req := &conversationReadStateReq{}
+	err := req.Unmarshal(msg.Content)
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", req.Uid)
+	assert.Equal(t, "d1", req.ExcludeDeviceId)
+	assert.Equal(t, "u1@u2", req.ChannelId)
+	assert.Equal(t, uint64(100), req.ReadToMsgSeq)
+	assert.Equal(t, uint32(2), req.Unread)
+
+	// 是领导节点时直接写本节点的连接，不转发
+	cluster.leaderId = s.opts.Cluster.NodeId
+	s.notifyConversationReadState("u1", "d1", "u1@u2", wkproto.ChannelTypePerson, 101, 0)
+	assert.Len(t, cluster.sends[1002], 1)
+	assert.Len(t, cluster.sends, 1)
+}
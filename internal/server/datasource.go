@@ -1,12 +1,20 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
 	"github.com/WuKongIM/WuKongIM/pkg/network"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
 // IDatasource 数据源第三方应用可以提供
@@ -26,6 +34,9 @@ type IDatasource interface {
 // Datasource Datasource
 type Datasource struct {
 	s *Server
+
+	grpcPoolLock sync.Mutex
+	grpcPool     *grpcpool.Pool // 配置了grpc地址时使用
 }
 
 // NewDatasource 创建一个数据源
@@ -52,7 +63,7 @@ func (d *Datasource) GetChannelInfo(channelID string, channelType uint8) (wkdb.C
 	channelInfo := channelInfoResp.ToChannelInfo()
 	channelInfo.ChannelId = channelID
 	channelInfo.ChannelType = channelType
-	return wkdb.EmptyChannelInfo, nil
+	return *channelInfo, nil
 
 }
 
@@ -132,6 +143,9 @@ func (d *Datasource) requestCMD(cmd string, param map[string]interface{}) (strin
 	if param != nil {
 		dataMap["data"] = param
 	}
+	if d.s.opts.Datasource.GRPCAddr != "" {
+		return d.requestCMDForGRPC(cmd, param)
+	}
 	resp, err := network.Post(d.s.opts.Datasource.Addr, []byte(wkutil.ToJSON(dataMap)), nil)
 	if err != nil {
 		return "", err
@@ -142,3 +156,51 @@ func (d *Datasource) requestCMD(cmd string, param map[string]interface{}) (strin
 
 	return resp.Body, nil
 }
+
+// requestCMDForGRPC 通过grpc请求数据源，请求参数和返回数据与http数据源相同（json格式）
+func (d *Datasource) requestCMDForGRPC(cmd string, param map[string]interface{}) (string, error) {
+	pool, err := d.getGRPCPool()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	clientConn, err := pool.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer clientConn.Close()
+	cli := wkhook.NewDatasourceServiceClient(clientConn)
+
+	req := &wkhook.DatasourceReq{
+		Cmd: cmd,
+	}
+	if param != nil {
+		req.Data = []byte(wkutil.ToJSON(param))
+	}
+	resp, err := cli.Request(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return string(resp.Data), nil
+}
+
+func (d *Datasource) getGRPCPool() (*grpcpool.Pool, error) {
+	d.grpcPoolLock.Lock()
+	defer d.grpcPoolLock.Unlock()
+	if d.grpcPool != nil {
+		return d.grpcPool, nil
+	}
+	addr := d.s.opts.Datasource.GRPCAddr
+	pool, err := grpcpool.New(func() (*grpc.ClientConn, error) {
+		return grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
+			Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
+		}))
+	}, 2, 20, time.Minute*5) // 初始化2个连接 最多20个连接
+	if err != nil {
+		return nil, err
+	}
+	d.grpcPool = pool
+	return pool, nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+const (
+	datasourceCacheSubscribers = "subscribers"
+	datasourceCacheBlacklist   = "blacklist"
+	datasourceCacheWhitelist   = "whitelist"
+	datasourceCacheChannelInfo = "channelInfo"
+	datasourceCacheSystemUIDs  = "systemUIDs"
+)
+
+// 频道相关的缓存类型，频道失效时需要全部移除
+var datasourceChannelCacheKinds = []string{
+	datasourceCacheSubscribers,
+	datasourceCacheBlacklist,
+	datasourceCacheWhitelist,
+	datasourceCacheChannelInfo,
+}
+
+// cachedDatasource 在数据源前面加一层LRU+TTL缓存，只缓存请求成功的结果
+type cachedDatasource struct {
+	datasource IDatasource
+	cache      *lruCache
+}
+
+func newCachedDatasource(datasource IDatasource, size int, ttl time.Duration) *cachedDatasource {
+	return &cachedDatasource{
+		datasource: datasource,
+		cache:      newLRUCache(size, ttl),
+	}
+}
+
+func datasourceCacheKey(kind string, channelID string, channelType uint8) string {
+	return fmt.Sprintf("%s:%s", kind, wkutil.ChannelToKey(channelID, channelType))
+}
+
+func (c *cachedDatasource) GetSubscribers(channelID string, channelType uint8) ([]string, error) {
+	return c.getStrings(datasourceCacheKey(datasourceCacheSubscribers, channelID, channelType), func() ([]string, error) {
+		return c.datasource.GetSubscribers(channelID, channelType)
+	})
+}
+
+func (c *cachedDatasource) GetBlacklist(channelID string, channelType uint8) ([]string, error) {
+	return c.getStrings(datasourceCacheKey(datasourceCacheBlacklist, channelID, channelType), func() ([]string, error) {
+		return c.datasource.GetBlacklist(channelID, channelType)
+	})
+}
+
+func (c *cachedDatasource) GetWhitelist(channelID string, channelType uint8) ([]string, error) {
+	return c.getStrings(datasourceCacheKey(datasourceCacheWhitelist, channelID, channelType), func() ([]string, error) {
+		return c.datasource.GetWhitelist(channelID, channelType)
+	})
+}
+
+func (c *cachedDatasource) GetSystemUIDs() ([]string, error) {
+	return c.getStrings(datasourceCacheSystemUIDs, c.datasource.GetSystemUIDs)
+}
+
+func (c *cachedDatasource) GetChannelInfo(channelID string, channelType uint8) (wkdb.ChannelInfo, error) {
+	key := datasourceCacheKey(datasourceCacheChannelInfo, channelID, channelType)
+	value, version, ok := c.cache.get(key)
+	if ok {
+		return value.(wkdb.ChannelInfo), nil
+	}
+	channelInfo, err := c.datasource.GetChannelInfo(channelID, channelType)
+	if err != nil {
+		return wkdb.EmptyChannelInfo, err
+	}
+	c.cache.set(key, channelInfo, version)
+	return channelInfo, nil
+}
+
+func (c *cachedDatasource) getStrings(key string, load func() ([]string, error)) ([]string, error) {
+	value, version, ok := c.cache.get(key)
+	if ok {
+		return value.([]string), nil
+	}
+	values, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, values, version)
+	return values, nil
+}
+
+// invalidateChannel 移除频道的所有缓存（订阅者、黑白名单、频道信息）
+func (c *cachedDatasource) invalidateChannel(channelID string, channelType uint8) {
+	for _, kind := range datasourceChannelCacheKinds {
+		c.cache.remove(datasourceCacheKey(kind, channelID, channelType))
+	}
+}
+
+// invalidateSystemUIDs 移除系统账号的缓存
+func (c *cachedDatasource) invalidateSystemUIDs() {
+	c.cache.remove(datasourceCacheSystemUIDs)
+}
+
+// invalidateAll 移除所有缓存
+func (c *cachedDatasource) invalidateAll() {
+	c.cache.clear()
+}
+
+// ---------------------- lru ----------------------
+
+type lruEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+// lruCache 带过期时间的LRU缓存，超过最大条数后淘汰最久未使用的
+type lruCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	ll      *list.List
+	entries map[string]*list.Element
+	version uint64 // 每次移除缓存后递增，防止移除前发起的请求把旧数据写回缓存
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get 获取缓存，同时返回当前的版本号，未命中时需要将版本号传给set
+func (l *lruCache) get(key string) (interface{}, uint64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, l.version, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expireAt) {
+		l.removeElement(elem)
+		return nil, l.version, false
+	}
+	l.ll.MoveToFront(elem)
+	return entry.value, l.version, true
+}
+
+// set 写入缓存，期间有缓存被移除（版本号变化）则不写入
+func (l *lruCache) set(key string, value interface{}, version uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if version != l.version {
+		return
+	}
+	expireAt := time.Now().Add(l.ttl)
+	if elem, ok := l.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		l.ll.MoveToFront(elem)
+		return
+	}
+	l.entries[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	for l.ll.Len() > l.size {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *lruCache) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.version++
+	if elem, ok := l.entries[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+func (l *lruCache) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.version++
+	l.ll.Init()
+	l.entries = make(map[string]*list.Element)
+}
+
+func (l *lruCache) removeElement(elem *list.Element) {
+	l.ll.Remove(elem)
+	delete(l.entries, elem.Value.(*lruEntry).key)
+}
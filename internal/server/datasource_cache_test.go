@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDatasource 记录请求次数的数据源
+type testDatasource struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	err         error
+	subscribers []string
+	blacklist   []string
+	whitelist   []string
+	channelInfo wkdb.ChannelInfo
+}
+
+func newTestDatasource() *testDatasource {
+	return &testDatasource{calls: make(map[string]int)}
+}
+
+func (d *testDatasource) call(kind string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls[kind]++
+}
+
+func (d *testDatasource) count(kind string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls[kind]
+}
+
+func (d *testDatasource) GetSubscribers(channelID string, channelType uint8) ([]string, error) {
+	d.call(datasourceCacheSubscribers)
+	return d.subscribers, d.err
+}
+
+func (d *testDatasource) GetBlacklist(channelID string, channelType uint8) ([]string, error) {
+	d.call(datasourceCacheBlacklist)
+	return d.blacklist, d.err
+}
+
+func (d *testDatasource) GetWhitelist(channelID string, channelType uint8) ([]string, error) {
+	d.call(datasourceCacheWhitelist)
+	return d.whitelist, d.err
+}
+
+func (d *testDatasource) GetSystemUIDs() ([]string, error) {
+	d.call(datasourceCacheSystemUIDs)
+	return nil, d.err
+}
+
+func (d *testDatasource) GetChannelInfo(channelID string, channelType uint8) (wkdb.ChannelInfo, error) {
+	d.call(datasourceCacheChannelInfo)
+	return d.channelInfo, d.err
+}
+
+func TestLRUCacheEvict(t *testing.T) {
+	cache := newLRUCache(2, time.Minute)
+
+	_, version, ok := cache.get("a")
+	assert.False(t, ok)
+	cache.set("a", 1, version)
+	cache.set("b", 2, version)
+
+	// 访问a后，b是最久未使用的
+	value, _, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.set("c", 3, version)
+	_, _, ok = cache.get("b")
+	assert.False(t, ok)
+	_, _, ok = cache.get("a")
+	assert.True(t, ok)
+	_, _, ok = cache.get("c")
+	assert.True(t, ok)
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	cache := newLRUCache(10, time.Millisecond*50)
+
+	_, version, _ := cache.get("a")
+	cache.set("a", 1, version)
+	_, _, ok := cache.get("a")
+	assert.True(t, ok)
+
+	time.Sleep(time.Millisecond * 80)
+	_, _, ok = cache.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.ll.Len())
+}
+
+func TestLRUCacheVersion(t *testing.T) {
+	cache := newLRUCache(10, time.Minute)
+
+	// 请求期间有缓存被移除，旧的结果不能写回
+	_, version, _ := cache.get("a")
+	cache.remove("b")
+	cache.set("a", 1, version)
+	_, _, ok := cache.get("a")
+	assert.False(t, ok)
+
+	_, version, _ = cache.get("a")
+	cache.clear()
+	cache.set("a", 1, version)
+	_, _, ok = cache.get("a")
+	assert.False(t, ok)
+
+	// 使用新的版本号可以写入
+	_, version, _ = cache.get("a")
+	cache.set("a", 2, version)
+	value, _, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
+
+func TestCachedDatasource(t *testing.T) {
+	ds := newTestDatasource()
+	ds.subscribers = []string{"u1", "u2"}
+	cached := newCachedDatasource(ds, 100, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		subscribers, err := cached.GetSubscribers("g1", wkproto.ChannelTypeGroup)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"u1", "u2"}, subscribers)
+		_, err = cached.GetChannelInfo("g1", wkproto.ChannelTypeGroup)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, ds.count(datasourceCacheSubscribers))
+	assert.Equal(t, 1, ds.count(datasourceCacheChannelInfo))
+
+	// 不同频道分开缓存
+	_, err := cached.GetSubscribers("g2", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ds.count(datasourceCacheSubscribers))
+
+	cached.invalidateChannel("g1", wkproto.ChannelTypeGroup)
+	_, _ = cached.GetSubscribers("g1", wkproto.ChannelTypeGroup)
+	_, _ = cached.GetSubscribers("g2", wkproto.ChannelTypeGroup)
+	_, _ = cached.GetChannelInfo("g1", wkproto.ChannelTypeGroup)
+	assert.Equal(t, 3, ds.count(datasourceCacheSubscribers))
+	assert.Equal(t, 2, ds.count(datasourceCacheChannelInfo))
+
+	cached.invalidateAll()
+	_, _ = cached.GetSubscribers("g2", wkproto.ChannelTypeGroup)
+	assert.Equal(t, 4, ds.count(datasourceCacheSubscribers))
+}
+
+func TestCachedDatasourceError(t *testing.T) {
+	ds := newTestDatasource()
+	ds.err = errors.New("datasource error")
+	cached := newCachedDatasource(ds, 100, time.Minute)
+
+	// 请求失败的结果不缓存
+	_, err := cached.GetBlacklist("g1", wkproto.ChannelTypeGroup)
+	assert.Error(t, err)
+	_, err = cached.GetBlacklist("g1", wkproto.ChannelTypeGroup)
+	assert.Error(t, err)
+	assert.Equal(t, 2, ds.count(datasourceCacheBlacklist))
+
+	ds.err = nil
+	ds.blacklist = []string{"u3"}
+	blacklist, err := cached.GetBlacklist("g1", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u3"}, blacklist)
+	_, _ = cached.GetBlacklist("g1", wkproto.ChannelTypeGroup)
+	assert.Equal(t, 3, ds.count(datasourceCacheBlacklist))
+}
+
+func newDatasourceTestServer(t *testing.T, ds IDatasource, opt ...Option) *Server {
+	configureTestLog(t)
+	s := NewTestServer(t, append([]Option{WithDatasourceAddr("http://127.0.0.1:0"), WithDatasourceChannelInfoOn(true)}, opt...)...)
+	s.datasource = ds
+	return s
+}
+
+func TestHasPermissionFromDatasource(t *testing.T) {
+	ds := newTestDatasource()
+	ds.subscribers = []string{"u1", "u2", "u3"}
+	ds.blacklist = []string{"u2"}
+	s := newDatasourceTestServer(t, ds)
+	ch := newChannel(s.channelReactor.subs[0], "g1", wkproto.ChannelTypeGroup)
+
+	check := func(fromUid string) wkproto.ReasonCode {
+		reasonCode, err := s.channelReactor.hasPermission("g1", wkproto.ChannelTypeGroup, fromUid, ch)
+		assert.NoError(t, err)
+		return reasonCode
+	}
+
+	assert.Equal(t, wkproto.ReasonSuccess, check("u1"))
+	assert.Equal(t, wkproto.ReasonInBlacklist, check("u2"))
+	assert.Equal(t, wkproto.ReasonSubscriberNotExist, check("u4"))
+
+	ds.whitelist = []string{"u1"}
+	assert.Equal(t, wkproto.ReasonSuccess, check("u1"))
+	assert.Equal(t, wkproto.ReasonNotInWhitelist, check("u3"))
+
+	// 开启频道信息获取后，封禁状态以数据源为准
+	ds.channelInfo = wkdb.ChannelInfo{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, Ban: true}
+	assert.Equal(t, wkproto.ReasonBan, check("u1"))
+
+	ds.channelInfo = wkdb.ChannelInfo{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, Mute: true}
+	assert.Equal(t, ReasonChannelMuted, check("u1"))
+	assert.Equal(t, wkproto.ReasonInBlacklist, check("u2"))
+
+	ds.err = errors.New("datasource error")
+	_, err := s.channelReactor.hasPermission("g1", wkproto.ChannelTypeGroup, "u1", ch)
+	assert.Error(t, err)
+}
+
+func TestAllowSendFromDatasource(t *testing.T) {
+	ds := newTestDatasource()
+	ds.blacklist = []string{"u2"}
+	ds.whitelist = []string{"u1"}
+	s := newDatasourceTestServer(t, ds, WithWhitelistOffOfPerson(false))
+
+	reasonCode, err := s.channelReactor.allowSend("u1", "u9")
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonSuccess, reasonCode)
+
+	reasonCode, err = s.channelReactor.allowSend("u2", "u9")
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonInBlacklist, reasonCode)
+
+	reasonCode, err = s.channelReactor.allowSend("u3", "u9")
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.ReasonNotInWhitelist, reasonCode)
+}
+
+func TestDatasourceInvalidateReleaseReceiverTag(t *testing.T) {
+	s := newDatasourceTestServer(t, newTestDatasource())
+
+	addChannelWithTag := func(channelId string) *channel {
+		key := wkutil.ChannelToKey(channelId, wkproto.ChannelTypeGroup)
+		ch := newChannel(s.channelReactor.reactorSub(key), channelId, wkproto.ChannelTypeGroup)
+		s.channelReactor.reactorSub(key).addChannel(ch)
+		tagKey := wkutil.GenUUID()
+		s.tagManager.addOrUpdateReceiverTag(tagKey, nil)
+		ch.receiverTagKey.Store(tagKey)
+		return ch
+	}
+	g1 := addChannelWithTag("g1")
+	g1Cmd := addChannelWithTag(s.opts.OrginalConvertCmdChannel("g1"))
+	g2 := addChannelWithTag("g2")
+	g2TagKey := g2.receiverTagKey.Load()
+	g1TagKey := g1.receiverTagKey.Load()
+
+	var req datasourceInvalidateReq
+	assert.NoError(t, json.Unmarshal([]byte(`{"channels":[{"channel_id":"g1","channel_type":2}]}`), &req))
+	NewDatasourceAPI(s).invalidateCache(req)
+
+	// 频道和对应的命令频道的tag都被释放，其他频道不受影响
+	assert.Equal(t, "", g1.receiverTagKey.Load())
+	assert.Equal(t, "", g1Cmd.receiverTagKey.Load())
+	assert.Nil(t, s.tagManager.getReceiverTag(g1TagKey))
+	assert.Equal(t, g2TagKey, g2.receiverTagKey.Load())
+	assert.NotNil(t, s.tagManager.getReceiverTag(g2TagKey))
+
+	NewDatasourceAPI(s).invalidateCache(datasourceInvalidateReq{All: true})
+	assert.Equal(t, "", g2.receiverTagKey.Load())
+	assert.Nil(t, s.tagManager.getReceiverTag(g2TagKey))
+}
+
+func TestDatasourceRequestInvalidateLocalTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	s := newDatasourceTestServer(t, newTestDatasource())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	// 节点没有响应时请求随上下文超时结束
+	start := time.Now()
+	err := NewDatasourceAPI(s).requestInvalidateLocal(ctx, &pb.Node{ApiServerAddr: ts.URL}, []byte(`{"all":true}`))
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -62,6 +62,42 @@ func (d *deliverManager) deliver(req *deliverReq) {
 	d.handleDeliver(req)
 }
 
+// deliverCMD 将不存储的cmd消息直接投递给频道的在线订阅者，需要在频道的领导节点上调用
+// sendChannelId为发送者视角的频道id（个人频道为对方uid）
+func (d *deliverManager) deliverCMD(fakeChannelId string, channelType uint8, fromUid string, sendChannelId string, payload []byte) error {
+	ch := d.s.channelReactor.loadOrCreateChannel(fakeChannelId, channelType)
+	if ch.receiverTagKey.Load() == "" {
+		if _, err := ch.makeReceiverTag(); err != nil {
+			return err
+		}
+	}
+	d.deliver(&deliverReq{
+		ch:          ch,
+		channelId:   fakeChannelId,
+		channelType: channelType,
+		channelKey:  wkutil.ChannelToKey(fakeChannelId, channelType),
+		tagKey:      ch.receiverTagKey.Load(),
+		messages: []ReactorChannelMessage{
+			{
+				FromUid:    fromUid,
+				MessageId:  d.s.channelReactor.messageIDGen.Generate().Int64(),
+				ReasonCode: wkproto.ReasonSuccess,
+				SendPacket: &wkproto.SendPacket{
+					Framer: wkproto.Framer{
+						NoPersist: true,
+						SyncOnce:  true,
+					},
+					ClientMsgNo: fmt.Sprintf("%s0", wkutil.GenUUID()),
+					ChannelID:   sendChannelId,
+					ChannelType: channelType,
+					Payload:     payload,
+				},
+			},
+		},
+	})
+	return nil
+}
+
 func (d *deliverManager) handleDeliver(req *deliverReq) {
 
 	retry := 0
@@ -234,9 +270,11 @@ func (d *deliverr) handleDeliverReq(req *deliverReq) {
 					d.MessageTrace("投递节点", msg.SendPacket.ClientMsgNo, "deliverNode", zap.Int("userCount", len(nodeUser.uids)))
 				}
 			}
-			// 更新最近会话
+			// 更新最近会话（临时事件不更新最近会话）
 			if d.dm.s.opts.Conversation.On {
-				d.dm.s.conversationManager.Push(req.channelId, req.channelType, nodeUser.uids, req.messages)
+				if conversationMessages := excludeEphemerals(req.messages); len(conversationMessages) > 0 {
+					d.dm.s.conversationManager.Push(req.channelId, req.channelType, nodeUser.uids, conversationMessages)
+				}
 			}
 
 			// 投递消息
@@ -379,7 +417,7 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 				continue
 			}
 
-			if !recvPacket.NoPersist { // 只有存储的消息才重试
+			if !recvPacket.NoPersist && !isEphemeral(message) { // 只有存储的消息才重试
 				d.dm.s.retryManager.addRetry(&retryMessage{
 					uid:            conn.uid,
 					connId:         conn.connId,
@@ -403,10 +441,10 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 		}
 	}
 
-	if len(webhookOfflineUids) > 0 { // 有离线用户，发送webhook
-		for _, message := range req.messages {
-			d.dm.s.webhook.notifyOfflineMsg(message, webhookOfflineUids)
-		}
+	// 临时事件只投递给在线用户，不发送离线webhook
+	offlineMessages := excludeEphemerals(req.messages)
+	if len(webhookOfflineUids) > 0 && len(offlineMessages) > 0 && d.dm.s.webhook.on() { // 有离线用户，发送webhook
+		d.dm.s.webhook.notifyOfflineMsgs(req.channelId, req.channelType, offlineMessages, webhookOfflineUids)
 	}
 
 	if d.dm.s.opts.Logger.TraceOn {
@@ -444,3 +482,55 @@ func makeMsgKey(signStr string, conn *connContext) (string, error) {
 	}
 	return wkutil.MD5Bytes(msgKeyBytes), nil
 }
+
+// writeCMDToUser 直接写cmd消息到用户的在线连接（不存储，不走频道）
+// 用户的所有连接（包括其他节点上的代理连接）都在用户的领导节点上，所以只有领导节点会写入，excludeDeviceId的设备不写入
+func (s *Server) writeCMDToUser(uid string, excludeDeviceId string, cmd string, param interface{}) {
+	userHandler := s.userReactor.getUserHandler(uid)
+	if userHandler == nil || userHandler.role != userRoleLeader {
+		return
+	}
+	conns := userHandler.getConns()
+	if len(conns) == 0 {
+		return
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"cmd":   cmd,
+		"param": param,
+	}))
+
+	messageId := s.channelReactor.messageIDGen.Generate().Int64()
+	clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+	for _, conn := range conns {
+		if excludeDeviceId != "" && conn.deviceId == excludeDeviceId {
+			continue
+		}
+		recvPacket := &wkproto.RecvPacket{
+			Framer: wkproto.Framer{
+				NoPersist: true,
+				SyncOnce:  true,
+			},
+			MessageID:   messageId,
+			ClientMsgNo: clientMsgNo,
+			StreamFlag:  wkproto.StreamFlagIng,
+			ChannelID:   s.opts.SystemUID,
+			ChannelType: wkproto.ChannelTypePerson,
+			Timestamp:   int32(time.Now().Unix()),
+		}
+		payloadEnc, err := encryptMessagePayload(payload, conn)
+		if err != nil {
+			s.Warn("加密payload失败！", zap.Error(err), zap.String("uid", uid))
+			continue
+		}
+		recvPacket.Payload = payloadEnc
+		msgKey, err := makeMsgKey(recvPacket.VerityString(), conn)
+		if err != nil {
+			s.Warn("生成MsgKey失败！", zap.Error(err), zap.String("uid", uid))
+			continue
+		}
+		recvPacket.MsgKey = msgKey
+		if err = conn.writePacket(recvPacket); err != nil {
+			s.Warn("写入cmd消息失败！", zap.Error(err), zap.String("uid", uid), zap.String("deviceId", conn.deviceId), zap.String("cmd", cmd))
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	EventSinkHTTP = "http" // 推送到配置的webhook http地址
+	EventSinkGRPC = "grpc" // 推送到配置的webhook grpc地址
+	EventSinkFile = "file" // 写入本地NDJSON文件
+)
+
+// EventSink 事件输出端，webhook的所有事件最终都通过输出端发送
+type EventSink interface {
+	// Name 输出端名称（地址），用于日志
+	Name() string
+	// Send 发送事件，data为json格式的事件数据，返回错误时由调用方按退避间隔重试
+	Send(event string, data []byte) error
+}
+
+// sink 推送目标对应的输出端
+func (w *webhook) sink(target webhookTarget) EventSink {
+	if target.isFile {
+		return w.fileSink
+	}
+	if target.grpcAddr != "" {
+		return &grpcEventSink{w: w, addr: target.grpcAddr, secret: target.secret}
+	}
+	return &httpEventSink{w: w, url: target.httpAddr, secret: target.secret}
+}
+
+// ---------------------- http ----------------------
+
+type httpEventSink struct {
+	w      *webhook
+	url    string
+	secret string // 签名密钥
+}
+
+func (h *httpEventSink) Name() string {
+	return h.url
+}
+
+func (h *httpEventSink) Send(event string, data []byte) error {
+	sep := "?"
+	if strings.Contains(h.url, "?") {
+		sep = "&"
+	}
+	eventURL := fmt.Sprintf("%s%sevent=%s", h.url, sep, event)
+	startTime := time.Now().UnixNano() / 1000 / 1000
+	h.w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(webhookHeaderTimestamp, timestamp)
+		req.Header.Set(webhookHeaderSignature, webhookSign(h.secret, timestamp, event, data))
+	}
+	resp, err := h.w.httpClient.Do(req)
+	h.w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+	if err != nil {
+		h.w.Warn("调用第三方消息通知失败！", zap.String("Webhook", h.url), zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		h.w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", h.url))
+		return errors.New("第三方消息通知接口返回状态错误！")
+	}
+	return nil
+}
+
+// ---------------------- grpc ----------------------
+
+type grpcEventSink struct {
+	w      *webhook
+	addr   string
+	secret string // 签名密钥
+}
+
+func (g *grpcEventSink) Name() string {
+	return g.addr
+}
+
+func (g *grpcEventSink) Send(event string, data []byte) error {
+	startTime := time.Now().UnixNano() / 1000 / 1000
+	g.w.Debug("webhook grpc 开始请求", zap.String("event", event))
+
+	pool, err := g.w.getGRPCPool(g.addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	clientConn, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer sendCancel()
+	if g.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		sendCtx = metadata.AppendToOutgoingContext(sendCtx, strings.ToLower(webhookHeaderTimestamp), timestamp, strings.ToLower(webhookHeaderSignature), webhookSign(g.secret, timestamp, event, data))
+	}
+	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
+		Event: event,
+		Data:  data,
+	})
+	g.w.Debug("webhook grpc 请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+
+	if err != nil {
+		return err
+	}
+	if resp.Status != wkhook.EventStatus_Success {
+		return errors.New("grpc返回状态错误！")
+	}
+	return nil
+}
+
+// ---------------------- file ----------------------
+
+// fileEventSink 将事件按行写入本地NDJSON文件，文件超过大小后滚动
+type fileEventSink struct {
+	nodeId uint64
+	writer *lumberjack.Logger
+}
+
+func newFileEventSink(s *Server) *fileEventSink {
+	dir := s.opts.EventSink.File.Dir
+	if strings.TrimSpace(dir) == "" {
+		dir = filepath.Join(s.opts.RootDir, "events")
+	}
+	return &fileEventSink{
+		nodeId: s.opts.Cluster.NodeId,
+		writer: &lumberjack.Logger{
+			Filename:   filepath.Join(dir, "events.ndjson"),
+			MaxSize:    s.opts.EventSink.File.MaxSize, // megabytes
+			MaxBackups: s.opts.EventSink.File.MaxBackups,
+			MaxAge:     s.opts.EventSink.File.MaxAge, // days
+		},
+	}
+}
+
+// fileEvent 文件中的一行
+type fileEvent struct {
+	Event     string          `json:"event"`     // 事件类型
+	NodeId    uint64          `json:"node_id"`   // 产生事件的节点
+	Timestamp int64           `json:"timestamp"` // 写入时间（单位毫秒）
+	Data      json.RawMessage `json:"data"`      // 事件数据，与webhook推送的数据相同
+}
+
+func (f *fileEventSink) Name() string {
+	return fmt.Sprintf("file://%s", f.writer.Filename)
+}
+
+func (f *fileEventSink) Send(event string, data []byte) error {
+	line, err := json.Marshal(fileEvent{
+		Event:     event,
+		NodeId:    f.nodeId,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      json.RawMessage(data),
+	})
+	if err != nil {
+		return err
+	}
+	// 一行一次写入，lumberjack内部有锁，并发写入不会交错
+	_, err = f.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (f *fileEventSink) close() error {
+	return f.writer.Close()
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// configureTestLog 日志输出到临时目录
+func configureTestLog(t *testing.T) {
+	logOpts := wklog.NewOptions()
+	logOpts.Level = zapcore.InfoLevel
+	logOpts.LogDir = t.TempDir()
+	wklog.Configure(logOpts)
+}
+
+// readFileEvents 读取文件输出端写入的所有事件
+func readFileEvents(t *testing.T, dir string) []fileEvent {
+	f, err := os.Open(filepath.Join(dir, "events.ndjson"))
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var events []fileEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event fileEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	assert.NoError(t, scanner.Err())
+	return events
+}
+
+func TestEventSinks(t *testing.T) {
+	opts := NewOptions()
+	assert.Nil(t, opts.EventSinks(EventMsgNotify))
+
+	// 没有单独配置的事件输出到webhook地址，配置了grpc地址优先使用grpc
+	opts.Webhook.HTTPAddr = "http://127.0.0.1:8080/webhook"
+	assert.Equal(t, []string{EventSinkHTTP}, opts.EventSinks(EventMsgNotify))
+	opts.Webhook.GRPCAddr = "127.0.0.1:6979"
+	assert.Equal(t, []string{EventSinkGRPC}, opts.EventSinks(EventMsgNotify))
+
+	// 按事件类型配置输出端
+	vp := viper.New()
+	vp.Set("eventSink.events", []string{"msg.notify:http,file", "user.onlinestatus: file "})
+	opts.ConfigureWithViper(vp)
+	assert.Equal(t, []string{EventSinkHTTP, EventSinkFile}, opts.EventSinks(EventMsgNotify))
+	assert.Equal(t, []string{EventSinkFile}, opts.EventSinks(EventOnlineStatus))
+	assert.Equal(t, []string{EventSinkGRPC}, opts.EventSinks(EventMsgOffline))
+	assert.True(t, opts.EventSinkFileOn())
+}
+
+func TestWebhookGlobalTargets(t *testing.T) {
+	configureTestLog(t)
+	s := NewTestServer(t, WithWebhookHTTPAddr("http://127.0.0.1:8080/webhook"), WithWebhookSecret("secret"))
+	s.opts.EventSink.File.Dir = t.TempDir()
+	s.opts.EventSink.Events[EventMsgNotify] = []string{EventSinkFile, EventSinkHTTP}
+
+	w := newWebhook(s)
+	defer w.Stop()
+
+	targets := w.globalTargets(EventMsgNotify)
+	assert.Len(t, targets, 2)
+	assert.True(t, targets[0].isFile)
+	assert.Equal(t, EventSinkFile, targets[0].addr())
+	assert.Equal(t, "http://127.0.0.1:8080/webhook", targets[1].httpAddr)
+	assert.Equal(t, "secret", targets[1].secret)
+
+	// 没有单独配置的事件输出到webhook地址
+	targets = w.globalTargets(EventMsgOffline)
+	assert.Len(t, targets, 1)
+	assert.Equal(t, "http://127.0.0.1:8080/webhook", targets[0].httpAddr)
+
+	// 没有文件输出端时不会返回文件推送目标
+	w.fileSink = nil
+	targets = w.globalTargets(EventMsgNotify)
+	assert.Len(t, targets, 1)
+	assert.False(t, targets[0].isFile)
+}
+
+func TestFileEventSink(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.EventSink.File.Dir = t.TempDir()
+	sink := newFileEventSink(s)
+
+	err := sink.Send(EventMsgNotify, []byte(`[{"message_id":1}]`))
+	assert.NoError(t, err)
+	err = sink.Send(EventOnlineStatus, []byte(`["u1-1-1-1-1-1"]`))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.close())
+
+	events := readFileEvents(t, s.opts.EventSink.File.Dir)
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventMsgNotify, events[0].Event)
+	assert.Equal(t, s.opts.Cluster.NodeId, events[0].NodeId)
+	assert.True(t, events[0].Timestamp > 0)
+	assert.JSONEq(t, `[{"message_id":1}]`, string(events[0].Data))
+	assert.Equal(t, EventOnlineStatus, events[1].Event)
+	assert.JSONEq(t, `["u1-1-1-1-1-1"]`, string(events[1].Data))
+}
+
+// 第一个输出端推送失败时重试，其他输出端只在第一次推送时分发
+func TestWebhookFirstSinkRetry(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var data []string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		bodies = append(bodies, data[0])
+		if len(bodies) == 1 { // 第一次请求失败
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	configureTestLog(t)
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL), WithWebhookRetryBackoff(time.Millisecond*10, time.Millisecond*20))
+	s.opts.EventSink.File.Dir = t.TempDir()
+	s.opts.EventSink.Events[EventOnlineStatus] = []string{EventSinkHTTP, EventSinkFile}
+
+	w := newWebhook(s)
+	w.onlinestatusList = append(w.onlinestatusList, "u1-1-1-1001-1-1")
+	go w.loopOnlineStatus()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(bodies) >= 2
+	}, time.Second*5, time.Millisecond*10)
+	assert.Eventually(t, func() bool {
+		w.onlinestatusLock.Lock()
+		defer w.onlinestatusLock.Unlock()
+		return len(w.onlinestatusList) == 0
+	}, time.Second*5, time.Millisecond*10)
+	w.Stop()
+
+	mu.Lock()
+	assert.Equal(t, []string{"u1-1-1-1001-1-1", "u1-1-1-1001-1-1"}, bodies)
+	mu.Unlock()
+
+	events := readFileEvents(t, s.opts.EventSink.File.Dir)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventOnlineStatus, events[0].Event)
+	assert.JSONEq(t, `["u1-1-1-1001-1-1"]`, string(events[0].Data))
+}
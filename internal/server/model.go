@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,30 @@ type ReactorChannelMessage struct {
 	IsSystem     bool // 是否是系统发送的消息
 	ReasonCode   wkproto.ReasonCode
 	Index        uint64
+	IsEphemeral  bool // 是否是临时事件（正在输入等），由服务端设置
+	IsSensitive  bool // 是否含有敏感词（敏感词过滤策略为flag时），由服务端设置
+}
+
+// 服务端设置的消息标记，编码在消息数据的末尾
+const (
+	reactorMessageFlagEphemeral uint8 = 1 << 0 // 临时事件
+	reactorMessageFlagSensitive uint8 = 1 << 1 // 含有敏感词
+)
+
+func (r *ReactorChannelMessage) flags() uint8 {
+	var flags uint8
+	if r.IsEphemeral {
+		flags |= reactorMessageFlagEphemeral
+	}
+	if r.IsSensitive {
+		flags |= reactorMessageFlagSensitive
+	}
+	return flags
+}
+
+func (r *ReactorChannelMessage) setFlags(flags uint8) {
+	r.IsEphemeral = flags&reactorMessageFlagEphemeral != 0
+	r.IsSensitive = flags&reactorMessageFlagSensitive != 0
 }
 
 func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
@@ -51,6 +76,7 @@ func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
 		}
 	}
 	enc.WriteBinary(packetData)
+	enc.WriteUint8(r.flags())
 
 	return enc.Bytes(), nil
 }
@@ -108,6 +134,15 @@ func (r *ReactorChannelMessage) Unmarshal(data []byte) error {
 		r.SendPacket = packet.(*wkproto.SendPacket)
 	}
 
+	// 兼容旧版本数据，没有消息标记
+	if dec.Len() > 0 {
+		var flags uint8
+		if flags, err = dec.Uint8(); err != nil {
+			return err
+		}
+		r.setFlags(flags)
+	}
+
 	return nil
 }
 
@@ -121,6 +156,7 @@ func (m *ReactorChannelMessage) Size() uint64 {
 	size += 8 // FromNodeId
 	size += 8 // messageId
 	size += 4 // messageSeq
+	size += 1 // flags
 	if m.SendPacket != nil {
 		size += uint64(m.SendPacket.RemainingLength) + 2
 	} else {
@@ -209,6 +245,11 @@ func (rs ReactorChannelMessageSet) Marshal() ([]byte, error) {
 		enc.WriteBinary(packetData)
 	}
 
+	// 消息标记写在末尾，兼容旧版本数据
+	for _, r := range rs {
+		enc.WriteUint8(r.flags())
+	}
+
 	return enc.Bytes(), nil
 }
 
@@ -256,6 +297,16 @@ func (rs *ReactorChannelMessageSet) Unmarshal(data []byte) error {
 
 		*rs = append(*rs, r)
 	}
+
+	if dec.Len() > 0 {
+		for i := range *rs {
+			flags, err := dec.Uint8()
+			if err != nil {
+				return err
+			}
+			(*rs)[i].setFlags(flags)
+		}
+	}
 	return nil
 }
 
@@ -366,22 +417,27 @@ type ChannelAction struct {
 
 // MessageResp 消息返回
 type MessageResp struct {
-	Header       MessageHeader      `json:"header"`                // 消息头
-	Setting      uint8              `json:"setting"`               // 设置
-	MessageId    int64              `json:"message_id"`            // 服务端的消息ID(全局唯一)
-	MessageIdStr string             `json:"message_idstr"`         // 服务端的消息ID(全局唯一)
-	ClientMsgNo  string             `json:"client_msg_no"`         // 客户端消息唯一编号
-	StreamNo     string             `json:"stream_no,omitempty"`   // 流编号
-	StreamSeq    uint32             `json:"stream_seq,omitempty"`  // 流序号
-	StreamFlag   wkproto.StreamFlag `json:"stream_flag,omitempty"` // 流标记
-	MessageSeq   uint64             `json:"message_seq"`           // 消息序列号 （用户唯一，有序递增）
-	FromUID      string             `json:"from_uid"`              // 发送者UID
-	ChannelID    string             `json:"channel_id"`            // 频道ID
-	ChannelType  uint8              `json:"channel_type"`          // 频道类型
-	Topic        string             `json:"topic,omitempty"`       // 话题ID
-	Expire       uint32             `json:"expire"`                // 消息过期时间
-	Timestamp    int32              `json:"timestamp"`             // 服务器消息时间戳(10位，到秒)
-	Payload      []byte             `json:"payload"`               // 消息内容
+	Header       MessageHeader      `json:"header"`                 // 消息头
+	Setting      uint8              `json:"setting"`                // 设置
+	MessageId    int64              `json:"message_id"`             // 服务端的消息ID(全局唯一)
+	MessageIdStr string             `json:"message_idstr"`          // 服务端的消息ID(全局唯一)
+	ClientMsgNo  string             `json:"client_msg_no"`          // 客户端消息唯一编号
+	StreamNo     string             `json:"stream_no,omitempty"`    // 流编号
+	StreamSeq    uint32             `json:"stream_seq,omitempty"`   // 流序号
+	StreamFlag   wkproto.StreamFlag `json:"stream_flag,omitempty"`  // 流标记
+	MessageSeq   uint64             `json:"message_seq"`            // 消息序列号 （用户唯一，有序递增）
+	FromUID      string             `json:"from_uid"`               // 发送者UID
+	ChannelID    string             `json:"channel_id"`             // 频道ID
+	ChannelType  uint8              `json:"channel_type"`           // 频道类型
+	Topic        string             `json:"topic,omitempty"`        // 话题ID
+	Expire       uint32             `json:"expire"`                 // 消息过期时间
+	Timestamp    int32              `json:"timestamp"`              // 服务器消息时间戳(10位，到秒)
+	Payload      []byte             `json:"payload"`                // 消息内容
+	Revoke       int                `json:"revoke,omitempty"`       // 是否已撤回 1.是
+	Revoker      string             `json:"revoker,omitempty"`      // 撤回者uid
+	EditVersion  uint32             `json:"edit_version,omitempty"` // 编辑版本，0表示未编辑
+	EditedAt     int64              `json:"edited_at,omitempty"`    // 最后编辑时间(10位，到秒)
+	Sensitive    int                `json:"sensitive,omitempty"`    // 是否含有敏感词（敏感词过滤策略为flag时标记） 1.是
 	// Streams      []*StreamItemResp  `json:"streams,omitempty"`     // 消息流内容
 }
 
@@ -397,6 +453,7 @@ func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
 	m.Header.RedDot = wkutil.BoolToInt(messageD.RedDot)
 	m.Header.SyncOnce = wkutil.BoolToInt(messageD.SyncOnce)
 	m.Setting = messageD.Setting.Uint8()
+	m.Sensitive = wkutil.BoolToInt(messageD.Sensitive)
 	m.MessageId = messageD.MessageID
 	m.MessageIdStr = strconv.FormatInt(messageD.MessageID, 10)
 	m.ClientMsgNo = messageD.ClientMsgNo
@@ -423,6 +480,10 @@ func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
 	m.ChannelType = messageD.ChannelType
 	m.Topic = messageD.Topic
 	m.Payload = messageD.Payload
+	m.Revoke = wkutil.BoolToInt(messageD.Revoke)
+	m.Revoker = messageD.Revoker
+	m.EditVersion = messageD.EditVersion
+	m.EditedAt = messageD.EditedAt
 }
 
 type MessageOfflineNotify struct {
@@ -431,6 +492,7 @@ type MessageOfflineNotify struct {
 	Compress        string   `json:"compress,omitempty"`         // 压缩ToUIDs 如果为空 表示不压缩 为gzip则采用gzip压缩
 	CompresssToUIDs []byte   `json:"compress_to_uids,omitempty"` // 已压缩的to_uids
 	SourceID        int64    `json:"source_id,omitempty"`        // 来源节点ID
+	MutedUIDs       []string `json:"muted_uids,omitempty"`       // 离线用户中对此会话开启了免打扰的用户
 }
 
 // MessageHeader Message header
@@ -630,6 +692,7 @@ func (f *FowardWriteReq) Unmarshal(data []byte) error {
 
 type clearConversationUnreadReq struct {
 	UID         string `json:"uid"`
+	DeviceId    string `json:"device_id"` // 发起操作的设备，不会收到已读状态同步通知
 	ChannelID   string `json:"channel_id"`
 	ChannelType uint8  `json:"channel_type"`
 	MessageSeq  uint32 `json:"message_seq"` // messageSeq 只有超大群才会传 因为超大群最近会话服务器不会维护，需要客户端传递messageSeq进行主动维护
@@ -645,6 +708,53 @@ func (req clearConversationUnreadReq) Check() error {
 	return nil
 }
 
+type clearConversationHistoryReq struct {
+	UID         string `json:"uid"`
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	MessageSeq  uint64 `json:"message_seq"` // 清空至的消息seq（包含），为0则清空至频道最新的消息
+}
+
+func (req clearConversationHistoryReq) Check() error {
+	if req.UID == "" {
+		return errors.New("uid cannot be empty")
+	}
+	if req.ChannelID == "" || req.ChannelType == 0 {
+		return errors.New("channel_id or channel_type cannot be empty")
+	}
+	return nil
+}
+
+// setConversationAttrsReq 修改会话属性，不传的属性不修改
+type setConversationAttrsReq struct {
+	UID         string  `json:"uid"`
+	ChannelID   string  `json:"channel_id"`
+	ChannelType uint8   `json:"channel_type"`
+	Pinned      *bool   `json:"pinned,omitempty"`      // 是否置顶
+	MutedUntil  *int64  `json:"muted_until,omitempty"` // 免打扰截止时间（单位秒），0为取消免打扰，-1为永久免打扰
+	Folder      *string `json:"folder,omitempty"`      // 分组/标签，空字符串为移出分组
+	Draft       *string `json:"draft,omitempty"`       // 草稿，空字符串为清空草稿
+}
+
+func (req setConversationAttrsReq) Check() error {
+	if req.UID == "" {
+		return errors.New("uid cannot be empty")
+	}
+	if req.ChannelID == "" || req.ChannelType == 0 {
+		return errors.New("channel_id or channel_type cannot be empty")
+	}
+	if req.Pinned == nil && req.MutedUntil == nil && req.Folder == nil && req.Draft == nil {
+		return errors.New("pinned, muted_until, folder or draft must be set")
+	}
+	if req.MutedUntil != nil && *req.MutedUntil < wkdb.ConversationMuteForever {
+		return errors.New("muted_until is invalid")
+	}
+	if req.Draft != nil && len(*req.Draft) > maxConversationDraftLen {
+		return fmt.Errorf("draft cannot exceed %d bytes", maxConversationDraftLen)
+	}
+	return nil
+}
+
 type deleteChannelReq struct {
 	UID         string `json:"uid"`
 	ChannelID   string `json:"channel_id"`
@@ -672,6 +782,13 @@ type syncUserConversationResp struct {
 	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`  // 已读至的消息seq
 	Version         int64          `json:"version"`            // 数据版本
 	Recents         []*MessageResp `json:"recents"`            // 最近N条消息
+
+	Pinned              bool   `json:"pinned,omitempty"`      // 是否置顶
+	MutedUntil          int64  `json:"muted_until,omitempty"` // 免打扰截止时间（单位秒），-1为永久免打扰
+	Folder              string `json:"folder,omitempty"`      // 分组/标签
+	Draft               string `json:"draft,omitempty"`       // 草稿
+	AttrVersion         uint64 `json:"attr_version"`          // 属性最后一次变更时用户的会话版本
+	ConversationVersion uint64 `json:"conversation_version"`  // 用户当前的会话版本，客户端下次同步时传入
 }
 
 func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConversationResp {
@@ -684,18 +801,29 @@ func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConver
 			realChannelId = from
 		}
 	}
+	// 清空的消息不计入未读
+	readedToMsgSeq := conversation.ReadToMsgSeq
+	if conversation.ClearedToMsgSeq > readedToMsgSeq {
+		readedToMsgSeq = conversation.ClearedToMsgSeq
+	}
 	return &syncUserConversationResp{
 		ChannelId:      realChannelId,
 		ChannelType:    conversation.ChannelType,
 		Unread:         int(conversation.UnreadCount),
-		ReadedToMsgSeq: uint32(conversation.ReadToMsgSeq),
+		ReadedToMsgSeq: uint32(readedToMsgSeq),
+		Pinned:         conversation.Pinned,
+		MutedUntil:     conversation.MutedUntil,
+		Folder:         conversation.Folder,
+		Draft:          conversation.Draft,
+		AttrVersion:    conversation.Version,
 	}
 }
 
 type channelRecentMessageReq struct {
-	ChannelId   string `json:"channel_id"`
-	ChannelType uint8  `json:"channel_type"`
-	LastMsgSeq  uint64 `json:"last_msg_seq"`
+	ChannelId       string `json:"channel_id"`
+	ChannelType     uint8  `json:"channel_type"`
+	LastMsgSeq      uint64 `json:"last_msg_seq"`
+	ClearedToMsgSeq uint64 `json:"cleared_to_msg_seq,omitempty"` // 用户清空聊天记录至的消息seq，小于等于此seq的消息不返回
 }
 
 type channelRecentMessage struct {
@@ -753,6 +881,64 @@ func (s subscriberAddReq) Check() error {
 	return nil
 }
 
+type subscriberUpdateReq struct {
+	ChannelId   string                   `json:"channel_id"`   // 频道ID
+	ChannelType uint8                    `json:"channel_type"` // 频道类型
+	Subscribers []subscriberUpdateMember `json:"subscribers"`  // 需要更新的订阅者
+}
+
+// 字段为空表示不修改
+type subscriberUpdateMember struct {
+	Uid        string            `json:"uid"`
+	Role       *uint8            `json:"role,omitempty"`       // 角色 0.普通成员 1.管理员 2.创建者
+	MuteUntil  *int64            `json:"mute_until,omitempty"` // 禁言截止时间（单位秒），0表示解除禁言
+	Attributes map[string]string `json:"attributes,omitempty"` // 扩展属性（覆盖原来的扩展属性）
+}
+
+func (s subscriberUpdateReq) Check() error {
+	if strings.TrimSpace(s.ChannelId) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if IsSpecialChar(s.ChannelId) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	if len(s.Subscribers) == 0 {
+		return errors.New("订阅者不能为空！")
+	}
+	for _, subscriber := range s.Subscribers {
+		if strings.TrimSpace(subscriber.Uid) == "" {
+			return errors.New("订阅者uid不能为空！")
+		}
+		if subscriber.Role != nil && wkdb.MemberRole(*subscriber.Role) > wkdb.MemberRoleOwner {
+			return fmt.Errorf("不支持的角色：%d", *subscriber.Role)
+		}
+	}
+	return nil
+}
+
+type subscriberListReq struct {
+	ChannelId   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	OffsetId    uint64 `json:"offset_id"`    // 偏移的订阅者id，第一页传0
+	Limit       int    `json:"limit"`        // 每页数量
+}
+
+func (s subscriberListReq) Check() error {
+	if strings.TrimSpace(s.ChannelId) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if s.Limit < 0 {
+		return errors.New("limit不能小于0！")
+	}
+	return nil
+}
+
+type subscriberListResp struct {
+	Subscribers  []wkdb.Member `json:"subscribers"`
+	NextOffsetId uint64        `json:"next_offset_id"` // 下一页的偏移id
+	More         int           `json:"more"`           // 是否还有更多 1.是 0.否
+}
+
 type subscriberRemoveReq struct {
 	ChannelID      string   `json:"channel_id"`
 	ChannelType    uint8    `json:"channel_type"`
@@ -857,10 +1043,11 @@ var emptySyncMessageResp = syncMessageResp{
 }
 
 type syncMessageResp struct {
-	StartMessageSeq uint64         `json:"start_message_seq"` // 开始序列号
-	EndMessageSeq   uint64         `json:"end_message_seq"`   // 结束序列号
-	More            int            `json:"more"`              // 是否还有更多 1.是 0.否
-	Messages        []*MessageResp `json:"messages"`          // 消息数据
+	StartMessageSeq uint64               `json:"start_message_seq"` // 开始序列号
+	EndMessageSeq   uint64               `json:"end_message_seq"`   // 结束序列号
+	More            int                  `json:"more"`              // 是否还有更多 1.是 0.否
+	Messages        []*MessageResp       `json:"messages"`          // 消息数据
+	Pins            []wkdb.PinnedMessage `json:"pins,omitempty"`    // 频道的置顶消息（频道消息同步时返回）
 }
 
 type syncackReq struct {
@@ -887,6 +1074,9 @@ type ChannelInfoReq struct {
 	Large       int    `json:"large"`        // 是否是超大群
 	Ban         int    `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
 	Disband     int    `json:"disband"`      // 是否解散频道
+	Mute        int    `json:"mute"`         // 是否全员禁言（禁言后只有系统账号、创建者和管理员能发消息）
+	MuteUntil   int64  `json:"mute_until"`   // 全员禁言截止时间（单位秒），0表示一直禁言直到解除
+	Webhook     string `json:"webhook"`      // 频道的webhook地址，开启webhook.channelOn后此频道的消息事件也会推送到此地址
 }
 
 func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
@@ -898,6 +1088,9 @@ func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
 		Large:       c.Large == 1,
 		Ban:         c.Ban == 1,
 		Disband:     c.Disband == 1,
+		Mute:        c.Mute == 1,
+		MuteUntil:   c.MuteUntil,
+		Webhook:     strings.TrimSpace(c.Webhook),
 		CreatedAt:   &createdAt,
 		UpdatedAt:   &updatedAt,
 	}
@@ -914,6 +1107,8 @@ type MessageSendReq struct {
 	Expire      uint32        `json:"expire"`        // 消息过期时间
 	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
 	Payload     []byte        `json:"payload"`       // 消息内容
+	SendAt      int64         `json:"send_at"`       // 定时发送的时间点（单位秒），大于当前时间时消息会在该时间点发送
+	Ephemeral   int           `json:"ephemeral"`     // 是否是临时事件（正在输入等），临时事件不存储只投递给在线订阅者
 }
 
 // Check 检查输入
@@ -921,6 +1116,331 @@ func (m MessageSendReq) Check() error {
 	if m.Payload == nil || len(m.Payload) <= 0 {
 		return errors.New("payload不能为空！")
 	}
+	if m.SendAt > 0 && len(m.Subscribers) > 0 {
+		return errors.New("指定了subscribers的消息不支持定时发送！")
+	}
+	if m.SendAt > 0 && strings.TrimSpace(m.StreamNo) != "" {
+		return errors.New("流消息不支持定时发送！")
+	}
+	if m.Ephemeral == 1 && (m.SendAt > 0 || strings.TrimSpace(m.StreamNo) != "") {
+		return errors.New("临时事件不支持定时发送和流消息！")
+	}
+	return nil
+}
+
+// messageRevokeReq 撤回消息请求
+type messageRevokeReq struct {
+	LoginUID    string `json:"login_uid"`    // 操作者uid（个人频道必填）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+}
+
+func (m messageRevokeReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	if m.MessageSeq == 0 {
+		return errors.New("message_seq不能为0！")
+	}
+	return nil
+}
+
+// messageEditReq 编辑消息请求
+type messageEditReq struct {
+	messageRevokeReq
+	Payload []byte `json:"payload"` // 编辑后的消息内容
+}
+
+func (m messageEditReq) Check() error {
+	if err := m.messageRevokeReq.Check(); err != nil {
+		return err
+	}
+	if len(m.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	return nil
+}
+
+// messageReactionReq 添加/取消消息回应请求
+type messageReactionReq struct {
+	messageRevokeReq
+	Emoji string `json:"emoji"` // 回应的表情
+}
+
+func (m messageReactionReq) Check() error {
+	if err := m.messageRevokeReq.Check(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if strings.TrimSpace(m.Emoji) == "" {
+		return errors.New("emoji不能为空！")
+	}
+	if len(m.Emoji) > 64 {
+		return errors.New("emoji过长！")
+	}
+	return nil
+}
+
+// messageReactionSyncReq 同步频道内的回应变更
+type messageReactionSyncReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户（个人频道必传）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Seq         uint64 `json:"seq"`          // 客户端已同步到的回应版本
+	Limit       int    `json:"limit"`        // 数量限制
+}
+
+func (m messageReactionSyncReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	return nil
+}
+
+type messageReactionSyncResp struct {
+	Reactions []wkdb.Reaction `json:"reactions"`
+	Version   uint64          `json:"version"` // 频道当前的回应版本
+	More      int             `json:"more"`    // 是否还有更多 1.是 0.否
+}
+
+// messageThreadSyncReq 同步父消息的回复
+type messageThreadSyncReq struct {
+	LoginUID         string `json:"login_uid"`          // 当前登录用户（个人频道必传）
+	ChannelID        string `json:"channel_id"`         // 频道ID
+	ChannelType      uint8  `json:"channel_type"`       // 频道类型
+	ParentMessageSeq uint64 `json:"parent_message_seq"` // 父消息seq
+	StartMessageSeq  uint64 `json:"start_message_seq"`  // 开始消息seq（结果包含start_message_seq的消息）
+	Limit            int    `json:"limit"`              // 数量限制
+}
+
+func (m messageThreadSyncReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	if m.ParentMessageSeq == 0 {
+		return errors.New("parent_message_seq不能为0！")
+	}
+	return nil
+}
+
+// messageThreadSummaryReq 获取父消息的回复概要
+type messageThreadSummaryReq struct {
+	LoginUID          string   `json:"login_uid"`           // 当前登录用户（个人频道必传）
+	ChannelID         string   `json:"channel_id"`          // 频道ID
+	ChannelType       uint8    `json:"channel_type"`        // 频道类型
+	ParentMessageSeqs []uint64 `json:"parent_message_seqs"` // 父消息seq列表
+}
+
+func (m messageThreadSummaryReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	if len(m.ParentMessageSeqs) == 0 {
+		return errors.New("parent_message_seqs不能为空！")
+	}
+	if len(m.ParentMessageSeqs) > 1000 {
+		return errors.New("parent_message_seqs不能超过1000个！")
+	}
+	return nil
+}
+
+// messageScheduledReq 查询频道待发送的定时消息
+type messageScheduledReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户（个人频道必传）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (m messageScheduledReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	return nil
+}
+
+// messageScheduledCancelReq 取消定时消息
+type messageScheduledCancelReq struct {
+	messageScheduledReq
+	Id uint64 `json:"id"` // 定时消息id
+}
+
+func (m messageScheduledCancelReq) Check() error {
+	if err := m.messageScheduledReq.Check(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if m.Id == 0 {
+		return errors.New("id不能为0！")
+	}
+	return nil
+}
+
+// presenceSubscribeReq 订阅/取消订阅用户的在线状态
+type presenceSubscribeReq struct {
+	UID  string   `json:"uid"`  // 订阅者
+	UIDs []string `json:"uids"` // 被订阅的用户，取消订阅时为空表示取消全部
+}
+
+func (p presenceSubscribeReq) Check() error {
+	if strings.TrimSpace(p.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if len(p.UIDs) > presenceMaxSubscriptions {
+		return fmt.Errorf("uids数量不能超过%d个！", presenceMaxSubscriptions)
+	}
+	for _, uid := range p.UIDs {
+		if strings.TrimSpace(uid) == "" {
+			return errors.New("uids不能包含空的uid！")
+		}
+	}
+	return nil
+}
+
+// channelPinReq 置顶/取消置顶消息
+type channelPinReq struct {
+	LoginUID    string `json:"login_uid"`    // 操作者
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息seq
+	MessageID   int64  `json:"message_id"`   // 消息id（置顶时必须与message_seq对应的消息一致）
+}
+
+func (c channelPinReq) Check() error {
+	if strings.TrimSpace(c.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if c.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if strings.TrimSpace(c.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if c.MessageSeq == 0 {
+		return errors.New("message_seq不能为0！")
+	}
+	return nil
+}
+
+type channelInfoResp struct {
+	wkdb.ChannelInfo
+	Pins []wkdb.PinnedMessage `json:"pins"` // 置顶消息，按置顶时间倒序
+}
+
+// messageReceiptReq 上报已读回执
+type messageReceiptReq struct {
+	LoginUID    string `json:"login_uid"`    // 已读用户
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	ReadToSeq   uint64 `json:"read_to_seq"`  // 已读到的消息seq（包含）
+}
+
+func (m messageReceiptReq) Check() error {
+	if strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ReadToSeq == 0 {
+		return errors.New("read_to_seq不能为0！")
+	}
+	return nil
+}
+
+// messageReceiptsReq 查询消息的已读用户
+type messageReceiptsReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户（个人频道必传）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息seq
+	Limit       int    `json:"limit"`        // 已读用户数量限制
+}
+
+func (m messageReceiptsReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	if m.MessageSeq == 0 {
+		return errors.New("message_seq不能为0！")
+	}
+	return nil
+}
+
+type messageReceiptsResp struct {
+	MessageSeq uint64               `json:"message_seq"`
+	ReadCount  uint32               `json:"read_count"` // 已读数量
+	Readers    []wkdb.ReceiptReader `json:"readers"`    // 已读用户，大群只保存前MessageReceiptMaxReaders个
+}
+
+// messageKeywordSearchReq 按关键字搜索频道消息
+type messageKeywordSearchReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户（个人频道必传）
+	ChannelID   string `json:"channel_id"`   // 频道id
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Keyword     string `json:"keyword"`      // 关键字，空格分隔表示且，双引号包含表示短语
+	FromUID     string `json:"from_uid"`     // 发送者
+	StartTime   int64  `json:"start_time"`   // 开始时间（单位秒）
+	EndTime     int64  `json:"end_time"`     // 结束时间（单位秒，不包含，翻页时传上一页最后一条消息的时间）
+	Limit       int    `json:"limit"`        // 数量限制
+}
+
+func (m messageKeywordSearchReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("channel_type不能为0")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("login_uid不能为空！")
+	}
+	if strings.TrimSpace(m.Keyword) == "" {
+		return errors.New("keyword不能为空！")
+	}
 	return nil
 }
 
@@ -949,6 +1469,170 @@ func (a *allowSendReq) Marshal() ([]byte, error) {
 	return enc.Bytes(), nil
 }
 
+// 获取用户在频道里清空聊天记录的位置
+type clearedToMsgSeqReq struct {
+	Uid      string
+	Channels []wkdb.Channel
+}
+
+func (c *clearedToMsgSeqReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	var count uint32
+	if count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	c.Channels = make([]wkdb.Channel, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var channel wkdb.Channel
+		if channel.ChannelId, err = dec.String(); err != nil {
+			return err
+		}
+		if channel.ChannelType, err = dec.Uint8(); err != nil {
+			return err
+		}
+		c.Channels = append(c.Channels, channel)
+	}
+	return nil
+}
+
+func (c *clearedToMsgSeqReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.Uid)
+	enc.WriteUint32(uint32(len(c.Channels)))
+	for _, channel := range c.Channels {
+		enc.WriteString(channel.ChannelId)
+		enc.WriteUint8(channel.ChannelType)
+	}
+	return enc.Bytes(), nil
+}
+
+// 获取频道里开启了免打扰的用户
+type mutedUidsReq struct {
+	ChannelId   string
+	ChannelType uint8
+	Uids        []string
+}
+
+func (m *mutedUidsReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	var count uint32
+	if count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	m.Uids = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var uid string
+		if uid, err = dec.String(); err != nil {
+			return err
+		}
+		m.Uids = append(m.Uids, uid)
+	}
+	return nil
+}
+
+func (m *mutedUidsReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteUint32(uint32(len(m.Uids)))
+	for _, uid := range m.Uids {
+		enc.WriteString(uid)
+	}
+	return enc.Bytes(), nil
+}
+
+// 获取频道的置顶消息
+type channelReq struct {
+	ChannelId   string
+	ChannelType uint8
+}
+
+func (c *channelReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *channelReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	return enc.Bytes(), nil
+}
+
+// 获取用户在频道内已回执的位置和消息的已读数量
+type receiptStateReq struct {
+	ChannelId   string
+	ChannelType uint8
+	Uid         string
+	Seqs        []uint64
+}
+
+func (r *receiptStateReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	var count uint32
+	if count, err = dec.Uint32(); err != nil {
+		return err
+	}
+	r.Seqs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var seq uint64
+		if seq, err = dec.Uint64(); err != nil {
+			return err
+		}
+		r.Seqs = append(r.Seqs, seq)
+	}
+	return nil
+}
+
+func (r *receiptStateReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.ChannelId)
+	enc.WriteUint8(r.ChannelType)
+	enc.WriteString(r.Uid)
+	enc.WriteUint32(uint32(len(r.Seqs)))
+	for _, seq := range r.Seqs {
+		enc.WriteUint64(seq)
+	}
+	return enc.Bytes(), nil
+}
+
+type receiptStateResp struct {
+	ReadSeq uint64   `json:"read_seq"` // 用户已回执到的位置
+	Counts  []uint32 `json:"counts"`   // 与请求的seqs一一对应的已读数量
+}
+
 type reactorStreamMessage struct {
 }
 
@@ -0,0 +1,697 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/mqtt"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// mqtt网关
+// mqtt连接收到的报文会被翻译成悟空IM的协议包，复用现有的认证、发消息、投递和回执流程，
+// 写给mqtt连接的协议包在writeDirectly里再翻译成mqtt报文。
+// 主题与频道的对应关系为 channel/{channelType}/{channelId}，订阅只决定连接接收哪些频道的消息，频道的订阅者仍然通过api管理。
+// 暂不支持遗嘱消息、保留消息、离线消息和会话持久化。
+
+const (
+	mqttTopicPrefix        = "channel/"
+	mqttMaxPendingPackets  = 100                // 认证完成前最多缓存的报文数量
+	mqttQoS0ClientSeqStart = math.MaxUint16 + 1 // QoS0消息的clientSeq从65536开始，避免与报文标识符冲突
+)
+
+var errMQTTTooManyPendingPackets = errors.New("too many packets before connack")
+
+// mqttTopic 频道对应的主题
+func mqttTopic(channelId string, channelType uint8) string {
+	return fmt.Sprintf("%s%d/%s", mqttTopicPrefix, channelType, channelId)
+}
+
+// parseMQTTTopic 解析主题对应的频道
+func parseMQTTTopic(topic string) (string, uint8, bool) {
+	if !strings.HasPrefix(topic, mqttTopicPrefix) {
+		return "", 0, false
+	}
+	channelTypeStr, channelId, ok := strings.Cut(strings.TrimPrefix(topic, mqttTopicPrefix), "/")
+	if !ok || strings.TrimSpace(channelId) == "" {
+		return "", 0, false
+	}
+	channelType, err := strconv.ParseUint(channelTypeStr, 10, 8)
+	if err != nil {
+		return "", 0, false
+	}
+	return channelId, uint8(channelType), true
+}
+
+// mqttReasonCode 悟空IM的原因码转换为mqtt的原因码
+func mqttReasonCode(reasonCode wkproto.ReasonCode) mqtt.ReasonCode {
+	switch reasonCode {
+	case wkproto.ReasonSuccess:
+		return mqtt.Success
+	case wkproto.ReasonSubscriberNotExist, wkproto.ReasonInBlacklist, wkproto.ReasonNotInWhitelist, wkproto.ReasonNotAllowSend, wkproto.ReasonBan, wkproto.ReasonDisband, ReasonChannelMuted:
+		return mqtt.NotAuthorized
+	case wkproto.ReasonChannelIDError, wkproto.ReasonChannelNotExist, wkproto.ReasonNotSupportChannelType:
+		return mqtt.TopicNameInvalid
+	case wkproto.ReasonRateLimit:
+		return mqtt.QuotaExceeded
+	}
+	return mqtt.UnspecifiedError
+}
+
+// mqttInflight 已下发等待PUBACK的消息
+type mqttInflight struct {
+	messageId  int64
+	messageSeq uint32
+}
+
+// mqttSession mqtt连接的会话状态
+type mqttSession struct {
+	mu sync.Mutex
+
+	version          mqtt.ProtocolVersion
+	keepAlive        uint16
+	assignedClientId string // 客户端标识符为空时由服务端分配的标识符
+
+	connected bool                 // 是否已经回复了成功的CONNACK
+	replaying bool                 // 是否正在处理认证完成前缓存的报文
+	pending   []mqtt.ControlPacket // 认证完成前收到的报文
+
+	subscriptions map[string]mqtt.QoS // 主题过滤器 -> 授予的QoS
+
+	publishing  map[uint16]mqtt.QoS // 等待sendack的上行消息 报文标识符 -> QoS
+	awaitingRel map[uint16]struct{} // 已回复PUBREC等待PUBREL的QoS2消息
+	qos0Seq     uint64              // QoS0消息的clientSeq
+
+	nextPacketId    uint16
+	inflight        map[uint16]mqttInflight // 等待PUBACK的下行消息
+	inflightPackets map[int64]uint16        // 消息id -> 报文标识符，消息重试投递时复用报文标识符
+}
+
+func newMQTTSession(connect *mqtt.ConnectPacket, assignedClientId string) *mqttSession {
+	return &mqttSession{
+		version:          connect.ProtocolVersion,
+		keepAlive:        connect.KeepAlive,
+		assignedClientId: assignedClientId,
+		subscriptions:    map[string]mqtt.QoS{},
+		publishing:       map[uint16]mqtt.QoS{},
+		awaitingRel:      map[uint16]struct{}{},
+		qos0Seq:          mqttQoS0ClientSeqStart,
+		inflight:         map[uint16]mqttInflight{},
+		inflightPackets:  map[int64]uint16{},
+	}
+}
+
+// received 收到报文，认证完成前或者还在处理缓存的报文时先缓存起来并返回false
+func (m *mqttSession) received(packet mqtt.ControlPacket) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected && !m.replaying {
+		return true, nil
+	}
+	if len(m.pending) >= mqttMaxPendingPackets {
+		return false, errMQTTTooManyPendingPackets
+	}
+	m.pending = append(m.pending, packet)
+	return false, nil
+}
+
+// takePending 取出缓存的报文，没有缓存的报文时结束重放
+func (m *mqttSession) takePending() []mqtt.ControlPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.pending
+	m.pending = nil
+	if len(pending) == 0 {
+		m.replaying = false
+	}
+	return pending
+}
+
+// publish 记录上行消息，返回消息的clientSeq，重复的报文返回false和需要重新回复的报文
+func (m *mqttSession) publish(p *mqtt.PublishPacket) (uint64, mqtt.ControlPacket, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.QoS == mqtt.QoS0 {
+		clientSeq := m.qos0Seq
+		m.qos0Seq++
+		return clientSeq, nil, true
+	}
+	if _, ok := m.publishing[p.PacketID]; ok { // 还在等待sendack
+		return 0, nil, false
+	}
+	if p.QoS == mqtt.QoS2 {
+		if _, ok := m.awaitingRel[p.PacketID]; ok { // 已经发送过了，重新回复PUBREC
+			return 0, &mqtt.PubackPacket{PacketType: mqtt.PUBREC, ProtocolVersion: m.version, PacketID: p.PacketID}, false
+		}
+	}
+	m.publishing[p.PacketID] = p.QoS
+	return uint64(p.PacketID), nil, true
+}
+
+// ack 上行消息的回执
+func (m *mqttSession) ack(clientSeq uint64, reasonCode wkproto.ReasonCode) mqtt.ControlPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.handleSendack(&wkproto.SendackPacket{ClientSeq: clientSeq, ReasonCode: reasonCode})
+}
+
+// pubrel QoS2消息的第二阶段确认
+func (m *mqttSession) pubrel(packetId uint16) mqtt.ControlPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reasonCode := mqtt.Success
+	if _, ok := m.awaitingRel[packetId]; ok {
+		delete(m.awaitingRel, packetId)
+	} else {
+		reasonCode = mqtt.PacketIdentifierNotFound
+	}
+	return &mqtt.PubackPacket{PacketType: mqtt.PUBCOMP, ProtocolVersion: m.version, PacketID: packetId, ReasonCode: reasonCode}
+}
+
+// puback 下行消息的确认，返回需要回复给服务端的recvack
+func (m *mqttSession) puback(packetId uint16) *wkproto.RecvackPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inflight, ok := m.inflight[packetId]
+	if !ok {
+		return nil
+	}
+	delete(m.inflight, packetId)
+	delete(m.inflightPackets, inflight.messageId)
+	return &wkproto.RecvackPacket{
+		MessageID:  inflight.messageId,
+		MessageSeq: inflight.messageSeq,
+	}
+}
+
+func (m *mqttSession) subscribe(p *mqtt.SubscribePacket) mqtt.ControlPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reasonCodes := make([]mqtt.ReasonCode, 0, len(p.Subscriptions))
+	for _, sub := range p.Subscriptions {
+		if strings.HasPrefix(sub.TopicFilter, "$share/") {
+			reasonCodes = append(reasonCodes, m.subackFailure(mqtt.SharedSubscriptionsNotSupported))
+			continue
+		}
+		if !mqtt.ValidTopicFilter(sub.TopicFilter) {
+			reasonCodes = append(reasonCodes, m.subackFailure(mqtt.TopicFilterInvalid))
+			continue
+		}
+		qos := sub.QoS
+		if qos > mqtt.QoS1 { // 下行消息最高支持QoS1
+			qos = mqtt.QoS1
+		}
+		m.subscriptions[sub.TopicFilter] = qos
+		reasonCodes = append(reasonCodes, mqtt.ReasonCode(qos))
+	}
+	return &mqtt.SubackPacket{ProtocolVersion: m.version, PacketID: p.PacketID, ReasonCodes: reasonCodes}
+}
+
+func (m *mqttSession) subackFailure(reasonCode mqtt.ReasonCode) mqtt.ReasonCode {
+	if m.version == mqtt.Version5 {
+		return reasonCode
+	}
+	return mqtt.ReasonCode(mqtt.SubackFailure)
+}
+
+func (m *mqttSession) unsubscribe(p *mqtt.UnsubscribePacket) mqtt.ControlPacket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reasonCodes := make([]mqtt.ReasonCode, 0, len(p.TopicFilters))
+	for _, filter := range p.TopicFilters {
+		if _, ok := m.subscriptions[filter]; ok {
+			delete(m.subscriptions, filter)
+			reasonCodes = append(reasonCodes, mqtt.Success)
+		} else {
+			reasonCodes = append(reasonCodes, mqtt.NoSubscriptionExisted)
+		}
+	}
+	return &mqtt.UnsubackPacket{ProtocolVersion: m.version, PacketID: p.PacketID, ReasonCodes: reasonCodes}
+}
+
+// outbound 把写给连接的悟空IM协议包翻译成mqtt报文，返回需要回复给服务端的recvack、是否需要重放缓存的报文和连接是否被拒绝
+func (m *mqttSession) outbound(c *connContext, data []byte) ([]byte, []*wkproto.RecvackPacket, bool, bool) {
+	var (
+		buf      bytes.Buffer
+		recvacks []*wkproto.RecvackPacket
+		replay   bool
+		refused  bool
+	)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	proto := c.subReactor.r.s.opts.Proto
+	offset := 0
+	for len(data) > offset {
+		frame, size, err := proto.DecodeFrame(data[offset:], c.protoVersion)
+		if err != nil || frame == nil {
+			c.Warn("Failed to decode the packet of mqtt conn", zap.Error(err))
+			break
+		}
+		offset += size
+
+		var packet mqtt.ControlPacket
+		switch f := frame.(type) {
+		case *wkproto.ConnackPacket:
+			packet, replay = m.handleConnack(c, f)
+			refused = f.ReasonCode != wkproto.ReasonSuccess
+		case *wkproto.RecvPacket:
+			var recvack *wkproto.RecvackPacket
+			packet, recvack = m.handleRecv(c, f)
+			if recvack != nil {
+				recvacks = append(recvacks, recvack)
+			}
+		case *wkproto.SendackPacket:
+			packet = m.handleSendack(f)
+		case *wkproto.PongPacket:
+			packet = &mqtt.PingrespPacket{}
+		case *wkproto.DisconnectPacket:
+			packet = m.handleDisconnect(f)
+		}
+		if packet == nil {
+			continue
+		}
+		if err = packet.Encode(&buf); err != nil {
+			c.Warn("Failed to encode the mqtt packet", zap.Error(err), zap.String("packetType", packet.Type().String()))
+		}
+	}
+	return buf.Bytes(), recvacks, replay, refused
+}
+
+func (m *mqttSession) handleConnack(c *connContext, connack *wkproto.ConnackPacket) (mqtt.ControlPacket, bool) {
+	packet := &mqtt.ConnackPacket{ProtocolVersion: m.version}
+	switch connack.ReasonCode {
+	case wkproto.ReasonSuccess:
+		m.connected = true
+		m.replaying = len(m.pending) > 0
+		// 按照客户端的保持连接时间设置空闲时间，为0表示不检查
+		c.conn.SetMaxIdle(time.Duration(m.keepAlive) * time.Second * 3 / 2)
+		if m.version == mqtt.Version5 {
+			packet.Properties = &mqtt.Properties{
+				AssignedClientIdentifier:        m.assignedClientId,
+				RetainAvailable:                 mqtt.ByteProp(0),
+				SubscriptionIdentifierAvailable: mqtt.ByteProp(0),
+				SharedSubscriptionAvailable:     mqtt.ByteProp(0),
+			}
+		}
+		return packet, m.replaying
+	case wkproto.ReasonAuthFail:
+		packet.ReturnCode = mqtt.ConnRefusedBadUsernameOrPassword
+		packet.ReasonCode = mqtt.BadUserNameOrPassword
+	case wkproto.ReasonBan:
+		packet.ReturnCode = mqtt.ConnRefusedNotAuthorized
+		packet.ReasonCode = mqtt.Banned
+	default:
+		packet.ReturnCode = mqtt.ConnRefusedServerUnavailable
+		packet.ReasonCode = mqtt.UnspecifiedError
+	}
+	// 认证失败的连接在CONNACK发送后关闭
+	m.pending = nil
+	return packet, false
+}
+
+func (m *mqttSession) handleRecv(c *connContext, recv *wkproto.RecvPacket) (mqtt.ControlPacket, *wkproto.RecvackPacket) {
+	recvack := &wkproto.RecvackPacket{
+		MessageID:  recv.MessageID,
+		MessageSeq: recv.MessageSeq,
+	}
+	topic := mqttTopic(recv.ChannelID, recv.ChannelType)
+	qos, ok := m.matchQoS(topic)
+	if !ok { // 没有订阅此频道，直接回执
+		return nil, recvack
+	}
+	payload, err := wkutil.AesDecryptPkcs7Base64(recv.Payload, c.aesKey, c.aesIV)
+	if err != nil {
+		c.Warn("Failed to decrypt the payload for mqtt conn", zap.Error(err), zap.Int64("messageId", recv.MessageID))
+		return nil, recvack
+	}
+	publish := &mqtt.PublishPacket{
+		ProtocolVersion: m.version,
+		QoS:             qos,
+		TopicName:       topic,
+		Payload:         payload,
+	}
+	if m.version == mqtt.Version5 {
+		publish.Properties = &mqtt.Properties{
+			UserProperties: []mqtt.UserProperty{{Key: "from_uid", Value: recv.FromUID}},
+		}
+	}
+	if qos == mqtt.QoS0 {
+		return publish, recvack
+	}
+	packetId, dup, ok := m.allocPacketId(recv.MessageID, recv.MessageSeq)
+	if !ok { // 报文标识符用完了，降级为QoS0
+		publish.QoS = mqtt.QoS0
+		return publish, recvack
+	}
+	publish.PacketID = packetId
+	publish.Dup = dup
+	return publish, nil
+}
+
+// matchQoS 匹配主题的订阅中授予的最大QoS
+func (m *mqttSession) matchQoS(topic string) (mqtt.QoS, bool) {
+	var (
+		maxQoS  mqtt.QoS
+		matched bool
+	)
+	for filter, qos := range m.subscriptions {
+		if !mqtt.TopicMatch(filter, topic) {
+			continue
+		}
+		if !matched || qos > maxQoS {
+			maxQoS = qos
+		}
+		matched = true
+	}
+	return maxQoS, matched
+}
+
+// allocPacketId 分配下行消息的报文标识符，重试投递的消息返回原来的标识符和dup为true
+func (m *mqttSession) allocPacketId(messageId int64, messageSeq uint32) (uint16, bool, bool) {
+	if packetId, ok := m.inflightPackets[messageId]; ok {
+		return packetId, true, true
+	}
+	if len(m.inflight) >= math.MaxUint16 {
+		return 0, false, false
+	}
+	for {
+		m.nextPacketId++
+		if m.nextPacketId == 0 {
+			continue
+		}
+		if _, ok := m.inflight[m.nextPacketId]; !ok {
+			break
+		}
+	}
+	m.inflight[m.nextPacketId] = mqttInflight{messageId: messageId, messageSeq: messageSeq}
+	m.inflightPackets[messageId] = m.nextPacketId
+	return m.nextPacketId, false, true
+}
+
+func (m *mqttSession) handleSendack(sendack *wkproto.SendackPacket) mqtt.ControlPacket {
+	if sendack.ClientSeq > math.MaxUint16 { // QoS0的消息不需要回复
+		return nil
+	}
+	packetId := uint16(sendack.ClientSeq)
+	qos, ok := m.publishing[packetId]
+	if !ok {
+		return nil
+	}
+	delete(m.publishing, packetId)
+
+	reasonCode := mqttReasonCode(sendack.ReasonCode)
+	if qos == mqtt.QoS1 {
+		return &mqtt.PubackPacket{PacketType: mqtt.PUBACK, ProtocolVersion: m.version, PacketID: packetId, ReasonCode: reasonCode}
+	}
+	// 5.0中PUBREC的原因码表示失败时客户端不会再发送PUBREL
+	if m.version != mqtt.Version5 || reasonCode < mqtt.UnspecifiedError {
+		m.awaitingRel[packetId] = struct{}{}
+	}
+	return &mqtt.PubackPacket{PacketType: mqtt.PUBREC, ProtocolVersion: m.version, PacketID: packetId, ReasonCode: reasonCode}
+}
+
+func (m *mqttSession) handleDisconnect(disconnect *wkproto.DisconnectPacket) mqtt.ControlPacket {
+	if m.version != mqtt.Version5 { // 3.1.1中服务端不能发送DISCONNECT，等待连接被关闭即可
+		return nil
+	}
+	packet := &mqtt.DisconnectPacket{ProtocolVersion: m.version, ReasonCode: mqtt.UnspecifiedError}
+	if disconnect.ReasonCode == wkproto.ReasonConnectKick {
+		packet.ReasonCode = mqtt.SessionTakenOver
+	}
+	if disconnect.Reason != "" {
+		packet.Properties = &mqtt.Properties{ReasonString: disconnect.Reason}
+	}
+	return packet
+}
+
+// onMQTTData 处理mqtt连接的数据
+func (s *Server) onMQTTData(conn wknet.Conn, buff []byte) error {
+	var connCtx *connContext
+	if connCtxObj := conn.Context(); connCtxObj != nil {
+		connCtx = connCtxObj.(*connContext)
+	}
+
+	offset := 0
+	for len(buff) > offset {
+		var version mqtt.ProtocolVersion
+		if connCtx != nil {
+			version = connCtx.mqtt.version
+		}
+		packet, size, err := mqtt.Decode(buff[offset:], version)
+		if err != nil {
+			if connCtx == nil && errors.Is(err, mqtt.ErrUnsupportedVersion) {
+				// 不支持的协议级别需要回复返回码为0x01的CONNACK，然后关闭连接
+				s.refuseMQTTConn(conn, &mqtt.ConnackPacket{ProtocolVersion: mqtt.Version311, ReturnCode: mqtt.ConnRefusedProtocolVersion})
+				return nil
+			}
+			s.Warn("Failed to decode the mqtt packet,conn will be closed", zap.Error(err))
+			conn.Close()
+			return nil
+		}
+		if packet == nil {
+			break
+		}
+		offset += size
+
+		if connCtx == nil {
+			if packet.Type() != mqtt.CONNECT {
+				s.Warn("the first mqtt packet must be CONNECT,conn will be closed", zap.String("packetType", packet.Type().String()))
+				conn.Close()
+				return nil
+			}
+			connCtx = s.handleMQTTConnect(conn, packet.(*mqtt.ConnectPacket))
+			if connCtx == nil { // 连接被拒绝，已经关闭
+				return nil
+			}
+			continue
+		}
+
+		handleNow, err := connCtx.mqtt.received(packet)
+		if err != nil {
+			s.Warn("mqtt conn will be closed", zap.Error(err), zap.String("uid", connCtx.uid))
+			connCtx.close()
+			return nil
+		}
+		if handleNow {
+			s.handleMQTTPacket(connCtx, packet)
+		}
+	}
+	_, _ = conn.Discard(offset)
+	return nil
+}
+
+// handleMQTTConnect 把CONNECT翻译成悟空IM的连接包，用户名为uid，密码为token，客户端标识符为设备id
+func (s *Server) handleMQTTConnect(conn wknet.Conn, packet *mqtt.ConnectPacket) *connContext {
+	version := packet.ProtocolVersion
+	reject := func(returnCode mqtt.ConnectReturnCode, reasonCode mqtt.ReasonCode) *connContext {
+		s.refuseMQTTConn(conn, &mqtt.ConnackPacket{ProtocolVersion: version, ReturnCode: returnCode, ReasonCode: reasonCode})
+		return nil
+	}
+	if version == mqtt.Version5 && packet.Properties != nil && packet.Properties.AuthenticationMethod != "" {
+		s.Warn("mqtt enhanced authentication is not supported", zap.String("authenticationMethod", packet.Properties.AuthenticationMethod))
+		return reject(mqtt.ConnRefusedNotAuthorized, mqtt.BadAuthMethod)
+	}
+	uid := packet.Username
+	if strings.TrimSpace(uid) == "" || IsSpecialChar(uid) {
+		s.Warn("mqtt username is illegal", zap.String("username", uid))
+		return reject(mqtt.ConnRefusedBadUsernameOrPassword, mqtt.BadUserNameOrPassword)
+	}
+	deviceId := packet.ClientID
+	var assignedClientId string
+	if deviceId == "" {
+		if version != mqtt.Version5 && !packet.CleanStart { // 3.1.1中客户端标识符为空时必须清理会话
+			return reject(mqtt.ConnRefusedIdentifierRejected, mqtt.ClientIdentifierNotValid)
+		}
+		deviceId = wkutil.GenUUID()
+		assignedClientId = deviceId
+	}
+
+	_, dhPublicKey := wkutil.GetCurve25519KeypPair() // 消息由网关加解密，只需要提供合法的客户端公钥
+	connectPacket := &wkproto.ConnectPacket{
+		Version:         wkproto.LatestVersion,
+		ClientKey:       base64.StdEncoding.EncodeToString(dhPublicKey[:]),
+		DeviceID:        deviceId,
+		DeviceFlag:      s.opts.MQTT.DeviceFlag,
+		ClientTimestamp: time.Now().UnixNano() / 1000 / 1000,
+		UID:             uid,
+		Token:           string(packet.Password),
+	}
+
+	sub := s.userReactor.reactorSub(uid)
+	connInfo := connInfo{
+		connId:       conn.ID(),
+		uid:          uid,
+		deviceId:     deviceId,
+		deviceFlag:   s.opts.MQTT.DeviceFlag,
+		protoVersion: connectPacket.Version,
+	}
+	connCtx := newConnContext(connInfo, conn, sub)
+	connCtx.mqtt = newMQTTSession(packet, assignedClientId)
+	conn.SetContext(connCtx)
+	s.userReactor.addConnAndCreateUserHandlerIfNotExist(connCtx)
+	connCtx.addConnectPacket(connectPacket)
+	return connCtx
+}
+
+func (s *Server) handleMQTTPacket(c *connContext, packet mqtt.ControlPacket) {
+	switch p := packet.(type) {
+	case *mqtt.PublishPacket:
+		s.handleMQTTPublish(c, p)
+	case *mqtt.PubackPacket:
+		switch p.PacketType {
+		case mqtt.PUBACK:
+			if recvack := c.mqtt.puback(p.PacketID); recvack != nil {
+				c.addOtherPacket(recvack)
+			}
+		case mqtt.PUBREL:
+			c.writeMQTT(c.mqtt.pubrel(p.PacketID))
+		}
+		// 服务端不会下发QoS2的消息，忽略PUBREC和PUBCOMP
+	case *mqtt.SubscribePacket:
+		c.writeMQTT(c.mqtt.subscribe(p))
+	case *mqtt.UnsubscribePacket:
+		c.writeMQTT(c.mqtt.unsubscribe(p))
+	case *mqtt.PingreqPacket:
+		c.addOtherPacket(&wkproto.PingPacket{})
+	case *mqtt.DisconnectPacket:
+		c.close()
+	default:
+		c.Warn("unsupported mqtt packet,conn will be closed", zap.String("packetType", packet.Type().String()))
+		c.close()
+	}
+}
+
+// handleMQTTPublish 把PUBLISH翻译成悟空IM的发送包，QoS1和QoS2的报文标识符作为clientSeq，收到sendack后回复PUBACK或PUBREC
+func (s *Server) handleMQTTPublish(c *connContext, p *mqtt.PublishPacket) {
+	channelId, channelType, ok := parseMQTTTopic(p.TopicName)
+	if !ok {
+		c.Warn("mqtt publish topic is illegal", zap.String("topic", p.TopicName), zap.String("uid", c.uid))
+		switch {
+		case p.QoS == mqtt.QoS0:
+		case c.mqtt.version == mqtt.Version5:
+			packetType := mqtt.PUBACK
+			if p.QoS == mqtt.QoS2 {
+				packetType = mqtt.PUBREC
+			}
+			c.writeMQTT(&mqtt.PubackPacket{PacketType: packetType, ProtocolVersion: c.mqtt.version, PacketID: p.PacketID, ReasonCode: mqtt.TopicNameInvalid})
+		default: // 3.1.1中没有办法告诉客户端发送失败，只能关闭连接
+			c.close()
+		}
+		return
+	}
+
+	clientSeq, reply, ok := c.mqtt.publish(p)
+	if !ok {
+		if reply != nil {
+			c.writeMQTT(reply)
+		}
+		return
+	}
+
+	payload, err := encryptMessagePayload(p.Payload, c)
+	if err != nil {
+		c.Error("加密payload失败！", zap.Error(err))
+		c.writeMQTT(c.mqtt.ack(clientSeq, wkproto.ReasonPayloadDecodeError))
+		return
+	}
+	sendPacket := &wkproto.SendPacket{
+		ClientSeq:   clientSeq,
+		ClientMsgNo: wkutil.GenUUID(),
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Payload:     payload,
+	}
+	msgKey, err := makeMsgKey(sendPacket.VerityString(), c)
+	if err != nil {
+		c.writeMQTT(c.mqtt.ack(clientSeq, wkproto.ReasonMsgKeyError))
+		return
+	}
+	sendPacket.MsgKey = msgKey
+	c.addSendPacket(sendPacket)
+}
+
+// replayMQTTPending 处理认证完成前缓存的报文
+func (s *Server) replayMQTTPending(c *connContext) {
+	for {
+		pending := c.mqtt.takePending()
+		if len(pending) == 0 {
+			return
+		}
+		for _, packet := range pending {
+			s.handleMQTTPacket(c, packet)
+		}
+	}
+}
+
+// refuseMQTTConn 连接还没有上下文时拒绝连接，发送CONNACK后关闭连接
+func (s *Server) refuseMQTTConn(conn wknet.Conn, connack *mqtt.ConnackPacket) {
+	data, err := mqtt.Encode(connack)
+	if err != nil {
+		s.Warn("Failed to encode the mqtt packet", zap.Error(err))
+	} else {
+		flushMQTTConn(conn, data)
+	}
+	_ = conn.Close()
+}
+
+// flushMQTTConn 写入数据并立即发送，用于关闭连接前发送拒绝连接的CONNACK
+func flushMQTTConn(conn wknet.Conn, data []byte) {
+	if _, err := conn.WriteToOutboundBuffer(data); err != nil {
+		wklog.Warn("Failed to write the mqtt packet", zap.Error(err))
+		return
+	}
+	if err := conn.Flush(); err != nil {
+		wklog.Warn("Failed to flush the mqtt packet", zap.Error(err))
+	}
+}
+
+// writeMQTT 直接写入mqtt报文，不经过协议包的翻译
+func (c *connContext) writeMQTT(packets ...mqtt.ControlPacket) {
+	var buf bytes.Buffer
+	for _, packet := range packets {
+		if packet == nil {
+			continue
+		}
+		if err := packet.Encode(&buf); err != nil {
+			c.Warn("Failed to encode the mqtt packet", zap.Error(err), zap.String("packetType", packet.Type().String()))
+		}
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	_ = c.writeToConn(buf.Bytes(), 0)
+}
+
+// writeMQTTOutbound 把写给mqtt连接的协议包翻译成mqtt报文，连接被拒绝时发送CONNACK后关闭连接
+func (c *connContext) writeMQTTOutbound(data []byte, recvFrameCount uint32) error {
+	out, recvacks, replay, refused := c.mqtt.outbound(c, data)
+	for _, recvack := range recvacks {
+		c.addOtherPacket(recvack)
+	}
+	if replay {
+		go c.subReactor.r.s.replayMQTTPending(c)
+	}
+	if refused {
+		if c.conn != nil && len(out) > 0 {
+			flushMQTTConn(c.conn, out)
+		}
+		c.close()
+		return nil
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return c.writeToConn(out, recvFrameCount)
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/mqtt"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMQTTConn 记录写入数据的连接，只实现网关用到的方法
+type testMQTTConn struct {
+	wknet.Conn
+	mu      sync.Mutex
+	ctx     interface{}
+	out     bytes.Buffer
+	flushed int
+	closed  bool
+}
+
+func (c *testMQTTConn) ID() int64                         { return 1 }
+func (c *testMQTTConn) Context() interface{}              { return c.ctx }
+func (c *testMQTTConn) SetContext(ctx interface{})        { c.ctx = ctx }
+func (c *testMQTTConn) SetMaxIdle(duration time.Duration) {}
+func (c *testMQTTConn) WakeWrite() error                  { return nil }
+func (c *testMQTTConn) Discard(n int) (int, error)        { return n, nil }
+
+func (c *testMQTTConn) WriteToOutboundBuffer(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Write(b)
+}
+
+func (c *testMQTTConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushed = c.out.Len()
+	return nil
+}
+
+func (c *testMQTTConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// packets 解码写入连接的mqtt报文
+func (c *testMQTTConn) packets(t *testing.T, version mqtt.ProtocolVersion) []mqtt.ControlPacket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := c.out.Bytes()
+	var packets []mqtt.ControlPacket
+	for len(data) > 0 {
+		packet, size, err := mqtt.Decode(data, version)
+		assert.NoError(t, err)
+		if packet == nil {
+			break
+		}
+		packets = append(packets, packet)
+		data = data[size:]
+	}
+	c.out.Reset()
+	return packets
+}
+
+func newMQTTTestConn(t *testing.T, version mqtt.ProtocolVersion) (*Server, *connContext, *testMQTTConn) {
+	configureTestLog(t)
+	s := NewTestServer(t)
+	conn := &testMQTTConn{}
+	connCtx := newConnContext(connInfo{connId: conn.ID(), uid: "u1", deviceId: "d1", protoVersion: wkproto.LatestVersion}, conn, s.userReactor.reactorSub("u1"))
+	connCtx.aesKey = []byte("1234567890123456")
+	connCtx.aesIV = []byte("1234567890123456")
+	connCtx.mqtt = newMQTTSession(&mqtt.ConnectPacket{ProtocolVersion: version, KeepAlive: 60}, "")
+	conn.SetContext(connCtx)
+	return s, connCtx, conn
+}
+
+func newMQTTTestRecv(t *testing.T, c *connContext, messageId int64, channelId string, payload []byte) *wkproto.RecvPacket {
+	payloadEnc, err := encryptMessagePayload(payload, c)
+	assert.NoError(t, err)
+	return &wkproto.RecvPacket{
+		MessageID:   messageId,
+		MessageSeq:  uint32(messageId),
+		FromUID:     "u2",
+		ChannelID:   channelId,
+		ChannelType: wkproto.ChannelTypeGroup,
+		Payload:     payloadEnc,
+	}
+}
+
+func TestMQTTTopic(t *testing.T) {
+	topic := mqttTopic("g1", wkproto.ChannelTypeGroup)
+	assert.Equal(t, "channel/2/g1", topic)
+
+	channelId, channelType, ok := parseMQTTTopic(topic)
+	assert.True(t, ok)
+	assert.Equal(t, "g1", channelId)
+	assert.Equal(t, wkproto.ChannelTypeGroup, channelType)
+
+	// 频道id可以包含/
+	channelId, _, ok = parseMQTTTopic("channel/2/a/b")
+	assert.True(t, ok)
+	assert.Equal(t, "a/b", channelId)
+
+	for _, topic := range []string{"g1", "channel/2", "channel/2/", "channel/x/g1", "channel/256/g1", "other/2/g1"} {
+		_, _, ok = parseMQTTTopic(topic)
+		assert.False(t, ok, topic)
+	}
+}
+
+func TestMQTTPublishQoS1(t *testing.T) {
+	session := newMQTTSession(&mqtt.ConnectPacket{ProtocolVersion: mqtt.Version311}, "")
+
+	// 报文标识符作为clientSeq，等待sendack期间重复的报文被忽略
+	clientSeq, reply, ok := session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS1, PacketID: 7})
+	assert.True(t, ok)
+	assert.Nil(t, reply)
+	assert.Equal(t, uint64(7), clientSeq)
+	_, _, ok = session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS1, PacketID: 7, Dup: true})
+	assert.False(t, ok)
+
+	packet := session.ack(clientSeq, wkproto.ReasonSuccess)
+	assert.Equal(t, &mqtt.PubackPacket{PacketType: mqtt.PUBACK, ProtocolVersion: mqtt.Version311, PacketID: 7, ReasonCode: mqtt.Success}, packet)
+	// 重复的sendack不再回复
+	assert.Nil(t, session.ack(clientSeq, wkproto.ReasonSuccess))
+
+	_, _, ok = session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS1, PacketID: 8})
+	assert.True(t, ok)
+	packet = session.ack(8, wkproto.ReasonInBlacklist)
+	assert.Equal(t, mqtt.NotAuthorized, packet.(*mqtt.PubackPacket).ReasonCode)
+
+	// QoS0的clientSeq不会和报文标识符冲突，sendack不需要回复
+	clientSeq, _, ok = session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS0})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(mqttQoS0ClientSeqStart), clientSeq)
+	assert.Nil(t, session.ack(clientSeq, wkproto.ReasonSuccess))
+}
+
+func TestMQTTPublishQoS2(t *testing.T) {
+	session := newMQTTSession(&mqtt.ConnectPacket{ProtocolVersion: mqtt.Version311}, "")
+
+	clientSeq, _, ok := session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS2, PacketID: 3})
+	assert.True(t, ok)
+	packet := session.ack(clientSeq, wkproto.ReasonSuccess)
+	assert.Equal(t, mqtt.PUBREC, packet.(*mqtt.PubackPacket).PacketType)
+
+	// 收到PUBREL前重复的报文不再发送，重新回复PUBREC
+	_, reply, ok := session.publish(&mqtt.PublishPacket{QoS: mqtt.QoS2, PacketID: 3, Dup: true})
+	assert.False(t, ok)
+	assert.Equal(t, mqtt.PUBREC, reply.(*mqtt.PubackPacket).PacketType)
+
+	packet = session.pubrel(3)
+	assert.Equal(t, &mqtt.PubackPacket{PacketType: mqtt.PUBCOMP, ProtocolVersion: mqtt.Version311, PacketID: 3, ReasonCode: mqtt.Success}, packet)
+	packet = session.pubrel(3)
+	assert.Equal(t, mqtt.PacketIdentifierNotFound, packet.(*mqtt.PubackPacket).ReasonCode)
+}
+
+func TestMQTTSubscribe(t *testing.T) {
+	session := newMQTTSession(&mqtt.ConnectPacket{ProtocolVersion: mqtt.Version311}, "")
+	packet := session.subscribe(&mqtt.SubscribePacket{PacketID: 1, Subscriptions: []mqtt.Subscription{
+		{TopicFilter: "channel/2/+", QoS: mqtt.QoS2},
+		{TopicFilter: "channel/2/g1", QoS: mqtt.QoS0},
+		{TopicFilter: "$share/g/channel/2/g1", QoS: mqtt.QoS1},
+		{TopicFilter: "channel/#/g1", QoS: mqtt.QoS1},
+	}})
+	// 下行消息最高授予QoS1，3.1.1中失败的订阅返回0x80
+	assert.Equal(t, []mqtt.ReasonCode{mqtt.ReasonCode(mqtt.QoS1), mqtt.ReasonCode(mqtt.QoS0), mqtt.ReasonCode(mqtt.SubackFailure), mqtt.ReasonCode(mqtt.SubackFailure)}, packet.(*mqtt.SubackPacket).ReasonCodes)
+
+	// 多个订阅匹配时取最大的QoS
+	qos, ok := session.matchQoS("channel/2/g1")
+	assert.True(t, ok)
+	assert.Equal(t, mqtt.QoS1, qos)
+	_, ok = session.matchQoS("channel/1/u1")
+	assert.False(t, ok)
+
+	packet = session.unsubscribe(&mqtt.UnsubscribePacket{PacketID: 2, TopicFilters: []string{"channel/2/+", "channel/3/+"}})
+	assert.Equal(t, []mqtt.ReasonCode{mqtt.Success, mqtt.NoSubscriptionExisted}, packet.(*mqtt.UnsubackPacket).ReasonCodes)
+	qos, ok = session.matchQoS("channel/2/g1")
+	assert.True(t, ok)
+	assert.Equal(t, mqtt.QoS0, qos)
+}
+
+func TestMQTTRecvQoS1(t *testing.T) {
+	_, c, conn := newMQTTTestConn(t, mqtt.Version311)
+	c.mqtt.subscribe(&mqtt.SubscribePacket{PacketID: 1, Subscriptions: []mqtt.Subscription{{TopicFilter: "channel/2/g1", QoS: mqtt.QoS1}}})
+
+	// 没有订阅的频道直接回执，不下发
+	recv := newMQTTTestRecv(t, c, 100, "g2", []byte("hello"))
+	assert.NoError(t, c.writeDirectlyPacket(recv))
+	assert.Empty(t, conn.packets(t, mqtt.Version311))
+
+	recv = newMQTTTestRecv(t, c, 101, "g1", []byte("hello"))
+	assert.NoError(t, c.writeDirectlyPacket(recv))
+	packets := conn.packets(t, mqtt.Version311)
+	assert.Equal(t, 1, len(packets))
+	publish := packets[0].(*mqtt.PublishPacket)
+	assert.Equal(t, "channel/2/g1", publish.TopicName)
+	assert.Equal(t, mqtt.QoS1, publish.QoS)
+	assert.Equal(t, []byte("hello"), publish.Payload)
+	assert.False(t, publish.Dup)
+	assert.NotZero(t, publish.PacketID)
+
+	// 重试投递的消息复用报文标识符
+	assert.NoError(t, c.writeDirectlyPacket(recv))
+	packets = conn.packets(t, mqtt.Version311)
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, publish.PacketID, packets[0].(*mqtt.PublishPacket).PacketID)
+	assert.True(t, packets[0].(*mqtt.PublishPacket).Dup)
+
+	// PUBACK转换为recvack
+	recvack := c.mqtt.puback(publish.PacketID)
+	assert.Equal(t, &wkproto.RecvackPacket{MessageID: 101, MessageSeq: 101}, recvack)
+	assert.Nil(t, c.mqtt.puback(publish.PacketID))
+}
+
+func TestMQTTSendack(t *testing.T) {
+	_, c, conn := newMQTTTestConn(t, mqtt.Version5)
+	_, _, ok := c.mqtt.publish(&mqtt.PublishPacket{QoS: mqtt.QoS1, PacketID: 5})
+	assert.True(t, ok)
+
+	assert.NoError(t, c.writeDirectlyPacket(&wkproto.SendackPacket{ClientSeq: 5, ReasonCode: wkproto.ReasonNotInWhitelist}))
+	packets := conn.packets(t, mqtt.Version5)
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, &mqtt.PubackPacket{PacketType: mqtt.PUBACK, ProtocolVersion: mqtt.Version5, PacketID: 5, ReasonCode: mqtt.NotAuthorized}, packets[0])
+}
+
+func TestMQTTPendingReplay(t *testing.T) {
+	_, c, conn := newMQTTTestConn(t, mqtt.Version311)
+
+	// 认证完成前收到的报文先缓存
+	handleNow, err := c.mqtt.received(&mqtt.SubscribePacket{PacketID: 1, Subscriptions: []mqtt.Subscription{{TopicFilter: "channel/2/g1", QoS: mqtt.QoS1}}})
+	assert.NoError(t, err)
+	assert.False(t, handleNow)
+	handleNow, err = c.mqtt.received(&mqtt.UnsubscribePacket{PacketID: 2, TopicFilters: []string{"channel/2/g2"}})
+	assert.NoError(t, err)
+	assert.False(t, handleNow)
+
+	// 认证成功后先回复CONNACK，再按顺序处理缓存的报文
+	assert.NoError(t, c.writeDirectlyPacket(&wkproto.ConnackPacket{ReasonCode: wkproto.ReasonSuccess}))
+	assert.Eventually(t, func() bool {
+		c.mqtt.mu.Lock()
+		defer c.mqtt.mu.Unlock()
+		return !c.mqtt.replaying
+	}, time.Second, time.Millisecond*10)
+
+	packets := conn.packets(t, mqtt.Version311)
+	assert.Equal(t, 3, len(packets))
+	assert.Equal(t, mqtt.ConnAccepted, packets[0].(*mqtt.ConnackPacket).ReturnCode)
+	assert.Equal(t, uint16(1), packets[1].(*mqtt.SubackPacket).PacketID)
+	assert.Equal(t, uint16(2), packets[2].(*mqtt.UnsubackPacket).PacketID)
+
+	// 重放结束后的报文直接处理
+	handleNow, err = c.mqtt.received(&mqtt.PingreqPacket{})
+	assert.NoError(t, err)
+	assert.True(t, handleNow)
+	_, ok := c.mqtt.matchQoS("channel/2/g1")
+	assert.True(t, ok)
+}
+
+func TestMQTTTooManyPending(t *testing.T) {
+	session := newMQTTSession(&mqtt.ConnectPacket{ProtocolVersion: mqtt.Version311}, "")
+	for i := 0; i < mqttMaxPendingPackets; i++ {
+		_, err := session.received(&mqtt.PingreqPacket{})
+		assert.NoError(t, err)
+	}
+	_, err := session.received(&mqtt.PingreqPacket{})
+	assert.Equal(t, errMQTTTooManyPendingPackets, err)
+}
+
+func TestMQTTConnackRefusedClose(t *testing.T) {
+	_, c, conn := newMQTTTestConn(t, mqtt.Version311)
+	_, _ = c.mqtt.received(&mqtt.PingreqPacket{})
+
+	// 认证失败后发送CONNACK并关闭连接
+	assert.NoError(t, c.writeDirectlyPacket(&wkproto.ConnackPacket{ReasonCode: wkproto.ReasonAuthFail}))
+	assert.True(t, conn.closed)
+	assert.True(t, c.isClosed())
+	assert.NotZero(t, conn.flushed)
+	packets := conn.packets(t, mqtt.Version311)
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, mqtt.ConnRefusedBadUsernameOrPassword, packets[0].(*mqtt.ConnackPacket).ReturnCode)
+	assert.Empty(t, c.mqtt.takePending())
+}
+
+func TestMQTTConnectRejectClose(t *testing.T) {
+	configureTestLog(t)
+	s := NewTestServer(t)
+
+	// 用户名为空
+	conn := &testMQTTConn{}
+	data, err := mqtt.Encode(&mqtt.ConnectPacket{ProtocolName: "MQTT", ProtocolVersion: mqtt.Version5, CleanStart: true, ClientID: "d1"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.onMQTTData(conn, data))
+	assert.True(t, conn.closed)
+	assert.NotZero(t, conn.flushed)
+	assert.Nil(t, conn.Context())
+	packets := conn.packets(t, mqtt.Version5)
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, mqtt.BadUserNameOrPassword, packets[0].(*mqtt.ConnackPacket).ReasonCode)
+
+	// 不支持的协议级别
+	conn = &testMQTTConn{}
+	data, err = mqtt.Encode(&mqtt.ConnectPacket{ProtocolName: "MQTT", ProtocolVersion: 6, ClientID: "d1"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.onMQTTData(conn, data))
+	assert.True(t, conn.closed)
+	packets = conn.packets(t, mqtt.Version311)
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, mqtt.ConnRefusedProtocolVersion, packets[0].(*mqtt.ConnackPacket).ReturnCode)
+
+	// 第一个报文不是CONNECT
+	conn = &testMQTTConn{}
+	data, err = mqtt.Encode(&mqtt.PingreqPacket{})
+	assert.NoError(t, err)
+	assert.NoError(t, s.onMQTTData(conn, data))
+	assert.True(t, conn.closed)
+	assert.Empty(t, conn.packets(t, mqtt.Version311))
+}
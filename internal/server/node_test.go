@@ -34,3 +34,37 @@ func TestChannelMessagesSetMarshal(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(channelMessages))
 }
+
+func TestReactorChannelMessageEphemeralMarshal(t *testing.T) {
+	sendPacket := &wkproto.SendPacket{
+		ChannelID:   "test",
+		ChannelType: 1,
+		Payload:     []byte("typing"),
+	}
+	messageSet := ReactorChannelMessageSet{
+		ReactorChannelMessage{MessageId: 1, FromUid: "u1", SendPacket: sendPacket, IsEphemeral: true},
+		ReactorChannelMessage{MessageId: 2, FromUid: "u1", SendPacket: sendPacket, IsSensitive: true},
+	}
+	data, err := messageSet.Marshal()
+	assert.Nil(t, err)
+
+	resultSet := ReactorChannelMessageSet{}
+	err = resultSet.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(resultSet))
+	assert.True(t, resultSet[0].IsEphemeral)
+	assert.False(t, resultSet[0].IsSensitive)
+	assert.False(t, resultSet[1].IsEphemeral)
+	assert.True(t, resultSet[1].IsSensitive)
+
+	req := ChannelFowardReq{ChannelId: "test", ChannelType: 1, Messages: []ReactorChannelMessage{messageSet[0]}}
+	data, err = req.Marshal()
+	assert.Nil(t, err)
+
+	resultReq := &ChannelFowardReq{}
+	err = resultReq.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resultReq.Messages))
+	assert.True(t, resultReq.Messages[0].IsEphemeral)
+	assert.Equal(t, uint64(0), uint64(resultReq.Messages[0].SendPacket.Setting))
+}
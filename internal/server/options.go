@@ -64,6 +64,10 @@ type Options struct {
 		CertFile string // 证书文件
 		KeyFile  string // 私钥文件
 	}
+	MQTT struct { // mqtt网关配置，物联网设备可以通过mqtt协议收发频道消息
+		Addr       string             // mqtt监听地址 例如：tcp://0.0.0.0:1883 为空表示不开启
+		DeviceFlag wkproto.DeviceFlag // mqtt连接的设备标识，认证token时使用 默认为app
+	}
 
 	Logger struct {
 		Dir     string // 日志存储目录
@@ -100,6 +104,7 @@ type Options struct {
 		CreateIfNoExist           bool   // 如果频道不存在是否创建
 		SubscriberCompressOfCount int    // 订订阅者数组多大开始压缩（离线推送的时候订阅者数组太大 可以设置此参数进行压缩 默认为0 表示不压缩 ）
 		CmdSuffix                 string // cmd频道后缀
+		EphemeralRateLimit        int    // 每个发送者在每个频道里每秒最多发送的临时事件数量（正在输入等），0表示不限制
 	}
 	TmpChannel struct { // 临时频道配置
 		Suffix     string // 临时频道的后缀
@@ -110,11 +115,35 @@ type Options struct {
 		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
 		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
 		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		MsgNotifyEventRetryMaxCount int           // 事件推送失败最大重试次数 默认为5次，超过将进入死信队列
+		Secret                      string        // 签名密钥，设置后每个请求都会带上时间戳和HMAC-SHA256签名
+		RetryBackoffBase            time.Duration // 推送失败后重试的初始间隔，每次失败间隔翻倍并加入随机抖动 默认为1秒
+		RetryBackoffMax             time.Duration // 推送失败后重试的最大间隔 默认为1分钟
+		ChannelOn                   bool          // 是否推送给频道信息中设置的webhook地址（频道消息的msg.notify和msg.offline事件）
+		BeforeSendOn                bool          // 是否开启消息发送前的同步审核（消息存储前调用webhook的msg.before_send，可以拒绝消息或替换消息内容）
+		BeforeSendTimeout           time.Duration // 发送前审核的请求超时时间 默认为2秒，不能超过频道处理间隔（reactor.channel的processIntervalTick*tickInterval）的一半，超过时按一半处理
+		BeforeSendFailOpen          bool          // 发送前审核请求失败或超时时是否放行消息 默认为true，为false时消息将以ReasonSystemError失败
+	}
+	EventSink struct { // 事件输出端配置
+		File struct { // 本地NDJSON文件输出端，每行一个事件，可以由日志采集工具采集后做离线分析
+			Dir        string // 文件目录 默认为 {rootDir}/events
+			MaxSize    int    // 单个文件最大大小（单位MB），超过后滚动 默认为100
+			MaxBackups int    // 最多保留的滚动文件数量 默认为10
+			MaxAge     int    // 滚动文件最多保留天数 默认为0，表示不按时间清理
+		}
+		Events map[string][]string // 按事件类型配置输出端（http、grpc、file），没有配置的事件类型输出到配置的webhook地址
+	}
+	SensitiveWord struct { // 内置敏感词过滤
+		On                  bool                          // 是否开启敏感词过滤
+		Policy              SensitiveWordPolicy           // 命中敏感词的默认处理策略 reject:拒绝发送 mask:用*替换敏感词 flag:放行但在webhook事件中标记 默认为mask
+		ChannelTypePolicies map[uint8]SensitiveWordPolicy // 按频道类型设置处理策略，没有设置的频道类型使用默认策略
 	}
 	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
-		Addr          string // 数据源地址
-		ChannelInfoOn bool   // 是否开启频道信息获取
+		Addr          string        // 数据源地址
+		GRPCAddr      string        // 数据源grpc地址，配置后通过grpc请求数据源，优先于Addr
+		ChannelInfoOn bool          // 是否开启频道信息获取
+		CacheSize     int           // 数据源结果缓存的最大条数，超过后淘汰最久未使用的，为0表示不缓存
+		CacheTTL      time.Duration // 数据源结果缓存的过期时间，为0表示不缓存
 	}
 	Conversation struct {
 		On                 bool          // 是否开启最近会话
@@ -323,18 +352,25 @@ func NewOptions(op ...Option) *Options {
 			CreateIfNoExist           bool
 			SubscriberCompressOfCount int
 			CmdSuffix                 string
+			EphemeralRateLimit        int
 		}{
 			CacheCount:                1000,
 			CreateIfNoExist:           true,
 			SubscriberCompressOfCount: 0,
 			CmdSuffix:                 "____cmd",
+			EphemeralRateLimit:        5,
 		},
 		Datasource: struct {
 			Addr          string
+			GRPCAddr      string
 			ChannelInfoOn bool
+			CacheSize     int
+			CacheTTL      time.Duration
 		}{
 			Addr:          "",
 			ChannelInfoOn: false,
+			CacheSize:     10000,
+			CacheTTL:      time.Minute,
 		},
 		TokenAuthOn: false,
 		Conversation: struct {
@@ -377,10 +413,49 @@ func NewOptions(op ...Option) *Options {
 			MsgNotifyEventPushInterval  time.Duration
 			MsgNotifyEventCountPerPush  int
 			MsgNotifyEventRetryMaxCount int
+			Secret                      string
+			RetryBackoffBase            time.Duration
+			RetryBackoffMax             time.Duration
+			ChannelOn                   bool
+			BeforeSendOn                bool
+			BeforeSendTimeout           time.Duration
+			BeforeSendFailOpen          bool
 		}{
 			MsgNotifyEventPushInterval:  time.Millisecond * 500,
 			MsgNotifyEventCountPerPush:  100,
 			MsgNotifyEventRetryMaxCount: 5,
+			RetryBackoffBase:            time.Second,
+			RetryBackoffMax:             time.Minute,
+			BeforeSendTimeout:           time.Second * 2,
+			BeforeSendFailOpen:          true,
+		},
+		EventSink: struct {
+			File struct {
+				Dir        string
+				MaxSize    int
+				MaxBackups int
+				MaxAge     int
+			}
+			Events map[string][]string
+		}{
+			File: struct {
+				Dir        string
+				MaxSize    int
+				MaxBackups int
+				MaxAge     int
+			}{
+				MaxSize:    100,
+				MaxBackups: 10,
+			},
+			Events: make(map[string][]string),
+		},
+		SensitiveWord: struct {
+			On                  bool
+			Policy              SensitiveWordPolicy
+			ChannelTypePolicies map[uint8]SensitiveWordPolicy
+		}{
+			Policy:              SensitiveWordPolicyMask,
+			ChannelTypePolicies: make(map[uint8]SensitiveWordPolicy),
 		},
 		Manager: struct {
 			On   bool
@@ -605,9 +680,13 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.WSSConfig.CertFile = o.getString("wssConfig.certFile", o.WSSConfig.CertFile)
 	o.WSSConfig.KeyFile = o.getString("wssConfig.keyFile", o.WSSConfig.KeyFile)
 
+	o.MQTT.Addr = o.getString("mqtt.addr", o.MQTT.Addr)
+	o.MQTT.DeviceFlag = wkproto.DeviceFlag(o.getInt("mqtt.deviceFlag", int(o.MQTT.DeviceFlag)))
+
 	o.Channel.CacheCount = o.getInt("channel.cacheCount", o.Channel.CacheCount)
 	o.Channel.CreateIfNoExist = o.getBool("channel.createIfNoExist", o.Channel.CreateIfNoExist)
 	o.Channel.SubscriberCompressOfCount = o.getInt("channel.subscriberCompressOfCount", o.Channel.SubscriberCompressOfCount)
+	o.Channel.EphemeralRateLimit = o.getInt("channel.ephemeralRateLimit", o.Channel.EphemeralRateLimit)
 
 	o.ConnIdleTime = o.getDuration("connIdleTime", o.ConnIdleTime)
 
@@ -625,6 +704,74 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Webhook.MsgNotifyEventRetryMaxCount = o.getInt("webhook.msgNotifyEventRetryMaxCount", o.Webhook.MsgNotifyEventRetryMaxCount)
 	o.Webhook.MsgNotifyEventCountPerPush = o.getInt("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush)
 	o.Webhook.MsgNotifyEventPushInterval = o.getDuration("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval)
+	o.Webhook.Secret = o.getString("webhook.secret", o.Webhook.Secret)
+	o.Webhook.RetryBackoffBase = o.getDuration("webhook.retryBackoffBase", o.Webhook.RetryBackoffBase)
+	o.Webhook.RetryBackoffMax = o.getDuration("webhook.retryBackoffMax", o.Webhook.RetryBackoffMax)
+	o.Webhook.ChannelOn = o.getBool("webhook.channelOn", o.Webhook.ChannelOn)
+	o.Webhook.BeforeSendOn = o.getBool("webhook.beforeSendOn", o.Webhook.BeforeSendOn)
+	o.Webhook.BeforeSendTimeout = o.getDuration("webhook.beforeSendTimeout", o.Webhook.BeforeSendTimeout)
+	o.Webhook.BeforeSendFailOpen = o.getBool("webhook.beforeSendFailOpen", o.Webhook.BeforeSendFailOpen)
+
+	o.EventSink.File.Dir = o.getString("eventSink.file.dir", filepath.Join(o.RootDir, "events"))
+	o.EventSink.File.MaxSize = o.getInt("eventSink.file.maxSize", o.EventSink.File.MaxSize)
+	o.EventSink.File.MaxBackups = o.getInt("eventSink.file.maxBackups", o.EventSink.File.MaxBackups)
+	o.EventSink.File.MaxAge = o.getInt("eventSink.file.maxAge", o.EventSink.File.MaxAge)
+	eventSinks := o.getStringSlice("eventSink.events") // 格式为： event:sink,sink 例如 msg.notify:http,file
+	for _, eventSinkStr := range eventSinks {
+		strs := strings.Split(eventSinkStr, ":")
+		if len(strs) != 2 {
+			wklog.Panic("eventSink.events format error", zap.String("eventSink", eventSinkStr))
+		}
+		event := strings.TrimSpace(strs[0])
+		if !wkutil.ArrayContains(webhookEvents, event) {
+			wklog.Panic("eventSink.events event error", zap.String("eventSink", eventSinkStr))
+		}
+		sinks := make([]string, 0)
+		for _, sink := range strings.Split(strs[1], ",") {
+			sink = strings.TrimSpace(sink)
+			if sink == "" {
+				continue
+			}
+			switch sink {
+			case EventSinkHTTP:
+				if strings.TrimSpace(o.Webhook.HTTPAddr) == "" {
+					wklog.Panic("eventSink.events http sink requires webhook.httpAddr", zap.String("eventSink", eventSinkStr))
+				}
+			case EventSinkGRPC:
+				if !o.WebhookGRPCOn() {
+					wklog.Panic("eventSink.events grpc sink requires webhook.grpcAddr", zap.String("eventSink", eventSinkStr))
+				}
+			case EventSinkFile:
+			default:
+				wklog.Panic("eventSink.events sink error", zap.String("eventSink", eventSinkStr))
+			}
+			sinks = append(sinks, sink)
+		}
+		o.EventSink.Events[event] = sinks
+	}
+
+	o.SensitiveWord.On = o.getBool("sensitiveWord.on", o.SensitiveWord.On)
+	policy := SensitiveWordPolicy(o.getString("sensitiveWord.policy", string(o.SensitiveWord.Policy)))
+	if !policy.valid() {
+		wklog.Panic("sensitiveWord.policy error", zap.String("policy", string(policy)))
+	}
+	o.SensitiveWord.Policy = policy
+	channelTypePolicies := o.getStringSlice("sensitiveWord.channelTypePolicies") // 格式为： channelType:policy 例如 2:reject
+	for _, channelTypePolicyStr := range channelTypePolicies {
+		strs := strings.Split(channelTypePolicyStr, ":")
+		if len(strs) != 2 {
+			wklog.Panic("sensitiveWord.channelTypePolicies format error", zap.String("channelTypePolicy", channelTypePolicyStr))
+		}
+		channelType, err := strconv.ParseUint(strings.TrimSpace(strs[0]), 10, 8)
+		if err != nil {
+			wklog.Panic("sensitiveWord.channelTypePolicies channelType error", zap.String("channelTypePolicy", channelTypePolicyStr), zap.Error(err))
+		}
+		channelTypePolicy := SensitiveWordPolicy(strings.TrimSpace(strs[1]))
+		if !channelTypePolicy.valid() {
+			wklog.Panic("sensitiveWord.channelTypePolicies policy error", zap.String("channelTypePolicy", channelTypePolicyStr))
+		}
+		o.SensitiveWord.ChannelTypePolicies[uint8(channelType)] = channelTypePolicy
+	}
 
 	o.EventPoolSize = o.getInt("eventPoolSize", o.EventPoolSize)
 	o.DeliveryMsgPoolSize = o.getInt("deliveryMsgPoolSize", o.DeliveryMsgPoolSize)
@@ -634,7 +781,10 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.TmpChannel.Suffix = o.getString("tmpChannel.suffix", o.TmpChannel.Suffix)
 
 	o.Datasource.Addr = o.getString("datasource.addr", o.Datasource.Addr)
+	o.Datasource.GRPCAddr = o.getString("datasource.grpcAddr", o.Datasource.GRPCAddr)
 	o.Datasource.ChannelInfoOn = o.getBool("datasource.channelInfoOn", o.Datasource.ChannelInfoOn)
+	o.Datasource.CacheSize = o.getInt("datasource.cacheSize", o.Datasource.CacheSize)
+	o.Datasource.CacheTTL = o.getDuration("datasource.cacheTTL", o.Datasource.CacheTTL)
 
 	o.WhitelistOffOfPerson = o.getBool("whitelistOffOfPerson", o.WhitelistOffOfPerson)
 
@@ -1116,9 +1266,43 @@ func (o *Options) WebhookGRPCOn() bool {
 	return strings.TrimSpace(o.Webhook.GRPCAddr) != ""
 }
 
+// EventSinkOn 是否有任何事件输出端（不包括订阅和频道webhook）
+func (o *Options) EventSinkOn() bool {
+	return o.WebhookOn() || len(o.EventSink.Events) > 0
+}
+
+// EventSinks 事件的输出端，没有单独配置的事件输出到配置的webhook地址（配置了grpc地址则使用grpc）
+func (o *Options) EventSinks(event string) []string {
+	if sinks, ok := o.EventSink.Events[event]; ok {
+		return sinks
+	}
+	if o.WebhookGRPCOn() {
+		return []string{EventSinkGRPC}
+	}
+	if o.WebhookOn() {
+		return []string{EventSinkHTTP}
+	}
+	return nil
+}
+
+// EventSinkFileOn 是否有事件输出到本地文件
+func (o *Options) EventSinkFileOn() bool {
+	for _, sinks := range o.EventSink.Events {
+		if wkutil.ArrayContains(sinks, EventSinkFile) {
+			return true
+		}
+	}
+	return false
+}
+
 // HasDatasource 是否有配置数据源
 func (o *Options) HasDatasource() bool {
-	return strings.TrimSpace(o.Datasource.Addr) != ""
+	return strings.TrimSpace(o.Datasource.Addr) != "" || strings.TrimSpace(o.Datasource.GRPCAddr) != ""
+}
+
+// DatasourceCacheOn 是否缓存数据源的结果
+func (o *Options) DatasourceCacheOn() bool {
+	return o.Datasource.CacheSize > 0 && o.Datasource.CacheTTL > 0
 }
 
 // 获取客服频道的访客id
@@ -1249,6 +1433,18 @@ func WithWSSAddr(wssAddr string) Option {
 	}
 }
 
+func WithMQTTAddr(addr string) Option {
+	return func(opts *Options) {
+		opts.MQTT.Addr = addr
+	}
+}
+
+func WithMQTTDeviceFlag(deviceFlag wkproto.DeviceFlag) Option {
+	return func(opts *Options) {
+		opts.MQTT.DeviceFlag = deviceFlag
+	}
+}
+
 func WithWSSConfig(certFile, keyFile string) Option {
 	return func(opts *Options) {
 		opts.WSSConfig.CertFile = certFile
@@ -1358,6 +1554,12 @@ func WithChannelCmdSuffix(cmdSuffix string) Option {
 	}
 }
 
+func WithChannelEphemeralRateLimit(ephemeralRateLimit int) Option {
+	return func(opts *Options) {
+		opts.Channel.EphemeralRateLimit = ephemeralRateLimit
+	}
+}
+
 func WithConnIdleTime(connIdleTime time.Duration) Option {
 	return func(opts *Options) {
 		opts.ConnIdleTime = connIdleTime
@@ -1400,6 +1602,19 @@ func WithDatasourceAddr(addr string) Option {
 	}
 }
 
+func WithDatasourceGRPCAddr(grpcAddr string) Option {
+	return func(opts *Options) {
+		opts.Datasource.GRPCAddr = grpcAddr
+	}
+}
+
+func WithDatasourceCache(size int, ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.Datasource.CacheSize = size
+		opts.Datasource.CacheTTL = ttl
+	}
+}
+
 func WithDatasourceChannelInfoOn(channelInfoOn bool) Option {
 	return func(opts *Options) {
 		opts.Datasource.ChannelInfoOn = channelInfoOn
@@ -1532,6 +1747,59 @@ func WithWebhookMsgNotifyEventRetryMaxCount(retryMaxCount int) Option {
 	}
 }
 
+func WithWebhookSecret(secret string) Option {
+	return func(opts *Options) {
+		opts.Webhook.Secret = secret
+	}
+}
+
+func WithWebhookRetryBackoff(base time.Duration, max time.Duration) Option {
+	return func(opts *Options) {
+		opts.Webhook.RetryBackoffBase = base
+		opts.Webhook.RetryBackoffMax = max
+	}
+}
+
+func WithWebhookChannelOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Webhook.ChannelOn = on
+	}
+}
+
+func WithWebhookBeforeSend(on bool, timeout time.Duration, failOpen bool) Option {
+	return func(opts *Options) {
+		opts.Webhook.BeforeSendOn = on
+		opts.Webhook.BeforeSendTimeout = timeout
+		opts.Webhook.BeforeSendFailOpen = failOpen
+	}
+}
+
+func WithEventSinkFile(dir string, maxSize int, maxBackups int, maxAge int) Option {
+	return func(opts *Options) {
+		opts.EventSink.File.Dir = dir
+		opts.EventSink.File.MaxSize = maxSize
+		opts.EventSink.File.MaxBackups = maxBackups
+		opts.EventSink.File.MaxAge = maxAge
+	}
+}
+
+// WithEventSinks 按事件类型设置输出端
+func WithEventSinks(event string, sinks ...string) Option {
+	return func(opts *Options) {
+		opts.EventSink.Events[event] = sinks
+	}
+}
+
+func WithSensitiveWord(on bool, policy SensitiveWordPolicy, channelTypePolicies map[uint8]SensitiveWordPolicy) Option {
+	return func(opts *Options) {
+		opts.SensitiveWord.On = on
+		opts.SensitiveWord.Policy = policy
+		if channelTypePolicies != nil {
+			opts.SensitiveWord.ChannelTypePolicies = channelTypePolicies
+		}
+	}
+}
+
 func WithClusterNodeId(nodeId uint64) Option {
 	return func(opts *Options) {
 		opts.Cluster.NodeId = nodeId
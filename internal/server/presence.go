@@ -0,0 +1,262 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	presenceMaxSubscriptions = 1000                   // 每个用户最多订阅的用户数量
+	presenceFlushInterval    = time.Millisecond * 200 // 在线状态事件的广播间隔
+)
+
+// presenceManager 在线状态订阅管理
+// 订阅关系保存在订阅者的用户领导节点上（内存），订阅者的领导节点离线后订阅关系随之清除，客户端重连后需要重新订阅
+// 在线状态事件由webhook的Online/Offline产生，批量广播给集群内的所有节点，各节点推送给自己负责的订阅者
+type presenceManager struct {
+	s *Server
+
+	mu            sync.RWMutex
+	subscriptions map[string]map[string]struct{} // 订阅者 -> 被订阅者集合
+	subscribers   map[string]map[string]struct{} // 被订阅者 -> 订阅者集合
+
+	eventLock sync.Mutex
+	events    []*presenceEvent
+
+	flushTimer *timingwheel.Timer
+	wklog.Log
+}
+
+func newPresenceManager(s *Server) *presenceManager {
+	return &presenceManager{
+		s:             s,
+		subscriptions: make(map[string]map[string]struct{}),
+		subscribers:   make(map[string]map[string]struct{}),
+		Log:           wklog.NewWKLog("presenceManager"),
+	}
+}
+
+func (p *presenceManager) start() error {
+	p.flushTimer = p.s.Schedule(presenceFlushInterval, p.flush)
+	return nil
+}
+
+func (p *presenceManager) stop() {
+	if p.flushTimer != nil {
+		p.flushTimer.Stop()
+	}
+}
+
+// subscribe 订阅用户的在线状态，超过最大订阅数量返回错误
+func (p *presenceManager) subscribe(uid string, targets []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subscription := p.subscriptions[uid]
+	if subscription == nil {
+		subscription = make(map[string]struct{})
+	}
+	count := len(subscription)
+	for _, target := range targets {
+		if _, ok := subscription[target]; !ok {
+			count++
+		}
+	}
+	if count > presenceMaxSubscriptions {
+		return fmt.Errorf("订阅的用户数量不能超过%d个！", presenceMaxSubscriptions)
+	}
+	p.subscriptions[uid] = subscription
+	for _, target := range targets {
+		subscription[target] = struct{}{}
+		subscriber := p.subscribers[target]
+		if subscriber == nil {
+			subscriber = make(map[string]struct{})
+			p.subscribers[target] = subscriber
+		}
+		subscriber[uid] = struct{}{}
+	}
+	return nil
+}
+
+// unsubscribe 取消订阅，targets为空时取消全部订阅
+func (p *presenceManager) unsubscribe(uid string, targets []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subscription := p.subscriptions[uid]
+	if subscription == nil {
+		return
+	}
+	if len(targets) == 0 {
+		for target := range subscription {
+			targets = append(targets, target)
+		}
+	}
+	for _, target := range targets {
+		delete(subscription, target)
+		subscriber := p.subscribers[target]
+		if subscriber == nil {
+			continue
+		}
+		delete(subscriber, uid)
+		if len(subscriber) == 0 {
+			delete(p.subscribers, target)
+		}
+	}
+	if len(subscription) == 0 {
+		delete(p.subscriptions, uid)
+	}
+}
+
+// getSubscriptions 获取用户订阅的用户
+func (p *presenceManager) getSubscriptions(uid string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	subscription := p.subscriptions[uid]
+	targets := make([]string, 0, len(subscription))
+	for target := range subscription {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func (p *presenceManager) getSubscribers(target string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	subscriber := p.subscribers[target]
+	uids := make([]string, 0, len(subscriber))
+	for uid := range subscriber {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+// onPresence 用户设备上线或离线
+func (p *presenceManager) onPresence(uid string, deviceFlag wkproto.DeviceFlag, online bool, deviceOnlineCount int, totalOnlineCount int) {
+	p.eventLock.Lock()
+	defer p.eventLock.Unlock()
+	p.events = append(p.events, &presenceEvent{
+		Uid:               uid,
+		DeviceFlag:        uint8(deviceFlag),
+		Online:            online,
+		DeviceOnlineCount: uint32(deviceOnlineCount),
+		TotalOnlineCount:  uint32(totalOnlineCount),
+	})
+}
+
+// 广播在线状态事件给集群内的其他节点，并推送给本节点负责的订阅者
+func (p *presenceManager) flush() {
+	p.eventLock.Lock()
+	events := p.events
+	p.events = nil
+	p.eventLock.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	if p.s.opts.ClusterOn() {
+		req := &presenceEventReq{events: events}
+		data, err := req.Marshal()
+		if err != nil {
+			p.Error("presenceEventReq.Marshal error", zap.Error(err))
+		} else {
+			for _, node := range p.s.clusterServer.GetConfig().Nodes {
+				if node.Id == p.s.opts.Cluster.NodeId || !node.Online {
+					continue
+				}
+				err = p.s.cluster.Send(node.Id, &proto.Message{
+					MsgType: uint32(ClusterMsgTypePresence),
+					Content: data,
+				})
+				if err != nil {
+					p.Warn("广播在线状态事件失败！", zap.Error(err), zap.Uint64("nodeId", node.Id))
+				}
+			}
+		}
+	}
+	p.handleEvents(events)
+}
+
+// handleEvents 推送在线状态事件给本节点负责的订阅者
+func (p *presenceManager) handleEvents(events []*presenceEvent) {
+	for _, event := range events {
+		for _, subscriber := range p.getSubscribers(event.Uid) {
+			p.s.writeCMDToUser(subscriber, "", "presence", map[string]interface{}{
+				"uid":                 event.Uid,
+				"device_flag":         event.DeviceFlag,
+				"online":              event.Online,
+				"device_online_count": event.DeviceOnlineCount,
+				"total_online_count":  event.TotalOnlineCount,
+			})
+		}
+	}
+}
+
+type presenceEvent struct {
+	Uid               string
+	DeviceFlag        uint8
+	Online            bool
+	DeviceOnlineCount uint32 // 当前设备标记下的设备在线数量
+	TotalOnlineCount  uint32 // 当前用户下的所有设备在线数量
+}
+
+type presenceEventReq struct {
+	events []*presenceEvent
+}
+
+func (p *presenceEventReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(p.events)))
+	for _, event := range p.events {
+		enc.WriteString(event.Uid)
+		enc.WriteUint8(event.DeviceFlag)
+		if event.Online {
+			enc.WriteUint8(1)
+		} else {
+			enc.WriteUint8(0)
+		}
+		enc.WriteUint32(event.DeviceOnlineCount)
+		enc.WriteUint32(event.TotalOnlineCount)
+	}
+	return enc.Bytes(), nil
+}
+
+func (p *presenceEventReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	p.events = make([]*presenceEvent, 0, count)
+	for i := uint32(0); i < count; i++ {
+		event := &presenceEvent{}
+		if event.Uid, err = dec.String(); err != nil {
+			return err
+		}
+		if event.DeviceFlag, err = dec.Uint8(); err != nil {
+			return err
+		}
+		var online uint8
+		if online, err = dec.Uint8(); err != nil {
+			return err
+		}
+		event.Online = online == 1
+		if event.DeviceOnlineCount, err = dec.Uint32(); err != nil {
+			return err
+		}
+		if event.TotalOnlineCount, err = dec.Uint32(); err != nil {
+			return err
+		}
+		p.events = append(p.events, event)
+	}
+	return nil
+}
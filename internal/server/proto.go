@@ -35,6 +35,10 @@ func (s *Server) onData(conn wknet.Conn) error {
 		}
 	}
 
+	if _, ok := conn.(wknet.IMQTTConn); ok { // mqtt连接
+		return s.onMQTTData(conn, buff)
+	}
+
 	data, _ := gnetUnpacket(buff)
 	if len(data) == 0 {
 		return nil
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	scheduledMessageScanInterval = time.Second // 扫描到期定时消息的间隔
+	scheduledMessageBatchSize    = 100         // 每次扫描的最大数量
+	scheduledMessageMaxDelay     = time.Hour   // 超过发送时间点这么久仍发送失败的定时消息将被丢弃
+)
+
+// scheduledMessageManager 定时消息管理
+// 定时消息随槽日志复制到槽的各个副本，每个节点都会扫描本地到期的定时消息，但只发送自己是槽领导的那部分，
+// 这样节点重启或槽领导切换后，新的领导会继续发送未发送的定时消息
+type scheduledMessageManager struct {
+	s         *Server
+	scanTimer *timingwheel.Timer
+	scanning  atomic.Bool
+	wklog.Log
+}
+
+func newScheduledMessageManager(s *Server) *scheduledMessageManager {
+	return &scheduledMessageManager{
+		s:   s,
+		Log: wklog.NewWKLog("scheduledMessageManager"),
+	}
+}
+
+func (m *scheduledMessageManager) start() error {
+	m.scanTimer = m.s.Schedule(scheduledMessageScanInterval, m.scan)
+	return nil
+}
+
+func (m *scheduledMessageManager) stop() {
+	if m.scanTimer != nil {
+		m.scanTimer.Stop()
+	}
+}
+
+func (m *scheduledMessageManager) scan() {
+	// 上一次扫描还没结束
+	if !m.scanning.CompareAndSwap(false, true) {
+		return
+	}
+	defer m.scanning.Store(false)
+
+	// 扫描时就过滤掉不是自己领导的槽的定时消息，避免它们占满每次扫描的数量导致自己的消息一直得不到发送
+	now := time.Now()
+	messages, err := m.s.store.GetDueScheduledMessages(now.Unix(), scheduledMessageBatchSize, m.isSlotLeader)
+	if err != nil {
+		m.Error("获取到期的定时消息失败！", zap.Error(err))
+		return
+	}
+	for _, msg := range messages {
+		m.send(msg, now)
+	}
+}
+
+// 当前节点是否是定时消息所在槽的领导节点
+func (m *scheduledMessageManager) isSlotLeader(msg wkdb.ScheduledMessage) bool {
+	leaderInfo, err := m.s.cluster.SlotLeaderOfChannel(msg.ChannelId, msg.ChannelType)
+	if err != nil {
+		m.Warn("获取频道所在槽的领导节点失败！", zap.Error(err), zap.String("channelId", msg.ChannelId), zap.Uint8("channelType", msg.ChannelType))
+		return false
+	}
+	return leaderInfo.Id == m.s.opts.Cluster.NodeId
+}
+
+// 发送定时消息，发送成功后移除
+// 发送成功但移除失败时会重复发送，重复的消息使用相同的clientMsgNo，客户端可以据此去重
+func (m *scheduledMessageManager) send(msg wkdb.ScheduledMessage, now time.Time) {
+	channelId := msg.ChannelId
+	if msg.ChannelType == wkproto.ChannelTypePerson {
+		fromUid, toUid := GetFromUIDAndToUIDWith(msg.ChannelId)
+		channelId = toUid
+		if toUid == msg.FromUid {
+			channelId = fromUid
+		}
+	}
+	_, err := sendMessageToChannel(m.s, MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: wkutil.BoolToInt(msg.NoPersist),
+			RedDot:    wkutil.BoolToInt(msg.RedDot),
+			SyncOnce:  wkutil.BoolToInt(msg.SyncOnce),
+		},
+		ClientMsgNo: msg.ClientMsgNo,
+		FromUID:     msg.FromUid,
+		ChannelID:   channelId,
+		ChannelType: msg.ChannelType,
+		Expire:      msg.Expire,
+		Payload:     msg.Payload,
+	}, channelId, msg.ChannelType, msg.ClientMsgNo, wkproto.StreamFlagIng)
+	if err != nil {
+		if now.Sub(time.Unix(msg.SendAt, 0)) < scheduledMessageMaxDelay {
+			m.Warn("发送定时消息失败，稍后重试！", zap.Error(err), zap.Uint64("id", msg.Id), zap.String("channelId", msg.ChannelId), zap.Uint8("channelType", msg.ChannelType))
+			return
+		}
+		m.Error("定时消息超过最大延迟仍发送失败，丢弃！", zap.Error(err), zap.Uint64("id", msg.Id), zap.String("channelId", msg.ChannelId), zap.Uint8("channelType", msg.ChannelType), zap.Int64("sendAt", msg.SendAt))
+	}
+	if err = m.s.store.RemoveScheduledMessage(msg.ChannelId, msg.ChannelType, msg.Id); err != nil {
+		m.Error("移除定时消息失败！", zap.Error(err), zap.Uint64("id", msg.Id), zap.String("channelId", msg.ChannelId), zap.Uint8("channelType", msg.ChannelType))
+	}
+}
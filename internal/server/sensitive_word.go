@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/WuKongIM/WuKongIM/pkg/ahocorasick"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// SensitiveWordPolicy 命中敏感词后的处理策略
+type SensitiveWordPolicy string
+
+const (
+	SensitiveWordPolicyReject SensitiveWordPolicy = "reject" // 拒绝发送，发送者收到ReasonNotAllowSend
+	SensitiveWordPolicyMask   SensitiveWordPolicy = "mask"   // 将敏感词的每个字符替换为*后发送
+	SensitiveWordPolicyFlag   SensitiveWordPolicy = "flag"   // 原样发送，但在webhook事件中标记为含有敏感词
+)
+
+func (p SensitiveWordPolicy) valid() bool {
+	return p == SensitiveWordPolicyReject || p == SensitiveWordPolicyMask || p == SensitiveWordPolicyFlag
+}
+
+const (
+	sensitiveWordReloadInterval = time.Minute // 定时重新加载敏感词，防止漏掉其他节点的变更通知
+	sensitiveWordMask           = '*'
+)
+
+// sensitiveWordManager 敏感词管理
+// 敏感词存储在slot 0上，各节点从slot 0的领导节点加载后构建成自动机，词库变更后通知所有节点重新加载
+type sensitiveWordManager struct {
+	s *Server
+
+	mu        sync.RWMutex
+	automaton *ahocorasick.Automaton
+
+	stopped chan struct{}
+	wklog.Log
+}
+
+func newSensitiveWordManager(s *Server) *sensitiveWordManager {
+	return &sensitiveWordManager{
+		s:         s,
+		automaton: ahocorasick.New(nil),
+		stopped:   make(chan struct{}),
+		Log:       wklog.NewWKLog("sensitiveWordManager"),
+	}
+}
+
+func (m *sensitiveWordManager) start() error {
+	if m.s.opts.SensitiveWord.On {
+		go m.loopLoad()
+	}
+	return nil
+}
+
+func (m *sensitiveWordManager) stop() {
+	close(m.stopped)
+}
+
+// loopLoad 启动时加载敏感词，之后定时重新加载
+func (m *sensitiveWordManager) loopLoad() {
+	loaded := false
+	for {
+		interval := sensitiveWordReloadInterval
+		if err := m.load(); err != nil {
+			m.Warn("加载敏感词失败！", zap.Error(err))
+			if !loaded {
+				interval = time.Second * 2 // 还没加载成功过，尽快重试
+			}
+		} else {
+			loaded = true
+		}
+		select {
+		case <-time.After(interval):
+		case <-m.stopped:
+			return
+		}
+	}
+}
+
+// load 从slot 0的领导节点加载敏感词并重建自动机
+func (m *sensitiveWordManager) load() error {
+	words, err := m.getOrRequestWords()
+	if err != nil {
+		return err
+	}
+	m.setWords(words)
+	return nil
+}
+
+func (m *sensitiveWordManager) setWords(words []string) {
+	automaton := ahocorasick.New(words)
+	m.mu.Lock()
+	m.automaton = automaton
+	m.mu.Unlock()
+}
+
+func (m *sensitiveWordManager) getAutomaton() *ahocorasick.Automaton {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.automaton
+}
+
+// policy 频道类型对应的处理策略
+func (m *sensitiveWordManager) policy(channelType uint8) SensitiveWordPolicy {
+	if policy, ok := m.s.opts.SensitiveWord.ChannelTypePolicies[channelType]; ok {
+		return policy
+	}
+	return m.s.opts.SensitiveWord.Policy
+}
+
+// filter 检查消息内容，返回处理策略、处理后的内容和是否命中敏感词
+// 只处理utf8文本的内容，二进制内容直接放行
+// json内容只检查解码后的字符串值（不检查键名，转义的字符先解码再检查），其他文本按原文检查
+func (m *sensitiveWordManager) filter(channelType uint8, payload []byte) (SensitiveWordPolicy, []byte, bool) {
+	automaton := m.getAutomaton()
+	if automaton.Len() == 0 || len(payload) == 0 || !utf8.Valid(payload) {
+		return "", payload, false
+	}
+	policy := m.policy(channelType)
+	mask := policy == SensitiveWordPolicyMask
+
+	if json.Valid(payload) {
+		var value interface{}
+		dec := json.NewDecoder(bytes.NewReader(payload))
+		dec.UseNumber() // 保持数字的原样
+		if err := dec.Decode(&value); err == nil {
+			value, hit := filterJSONValue(automaton, value, mask)
+			if !hit || !mask {
+				return policy, payload, hit
+			}
+			// 替换后重新编码（对象的键会按字典序排列）
+			buff := new(bytes.Buffer)
+			enc := json.NewEncoder(buff)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(value); err != nil {
+				m.Warn("敏感词替换后编码json失败！", zap.Error(err))
+				return policy, payload, true
+			}
+			return policy, bytes.TrimSuffix(buff.Bytes(), []byte("\n")), true
+		}
+	}
+
+	if mask {
+		text, hit := automaton.Replace(string(payload), sensitiveWordMask)
+		if !hit {
+			return policy, payload, false
+		}
+		return policy, []byte(text), true
+	}
+	return policy, payload, automaton.Contains(string(payload))
+}
+
+// filterJSONValue 检查json中的字符串值，mask为true时替换命中的敏感词，否则命中后直接返回
+func filterJSONValue(automaton *ahocorasick.Automaton, value interface{}, mask bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if !mask {
+			return v, automaton.Contains(v)
+		}
+		return automaton.Replace(v, sensitiveWordMask)
+	case map[string]interface{}:
+		hit := false
+		for key, item := range v {
+			newItem, itemHit := filterJSONValue(automaton, item, mask)
+			if !itemHit {
+				continue
+			}
+			if !mask {
+				return v, true
+			}
+			hit = true
+			v[key] = newItem
+		}
+		return v, hit
+	case []interface{}:
+		hit := false
+		for i, item := range v {
+			newItem, itemHit := filterJSONValue(automaton, item, mask)
+			if !itemHit {
+				continue
+			}
+			if !mask {
+				return v, true
+			}
+			hit = true
+			v[i] = newItem
+		}
+		return v, hit
+	}
+	return value, false
+}
+
+func (m *sensitiveWordManager) getOrRequestWords() ([]string, error) {
+	var slotId uint32 = 0 // 敏感词默认存储在slot 0上
+	nodeInfo, err := m.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo.Id == m.s.opts.Cluster.NodeId {
+		return m.s.store.GetSensitiveWords()
+	}
+	return m.requestWords(nodeInfo)
+}
+
+func (m *sensitiveWordManager) requestWords(nodeInfo *pb.Node) ([]string, error) {
+	resp, err := network.Get(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, "/sensitive_words"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requestWords error: %s", resp.Body)
+	}
+	var words []string
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &words)
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// processSensitiveWord 在领导节点上对解密后的消息做敏感词过滤
+func (r *channelReactor) processSensitiveWord(req *payloadDecryptReq) {
+	manager := r.s.sensitiveWordManager
+	for i, msg := range req.messages {
+		if msg.ReasonCode != wkproto.ReasonSuccess || msg.IsSystem || msg.IsEncrypt || msg.SendPacket == nil {
+			continue
+		}
+		policy, payload, hit := manager.filter(req.ch.channelType, msg.SendPacket.Payload)
+		if !hit {
+			continue
+		}
+		r.MessageTrace("命中敏感词", msg.SendPacket.ClientMsgNo, "processSensitiveWord", zap.String("policy", string(policy)))
+		switch policy {
+		case SensitiveWordPolicyReject:
+			msg.ReasonCode = wkproto.ReasonNotAllowSend
+		case SensitiveWordPolicyMask:
+			msg.SendPacket.Payload = payload
+		case SensitiveWordPolicyFlag:
+			msg.IsSensitive = true
+		}
+		req.messages[i] = msg
+	}
+}
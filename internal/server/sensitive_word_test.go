@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSensitiveWordTestManager(t *testing.T, policy SensitiveWordPolicy, words ...string) *sensitiveWordManager {
+	s := NewTestServer(t)
+	s.opts.SensitiveWord.Policy = policy
+	m := newSensitiveWordManager(s)
+	m.setWords(words)
+	return m
+}
+
+func TestSensitiveWordFilterJSONStringValues(t *testing.T) {
+	m := newSensitiveWordTestManager(t, SensitiveWordPolicyMask, "type", "bad")
+
+	// 键名命中敏感词不处理，只替换字符串值
+	_, payload, hit := m.filter(wkproto.ChannelTypeGroup, []byte(`{"type":1,"content":"a bad <type>"}`))
+	assert.True(t, hit)
+	assert.JSONEq(t, `{"type":1,"content":"a *** <****>"}`, string(payload))
+
+	// 只有键名命中
+	_, payload, hit = m.filter(wkproto.ChannelTypeGroup, []byte(`{"type":1,"content":"hello"}`))
+	assert.False(t, hit)
+	assert.Equal(t, `{"type":1,"content":"hello"}`, string(payload))
+
+	// 转义的字符先解码再检查
+	_, payload, hit = m.filter(wkproto.ChannelTypeGroup, []byte(`{"content":"\u0062ad word"}`))
+	assert.True(t, hit)
+	assert.JSONEq(t, `{"content":"*** word"}`, string(payload))
+
+	// 数字保持原样
+	_, payload, hit = m.filter(wkproto.ChannelTypeGroup, []byte(`{"n":12345678901234567890,"list":["bad"]}`))
+	assert.True(t, hit)
+	assert.JSONEq(t, `{"n":12345678901234567890,"list":["***"]}`, string(payload))
+
+	// 非json内容按原文处理
+	_, payload, hit = m.filter(wkproto.ChannelTypeGroup, []byte(`this is bad`))
+	assert.True(t, hit)
+	assert.Equal(t, `this is ***`, string(payload))
+}
+
+func TestSensitiveWordFilterFlag(t *testing.T) {
+	m := newSensitiveWordTestManager(t, SensitiveWordPolicyFlag, "bad")
+
+	policy, payload, hit := m.filter(wkproto.ChannelTypeGroup, []byte(`{"content":"bad"}`))
+	assert.True(t, hit)
+	assert.Equal(t, SensitiveWordPolicyFlag, policy)
+	assert.Equal(t, `{"content":"bad"}`, string(payload)) // flag策略不修改内容
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/RussellLuo/timingwheel"
@@ -57,18 +58,27 @@ type Server struct {
 
 	systemUIDManager *SystemUIDManager // 系统账号管理
 
+	sensitiveWordManager *sensitiveWordManager // 敏感词管理
+
 	tagManager     *tagManager     // tag管理，用来管理频道订阅者的tag，用于快速查找订阅者所在节点
 	deliverManager *deliverManager // 消息投递管理
 	retryManager   *retryManager   // 消息重试管理
 
+	scheduledMessageManager *scheduledMessageManager // 定时消息管理
+	presenceManager         *presenceManager         // 在线状态订阅管理
+
 	conversationManager *ConversationManager // 会话管理
 
 	migrateTask *MigrateTask // 迁移任务
+	exportTask  *ExportTask  // 数据导出任务
+	importTask  *ImportTask  // 数据导入任务
 
-	datasource IDatasource // 数据源
+	datasource      IDatasource       // 数据源
+	datasourceCache *cachedDatasource // 数据源缓存，没有开启缓存时为nil
 
 	promtailServer *promtail.Promtail // 日志收集, 负责收集WuKongIM的日志 上报给Loki
 
+	backupLock sync.Mutex // 同一时间只允许一个备份任务
 }
 
 func New(opts *Options) *Server {
@@ -112,6 +122,10 @@ func New(opts *Options) *Server {
 
 	// 数据源
 	s.datasource = NewDatasource(s)
+	if s.opts.DatasourceCacheOn() {
+		s.datasourceCache = newCachedDatasource(s.datasource, s.opts.Datasource.CacheSize, s.opts.Datasource.CacheTTL)
+		s.datasource = s.datasourceCache
+	}
 	// 初始化tag管理
 	s.tagManager = newTagManager(s)
 
@@ -121,6 +135,7 @@ func New(opts *Options) *Server {
 		wknet.WithWSAddr(s.opts.WSAddr),
 		wknet.WithWSSAddr(s.opts.WSSAddr),
 		wknet.WithWSTLSConfig(s.opts.WSTLSConfig),
+		wknet.WithMQTTAddr(s.opts.MQTT.Addr),
 		wknet.WithOnReadBytes(func(n int) {
 			trace.GlobalTrace.Metrics.System().ExtranetIncomingAdd(int64(n))
 		}),
@@ -138,6 +153,12 @@ func New(opts *Options) *Server {
 	s.retryManager = newRetryManager(s)               // 消息重试管理
 	s.conversationManager = NewConversationManager(s) // 会话管理
 	s.migrateTask = NewMigrateTask(s)                 // 迁移任务
+	s.exportTask = NewExportTask(s)                   // 数据导出任务
+	s.importTask = NewImportTask(s)                   // 数据导入任务
+
+	s.scheduledMessageManager = newScheduledMessageManager(s) // 定时消息管理
+	s.presenceManager = newPresenceManager(s)                 // 在线状态订阅管理
+	s.sensitiveWordManager = newSensitiveWordManager(s)       // 敏感词管理
 
 	// 初始化分布式服务
 	initNodes := make(map[uint64]string)
@@ -249,6 +270,9 @@ func (s *Server) Start() error {
 	if s.opts.WSSAddr != "" {
 		s.Info(fmt.Sprintf("Listening  for WSS client on %s", s.opts.WSSAddr))
 	}
+	if s.opts.MQTT.Addr != "" {
+		s.Info(fmt.Sprintf("Listening  for MQTT client on %s", s.opts.MQTT.Addr))
+	}
 	s.Info(fmt.Sprintf("Listening  for Manager http api on %s", fmt.Sprintf("http://%s", s.opts.HTTPAddr)))
 
 	if s.opts.Manager.On {
@@ -318,6 +342,21 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	err = s.scheduledMessageManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.presenceManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.sensitiveWordManager.start()
+	if err != nil {
+		return err
+	}
+
 	if s.opts.Conversation.On {
 		err = s.conversationManager.Start()
 		if err != nil {
@@ -357,6 +396,12 @@ func (s *Server) Stop() error {
 
 	s.retryManager.stop()
 
+	s.scheduledMessageManager.stop()
+
+	s.presenceManager.stop()
+
+	s.sensitiveWordManager.stop()
+
 	if s.opts.Conversation.On {
 		s.conversationManager.Stop()
 	}
@@ -20,6 +20,10 @@ func (s *Server) handleClusterMessage(fromNodeId uint64, msg *proto.Message) {
 		s.handleNodePing(fromNodeId, msg)
 	case ClusterMsgTypeNodePong: // 节点Pong
 		s.handleNodePong(fromNodeId, msg)
+	case ClusterMsgTypePresence: // 用户在线状态事件
+		s.handlePresence(fromNodeId, msg)
+	case ClusterMsgTypeConversationReadState: // 会话已读状态变化
+		s.handleConversationReadState(fromNodeId, msg)
 
 	}
 	// switch ClusterMsgType(msg.MsgType) {
@@ -55,6 +59,18 @@ func (s *Server) setClusterRoutes() {
 	s.cluster.Route("/wk/getNodeUidsByTag", s.getNodeUidsByTag)
 	// 是否允许发送消息
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
+	// 获取用户在频道里清空聊天记录的位置
+	s.cluster.Route("/wk/getClearedToMsgSeqs", s.handleGetClearedToMsgSeqs)
+	// 获取频道的置顶消息
+	s.cluster.Route("/wk/getPinnedMessages", s.handleGetPinnedMessages)
+	// 获取频道里开启了免打扰的用户
+	s.cluster.Route("/wk/getMutedUids", s.handleGetMutedUids)
+	// 获取用户在频道内已回执的位置和消息的已读数量
+	s.cluster.Route("/wk/getReceiptState", s.handleGetReceiptState)
+	// 获取webhook订阅（包含签名密钥，只在节点间同步）
+	s.cluster.Route("/wk/getWebhookSubscriptions", s.handleGetWebhookSubscriptions)
+	// 重新加载当前节点的webhook订阅
+	s.cluster.Route("/wk/webhookSubscriptionsReload", s.handleWebhookSubscriptionsReload)
 
 }
 
@@ -87,10 +103,17 @@ func (s *Server) handleChannelForward(c *wkserver.Context) {
 		return
 	}
 	for _, reactorChannelMessage := range req.Messages {
-		sendPacket := reactorChannelMessage.SendPacket
-		// 提案频道消息
+		// 提案频道消息（保留临时事件等服务端标记）
 		ch := s.channelReactor.loadOrCreateChannel(req.ChannelId, req.ChannelType)
-		err = ch.proposeSend(reactorChannelMessage.MessageId, reactorChannelMessage.FromUid, reactorChannelMessage.FromDeviceId, reactorChannelMessage.FromConnId, reactorChannelMessage.FromNodeId, false, sendPacket)
+		err = ch.proposeMessage(ReactorChannelMessage{
+			FromConnId:   reactorChannelMessage.FromConnId,
+			FromUid:      reactorChannelMessage.FromUid,
+			FromDeviceId: reactorChannelMessage.FromDeviceId,
+			FromNodeId:   reactorChannelMessage.FromNodeId,
+			MessageId:    reactorChannelMessage.MessageId,
+			SendPacket:   reactorChannelMessage.SendPacket,
+			IsEphemeral:  reactorChannelMessage.IsEphemeral,
+		})
 		if err != nil {
 			s.Error("handleChannelForward: proposeSend failed")
 			c.WriteErr(err)
@@ -414,6 +437,26 @@ func (s *Server) handleNodePing(fromNodeId uint64, msg *proto.Message) {
 	}
 }
 
+func (s *Server) handlePresence(fromNodeId uint64, msg *proto.Message) {
+	req := &presenceEventReq{}
+	err := req.Unmarshal(msg.Content)
+	if err != nil {
+		s.Error("handlePresence Unmarshal", zap.Error(err), zap.Uint64("fromNodeId", fromNodeId))
+		return
+	}
+	s.presenceManager.handleEvents(req.events)
+}
+
+func (s *Server) handleConversationReadState(fromNodeId uint64, msg *proto.Message) {
+	req := &conversationReadStateReq{}
+	err := req.Unmarshal(msg.Content)
+	if err != nil {
+		s.Error("handleConversationReadState Unmarshal", zap.Error(err), zap.Uint64("fromNodeId", fromNodeId))
+		return
+	}
+	s.notifyConversationReadStateToLocal(req)
+}
+
 func (s *Server) handleNodePong(fromNodeId uint64, msg *proto.Message) {
 
 	userConns := &userConns{}
@@ -489,3 +532,102 @@ func (s *Server) handleAllowSend(c *wkserver.Context) {
 	}
 	c.WriteErrorAndStatus(errors.New("not allow send"), proto.Status(reasonCode))
 }
+
+func (s *Server) handleGetClearedToMsgSeqs(c *wkserver.Context) {
+	req := &clearedToMsgSeqReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleGetClearedToMsgSeqs Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+
+	seqs, err := s.getClearedToMsgSeqsFromLocal(req.Uid, req.Channels)
+	if err != nil {
+		s.Error("handleGetClearedToMsgSeqs: get conversation failed", zap.Error(err), zap.String("uid", req.Uid))
+		c.WriteErr(err)
+		return
+	}
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	for _, seq := range seqs {
+		enc.WriteUint64(seq)
+	}
+	c.Write(enc.Bytes())
+}
+
+func (s *Server) handleGetPinnedMessages(c *wkserver.Context) {
+	req := &channelReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleGetPinnedMessages Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	pins, err := s.store.GetPinnedMessages(req.ChannelId, req.ChannelType)
+	if err != nil {
+		s.Error("handleGetPinnedMessages: get pinned messages failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(pins)))
+}
+
+func (s *Server) handleGetReceiptState(c *wkserver.Context) {
+	req := &receiptStateReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleGetReceiptState Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	state, err := s.getReceiptStateFromLocal(req.ChannelId, req.ChannelType, req.Uid, req.Seqs)
+	if err != nil {
+		s.Error("handleGetReceiptState: get receipt state failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(state)))
+}
+
+func (s *Server) handleGetMutedUids(c *wkserver.Context) {
+	req := &mutedUidsReq{}
+	err := req.Unmarshal(c.Body())
+	if err != nil {
+		s.Error("handleGetMutedUids Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	mutedUids, err := s.getMutedUidsFromLocal(req.ChannelId, req.ChannelType, req.Uids)
+	if err != nil {
+		s.Error("handleGetMutedUids: get conversation failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.WriteErr(err)
+		return
+	}
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(mutedUids)))
+	for _, uid := range mutedUids {
+		enc.WriteString(uid)
+	}
+	c.Write(enc.Bytes())
+}
+
+func (s *Server) handleGetWebhookSubscriptions(c *wkserver.Context) {
+	subscriptions, err := s.store.GetWebhookSubscriptions()
+	if err != nil {
+		s.Error("handleGetWebhookSubscriptions: get webhook subscriptions failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(subscriptions)))
+}
+
+func (s *Server) handleWebhookSubscriptionsReload(c *wkserver.Context) {
+	if err := s.webhook.loadSubscriptions(); err != nil {
+		s.Error("handleWebhookSubscriptionsReload: load webhook subscriptions failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
@@ -115,6 +115,18 @@ func (s *APIServer) setRoutes() {
 	stream := NewStreamAPI(s.s)
 	stream.Route(s.r)
 
+	// webhook订阅api
+	webhook := NewWebhookAPI(s.s)
+	webhook.Route(s.r)
+
+	// 敏感词api
+	sensitiveWord := NewSensitiveWordAPI(s.s)
+	sensitiveWord.Route(s.r)
+
+	// 数据源api
+	datasource := NewDatasourceAPI(s.s)
+	datasource.Route(s.r)
+
 	// 分布式api
 	clusterServer, ok := s.s.cluster.(*cluster.Server)
 	if ok {
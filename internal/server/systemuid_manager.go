@@ -116,6 +116,15 @@ func (s *SystemUIDManager) RemoveSystemUidsFromCache(uids []string) {
 	}
 }
 
+// Invalidate 清空系统账号缓存，下次使用时重新加载
+func (s *SystemUIDManager) Invalidate() {
+	s.loaded.Store(false)
+	s.systemUIDs.Range(func(key, _ interface{}) bool {
+		s.systemUIDs.Delete(key)
+		return true
+	})
+}
+
 func (s *SystemUIDManager) getOrRequestSystemUids() ([]string, error) {
 
 	var slotId uint32 = 0
@@ -0,0 +1,470 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 导出文件（每行一条json记录）
+const (
+	exportFileUsers         = "users.ndjson"
+	exportFileDevices       = "devices.ndjson"
+	exportFileChannels      = "channels.ndjson"
+	exportFileMembers       = "members.ndjson"
+	exportFileConversations = "conversations.ndjson"
+	exportFileMessages      = "messages.ndjson"
+)
+
+// 频道成员列表类型
+const (
+	memberListSubscriber = "subscriber"
+	memberListDenylist   = "denylist"
+	memberListAllowlist  = "allowlist"
+)
+
+// 每次读取的消息大小
+const exportMessageBatchSize = 1024 * 1024 * 4
+
+// ExportMember 频道成员列表（订阅者/黑名单/白名单）
+type ExportMember struct {
+	ChannelId   string        `json:"channel_id"`
+	ChannelType uint8         `json:"channel_type"`
+	List        string        `json:"list"` // subscriber/denylist/allowlist
+	Members     []wkdb.Member `json:"members"`
+}
+
+// ExportMessage 频道日志里的消息（包含撤回/编辑日志）
+type ExportMessage struct {
+	MessageId   int64         `json:"message_id"`
+	MessageSeq  uint64        `json:"message_seq"`
+	ClientMsgNo string        `json:"client_msg_no,omitempty"`
+	StreamNo    string        `json:"stream_no,omitempty"`
+	StreamSeq   uint32        `json:"stream_seq,omitempty"`
+	StreamFlag  uint8         `json:"stream_flag,omitempty"`
+	NoPersist   bool          `json:"no_persist,omitempty"`
+	RedDot      bool          `json:"red_dot,omitempty"`
+	SyncOnce    bool          `json:"sync_once,omitempty"`
+	Setting     uint8         `json:"setting,omitempty"`
+	Expire      uint32        `json:"expire,omitempty"`
+	Timestamp   int32         `json:"timestamp"`
+	ChannelId   string        `json:"channel_id"`
+	ChannelType uint8         `json:"channel_type"`
+	Topic       string        `json:"topic,omitempty"`
+	FromUid     string        `json:"from_uid"`
+	Payload     []byte        `json:"payload"` // base64
+	Modify      *ExportModify `json:"modify,omitempty"`
+	// 回复的父消息seq（导出集群里的seq）
+	ParentMessageSeq uint64 `json:"parent_message_seq,omitempty"`
+}
+
+// ExportModify 消息修改日志
+type ExportModify struct {
+	Type       uint8  `json:"type"`
+	MessageSeq uint64 `json:"message_seq"` // 被修改的消息seq（导出集群里的seq）
+	Operator   string `json:"operator,omitempty"`
+	Version    uint32 `json:"version,omitempty"`
+	Payload    []byte `json:"payload,omitempty"`
+}
+
+func newExportMessage(m wkdb.Message) ExportMessage {
+	em := ExportMessage{
+		MessageId:   m.MessageID,
+		MessageSeq:  uint64(m.MessageSeq),
+		ClientMsgNo: m.ClientMsgNo,
+		StreamNo:    m.StreamNo,
+		StreamSeq:   m.StreamSeq,
+		StreamFlag:  uint8(m.StreamFlag),
+		NoPersist:   m.NoPersist,
+		RedDot:      m.RedDot,
+		SyncOnce:    m.SyncOnce,
+		Setting:     m.Setting.Uint8(),
+		Expire:      m.Expire,
+		Timestamp:   m.Timestamp,
+		ChannelId:   m.ChannelID,
+		ChannelType: m.ChannelType,
+		Topic:       m.Topic,
+		FromUid:     m.FromUID,
+		Payload:     m.Payload,
+
+		ParentMessageSeq: m.ParentMessageSeq,
+	}
+	if m.Modify != nil {
+		em.Modify = &ExportModify{
+			Type:       uint8(m.Modify.Type),
+			MessageSeq: m.Modify.MessageSeq,
+			Operator:   m.Modify.Operator,
+			Version:    m.Modify.Version,
+			Payload:    m.Modify.Payload,
+		}
+	}
+	return em
+}
+
+func (em ExportMessage) toDBMessage() wkdb.Message {
+	m := wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			Framer: wkproto.Framer{
+				NoPersist: em.NoPersist,
+				RedDot:    em.RedDot,
+				SyncOnce:  em.SyncOnce,
+			},
+			Setting:     wkproto.Setting(em.Setting),
+			Expire:      em.Expire,
+			MessageID:   em.MessageId,
+			ClientMsgNo: em.ClientMsgNo,
+			StreamNo:    em.StreamNo,
+			StreamSeq:   em.StreamSeq,
+			StreamFlag:  wkproto.StreamFlag(em.StreamFlag),
+			Timestamp:   em.Timestamp,
+			ChannelID:   em.ChannelId,
+			ChannelType: em.ChannelType,
+			Topic:       em.Topic,
+			FromUID:     em.FromUid,
+			Payload:     em.Payload,
+		},
+		ParentMessageSeq: em.ParentMessageSeq,
+	}
+	if em.Modify != nil {
+		m.Modify = &wkdb.MessageModify{
+			Type:       wkdb.MessageModifyType(em.Modify.Type),
+			MessageSeq: em.Modify.MessageSeq,
+			Operator:   em.Modify.Operator,
+			Version:    em.Modify.Version,
+			Payload:    em.Modify.Payload,
+		}
+	}
+	return m
+}
+
+// TransferResult 导出/导入结果
+type TransferResult struct {
+	Status    string           `json:"status"` // running/completed/failed
+	Dir       string           `json:"dir"`
+	Counts    map[string]int64 `json:"counts"` // 每种数据的数量
+	LastErr   string           `json:"last_err,omitempty"`
+	StartedAt int64            `json:"started_at"`
+	EndedAt   int64            `json:"ended_at,omitempty"`
+}
+
+// 导出/导入任务的运行状态
+type transferState struct {
+	mu      sync.RWMutex
+	running bool
+	result  *TransferResult
+}
+
+func (t *transferState) start(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		return errors.New("task is running")
+	}
+	t.running = true
+	t.result = &TransferResult{
+		Status:    "running",
+		Dir:       dir,
+		Counts:    map[string]int64{},
+		StartedAt: time.Now().Unix(),
+	}
+	return nil
+}
+
+func (t *transferState) incr(name string, n int) {
+	t.mu.Lock()
+	t.result.Counts[name] += int64(n)
+	t.mu.Unlock()
+}
+
+func (t *transferState) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+	t.result.EndedAt = time.Now().Unix()
+	if err != nil {
+		t.result.Status = "failed"
+		t.result.LastErr = err.Error()
+		return
+	}
+	t.result.Status = "completed"
+}
+
+func (t *transferState) get() *TransferResult {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.result == nil {
+		return nil
+	}
+	result := *t.result
+	result.Counts = make(map[string]int64, len(t.result.Counts))
+	for k, v := range t.result.Counts {
+		result.Counts[k] = v
+	}
+	return &result
+}
+
+// ExportTask 将本节点负责的数据导出为NDJSON文件
+// 用户、设备、频道、成员、最近会话只导出本节点是槽领导的数据，消息只导出本节点是频道领导的频道，
+// 这样在集群的每个节点上各执行一次即可得到不重复的完整数据
+type ExportTask struct {
+	s *Server
+	transferState
+	wklog.Log
+}
+
+func NewExportTask(s *Server) *ExportTask {
+	return &ExportTask{
+		s:   s,
+		Log: wklog.NewWKLog("ExportTask"),
+	}
+}
+
+// Start 异步导出数据到dir目录，dir必须不存在或者为空
+func (e *ExportTask) Start(dir string) error {
+	if !isEmptyDir(dir) {
+		return fmt.Errorf("export dir[%s] is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := e.start(dir); err != nil {
+		return err
+	}
+	go func() {
+		start := time.Now()
+		err := e.export(dir)
+		if err != nil {
+			e.Error("export failed", zap.Error(err), zap.String("dir", dir))
+		} else {
+			e.Info("export done", zap.String("dir", dir), zap.Duration("cost", time.Since(start)))
+		}
+		e.finish(err)
+	}()
+	return nil
+}
+
+// Result 获取导出结果，没有执行过返回nil
+func (e *ExportTask) Result() *TransferResult {
+	return e.get()
+}
+
+func (e *ExportTask) export(dir string) error {
+	steps := []struct {
+		file string
+		fnc  func(enc *json.Encoder) error
+	}{
+		{exportFileUsers, e.exportUsers},
+		{exportFileDevices, e.exportDevices},
+		{exportFileChannels, e.exportChannels},
+		{exportFileMembers, e.exportMembers},
+		{exportFileConversations, e.exportConversations},
+		{exportFileMessages, e.exportMessages},
+	}
+	for _, step := range steps {
+		if err := writeNDJSON(path.Join(dir, step.file), step.fnc); err != nil {
+			return fmt.Errorf("export %s failed: %w", step.file, err)
+		}
+	}
+	return nil
+}
+
+func (e *ExportTask) exportUsers(enc *json.Encoder) error {
+	var err error
+	iterErr := e.s.store.DB().IterateUsers(func(u wkdb.User) bool {
+		if !e.isSlotLeader(u.Uid) {
+			return true
+		}
+		if err = enc.Encode(u); err != nil {
+			return false
+		}
+		e.incr("users", 1)
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+func (e *ExportTask) exportDevices(enc *json.Encoder) error {
+	var err error
+	iterErr := e.s.store.DB().IterateDevices(func(d wkdb.Device) bool {
+		if !e.isSlotLeader(d.Uid) {
+			return true
+		}
+		if err = enc.Encode(d); err != nil {
+			return false
+		}
+		e.incr("devices", 1)
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+func (e *ExportTask) exportChannels(enc *json.Encoder) error {
+	var err error
+	iterErr := e.s.store.DB().IterateChannels(func(channelInfo wkdb.ChannelInfo) bool {
+		if !e.isSlotLeader(channelInfo.ChannelId) {
+			return true
+		}
+		if err = enc.Encode(channelInfo); err != nil {
+			return false
+		}
+		e.incr("channels", 1)
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+// 成员列表依附于频道，所以按频道遍历
+func (e *ExportTask) exportMembers(enc *json.Encoder) error {
+	db := e.s.store.DB()
+	var err error
+	iterErr := db.IterateChannels(func(channelInfo wkdb.ChannelInfo) bool {
+		if !e.isSlotLeader(channelInfo.ChannelId) {
+			return true
+		}
+		lists := []struct {
+			name string
+			get  func(channelId string, channelType uint8) ([]wkdb.Member, error)
+		}{
+			{memberListSubscriber, db.GetSubscribers},
+			{memberListDenylist, db.GetDenylist},
+			{memberListAllowlist, db.GetAllowlist},
+		}
+		for _, list := range lists {
+			var members []wkdb.Member
+			members, err = list.get(channelInfo.ChannelId, channelInfo.ChannelType)
+			if err != nil {
+				return false
+			}
+			if len(members) == 0 {
+				continue
+			}
+			if err = enc.Encode(ExportMember{
+				ChannelId:   channelInfo.ChannelId,
+				ChannelType: channelInfo.ChannelType,
+				List:        list.name,
+				Members:     members,
+			}); err != nil {
+				return false
+			}
+			e.incr(list.name, len(members))
+		}
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+func (e *ExportTask) exportConversations(enc *json.Encoder) error {
+	var err error
+	iterErr := e.s.store.DB().IterateConversations(func(conversation wkdb.Conversation) bool {
+		if !e.isSlotLeader(conversation.Uid) {
+			return true
+		}
+		if err = enc.Encode(conversation); err != nil {
+			return false
+		}
+		e.incr("conversations", 1)
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+	return err
+}
+
+// 按频道顺序导出消息日志，同一频道的消息是连续且按seq递增的
+func (e *ExportTask) exportMessages(enc *json.Encoder) error {
+	db := e.s.store.DB()
+	var offsetId uint64
+	for {
+		cfgs, err := db.GetChannelClusterConfigs(offsetId, 1000)
+		if err != nil {
+			return err
+		}
+		if len(cfgs) == 0 {
+			return nil
+		}
+		for _, cfg := range cfgs {
+			offsetId = cfg.Id
+			if cfg.LeaderId != e.s.opts.Cluster.NodeId || e.s.opts.IsCmdChannel(cfg.ChannelId) {
+				continue
+			}
+			if err = e.exportChannelMessages(enc, cfg.ChannelId, cfg.ChannelType); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *ExportTask) exportChannelMessages(enc *json.Encoder, channelId string, channelType uint8) error {
+	db := e.s.store.DB()
+	var startSeq uint64 = 1
+	for {
+		msgs, err := db.LoadNextRangeMsgsForSize(channelId, channelType, startSeq, 0, exportMessageBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		now := time.Now().Unix()
+		for _, msg := range msgs {
+			if msg.IsExpired(now) {
+				continue
+			}
+			if err = enc.Encode(newExportMessage(msg)); err != nil {
+				return err
+			}
+			e.incr("messages", 1)
+		}
+		startSeq = uint64(msgs[len(msgs)-1].MessageSeq) + 1
+	}
+}
+
+func (e *ExportTask) isSlotLeader(v string) bool {
+	slotId := e.s.getSlotId(v)
+	node, err := e.s.clusterServer.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		e.Warn("get slot leader failed", zap.Error(err), zap.Uint32("slotId", slotId))
+		return false
+	}
+	return node.Id == e.s.opts.Cluster.NodeId
+}
+
+// 创建文件并写入NDJSON
+func writeNDJSON(filePath string, fnc func(enc *json.Encoder) error) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err = fnc(json.NewEncoder(w)); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// 每次提案的消息数量
+const importMessageBatchCount = 100
+
+// ImportTask 将ExportTask导出的NDJSON文件通过提案导入到当前集群
+// 消息按频道顺序重新追加，seq由当前集群重新分配，撤回/编辑日志以及会话引用的seq会映射为新的seq
+type ImportTask struct {
+	s *Server
+	transferState
+	wklog.Log
+	goroutineCount int
+
+	seqRemapLock sync.RWMutex
+	seqRemaps    map[string]*seqRemap // 频道 -> seq映射，会话导入时使用
+}
+
+func NewImportTask(s *Server) *ImportTask {
+	return &ImportTask{
+		s:              s,
+		Log:            wklog.NewWKLog("ImportTask"),
+		goroutineCount: 20,
+	}
+}
+
+// Start 异步导入dir目录下的数据，不存在的文件会被跳过
+func (i *ImportTask) Start(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	if err := i.start(dir); err != nil {
+		return err
+	}
+	go func() {
+		start := time.Now()
+		err := i.importAll(dir)
+		if err != nil {
+			i.Error("import failed", zap.Error(err), zap.String("dir", dir))
+		} else {
+			i.Info("import done", zap.String("dir", dir), zap.Duration("cost", time.Since(start)))
+		}
+		i.finish(err)
+	}()
+	return nil
+}
+
+// Result 获取导入结果，没有执行过返回nil
+func (i *ImportTask) Result() *TransferResult {
+	return i.get()
+}
+
+// 频道需要在成员之前导入，会话需要在消息之后导入
+func (i *ImportTask) importAll(dir string) error {
+	i.seqRemaps = make(map[string]*seqRemap)
+	defer func() {
+		i.seqRemaps = nil
+	}()
+
+	steps := []struct {
+		file string
+		fnc  func(dec *json.Decoder) error
+	}{
+		{exportFileUsers, i.importUsers},
+		{exportFileDevices, i.importDevices},
+		{exportFileChannels, i.importChannels},
+		{exportFileMembers, i.importMembers},
+		{exportFileMessages, i.importMessages},
+		{exportFileConversations, i.importConversations},
+	}
+	for _, step := range steps {
+		err := readNDJSON(path.Join(dir, step.file), step.fnc)
+		if err != nil {
+			if os.IsNotExist(err) {
+				i.Info("import file not exist, skip", zap.String("file", step.file))
+				continue
+			}
+			return fmt.Errorf("import %s failed: %w", step.file, err)
+		}
+	}
+	return nil
+}
+
+func (i *ImportTask) importUsers(dec *json.Decoder) error {
+	return i.importConcurrently(dec, func() interface{} { return &wkdb.User{} }, func(v interface{}) error {
+		if err := i.s.store.AddUser(*v.(*wkdb.User)); err != nil {
+			return err
+		}
+		i.incr("users", 1)
+		return nil
+	})
+}
+
+func (i *ImportTask) importDevices(dec *json.Decoder) error {
+	return i.importConcurrently(dec, func() interface{} { return &wkdb.Device{} }, func(v interface{}) error {
+		if err := i.s.store.AddDevice(*v.(*wkdb.Device)); err != nil {
+			return err
+		}
+		i.incr("devices", 1)
+		return nil
+	})
+}
+
+func (i *ImportTask) importChannels(dec *json.Decoder) error {
+	return i.importConcurrently(dec, func() interface{} { return &wkdb.ChannelInfo{} }, func(v interface{}) error {
+		if err := i.s.store.AddChannelInfo(*v.(*wkdb.ChannelInfo)); err != nil {
+			return err
+		}
+		i.incr("channels", 1)
+		return nil
+	})
+}
+
+func (i *ImportTask) importMembers(dec *json.Decoder) error {
+	return i.importConcurrently(dec, func() interface{} { return &ExportMember{} }, func(v interface{}) error {
+		m := v.(*ExportMember)
+		var err error
+		switch m.List {
+		case memberListSubscriber:
+			err = i.s.store.AddSubscribers(m.ChannelId, m.ChannelType, m.Members)
+		case memberListDenylist:
+			err = i.s.store.AddDenylist(m.ChannelId, m.ChannelType, m.Members)
+		case memberListAllowlist:
+			err = i.s.store.AddAllowlist(m.ChannelId, m.ChannelType, m.Members)
+		default:
+			return fmt.Errorf("unknown member list: %s", m.List)
+		}
+		if err != nil {
+			return err
+		}
+		i.incr(m.List, len(m.Members))
+		return nil
+	})
+}
+
+func (i *ImportTask) importConversations(dec *json.Decoder) error {
+	return i.importConcurrently(dec, func() interface{} { return &wkdb.Conversation{} }, func(v interface{}) error {
+		conversation := *v.(*wkdb.Conversation)
+		// 已读和清空的位置映射为当前集群的seq，频道没有导入消息的保持为0
+		remap := i.getSeqRemap(conversation.ChannelId, conversation.ChannelType)
+		conversation.ReadToMsgSeq = remap.floor(conversation.ReadToMsgSeq)
+		conversation.ClearedToMsgSeq = remap.floor(conversation.ClearedToMsgSeq)
+		if err := i.s.store.AddOrUpdateConversations(conversation.Uid, []wkdb.Conversation{conversation}); err != nil {
+			return err
+		}
+		i.incr("conversations", 1)
+		return nil
+	})
+}
+
+// 并发导入每一行记录
+func (i *ImportTask) importConcurrently(dec *json.Decoder, newFnc func() interface{}, importFnc func(v interface{}) error) error {
+	g := errgroup.Group{}
+	g.SetLimit(i.goroutineCount)
+	for {
+		v := newFnc()
+		if err := dec.Decode(v); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			_ = g.Wait()
+			return err
+		}
+		g.Go(func() error {
+			return importFnc(v)
+		})
+	}
+	return g.Wait()
+}
+
+// 导入频道消息，导出文件中同一频道的消息是连续的
+func (i *ImportTask) importMessages(dec *json.Decoder) error {
+	var (
+		channelId   string
+		channelType uint8
+		seqMap      map[uint64]uint64 // 导出集群的seq -> 当前集群的seq
+		pending     []wkdb.Message
+		pendingSeqs map[int64]uint64 // 待提案的消息id -> 导出集群的seq
+	)
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		results, err := i.s.store.AppendMessages(timeoutCtx, channelId, channelType, pending)
+		cancel()
+		if err != nil {
+			return err
+		}
+		remap := i.getOrCreateSeqRemap(channelId, channelType)
+		for _, result := range results {
+			if oldSeq, ok := pendingSeqs[int64(result.LogId())]; ok {
+				seqMap[oldSeq] = result.LogIndex()
+				remap.add(oldSeq, result.LogIndex())
+			}
+		}
+		i.incr("messages", len(pending))
+		pending = pending[:0]
+		pendingSeqs = make(map[int64]uint64, importMessageBatchCount)
+		return nil
+	}
+
+	for {
+		var em ExportMessage
+		if err := dec.Decode(&em); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if em.ChannelId != channelId || em.ChannelType != channelType {
+			if err := flush(); err != nil {
+				return err
+			}
+			channelId = em.ChannelId
+			channelType = em.ChannelType
+			seqMap = make(map[uint64]uint64)
+			pendingSeqs = make(map[int64]uint64, importMessageBatchCount)
+		}
+
+		msg := em.toDBMessage()
+		if msg.Modify != nil {
+			newSeq, ok := seqMap[msg.Modify.MessageSeq]
+			if !ok {
+				// 被修改的消息可能还在待提案的消息里
+				if err := flush(); err != nil {
+					return err
+				}
+				newSeq, ok = seqMap[msg.Modify.MessageSeq]
+			}
+			if !ok {
+				i.Warn("modified message not found, skip", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("messageSeq", msg.Modify.MessageSeq))
+				continue
+			}
+			msg.Modify.MessageSeq = newSeq
+		}
+		if msg.ParentMessageSeq > 0 {
+			newSeq, ok := seqMap[msg.ParentMessageSeq]
+			if !ok {
+				// 父消息可能还在待提案的消息里
+				if err := flush(); err != nil {
+					return err
+				}
+				newSeq, ok = seqMap[msg.ParentMessageSeq]
+			}
+			if !ok {
+				i.Warn("parent message not found, import as a normal message", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("parentMessageSeq", msg.ParentMessageSeq))
+				newSeq = 0
+			}
+			msg.ParentMessageSeq = newSeq
+		}
+		pending = append(pending, msg)
+		pendingSeqs[msg.MessageID] = em.MessageSeq
+		if len(pending) >= importMessageBatchCount {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+func (i *ImportTask) getOrCreateSeqRemap(channelId string, channelType uint8) *seqRemap {
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+	i.seqRemapLock.Lock()
+	defer i.seqRemapLock.Unlock()
+	remap := i.seqRemaps[channelKey]
+	if remap == nil {
+		remap = &seqRemap{}
+		i.seqRemaps[channelKey] = remap
+	}
+	return remap
+}
+
+func (i *ImportTask) getSeqRemap(channelId string, channelType uint8) *seqRemap {
+	i.seqRemapLock.RLock()
+	defer i.seqRemapLock.RUnlock()
+	return i.seqRemaps[wkutil.ChannelToKey(channelId, channelType)]
+}
+
+// seqRemap 导出集群的seq到当前集群的seq的映射
+// 两边的seq都是递增的，所以按连续的区间保存，一个频道通常只有很少的几个区间
+type seqRemap struct {
+	segments []seqSegment
+}
+
+type seqSegment struct {
+	oldStart uint64
+	newStart uint64
+	count    uint64
+}
+
+// add 添加映射，oldSeq需要递增
+func (r *seqRemap) add(oldSeq, newSeq uint64) {
+	if n := len(r.segments); n > 0 {
+		last := &r.segments[n-1]
+		if oldSeq == last.oldStart+last.count && newSeq == last.newStart+last.count {
+			last.count++
+			return
+		}
+	}
+	r.segments = append(r.segments, seqSegment{oldStart: oldSeq, newStart: newSeq, count: 1})
+}
+
+// floor 返回不大于oldSeq的最大的已导入消息对应的新seq，没有返回0
+func (r *seqRemap) floor(oldSeq uint64) uint64 {
+	if r == nil || oldSeq == 0 {
+		return 0
+	}
+	idx := sort.Search(len(r.segments), func(i int) bool {
+		return r.segments[i].oldStart > oldSeq
+	}) - 1
+	if idx < 0 {
+		return 0
+	}
+	segment := r.segments[idx]
+	if oldSeq < segment.oldStart+segment.count {
+		return segment.newStart + (oldSeq - segment.oldStart)
+	}
+	return segment.newStart + segment.count - 1
+}
+
+// 打开文件并读取NDJSON
+func readNDJSON(filePath string, fnc func(dec *json.Decoder) error) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fnc(json.NewDecoder(bufio.NewReader(f)))
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -21,6 +23,8 @@ type userHandler struct {
 
 	conns []*connContext
 
+	lastSeens map[wkproto.DeviceFlag]*wkdb.DeviceLastSeen // 各设备的最后上线/离线时间，用户关闭时持久化
+
 	connNodeIds []uint64 // 连接涉及到的节点id集合
 
 	sendPing  bool          // 正在发送ping
@@ -71,6 +75,7 @@ func newUserHandler(uid string, sub *userReactorSub) *userHandler {
 		Log:                 wklog.NewWKLog(fmt.Sprintf("userHandler[%d][%s]", sub.r.s.opts.Cluster.NodeId, uid)),
 		sub:                 sub,
 		nodePongTimeoutTick: make(map[uint64]int),
+		lastSeens:           make(map[wkproto.DeviceFlag]*wkdb.DeviceLastSeen),
 		uniqueNo:            wkutil.GenUUID(),
 		opts:                opts,
 		initTick:            opts.Reactor.User.ProcessIntervalTick,
@@ -382,6 +387,7 @@ func (u *userHandler) addConnIfNotExist(conn *connContext) {
 	if !exist {
 		u.Debug("add conn", zap.Int64("connId", conn.connId), zap.String("uid", u.uid), zap.String("deviceId", conn.deviceId))
 		u.conns = append(u.conns, conn)
+		u.markLastSeenNotLock(conn.deviceFlag, true)
 	}
 
 	u.resetConnNodeIds()
@@ -461,6 +467,12 @@ func (u *userHandler) removeConnById(id int64) *connContext {
 	}
 	u.resetConnNodeIds()
 
+	if existConn != nil {
+		u.mu.Lock()
+		u.markLastSeenNotLock(existConn.deviceFlag, false)
+		u.mu.Unlock()
+	}
+
 	return existConn
 
 }
@@ -477,6 +489,7 @@ func (u *userHandler) removeConnsByNodeId(nodeId uint64) []*connContext {
 			newConns = append(newConns, u.conns[i])
 		} else {
 			removeConns = append(removeConns, u.conns[i])
+			u.markLastSeenNotLock(u.conns[i].deviceFlag, false)
 		}
 	}
 	u.conns = newConns
@@ -484,6 +497,37 @@ func (u *userHandler) removeConnsByNodeId(nodeId uint64) []*connContext {
 	return removeConns
 }
 
+// 记录设备的上线/离线时间
+func (u *userHandler) markLastSeenNotLock(deviceFlag wkproto.DeviceFlag, online bool) {
+	lastSeen := u.lastSeens[deviceFlag]
+	if lastSeen == nil {
+		lastSeen = &wkdb.DeviceLastSeen{DeviceFlag: uint8(deviceFlag)}
+		u.lastSeens[deviceFlag] = lastSeen
+	}
+	if online {
+		lastSeen.LastOnline = time.Now().Unix()
+	} else {
+		lastSeen.LastOffline = time.Now().Unix()
+	}
+}
+
+// getLastSeen 获取用户在本节点记录的最后上线/离线时间
+func (u *userHandler) getLastSeen() wkdb.UserLastSeen {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	lastSeen := wkdb.UserLastSeen{
+		Uid:     u.uid,
+		Devices: make([]wkdb.DeviceLastSeen, 0, len(u.lastSeens)),
+	}
+	for _, d := range u.lastSeens {
+		lastSeen.LastOnline = max(lastSeen.LastOnline, d.LastOnline)
+		lastSeen.LastOffline = max(lastSeen.LastOffline, d.LastOffline)
+		lastSeen.Devices = append(lastSeen.Devices, *d)
+	}
+	return lastSeen
+}
+
 func (u *userHandler) resetConnNodeIds() {
 	u.connNodeIds = u.connNodeIds[:0]
 	for _, conn := range u.conns {
@@ -6,6 +6,7 @@ import (
 	"hash/fnv"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/lni/goutils/syncutil"
@@ -26,6 +27,7 @@ type userReactor struct {
 	processProxyNodeTimeoutC  chan *proxyNodeTimeoutReq // 代理节点超时
 	processCloseC             chan *userCloseReq        // 关闭请求
 	processCheckLeaderC       chan *checkLeaderReq      // 检查leader请求
+	processLastSeenC          chan wkdb.UserLastSeen    // 保存用户最后上线/离线时间请求
 
 	stopper *syncutil.Stopper
 	wklog.Log
@@ -49,6 +51,7 @@ func newUserReactor(s *Server) *userReactor {
 		processProxyNodeTimeoutC:  make(chan *proxyNodeTimeoutReq, 1024),
 		processCloseC:             make(chan *userCloseReq, 1024),
 		processCheckLeaderC:       make(chan *checkLeaderReq, 1024),
+		processLastSeenC:          make(chan wkdb.UserLastSeen, 1024),
 		stopper:                   syncutil.NewStopper(),
 		Log:                       wklog.NewWKLog(fmt.Sprintf("userReactor[%d]", s.opts.Cluster.NodeId)),
 		s:                         s,
@@ -88,6 +91,7 @@ func (u *userReactor) start() error {
 		u.stopper.RunWorker(u.processPingLoop)
 		u.stopper.RunWorker(u.processAuthLoop)
 		u.stopper.RunWorker(u.processForwardUserActionLoop)
+		u.stopper.RunWorker(u.processLastSeenLoop)
 	}
 
 	for _, sub := range u.subs {
@@ -1245,7 +1245,13 @@ func (r *userReactor) processClose(req *userCloseReq) {
 	}
 
 	if req.handler.role == userRoleLeader {
+		// 连接移除时已记录了离线时间，由用户的领导节点异步持久化用户的最后上线/离线时间
+		if lastSeen := req.handler.getLastSeen(); len(lastSeen.Devices) > 0 {
+			r.addLastSeenReq(lastSeen)
+		}
 		r.s.trace.Metrics.App().OnlineUserCountAdd(-1)
+		// 在线状态的订阅关系保存在用户的领导节点上，用户离线后清除
+		r.s.presenceManager.unsubscribe(uid, nil)
 	}
 
 	r.removeUserHandler(uid)
@@ -1256,6 +1262,60 @@ type userCloseReq struct {
 	handler *userHandler
 }
 
+// =================================== 保存最后上线/离线时间 ===================================
+
+func (r *userReactor) addLastSeenReq(lastSeen wkdb.UserLastSeen) {
+	select {
+	case r.processLastSeenC <- lastSeen:
+	default:
+		r.Warn("addLastSeenReq: processLastSeenC is full, ignore ", zap.String("uid", lastSeen.Uid))
+	}
+}
+
+func (r *userReactor) processLastSeenLoop() {
+	lastSeens := make([]wkdb.UserLastSeen, 0, 100)
+	done := false
+	for !r.stopped.Load() {
+		select {
+		case lastSeen := <-r.processLastSeenC:
+			lastSeens = append(lastSeens, lastSeen)
+			for !done {
+				select {
+				case lastSeen := <-r.processLastSeenC:
+					lastSeens = append(lastSeens, lastSeen)
+				default:
+					done = true
+				}
+			}
+
+			r.processLastSeen(lastSeens)
+			done = false
+			lastSeens = lastSeens[:0]
+		case <-r.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// 同一个用户只保存最新的一条，每条提案都有超时，避免槽不可用时一直阻塞
+func (r *userReactor) processLastSeen(lastSeens []wkdb.UserLastSeen) {
+	latestMap := make(map[string]int, len(lastSeens)) // uid -> lastSeens下标
+	for i, lastSeen := range lastSeens {
+		latestMap[lastSeen.Uid] = i
+	}
+	for i, lastSeen := range lastSeens {
+		if latestMap[lastSeen.Uid] != i {
+			continue
+		}
+		timeoutCtx, cancel := context.WithTimeout(r.s.ctx, time.Second*5)
+		err := r.s.store.UpdateUserLastSeen(timeoutCtx, lastSeen)
+		cancel()
+		if err != nil {
+			r.Warn("保存用户最后上线/离线时间失败！", zap.Error(err), zap.String("uid", lastSeen.Uid))
+		}
+	}
+}
+
 // =================================== 检查领导的正确性 ===================================
 
 func (r *userReactor) addCheckLeaderReq(req *checkLeaderReq) {
@@ -3,9 +3,12 @@ package server
 import (
 	"bytes"
 	"compress/gzip"
-	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
 	"strconv"
@@ -13,16 +16,13 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
-	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/panjf2000/ants/v2"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/keepalive"
 )
 
 type webhook struct {
@@ -30,10 +30,20 @@ type webhook struct {
 	wklog.Log
 	eventPool        *ants.Pool
 	httpClient       *http.Client
-	webhookGRPCPool  *grpcpool.Pool // webhook grpc客户端
 	stoped           chan struct{}
 	onlinestatusLock sync.RWMutex
 	onlinestatusList []string
+
+	grpcPoolLock sync.Mutex
+	grpcPools    map[string]*grpcpool.Pool // webhook grpc客户端，key为grpc地址
+
+	subscriptionLock sync.RWMutex
+	subscriptions    []wkdb.WebhookSubscription // webhook订阅
+
+	channelWebhookLock sync.RWMutex
+	channelWebhooks    map[string]channelWebhook // 频道设置的webhook地址缓存
+
+	fileSink *fileEventSink // 本地文件输出端，没有事件配置输出到文件时为nil
 }
 
 func newWebhook(s *Server) *webhook {
@@ -43,26 +53,12 @@ func newWebhook(s *Server) *webhook {
 	if err != nil {
 		panic(err)
 	}
-	var (
-		webhookGRPCPool *grpcpool.Pool
-	)
-	if s.opts.WebhookGRPCOn() {
-		webhookGRPCPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
-			return grpc.Dial(s.opts.Webhook.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
-				Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
-				Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
-			}))
-		}, 2, 20, time.Minute*5) // 初始化2个连接 最多20个连接
-		if err != nil {
-			panic(err)
-		}
-
-	}
-	return &webhook{
+	w := &webhook{
 		s:                s,
 		Log:              wklog.NewWKLog("Webhook"),
 		eventPool:        eventPool,
-		webhookGRPCPool:  webhookGRPCPool,
+		grpcPools:        make(map[string]*grpcpool.Pool),
+		channelWebhooks:  make(map[string]channelWebhook),
 		onlinestatusList: make([]string, 0),
 		stoped:           make(chan struct{}),
 		httpClient: &http.Client{
@@ -81,15 +77,30 @@ func newWebhook(s *Server) *webhook {
 			},
 		},
 	}
+	if s.opts.WebhookGRPCOn() {
+		if _, err = w.getGRPCPool(s.opts.Webhook.GRPCAddr); err != nil {
+			panic(err)
+		}
+	}
+	if s.opts.EventSinkFileOn() {
+		w.fileSink = newFileEventSink(s)
+	}
+	return w
 }
 
 func (w *webhook) Start() {
 	go w.notifyQueueLoop()
 	go w.loopOnlineStatus()
+	go w.loopLoadSubscriptions()
 }
 
 func (w *webhook) Stop() {
 	close(w.stoped)
+	if w.fileSink != nil {
+		if err := w.fileSink.close(); err != nil {
+			w.Warn("关闭事件文件失败！", zap.Error(err))
+		}
+	}
 }
 
 // Online 用户设备上线通知
@@ -99,6 +110,8 @@ func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64
 	online := 1
 	w.onlinestatusList = append(w.onlinestatusList, fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
 
+	w.s.presenceManager.onPresence(uid, deviceFlag, true, deviceOnlineCount, totalOnlineCount)
+
 	w.Debug("User online", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()), zap.Int64("id", connId))
 }
 
@@ -109,38 +122,132 @@ func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int6
 	// 用户ID-用户设备标记-在线状态-socket ID-当前设备标记下的设备在线数量-当前用户下的所有设备在线数量
 	w.onlinestatusList = append(w.onlinestatusList, fmt.Sprintf("%s-%d-%d-%d-%d-%d", uid, deviceFlag, online, connId, deviceOnlineCount, totalOnlineCount))
 
+	w.s.presenceManager.onPresence(uid, deviceFlag, false, deviceOnlineCount, totalOnlineCount)
+
 	w.Debug("User offline", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()))
 }
 
-// TriggerEvent 触发事件
+// TriggerEvent 触发事件，推送给所有匹配的webhook
 func (w *webhook) TriggerEvent(event *Event) {
-	if !w.s.opts.WebhookOn() { // 没设置webhook直接忽略
+	if !w.on() { // 没设置webhook直接忽略
 		return
 	}
 	err := w.eventPool.Submit(func() {
-		jsonData, err := json.Marshal(event.Data)
-		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
-			return
-		}
+		w.sendEventToTargets(event)
+	})
+	if err != nil {
+		w.Error("提交事件失败", zap.Error(err))
+	}
+}
 
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(event.Event, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(event.Event, jsonData)
+// sendEventToTargets 推送事件到所有推送目标，需要在事件协程里调用
+func (w *webhook) sendEventToTargets(event *Event) {
+	channelId, channelType := event.channel()
+	targets := w.targets(event.Event, channelId, channelType)
+	if len(targets) == 0 {
+		return
+	}
+	jsonData, err := json.Marshal(event.Data)
+	if err != nil {
+		w.Error("webhook的event数据不能json化！", zap.Error(err))
+		return
+	}
+	for _, target := range targets {
+		w.sendEvent(target, event.Event, jsonData, 0)
+	}
+}
+
+// sendEvent 推送事件，失败后按退避间隔重试，超过最大重试次数放入死信队列
+func (w *webhook) sendEvent(target webhookTarget, event string, data []byte, retryCount int) {
+	err := w.sendWebhook(target, event, data)
+	if err == nil {
+		return
+	}
+	w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event), zap.String("addr", target.addr()), zap.Int("retryCount", retryCount))
+	if retryCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
+		w.addDeadLetter(target, event, data, retryCount, err)
+		return
+	}
+	time.AfterFunc(w.retryBackoff(retryCount+1), func() {
+		select {
+		case <-w.stoped:
+			w.Warn("webhook已停止，放弃重试事件", zap.String("event", event))
+			return
+		default:
 		}
+		err := w.eventPool.Submit(func() {
+			w.sendEvent(target, event, data, retryCount+1)
+		})
 		if err != nil {
-			w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
-			return
+			w.Error("提交重试事件失败", zap.Error(err), zap.String("event", event))
+			w.addDeadLetter(target, event, data, retryCount, err)
 		}
+	})
+}
 
+// retryBackoff 第attempt次失败后的重试间隔，指数退避并加入随机抖动（取[d/2, d]）
+func (w *webhook) retryBackoff(attempt int) time.Duration {
+	base := w.s.opts.Webhook.RetryBackoffBase
+	if base <= 0 {
+		base = time.Second
+	}
+	max := w.s.opts.Webhook.RetryBackoffMax
+	d := base
+	for i := 1; i < attempt && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// addDeadLetter 将推送失败的事件放入死信队列，可以通过管理接口查看、重放和清除
+func (w *webhook) addDeadLetter(target webhookTarget, event string, data []byte, retryCount int, cause error) {
+	deadLetter := wkdb.WebhookDeadLetter{
+		Id:             w.s.store.DB().NextPrimaryKey(),
+		Event:          event,
+		Data:           data,
+		RetryCount:     uint32(retryCount),
+		CreatedAt:      time.Now().Unix(),
+		SubscriptionId: target.subscriptionId,
+	}
+	if target.isChannel { // 频道webhook记录下推送地址，重放时推送到相同地址
+		deadLetter.Target = target.httpAddr
+	} else if target.isFile {
+		deadLetter.Target = EventSinkFile
+	}
+	if cause != nil {
+		deadLetter.Error = cause.Error()
+	}
+	err := w.s.store.DB().AddWebhookDeadLetter(deadLetter)
+	if err != nil {
+		w.Error("添加webhook死信失败！", zap.Error(err), zap.String("event", event))
+		return
+	}
+	w.Warn("webhook事件推送失败超过最大次数，已放入死信队列", zap.String("event", event), zap.Uint64("id", deadLetter.Id))
+}
+
+// notifyOfflineMsgs 推送离线消息，免打扰用户需要跨节点查询，放在事件协程里查询，不阻塞消息投递
+func (w *webhook) notifyOfflineMsgs(channelId string, channelType uint8, messages []ReactorChannelMessage, subscribers []string) {
+	messages = append([]ReactorChannelMessage(nil), messages...)
+	err := w.eventPool.Submit(func() {
+		mutedUids, err := w.s.getMutedUids(channelId, channelType, subscribers)
+		if err != nil { // 获取失败不影响离线通知
+			w.Warn("获取免打扰用户失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		}
+		for _, message := range messages {
+			w.sendEventToTargets(w.offlineMsgEvent(message, subscribers, mutedUids))
+		}
 	})
 	if err != nil {
-		w.Error("提交事件失败", zap.Error(err))
+		w.Error("提交离线消息事件失败", zap.Error(err))
 	}
 }
 
-func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []string) {
+// mutedUids为subscribers中对此会话开启了免打扰的用户，业务端可以据此决定是否推送
+func (w *webhook) offlineMsgEvent(msg ReactorChannelMessage, subscribers []string, mutedUids []string) *Event {
 	compress := ""
 	toUIDs := subscribers
 	var compresssToUIDs []byte
@@ -158,7 +265,7 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 		}
 	}
 	// 推送离线到上层应用
-	w.TriggerEvent(&Event{
+	return &Event{
 		Event: EventMsgOffline,
 		Data: MessageOfflineNotify{
 			MessageResp: MessageResp{
@@ -168,6 +275,7 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 					NoPersist: wkutil.BoolToInt(msg.SendPacket.NoPersist),
 				},
 				Setting:      msg.SendPacket.Setting.Uint8(),
+				Sensitive:    wkutil.BoolToInt(msg.IsSensitive),
 				ClientMsgNo:  msg.SendPacket.ClientMsgNo,
 				MessageId:    msg.MessageId,
 				MessageIdStr: strconv.FormatInt(msg.MessageId, 10),
@@ -184,8 +292,9 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 			Compress:        compress,
 			CompresssToUIDs: compresssToUIDs,
 			SourceID:        int64(w.s.opts.Cluster.NodeId),
+			MutedUIDs:       mutedUids,
 		},
-	})
+	}
 }
 
 // 通知上层应用 TODO: 此初报错可以做一个邮件报警处理类的东西，
@@ -194,21 +303,46 @@ func (w *webhook) notifyQueueLoop() {
 	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
 	defer ticker.Stop()
 	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
-	if w.s.opts.WebhookOn() {
-		for {
-			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
-			if err != nil {
-				w.Error("获取通知队列内的消息失败！", zap.Error(err))
-				time.Sleep(errorSleepTime) // 如果报错就休息下
+	failCount := 0                         // 连续失败次数
+	for {
+		if !w.on() { // 没有推送目标（订阅可能随时添加）
+			select {
+			case <-ticker.C:
 				continue
+			case <-w.stoped:
+				return
+			}
+		}
+		messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+		if err != nil {
+			w.Error("获取通知队列内的消息失败！", zap.Error(err))
+			time.Sleep(errorSleepTime) // 如果报错就休息下
+			continue
+		}
+		if len(messages) > 0 {
+			messageResps := make([]*MessageResp, 0, len(messages))
+			for _, msg := range messages {
+				resp := &MessageResp{}
+				resp.from(msg, w.s)
+				messageResps = append(messageResps, resp)
 			}
-			if len(messages) > 0 {
-				messageResps := make([]*MessageResp, 0, len(messages))
-				for _, msg := range messages {
-					resp := &MessageResp{}
-					resp.from(msg, w.s)
-					messageResps = append(messageResps, resp)
+
+			// 第一个输出端推送失败时整批消息重试，其他输出端、订阅和频道webhook只在消息第一次推送时分发，失败后各自按退避间隔重试
+			globalTargets := w.globalTargets(EventMsgNotify)
+			firstResps := make([]*MessageResp, 0, len(messageResps))
+			for _, resp := range messageResps {
+				if errMessageIDMap[resp.MessageId] == 0 {
+					firstResps = append(firstResps, resp)
 				}
+			}
+			var otherTargets []webhookTarget
+			if len(globalTargets) > 1 {
+				otherTargets = globalTargets[1:]
+			}
+			w.dispatchMessageNotify(firstResps, otherTargets)
+
+			if len(globalTargets) > 0 {
+				target := globalTargets[0]
 				messageData, err := json.Marshal(messageResps)
 				if err != nil {
 					w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
@@ -216,20 +350,20 @@ func (w *webhook) notifyQueueLoop() {
 					continue
 				}
 
-				if w.s.opts.WebhookGRPCOn() {
-					err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
-				} else {
-					err = w.sendWebhookForHttp(EventMsgNotify, messageData)
-				}
+				err = w.sendWebhook(target, EventMsgNotify, messageData)
 				if err != nil {
 					w.Error("请求所有消息通知webhook失败！", zap.Error(err))
+					failCount++
 					errMessageIDs := make([]int64, 0, len(messages))
-					for _, message := range messages {
+					for i, message := range messages {
 						errCount := errMessageIDMap[message.MessageID]
 						errCount++
 						errMessageIDMap[message.MessageID] = errCount
 						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
 							errMessageIDs = append(errMessageIDs, message.MessageID)
+							// 每条消息单独放入死信队列，重放时不影响其他消息
+							deadLetterData, _ := json.Marshal([]*MessageResp{messageResps[i]})
+							w.addDeadLetter(target, EventMsgNotify, deadLetterData, errCount, err)
 						}
 					}
 					if len(errMessageIDs) > 0 {
@@ -242,41 +376,77 @@ func (w *webhook) notifyQueueLoop() {
 							delete(errMessageIDMap, errMessageID)
 						}
 					}
-					time.Sleep(errorSleepTime) // 如果报错就休息下
+					time.Sleep(w.retryBackoff(failCount)) // 如果报错就按退避间隔休息下
 					continue
 				}
+				failCount = 0
+			}
 
-				messageIDs := make([]int64, 0, len(messages))
-				for _, message := range messages {
-					messageID := message.MessageID
-					messageIDs = append(messageIDs, messageID)
+			messageIDs := make([]int64, 0, len(messages))
+			for _, message := range messages {
+				messageID := message.MessageID
+				messageIDs = append(messageIDs, messageID)
 
-					delete(errMessageIDMap, messageID)
-				}
-				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
-				if err != nil {
-					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
+				delete(errMessageIDMap, messageID)
 			}
+			err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
+			if err != nil {
+				w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+				time.Sleep(errorSleepTime) // 如果报错就休息下
+				continue
+			}
+		}
 
-			select {
-			case <-ticker.C:
-			case <-w.stoped:
-				return
+		select {
+		case <-ticker.C:
+		case <-w.stoped:
+			return
+		}
+	}
+}
+
+// dispatchMessageNotify 将消息通知分发给globalTargets（收到所有消息）以及订阅和频道webhook（只收到与之匹配的消息）
+func (w *webhook) dispatchMessageNotify(messageResps []*MessageResp, globalTargets []webhookTarget) {
+	keys := make([]string, 0)
+	targets := make(map[string]webhookTarget)
+	targetResps := make(map[string][]*MessageResp)
+	for _, resp := range messageResps {
+		matchTargets := append(append([]webhookTarget{}, globalTargets...), w.extraTargets(EventMsgNotify, resp.ChannelID, resp.ChannelType)...)
+		for _, target := range matchTargets {
+			key := target.key()
+			if _, ok := targets[key]; !ok {
+				keys = append(keys, key)
+				targets[key] = target
 			}
+			targetResps[key] = append(targetResps[key], resp)
+		}
+	}
+	for _, key := range keys {
+		target := targets[key]
+		data, err := json.Marshal(targetResps[key])
+		if err != nil {
+			w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
+			continue
+		}
+		err = w.eventPool.Submit(func() {
+			w.sendEvent(target, EventMsgNotify, data, 0)
+		})
+		if err != nil {
+			w.Error("提交事件失败", zap.Error(err), zap.String("addr", target.addr()))
+			w.addDeadLetter(target, EventMsgNotify, data, 0, err)
 		}
 	}
 }
 
 func (w *webhook) loopOnlineStatus() {
-	if !w.s.opts.WebhookOn() {
-		return
-	}
 	opLen := 0    // 最后一次操作在线状态数组的长度
 	errCount := 0 // webhook请求失败重试次数
 	for {
+		select {
+		case <-w.stoped:
+			return
+		default:
+		}
 		if opLen == 0 {
 			w.onlinestatusLock.Lock()
 			opLen = len(w.onlinestatusList)
@@ -286,6 +456,12 @@ func (w *webhook) loopOnlineStatus() {
 			time.Sleep(time.Second * 2) // 没有数据就休息2秒
 			continue
 		}
+		globalTargets := w.globalTargets(EventOnlineStatus)
+		if !w.on() { // 没有推送目标直接丢弃
+			w.removeOnlineStatus(opLen)
+			opLen = 0
+			continue
+		}
 		w.onlinestatusLock.Lock()
 		data := w.onlinestatusList[:opLen]
 		w.onlinestatusLock.Unlock()
@@ -296,92 +472,81 @@ func (w *webhook) loopOnlineStatus() {
 			continue
 		}
 
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(EventOnlineStatus, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(EventOnlineStatus, jsonData)
+		// 第一个输出端推送失败时重试，其他输出端和订阅只在第一次推送时分发，失败后各自按退避间隔重试
+		if errCount == 0 {
+			var otherTargets []webhookTarget
+			if len(globalTargets) > 1 {
+				otherTargets = globalTargets[1:]
+			}
+			for _, target := range append(otherTargets, w.extraTargets(EventOnlineStatus, "", 0)...) {
+				err = w.eventPool.Submit(func() {
+					w.sendEvent(target, EventOnlineStatus, jsonData, 0)
+				})
+				if err != nil {
+					w.Error("提交事件失败", zap.Error(err), zap.String("addr", target.addr()))
+					w.addDeadLetter(target, EventOnlineStatus, jsonData, 0, err)
+				}
+			}
 		}
-		if err != nil {
-			errCount++
-			w.Error("请求在线状态webhook失败！", zap.Error(err))
-			if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
 
-				w.onlinestatusLock.Lock()
-				w.onlinestatusList = w.onlinestatusList[opLen:]
-				opLen = 0
-				w.onlinestatusLock.Unlock()
+		if len(globalTargets) > 0 {
+			target := globalTargets[0]
+			err = w.sendWebhook(target, EventOnlineStatus, jsonData)
+			if err != nil {
+				errCount++
+				w.Error("请求在线状态webhook失败！", zap.Error(err))
+				if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
+					w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
+					w.addDeadLetter(target, EventOnlineStatus, jsonData, errCount, err)
+
+					w.removeOnlineStatus(opLen)
+					opLen = 0
+					errCount = 0
+					continue
+				}
 
-				errCount = 0
+				time.Sleep(w.retryBackoff(errCount)) // 如果报错就按退避间隔休息下
+				continue
 			}
-
-			time.Sleep(time.Second * 1) // 如果报错就休息下
-			continue
 		}
 
-		w.onlinestatusLock.Lock()
-		w.onlinestatusList = w.onlinestatusList[opLen:]
+		w.removeOnlineStatus(opLen)
 		opLen = 0
-		w.onlinestatusLock.Unlock()
-
+		errCount = 0
 	}
 }
 
-func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
-	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
-	startTime := time.Now().UnixNano() / 1000 / 1000
-	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
-	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
-	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
-	if err != nil {
-		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-		return errors.New("第三方消息通知接口返回状态错误！")
-	}
-	return nil
+// removeOnlineStatus 移除已经处理的在线状态
+func (w *webhook) removeOnlineStatus(opLen int) {
+	w.onlinestatusLock.Lock()
+	w.onlinestatusList = w.onlinestatusList[opLen:]
+	w.onlinestatusLock.Unlock()
 }
 
-func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
-
-	startNow := time.Now()
-	startTime := startNow.UnixNano() / 1000 / 1000
-	w.Debug("webhook grpc 开始请求", zap.String("event", event))
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-	defer cancel()
-	clientConn, err := w.webhookGRPCPool.Get(ctx)
-	if err != nil {
-		return err
+// sendWebhook 通过推送目标对应的输出端发送事件
+func (w *webhook) sendWebhook(target webhookTarget, event string, data []byte) error {
+	if target.isFile && w.fileSink == nil {
+		return errors.New("没有配置文件输出端！")
 	}
-	defer clientConn.Close()
-	// cliConn, err := grpc.Dial(l.opts.WebhookGRPC, grpc.WithInsecure())
-	// if err != nil {
-	// 	return err
-	// }
-	cli := wkhook.NewWebhookServiceClient(clientConn)
-
-	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer sendCancel()
-	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
-		Event: event,
-		Data:  data,
-	})
-	w.Debug("webhook grpc 请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+	return w.sink(target).Send(event, data)
+}
 
-	if err != nil {
-		return err
-	}
-	if resp.Status != wkhook.EventStatus_Success {
-		return errors.New("grpc返回状态错误！")
-	}
-	return nil
+// webhookSign 签名内容为 timestamp.event.data，返回HMAC-SHA256的hex
+func webhookSign(secret string, timestamp string, event string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(event))
+	mac.Write([]byte("."))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
+const (
+	webhookHeaderTimestamp = "X-WK-Timestamp" // 签名时间戳（单位秒）
+	webhookHeaderSignature = "X-WK-Signature" // HMAC-SHA256签名
+)
+
 const (
 	// EventMsgOffline 离线消息
 	EventMsgOffline = "msg.offline"
@@ -389,6 +554,8 @@ const (
 	EventMsgNotify = "msg.notify"
 	// EventOnlineStatus 用户在线状态
 	EventOnlineStatus = "user.onlinestatus"
+	// EventMsgBeforeSend 消息发送前审核（同步调用，不会推送给订阅）
+	EventMsgBeforeSend = "msg.before_send"
 )
 
 // Event Event
@@ -397,6 +564,17 @@ type Event struct {
 	Data  interface{} `json:"data"`  // 事件数据
 }
 
+// channel 事件所属的频道，不属于任何频道的事件返回空
+func (e *Event) channel() (string, uint8) {
+	switch data := e.Data.(type) {
+	case MessageOfflineNotify:
+		return data.ChannelID, data.ChannelType
+	case *MessageOfflineNotify:
+		return data.ChannelID, data.ChannelType
+	}
+	return "", 0
+}
+
 func (e *Event) String() string {
 	return fmt.Sprintf("Event:%s Data:%v", e.Event, e.Data)
 }
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// beforeSendMessage 发送前审核的消息
+type beforeSendMessage struct {
+	MessageId   int64  `json:"message_id"`    // 服务端的消息ID
+	ClientMsgNo string `json:"client_msg_no"` // 客户端消息编号
+	FromUid     string `json:"from_uid"`      // 发送者uid
+	ChannelId   string `json:"channel_id"`    // 频道ID
+	ChannelType uint8  `json:"channel_type"`  // 频道类型
+	Payload     []byte `json:"payload"`       // 消息内容
+}
+
+// beforeSendResult 发送前审核的结果，没有返回结果的消息视为通过
+type beforeSendResult struct {
+	MessageId  int64  `json:"message_id"`        // 服务端的消息ID
+	ReasonCode uint32 `json:"reason_code"`       // 0或1表示通过，其他值表示拒绝并作为发送回执的原因码返回给发送者
+	Payload    []byte `json:"payload,omitempty"` // 替换后的消息内容，为空表示不替换
+}
+
+// reject 审核是否拒绝了消息，返回拒绝的原因码
+func (b beforeSendResult) reject() (wkproto.ReasonCode, bool) {
+	if b.ReasonCode == 0 || b.ReasonCode == uint32(wkproto.ReasonSuccess) {
+		return wkproto.ReasonSuccess, false
+	}
+	if b.ReasonCode > math.MaxUint8 {
+		return wkproto.ReasonSystemError, true
+	}
+	return wkproto.ReasonCode(b.ReasonCode), true
+}
+
+// beforeSendOn 是否开启了发送前审核（需要配置全局的webhook地址）
+func (w *webhook) beforeSendOn() bool {
+	return w.s.opts.Webhook.BeforeSendOn && w.s.opts.WebhookOn()
+}
+
+// beforeSend 同步调用审核服务，同一个频道的一批消息一次请求
+func (w *webhook) beforeSend(timeout time.Duration, messages []beforeSendMessage) ([]beforeSendResult, error) {
+	target, ok := w.globalTarget()
+	if !ok {
+		return nil, errors.New("没有配置webhook！")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if target.grpcAddr != "" {
+		return w.beforeSendForGRPC(ctx, target, messages)
+	}
+	return w.beforeSendForHttp(ctx, target, messages)
+}
+
+func (w *webhook) beforeSendForHttp(ctx context.Context, target webhookTarget, messages []beforeSendMessage) ([]beforeSendResult, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"messages": messages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sep := "?"
+	if strings.Contains(target.httpAddr, "?") {
+		sep = "&"
+	}
+	eventURL := fmt.Sprintf("%s%sevent=%s", target.httpAddr, sep, EventMsgBeforeSend)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, eventURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if target.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(webhookHeaderTimestamp, timestamp)
+		req.Header.Set(webhookHeaderSignature, webhookSign(target.secret, timestamp, EventMsgBeforeSend, data))
+	}
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("发送前审核接口返回状态错误！[%d]", resp.StatusCode)
+	}
+	var result struct {
+		Results []beforeSendResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+func (w *webhook) beforeSendForGRPC(ctx context.Context, target webhookTarget, messages []beforeSendMessage) ([]beforeSendResult, error) {
+	pool, err := w.getGRPCPool(target.grpcAddr)
+	if err != nil {
+		return nil, err
+	}
+	clientConn, err := pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer clientConn.Close()
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+
+	req := &wkhook.BeforeSendReq{
+		Messages: make([]*wkhook.BeforeSendMessage, 0, len(messages)),
+	}
+	for _, m := range messages {
+		req.Messages = append(req.Messages, &wkhook.BeforeSendMessage{
+			MessageId:   m.MessageId,
+			ClientMsgNo: m.ClientMsgNo,
+			FromUid:     m.FromUid,
+			ChannelId:   m.ChannelId,
+			ChannelType: uint32(m.ChannelType),
+			Payload:     m.Payload,
+		})
+	}
+	if target.secret != "" {
+		// protobuf编码在不同语言下不一定一致，grpc请求只对时间戳和事件签名
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(webhookHeaderTimestamp), timestamp, strings.ToLower(webhookHeaderSignature), webhookSign(target.secret, timestamp, EventMsgBeforeSend, nil))
+	}
+	resp, err := cli.BeforeSend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]beforeSendResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, beforeSendResult{
+			MessageId:  r.MessageId,
+			ReasonCode: r.ReasonCode,
+			Payload:    r.Payload,
+		})
+	}
+	return results, nil
+}
+
+// needBeforeSendCheck 消息是否需要发送前审核（系统消息和已经失败的消息不需要）
+func needBeforeSendCheck(msg ReactorChannelMessage) bool {
+	return msg.ReasonCode == wkproto.ReasonSuccess && !msg.IsSystem && msg.SendPacket != nil
+}
+
+// needBeforeSend 是否有需要发送前审核的消息
+func needBeforeSend(messages []ReactorChannelMessage) bool {
+	for _, msg := range messages {
+		if needBeforeSendCheck(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// beforeSendTimeout 发送前审核的超时时间
+// 频道发起权限校验后超过ProcessIntervalTick个tick没有收到结果会重新发起，审核的超时时间不能超过这段时间的一半（另一半留给权限校验和排队），否则同一批消息会被重复审核
+func (r *channelReactor) beforeSendTimeout() time.Duration {
+	timeout := r.opts.Webhook.BeforeSendTimeout
+	if timeout <= 0 {
+		timeout = time.Second * 2
+	}
+	maxTimeout := time.Duration(r.opts.Reactor.Channel.ProcessIntervalTick) * r.opts.Reactor.Channel.TickInterval / 2
+	if maxTimeout > 0 && timeout > maxTimeout {
+		timeout = maxTimeout
+	}
+	return timeout
+}
+
+func (r *channelReactor) addBeforeSendReq(req *permissionReq) {
+	req.beforeSendDeadline = time.Now().Add(r.beforeSendTimeout())
+	select {
+	case r.processBeforeSendC <- req:
+	default:
+		// 队列满了不能丢弃请求（频道会一直等待权限校验结果），直接按审核失败处理
+		r.Warn("processBeforeSendC is full", zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType), zap.Bool("failOpen", r.opts.Webhook.BeforeSendFailOpen))
+		r.failBeforeSend(req)
+		r.stepPermissionResp(req)
+	}
+}
+
+func (r *channelReactor) processBeforeSendLoop() {
+	for {
+		select {
+		case req := <-r.processBeforeSendC:
+			r.processBeforeSend(req)
+			r.stepPermissionResp(req)
+		case <-r.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// processBeforeSend 通过权限校验的消息在存储前同步交给审核服务，审核服务可以拒绝消息或替换消息内容
+func (r *channelReactor) processBeforeSend(req *permissionReq) {
+	indexes := make([]int, 0, len(req.messages))
+	messages := make([]beforeSendMessage, 0, len(req.messages))
+	for i, msg := range req.messages {
+		if !needBeforeSendCheck(msg) {
+			continue
+		}
+		indexes = append(indexes, i)
+		messages = append(messages, beforeSendMessage{
+			MessageId:   msg.MessageId,
+			ClientMsgNo: msg.SendPacket.ClientMsgNo,
+			FromUid:     msg.FromUid,
+			ChannelId:   msg.SendPacket.ChannelID,
+			ChannelType: msg.SendPacket.ChannelType,
+			Payload:     msg.SendPacket.Payload,
+		})
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	// 审核的超时时间从加入队列时开始计算，排队已经超时的不再请求
+	timeout := r.beforeSendTimeout()
+	if !req.beforeSendDeadline.IsZero() {
+		timeout = time.Until(req.beforeSendDeadline)
+	}
+	if timeout <= 0 {
+		r.Warn("发送前审核排队超时！", zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType), zap.Int("messageCount", len(messages)), zap.Bool("failOpen", r.opts.Webhook.BeforeSendFailOpen))
+		r.failBeforeSend(req)
+		return
+	}
+
+	results, err := r.s.webhook.beforeSend(timeout, messages)
+	if err != nil {
+		r.Warn("发送前审核请求失败！", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType), zap.Int("messageCount", len(messages)), zap.Bool("failOpen", r.opts.Webhook.BeforeSendFailOpen))
+		r.failBeforeSend(req)
+		return
+	}
+
+	resultMap := make(map[int64]beforeSendResult, len(results))
+	for _, result := range results {
+		resultMap[result.MessageId] = result
+	}
+	for _, i := range indexes {
+		msg := req.messages[i]
+		result, ok := resultMap[msg.MessageId]
+		if !ok {
+			continue
+		}
+		if reasonCode, reject := result.reject(); reject {
+			r.MessageTrace("发送前审核拒绝", msg.SendPacket.ClientMsgNo, "processBeforeSend", zap.String("reasonCode", reasonCode.String()))
+			req.messages[i].ReasonCode = reasonCode
+			continue
+		}
+		if len(result.Payload) > 0 {
+			// SendPacket与频道队列共用，替换内容时需要拷贝一份，由频道在处理审核结果时替换
+			sendPacket := *msg.SendPacket
+			sendPacket.Payload = result.Payload
+			req.messages[i].SendPacket = &sendPacket
+		}
+	}
+}
+
+// failBeforeSend 审核失败（请求失败、超时或队列已满），按配置放行或以ReasonSystemError拒绝需要审核的消息
+func (r *channelReactor) failBeforeSend(req *permissionReq) {
+	if r.opts.Webhook.BeforeSendFailOpen {
+		return
+	}
+	for i, msg := range req.messages {
+		if needBeforeSendCheck(msg) {
+			req.messages[i].ReasonCode = wkproto.ReasonSystemError
+		}
+	}
+}
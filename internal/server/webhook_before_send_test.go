@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBeforeSendTestServer(t *testing.T, addr string, timeout time.Duration, failOpen bool) *Server {
+	configureTestLog(t)
+	return NewTestServer(t, WithWebhookHTTPAddr(addr), WithWebhookBeforeSend(true, timeout, failOpen))
+}
+
+func newBeforeSendTestReq(s *Server) *permissionReq {
+	return &permissionReq{
+		ch: newChannel(s.channelReactor.subs[0], "g1", wkproto.ChannelTypeGroup),
+		messages: []ReactorChannelMessage{
+			{MessageId: 1, FromUid: "u1", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{ClientMsgNo: "1", ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup, Payload: []byte("hello")}},
+			{MessageId: 2, FromUid: "u2", ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{ClientMsgNo: "2", ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup, Payload: []byte("bad word")}},
+			{MessageId: 3, FromUid: "system", IsSystem: true, ReasonCode: wkproto.ReasonSuccess, SendPacket: &wkproto.SendPacket{ClientMsgNo: "3", ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup, Payload: []byte("system")}},
+		},
+	}
+}
+
+func TestBeforeSendReject(t *testing.T) {
+	var received []beforeSendMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, EventMsgBeforeSend, r.URL.Query().Get("event"))
+		var req struct {
+			Messages []beforeSendMessage `json:"messages"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received = req.Messages
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []beforeSendResult{
+				{MessageId: 2, ReasonCode: uint32(wkproto.ReasonNotAllowSend)},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	s := newBeforeSendTestServer(t, ts.URL, time.Second, true)
+	req := newBeforeSendTestReq(s)
+	s.channelReactor.processBeforeSend(req)
+
+	// 系统消息不需要审核
+	assert.Equal(t, 2, len(received))
+	assert.Equal(t, wkproto.ReasonSuccess, req.messages[0].ReasonCode)
+	assert.Equal(t, wkproto.ReasonNotAllowSend, req.messages[1].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSuccess, req.messages[2].ReasonCode)
+}
+
+func TestBeforeSendReplacePayload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []beforeSendResult{
+				{MessageId: 2, Payload: []byte("*** word")},
+			},
+		})
+	}))
+	defer ts.Close()
+
+	s := newBeforeSendTestServer(t, ts.URL, time.Second, true)
+	req := newBeforeSendTestReq(s)
+	originPacket := req.messages[1].SendPacket
+	s.channelReactor.processBeforeSend(req)
+
+	assert.Equal(t, wkproto.ReasonSuccess, req.messages[1].ReasonCode)
+	assert.Equal(t, []byte("*** word"), req.messages[1].SendPacket.Payload)
+	// 原SendPacket与频道队列共用，不能被修改
+	assert.Equal(t, []byte("bad word"), originPacket.Payload)
+	assert.Equal(t, []byte("hello"), req.messages[0].SendPacket.Payload)
+}
+
+func TestBeforeSendFailOpen(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	s := newBeforeSendTestServer(t, ts.URL, time.Second, true)
+	req := newBeforeSendTestReq(s)
+	s.channelReactor.processBeforeSend(req)
+
+	for _, msg := range req.messages {
+		assert.Equal(t, wkproto.ReasonSuccess, msg.ReasonCode)
+	}
+}
+
+func TestBeforeSendFailClosed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 300) // 超过审核超时时间
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": []beforeSendResult{}})
+	}))
+	defer ts.Close()
+
+	s := newBeforeSendTestServer(t, ts.URL, time.Millisecond*100, false)
+	req := newBeforeSendTestReq(s)
+	s.channelReactor.processBeforeSend(req)
+
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[0].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[1].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSuccess, req.messages[2].ReasonCode) // 系统消息不受影响
+}
+
+func TestBeforeSendTimeoutClamp(t *testing.T) {
+	s := newBeforeSendTestServer(t, "http://127.0.0.1:0", time.Second, true)
+	assert.Equal(t, time.Second, s.channelReactor.beforeSendTimeout())
+
+	// 超时时间不能超过频道重新发起权限校验时间的一半
+	s = newBeforeSendTestServer(t, "http://127.0.0.1:0", time.Minute, true)
+	window := time.Duration(s.opts.Reactor.Channel.ProcessIntervalTick) * s.opts.Reactor.Channel.TickInterval
+	assert.Equal(t, window/2, s.channelReactor.beforeSendTimeout())
+}
+
+func TestBeforeSendQueueFull(t *testing.T) {
+	s := newBeforeSendTestServer(t, "http://127.0.0.1:0", time.Second, false)
+	s.channelReactor.processBeforeSendC = make(chan *permissionReq)
+	req := newBeforeSendTestReq(s)
+	s.channelReactor.addBeforeSendReq(req)
+
+	// 队列满了直接按审核失败返回权限校验结果
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[0].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[1].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSuccess, req.messages[2].ReasonCode)
+	select {
+	case step := <-s.channelReactor.reactorSub(req.ch.key).stepChannelC:
+		assert.Equal(t, ChannelActionPermissionCheckResp, step.action.ActionType)
+		assert.Equal(t, req.messages, step.action.Messages)
+	default:
+		t.Fatal("permission check resp not stepped")
+	}
+}
+
+func TestBeforeSendQueueTimeout(t *testing.T) {
+	requested := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": []beforeSendResult{}})
+	}))
+	defer ts.Close()
+
+	s := newBeforeSendTestServer(t, ts.URL, time.Second, false)
+	req := newBeforeSendTestReq(s)
+	req.beforeSendDeadline = time.Now().Add(-time.Millisecond)
+	s.channelReactor.processBeforeSend(req)
+
+	// 排队已经超时的不再请求审核服务
+	assert.False(t, requested)
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[0].ReasonCode)
+	assert.Equal(t, wkproto.ReasonSystemError, req.messages[1].ReasonCode)
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	webhookSubscriptionReloadInterval = time.Minute // 定时重新加载订阅，防止漏掉其他节点的变更通知
+	webhookChannelCacheExpire         = time.Minute // 频道webhook地址的缓存时间
+	webhookChannelCacheMaxCount       = 10000       // 频道webhook地址最多缓存数量，超过将清空重新缓存
+)
+
+// webhookTarget 事件的推送目标
+type webhookTarget struct {
+	subscriptionId uint64 // 订阅id，全局配置和频道webhook为0
+	httpAddr       string
+	grpcAddr       string // 有值则使用grpc推送
+	secret         string // 签名密钥
+	isChannel      bool   // 是否是频道设置的webhook
+	isFile         bool   // 是否是本地文件输出端
+}
+
+func (t webhookTarget) addr() string {
+	if t.isFile {
+		return EventSinkFile
+	}
+	if t.grpcAddr != "" {
+		return t.grpcAddr
+	}
+	return t.httpAddr
+}
+
+func (t webhookTarget) key() string {
+	return fmt.Sprintf("%d-%s", t.subscriptionId, t.addr())
+}
+
+type channelWebhook struct {
+	url      string
+	expireAt time.Time
+}
+
+// on 是否有任何webhook推送目标
+func (w *webhook) on() bool {
+	return w.s.opts.EventSinkOn() || w.s.opts.Webhook.ChannelOn || w.hasSubscriptions()
+}
+
+func (w *webhook) hasSubscriptions() bool {
+	w.subscriptionLock.RLock()
+	defer w.subscriptionLock.RUnlock()
+	return len(w.subscriptions) > 0
+}
+
+// globalTarget 配置文件中的全局webhook
+func (w *webhook) globalTarget() (webhookTarget, bool) {
+	if !w.s.opts.WebhookOn() {
+		return webhookTarget{}, false
+	}
+	return webhookTarget{
+		httpAddr: w.s.opts.Webhook.HTTPAddr,
+		grpcAddr: w.s.opts.Webhook.GRPCAddr,
+		secret:   w.s.opts.Webhook.Secret,
+	}, true
+}
+
+// globalTargets 配置文件中事件对应的输出端
+func (w *webhook) globalTargets(event string) []webhookTarget {
+	sinks := w.s.opts.EventSinks(event)
+	targets := make([]webhookTarget, 0, len(sinks))
+	for _, sink := range sinks {
+		switch sink {
+		case EventSinkHTTP:
+			targets = append(targets, webhookTarget{
+				httpAddr: w.s.opts.Webhook.HTTPAddr,
+				secret:   w.s.opts.Webhook.Secret,
+			})
+		case EventSinkGRPC:
+			targets = append(targets, webhookTarget{
+				grpcAddr: w.s.opts.Webhook.GRPCAddr,
+				secret:   w.s.opts.Webhook.Secret,
+			})
+		case EventSinkFile:
+			if w.fileSink != nil {
+				targets = append(targets, webhookTarget{isFile: true})
+			}
+		}
+	}
+	return targets
+}
+
+// extraTargets 除全局webhook外匹配事件的推送目标（订阅和频道webhook）
+// channelId为空表示事件不属于任何频道，设置了频道过滤的订阅不会收到此类事件
+func (w *webhook) extraTargets(event string, channelId string, channelType uint8) []webhookTarget {
+	var targets []webhookTarget
+	w.subscriptionLock.RLock()
+	for _, subscription := range w.subscriptions {
+		if !webhookSubscriptionMatch(subscription, event, channelId, channelType) {
+			continue
+		}
+		targets = append(targets, webhookTarget{
+			subscriptionId: subscription.Id,
+			httpAddr:       subscription.URL,
+			grpcAddr:       subscription.GRPCAddr,
+			secret:         subscription.Secret,
+		})
+	}
+	w.subscriptionLock.RUnlock()
+
+	if channelId != "" && w.s.opts.Webhook.ChannelOn {
+		if url := w.channelWebhookURL(channelId, channelType); url != "" {
+			targets = append(targets, webhookTarget{
+				httpAddr:  url,
+				secret:    w.s.opts.Webhook.Secret,
+				isChannel: true,
+			})
+		}
+	}
+	return targets
+}
+
+// targets 匹配事件的所有推送目标
+func (w *webhook) targets(event string, channelId string, channelType uint8) []webhookTarget {
+	return append(w.globalTargets(event), w.extraTargets(event, channelId, channelType)...)
+}
+
+func webhookSubscriptionMatch(subscription wkdb.WebhookSubscription, event string, channelId string, channelType uint8) bool {
+	if len(subscription.Events) > 0 && !wkutil.ArrayContains(subscription.Events, event) {
+		return false
+	}
+	if len(subscription.ChannelTypes) == 0 && len(subscription.ChannelIds) == 0 {
+		return true
+	}
+	if channelId == "" {
+		return false
+	}
+	if len(subscription.ChannelTypes) > 0 {
+		matched := false
+		for _, t := range subscription.ChannelTypes {
+			if t == channelType {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(subscription.ChannelIds) > 0 && !wkutil.ArrayContains(subscription.ChannelIds, channelId) {
+		return false
+	}
+	return true
+}
+
+// channelWebhookURL 获取频道设置的webhook地址（频道信息中的webhook字段）
+func (w *webhook) channelWebhookURL(channelId string, channelType uint8) string {
+	key := wkutil.ChannelToKey(channelId, channelType)
+	w.channelWebhookLock.RLock()
+	cache, ok := w.channelWebhooks[key]
+	w.channelWebhookLock.RUnlock()
+	if ok && time.Now().Before(cache.expireAt) {
+		return cache.url
+	}
+
+	channelInfo, err := w.s.store.GetChannel(channelId, channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		w.Warn("获取频道信息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		return cache.url
+	}
+	url := strings.TrimSpace(channelInfo.Webhook)
+
+	w.channelWebhookLock.Lock()
+	if len(w.channelWebhooks) >= webhookChannelCacheMaxCount {
+		w.channelWebhooks = make(map[string]channelWebhook)
+	}
+	w.channelWebhooks[key] = channelWebhook{url: url, expireAt: time.Now().Add(webhookChannelCacheExpire)}
+	w.channelWebhookLock.Unlock()
+	return url
+}
+
+// removeChannelWebhookCache 频道信息变更后移除缓存的webhook地址
+func (w *webhook) removeChannelWebhookCache(channelId string, channelType uint8) {
+	w.channelWebhookLock.Lock()
+	delete(w.channelWebhooks, wkutil.ChannelToKey(channelId, channelType))
+	w.channelWebhookLock.Unlock()
+}
+
+// getGRPCPool 获取grpc地址对应的连接池，不存在则创建
+func (w *webhook) getGRPCPool(addr string) (*grpcpool.Pool, error) {
+	w.grpcPoolLock.Lock()
+	defer w.grpcPoolLock.Unlock()
+	pool := w.grpcPools[addr]
+	if pool != nil {
+		return pool, nil
+	}
+	pool, err := grpcpool.New(func() (*grpc.ClientConn, error) {
+		return grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
+			Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
+		}))
+	}, 2, 20, time.Minute*5) // 初始化2个连接 最多20个连接
+	if err != nil {
+		return nil, err
+	}
+	w.grpcPools[addr] = pool
+	return pool, nil
+}
+
+// loopLoadSubscriptions 启动时加载webhook订阅，之后定时重新加载
+func (w *webhook) loopLoadSubscriptions() {
+	loaded := false
+	for {
+		interval := webhookSubscriptionReloadInterval
+		if err := w.loadSubscriptions(); err != nil {
+			w.Warn("加载webhook订阅失败！", zap.Error(err))
+			if !loaded {
+				interval = time.Second * 2 // 还没加载成功过，尽快重试
+			}
+		} else {
+			loaded = true
+		}
+		select {
+		case <-time.After(interval):
+		case <-w.stoped:
+			return
+		}
+	}
+}
+
+// loadSubscriptions 从slot 0的领导节点加载webhook订阅
+func (w *webhook) loadSubscriptions() error {
+	subscriptions, err := w.getOrRequestSubscriptions()
+	if err != nil {
+		return err
+	}
+	w.setSubscriptions(subscriptions)
+	return nil
+}
+
+func (w *webhook) setSubscriptions(subscriptions []wkdb.WebhookSubscription) {
+	addrs := make(map[string]bool)
+	for _, subscription := range subscriptions {
+		if subscription.GRPCAddr != "" {
+			addrs[subscription.GRPCAddr] = true
+		}
+	}
+	if w.s.opts.WebhookGRPCOn() {
+		addrs[w.s.opts.Webhook.GRPCAddr] = true
+	}
+
+	w.subscriptionLock.Lock()
+	w.subscriptions = subscriptions
+	w.subscriptionLock.Unlock()
+
+	// 关闭不再使用的grpc连接池
+	w.grpcPoolLock.Lock()
+	for addr, pool := range w.grpcPools {
+		if !addrs[addr] {
+			pool.Close()
+			delete(w.grpcPools, addr)
+		}
+	}
+	w.grpcPoolLock.Unlock()
+}
+
+func (w *webhook) getOrRequestSubscriptions() ([]wkdb.WebhookSubscription, error) {
+	var slotId uint32 = 0 // webhook订阅默认存储在slot 0上
+	nodeInfo, err := w.s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo.Id == w.s.opts.Cluster.NodeId {
+		return w.s.store.GetWebhookSubscriptions()
+	}
+	return w.requestSubscriptions(nodeInfo.Id)
+}
+
+// requestSubscriptions 通过集群内部接口从slot 0的领导节点获取webhook订阅（包含签名密钥）
+func (w *webhook) requestSubscriptions(nodeId uint64) ([]wkdb.WebhookSubscription, error) {
+	timeoutCtx, cancel := context.WithTimeout(w.s.ctx, time.Second*5)
+	defer cancel()
+	resp, err := w.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/getWebhookSubscriptions", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestSubscriptions failed, status: %d body: %s", resp.Status, string(resp.Body))
+	}
+	var subscriptions []wkdb.WebhookSubscription
+	if err = json.Unmarshal(resp.Body, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
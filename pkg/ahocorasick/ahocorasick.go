@@ -0,0 +1,207 @@
+// Package ahocorasick Aho-Corasick多模式匹配自动机，用于敏感词过滤
+// 按rune匹配，匹配时不区分大小写
+package ahocorasick
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match 匹配结果
+type Match struct {
+	Start int    // 匹配开始的字节位置（包含）
+	End   int    // 匹配结束的字节位置（不包含）
+	Word  string // 匹配到的词
+}
+
+type node struct {
+	children map[rune]int32
+	fail     int32 // 失败指针
+	output   int32 // 后缀链上最近的词结尾节点，-1表示没有
+	word     int32 // 以此节点结尾的词，-1表示不是词结尾
+	depth    int32 // 节点深度（rune数量）
+}
+
+// Automaton 构建完成后只读，可以并发使用
+type Automaton struct {
+	nodes []node
+	words []string
+}
+
+// New 根据词列表构建自动机，空白的词和重复的词会被忽略
+func New(words []string) *Automaton {
+	a := &Automaton{
+		nodes: []node{newNode(0)},
+	}
+	for _, word := range words {
+		a.add(word)
+	}
+	a.build()
+	return a
+}
+
+func newNode(depth int32) node {
+	return node{
+		output: -1,
+		word:   -1,
+		depth:  depth,
+	}
+}
+
+func (a *Automaton) add(word string) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return
+	}
+	var cur int32
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		next, ok := a.nodes[cur].children[r]
+		if !ok {
+			next = int32(len(a.nodes))
+			a.nodes = append(a.nodes, newNode(a.nodes[cur].depth+1))
+			if a.nodes[cur].children == nil {
+				a.nodes[cur].children = make(map[rune]int32)
+			}
+			a.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	if a.nodes[cur].word >= 0 {
+		return
+	}
+	a.nodes[cur].word = int32(len(a.words))
+	a.words = append(a.words, word)
+}
+
+// build 广度优先计算失败指针和输出链
+func (a *Automaton) build() {
+	queue := make([]int32, 0, len(a.nodes))
+	for _, child := range a.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range a.nodes[cur].children {
+			fail := a.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := a.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			if next, ok := a.nodes[fail].children[r]; ok {
+				a.nodes[child].fail = next
+			}
+			failNode := a.nodes[a.nodes[child].fail]
+			if failNode.word >= 0 {
+				a.nodes[child].output = a.nodes[child].fail
+			} else {
+				a.nodes[child].output = failNode.output
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 词的数量
+func (a *Automaton) Len() int {
+	return len(a.words)
+}
+
+func (a *Automaton) next(state int32, r rune) int32 {
+	for {
+		if next, ok := a.nodes[state].children[r]; ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = a.nodes[state].fail
+	}
+}
+
+// scan 遍历文本，对匹配到的词回调fn（start和end为字节位置），fn返回false停止遍历
+// all为false时每个结束位置只回调最长的词
+func (a *Automaton) scan(text string, all bool, fn func(start, end int, word string) bool) {
+	if len(a.words) == 0 {
+		return
+	}
+	offsets := make([]int, 0, len(text)) // 每个rune的起始字节位置
+	var state int32
+	for i, r := range text {
+		offsets = append(offsets, i)
+		state = a.next(state, unicode.ToLower(r))
+		_, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		n := state
+		if a.nodes[n].word < 0 {
+			n = a.nodes[n].output
+		}
+		for n > 0 {
+			nd := a.nodes[n]
+			if !fn(offsets[len(offsets)-int(nd.depth)], end, a.words[nd.word]) {
+				return
+			}
+			if !all {
+				break
+			}
+			n = nd.output
+		}
+	}
+}
+
+// FindAll 查找文本中所有匹配的词（包括重叠的匹配），按结束位置排序
+func (a *Automaton) FindAll(text string) []Match {
+	var matches []Match
+	a.scan(text, true, func(start, end int, word string) bool {
+		matches = append(matches, Match{Start: start, End: end, Word: word})
+		return true
+	})
+	return matches
+}
+
+// Contains 文本中是否包含任意一个词
+func (a *Automaton) Contains(text string) bool {
+	found := false
+	a.scan(text, false, func(start, end int, word string) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// Replace 将文本中匹配的词的每个字符替换为mask，返回替换后的文本和是否有匹配
+func (a *Automaton) Replace(text string, mask rune) (string, bool) {
+	type span struct{ start, end int }
+	var spans []span
+	a.scan(text, false, func(start, end int, word string) bool {
+		// 合并重叠的区间
+		for len(spans) > 0 && start <= spans[len(spans)-1].end {
+			last := spans[len(spans)-1]
+			if last.start < start {
+				start = last.start
+			}
+			spans = spans[:len(spans)-1]
+		}
+		spans = append(spans, span{start: start, end: end})
+		return true
+	})
+	if len(spans) == 0 {
+		return text, false
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	prev := 0
+	for _, sp := range spans {
+		b.WriteString(text[prev:sp.start])
+		for range text[sp.start:sp.end] {
+			b.WriteRune(mask)
+		}
+		prev = sp.end
+	}
+	b.WriteString(text[prev:])
+	return b.String(), true
+}
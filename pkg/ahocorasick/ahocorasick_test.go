@@ -0,0 +1,42 @@
+package ahocorasick
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAll(t *testing.T) {
+	a := New([]string{"he", "she", "his", "hers", " ", "she"})
+	assert.Equal(t, 4, a.Len())
+
+	matches := a.FindAll("ushers")
+	assert.Equal(t, []Match{
+		{Start: 1, End: 4, Word: "she"},
+		{Start: 2, End: 4, Word: "he"},
+		{Start: 2, End: 6, Word: "hers"},
+	}, matches)
+
+	assert.Empty(t, a.FindAll("abc"))
+}
+
+func TestContains(t *testing.T) {
+	a := New([]string{"敏感词", "Bad"})
+	assert.True(t, a.Contains("这是一个敏感词测试"))
+	assert.True(t, a.Contains("a BAD word"))
+	assert.False(t, a.Contains("正常内容"))
+
+	assert.False(t, New(nil).Contains("anything"))
+}
+
+func TestReplace(t *testing.T) {
+	a := New([]string{"敏感", "敏感词", "词语", "bad"})
+
+	text, ok := a.Replace("这是敏感词语，BAD!", '*')
+	assert.True(t, ok)
+	assert.Equal(t, "这是****，***!", text)
+
+	text, ok = a.Replace("正常内容", '*')
+	assert.False(t, ok)
+	assert.Equal(t, "正常内容", text)
+}
@@ -496,6 +496,19 @@ func (s *Server) GetLogsInReverseOrder(startLogIndex uint64, endLogIndex uint64,
 	return s.storage.GetLogsInReverseOrder(startLogIndex, endLogIndex, limit)
 }
 
+// Checkpoint 将配置日志和配置文件备份到dir目录
+func (s *Server) Checkpoint(dir string) error {
+	if err := s.storage.Checkpoint(path.Join(dir, "cfglogdb")); err != nil {
+		return err
+	}
+	if wkutil.FileExists(s.opts.ConfigPath) {
+		if _, err := wkutil.CopyFile(path.Join(dir, path.Base(s.opts.ConfigPath)), s.opts.ConfigPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Server) AppliedLogIndex() (uint64, error) {
 	return s.storage.AppliedIndex()
 }
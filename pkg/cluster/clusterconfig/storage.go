@@ -49,6 +49,11 @@ func (p *PebbleShardLogStorage) Close() error {
 	return nil
 }
 
+// Checkpoint 将日志数据库的快照写入到dir目录
+func (p *PebbleShardLogStorage) Checkpoint(dir string) error {
+	return p.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
 // AppendLog 追加日志
 func (p *PebbleShardLogStorage) AppendLog(logs []replica.Log) error {
 
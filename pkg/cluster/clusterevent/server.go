@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -235,6 +236,19 @@ func (s *Server) saveLocalConfig(cfg *pb.Config) error {
 	return nil
 }
 
+// Checkpoint 将集群配置备份到dir目录
+func (s *Server) Checkpoint(dir string) error {
+	if err := s.cfgServer.Checkpoint(dir); err != nil {
+		return err
+	}
+	if wkutil.FileExists(s.localCfgPath) {
+		if _, err := wkutil.CopyFile(path.Join(dir, path.Base(s.localCfgPath)), s.localCfgPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Server) AppliedLogIndex() (uint64, error) {
 
 	return s.cfgServer.AppliedLogIndex()
@@ -32,6 +32,9 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 	payloadStr := strings.TrimSpace(c.Query("payload"))                   // base64编码的消息内容
 	messageId := wkutil.ParseInt64(c.Query("message_id"))
 	clientMsgNo := strings.TrimSpace(c.Query("client_msg_no"))
+	keyword := strings.TrimSpace(c.Query("keyword"))      // 搜索关键字（走全文索引）
+	startTime := wkutil.ParseInt64(c.Query("start_time")) // 开始时间（单位秒）
+	endTime := wkutil.ParseInt64(c.Query("end_time"))     // 结束时间（单位秒，不包含）
 
 	// 解密payload
 	var payload []byte
@@ -75,6 +78,9 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 			Pre:              pre == 1,
 			Payload:          payload,
 			ClientMsgNo:      clientMsgNo,
+			Keyword:          keyword,
+			StartTime:        startTime,
+			EndTime:          endTime,
 		})
 		if err != nil {
 			s.Error("查询消息失败！", zap.Error(err))
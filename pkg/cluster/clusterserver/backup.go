@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"errors"
+	"path"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// CheckpointResult 分布式数据的备份结果
+type CheckpointResult struct {
+	SlotAppliedIndexes map[uint32]uint64 // 本节点上槽的已应用日志下标
+	ConfigAppliedIndex uint64            // 集群配置的已应用日志下标
+}
+
+// Checkpoint 将槽日志和集群配置备份到dir目录，目录结构与分布式数据目录一致（dir/logdb, dir/config）
+// 已应用的日志下标从快照中读取，恢复后从这个下标之后继续应用
+func (s *Server) Checkpoint(dir string) (*CheckpointResult, error) {
+	if s.slotStorage == nil {
+		return nil, errors.New("slot storage not support checkpoint")
+	}
+	logDir := path.Join(dir, "logdb")
+	cfgDir := path.Join(dir, "config")
+
+	if err := s.slotStorage.Checkpoint(logDir); err != nil {
+		return nil, err
+	}
+	if err := s.clusterEventServer.Checkpoint(cfgDir); err != nil {
+		return nil, err
+	}
+
+	result := &CheckpointResult{
+		SlotAppliedIndexes: make(map[uint32]uint64),
+	}
+
+	// 槽的已应用下标
+	slotStorage := NewPebbleShardLogStorage(logDir, s.slotStorage.shardNum)
+	if err := slotStorage.Open(); err != nil {
+		return nil, err
+	}
+	defer slotStorage.Close()
+	for _, slot := range s.clusterEventServer.Slots() {
+		if !wkutil.ArrayContainsUint64(slot.Replicas, s.opts.NodeId) {
+			continue
+		}
+		appliedIndex, err := slotStorage.AppliedIndex(SlotIdToKey(slot.Id))
+		if err != nil {
+			return nil, err
+		}
+		result.SlotAppliedIndexes[slot.Id] = appliedIndex
+	}
+
+	// 集群配置的已应用下标
+	cfgStorage := clusterconfig.NewPebbleShardLogStorage(path.Join(cfgDir, "cfglogdb"))
+	if err := cfgStorage.Open(); err != nil {
+		return nil, err
+	}
+	defer cfgStorage.Close()
+	appliedIndex, err := cfgStorage.AppliedIndex()
+	if err != nil {
+		return nil, err
+	}
+	result.ConfigAppliedIndex = appliedIndex
+
+	return result, nil
+}
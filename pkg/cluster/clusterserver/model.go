@@ -1339,6 +1339,7 @@ type conversationResp struct {
 	UnreadCount       uint32                `json:"unread_count"`        // 未读消息数量（这个可以用户自己设置）
 	LastMsgSeq        uint64                `json:"last_msg_seq"`        // 最新消息序号
 	ReadedToMsgSeq    uint64                `json:"readed_to_msg_seq"`   // 已经读至的消息序号
+	ClearedToMsgSeq   uint64                `json:"cleared_to_msg_seq"`  // 清空聊天记录至的消息序号
 	CreatedAt         int64                 `json:"created_at"`          // 创建时间
 	UpdatedAt         int64                 `json:"updated_at"`          // 更新时间
 	CreatedAtFormat   string                `json:"created_at_format"`   // 创建时间格式化
@@ -1372,6 +1373,7 @@ func newConversationResp(c wkdb.Conversation) *conversationResp {
 		ChannelTypeFormat: formatChannelType(c.ChannelType),
 		UnreadCount:       c.UnreadCount,
 		ReadedToMsgSeq:    c.ReadToMsgSeq,
+		ClearedToMsgSeq:   c.ClearedToMsgSeq,
 		CreatedAt:         createdAt,
 		UpdatedAt:         updatedAt,
 		CreatedAtFormat:   createdAtFormat,
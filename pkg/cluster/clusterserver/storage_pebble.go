@@ -103,6 +103,16 @@ func (p *PebbleShardLogStorage) Close() error {
 	return nil
 }
 
+// Checkpoint 将所有分片的快照写入到dir目录（dir/shardxxx）
+func (p *PebbleShardLogStorage) Checkpoint(dir string) error {
+	for i, db := range p.dbs {
+		if err := db.Checkpoint(fmt.Sprintf("%s/shard%03d", dir, i), pebble.WithFlushedWAL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *PebbleShardLogStorage) shardDB(v string) *pebble.DB {
 	shardId := p.shardId(v)
 	return p.dbs[shardId]
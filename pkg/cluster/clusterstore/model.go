@@ -82,6 +82,32 @@ const (
 	CMDAddStreamMeta
 	// 添加流元数据
 	CMDAddStreams
+	// 更新订阅者
+	CMDUpdateSubscribers
+	// 置顶消息
+	CMDAddPinnedMessage
+	// 取消置顶消息
+	CMDRemovePinnedMessage
+	// 添加定时消息
+	CMDAddScheduledMessage
+	// 移除定时消息
+	CMDRemoveScheduledMessage
+	// 修改会话属性
+	CMDUpdateConversationAttrs
+	// 更新用户最后上线/离线时间
+	CMDUpdateUserLastSeen
+	// 添加或更新webhook订阅
+	CMDAddOrUpdateWebhookSubscription
+	// 移除webhook订阅
+	CMDRemoveWebhookSubscription
+	// 添加敏感词
+	CMDAddSensitiveWords
+	// 移除敏感词
+	CMDRemoveSensitiveWords
+	// 添加或取消消息回应
+	CMDSetReaction
+	// 添加已读回执
+	CMDAddReceipt
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -158,6 +184,32 @@ func (c CMDType) String() string {
 		return "CMDAddStreamMeta"
 	case CMDAddStreams:
 		return "CMDAddStreams"
+	case CMDUpdateSubscribers:
+		return "CMDUpdateSubscribers"
+	case CMDAddPinnedMessage:
+		return "CMDAddPinnedMessage"
+	case CMDRemovePinnedMessage:
+		return "CMDRemovePinnedMessage"
+	case CMDAddScheduledMessage:
+		return "CMDAddScheduledMessage"
+	case CMDRemoveScheduledMessage:
+		return "CMDRemoveScheduledMessage"
+	case CMDUpdateConversationAttrs:
+		return "CMDUpdateConversationAttrs"
+	case CMDUpdateUserLastSeen:
+		return "CMDUpdateUserLastSeen"
+	case CMDAddOrUpdateWebhookSubscription:
+		return "CMDAddOrUpdateWebhookSubscription"
+	case CMDRemoveWebhookSubscription:
+		return "CMDRemoveWebhookSubscription"
+	case CMDAddSensitiveWords:
+		return "CMDAddSensitiveWords"
+	case CMDRemoveSensitiveWords:
+		return "CMDRemoveSensitiveWords"
+	case CMDSetReaction:
+		return "CMDSetReaction"
+	case CMDAddReceipt:
+		return "CMDAddReceipt"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -267,6 +319,96 @@ func (c *CMD) CMDContent() (string, error) {
 			"channelType": channelType,
 			"uids":        uids,
 		}), nil
+	case CMDUpdateSubscribers:
+		channelId, channelType, members, err := c.DecodeMembers()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"members":     members,
+		}), nil
+	case CMDAddPinnedMessage:
+		pin, err := c.DecodeCMDAddPinnedMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(pin), nil
+	case CMDRemovePinnedMessage:
+		channelId, channelType, messageSeq, err := c.DecodeCMDRemovePinnedMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"messageSeq":  messageSeq,
+		}), nil
+	case CMDAddScheduledMessage:
+		m, err := c.DecodeCMDAddScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(m), nil
+	case CMDRemoveScheduledMessage:
+		channelId, channelType, id, err := c.DecodeCMDRemoveScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"id":          id,
+		}), nil
+	case CMDUpdateConversationAttrs:
+		uid, id, attrs, err := c.DecodeCMDUpdateConversationAttrs()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":   uid,
+			"id":    id,
+			"attrs": attrs,
+		}), nil
+	case CMDUpdateUserLastSeen:
+		lastSeen, err := c.DecodeCMDUpdateUserLastSeen()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(lastSeen), nil
+	case CMDAddOrUpdateWebhookSubscription:
+		subscription, err := c.DecodeCMDAddOrUpdateWebhookSubscription()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(subscription), nil
+	case CMDRemoveWebhookSubscription:
+		id, err := c.DecodeCMDRemoveWebhookSubscription()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"id": id,
+		}), nil
+	case CMDAddSensitiveWords, CMDRemoveSensitiveWords:
+		words, err := c.DecodeCMDSensitiveWords()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(words), nil
+	case CMDSetReaction:
+		reaction, err := c.DecodeCMDSetReaction()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(reaction), nil
+	case CMDAddReceipt:
+		receipt, err := c.DecodeCMDAddReceipt()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(receipt), nil
 	case CMDRemoveSubscribers:
 		channelId, channelType, uids, err := c.DecodeChannelUids()
 		if err != nil {
@@ -777,6 +919,10 @@ func EncodeChannelInfo(c wkdb.ChannelInfo, version CmdVersion) ([]byte, error) {
 	if version > 0 {
 		enc.WriteString(c.Webhook)
 	}
+	if version > 2 {
+		enc.WriteUint8(wkutil.BoolToUint8(c.Mute))
+		enc.WriteInt64(c.MuteUntil)
+	}
 	return enc.Bytes(), nil
 }
 
@@ -832,6 +978,17 @@ func (c *CMD) DecodeChannelInfo() (wkdb.ChannelInfo, error) {
 		}
 	}
 
+	if c.version > 2 {
+		var mute uint8
+		if mute, err = dec.Uint8(); err != nil {
+			return channelInfo, err
+		}
+		channelInfo.Mute = wkutil.Uint8ToBool(mute)
+		if channelInfo.MuteUntil, err = dec.Int64(); err != nil {
+			return channelInfo, err
+		}
+	}
+
 	return channelInfo, err
 }
 
@@ -1257,4 +1414,343 @@ func (c *CMD) DecodeCMDAddStreams() ([]*wkdb.Stream, error) {
 	return streams, nil
 }
 
+func EncodeCMDAddPinnedMessage(pin wkdb.PinnedMessage) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(pin.ChannelId)
+	encoder.WriteUint8(pin.ChannelType)
+	encoder.WriteUint64(pin.MessageSeq)
+	encoder.WriteInt64(pin.MessageId)
+	encoder.WriteString(pin.PinnedBy)
+	encoder.WriteInt64(pin.PinnedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddPinnedMessage() (pin wkdb.PinnedMessage, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if pin.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if pin.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if pin.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if pin.MessageId, err = decoder.Int64(); err != nil {
+		return
+	}
+	if pin.PinnedBy, err = decoder.String(); err != nil {
+		return
+	}
+	pin.PinnedAt, err = decoder.Int64()
+	return
+}
+
+func EncodeCMDRemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint64(messageSeq)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemovePinnedMessage() (channelId string, channelType uint8, messageSeq uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	messageSeq, err = decoder.Uint64()
+	return
+}
+
+func EncodeCMDAddScheduledMessage(m wkdb.ScheduledMessage) ([]byte, error) {
+	data, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// Marshal返回的是编码器缓冲区，CMD编码时会复用，需要拷贝一份
+	return append([]byte(nil), data...), nil
+}
+
+func (c *CMD) DecodeCMDAddScheduledMessage() (m wkdb.ScheduledMessage, err error) {
+	err = m.Unmarshal(c.Data)
+	return
+}
+
+func EncodeCMDRemoveScheduledMessage(channelId string, channelType uint8, id uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint64(id)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveScheduledMessage() (channelId string, channelType uint8, id uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	id, err = decoder.Uint64()
+	return
+}
+
+// 会话属性变更中包含的属性
+const (
+	conversationAttrPinned uint8 = 1 << iota
+	conversationAttrMutedUntil
+	conversationAttrFolder
+	conversationAttrDraft
+)
+
+func EncodeCMDUpdateConversationAttrs(uid string, id uint64, attrs wkdb.ConversationAttrs) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint64(id)
+	encoder.WriteString(attrs.ChannelId)
+	encoder.WriteUint8(attrs.ChannelType)
+
+	var flag uint8
+	if attrs.Pinned != nil {
+		flag |= conversationAttrPinned
+	}
+	if attrs.MutedUntil != nil {
+		flag |= conversationAttrMutedUntil
+	}
+	if attrs.Folder != nil {
+		flag |= conversationAttrFolder
+	}
+	if attrs.Draft != nil {
+		flag |= conversationAttrDraft
+	}
+	encoder.WriteUint8(flag)
+
+	if attrs.Pinned != nil {
+		encoder.WriteUint8(wkutil.BoolToUint8(*attrs.Pinned))
+	}
+	if attrs.MutedUntil != nil {
+		encoder.WriteInt64(*attrs.MutedUntil)
+	}
+	if attrs.Folder != nil {
+		encoder.WriteString(*attrs.Folder)
+	}
+	if attrs.Draft != nil {
+		encoder.WriteString(*attrs.Draft)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateConversationAttrs() (uid string, id uint64, attrs wkdb.ConversationAttrs, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	if id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if attrs.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if attrs.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var flag uint8
+	if flag, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if flag&conversationAttrPinned != 0 {
+		var pinned uint8
+		if pinned, err = decoder.Uint8(); err != nil {
+			return
+		}
+		isPinned := wkutil.Uint8ToBool(pinned)
+		attrs.Pinned = &isPinned
+	}
+	if flag&conversationAttrMutedUntil != 0 {
+		var mutedUntil int64
+		if mutedUntil, err = decoder.Int64(); err != nil {
+			return
+		}
+		attrs.MutedUntil = &mutedUntil
+	}
+	if flag&conversationAttrFolder != 0 {
+		var folder string
+		if folder, err = decoder.String(); err != nil {
+			return
+		}
+		attrs.Folder = &folder
+	}
+	if flag&conversationAttrDraft != 0 {
+		var draft string
+		if draft, err = decoder.String(); err != nil {
+			return
+		}
+		attrs.Draft = &draft
+	}
+	return
+}
+
+func EncodeCMDUpdateUserLastSeen(lastSeen wkdb.UserLastSeen) ([]byte, error) {
+	data, err := lastSeen.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// Marshal返回的是编码器缓冲区，CMD编码时会复用，需要拷贝一份
+	return append([]byte(nil), data...), nil
+}
+
+func (c *CMD) DecodeCMDUpdateUserLastSeen() (lastSeen wkdb.UserLastSeen, err error) {
+	err = lastSeen.Unmarshal(c.Data)
+	return
+}
+
+func EncodeCMDAddOrUpdateWebhookSubscription(subscription wkdb.WebhookSubscription) ([]byte, error) {
+	data, err := subscription.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// Marshal返回的是编码器缓冲区，CMD编码时会复用，需要拷贝一份
+	return append([]byte(nil), data...), nil
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateWebhookSubscription() (subscription wkdb.WebhookSubscription, err error) {
+	err = subscription.Unmarshal(c.Data)
+	return
+}
+
+func EncodeCMDRemoveWebhookSubscription(id uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(id)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveWebhookSubscription() (id uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	id, err = decoder.Uint64()
+	return
+}
+
+func EncodeCMDSensitiveWords(words []string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint32(uint32(len(words)))
+	for _, word := range words {
+		encoder.WriteString(word)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSensitiveWords() (words []string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var word string
+		if word, err = decoder.String(); err != nil {
+			return
+		}
+		words = append(words, word)
+	}
+	return
+}
+
 var ErrStoreStopped = fmt.Errorf("store stopped")
+
+// 回应的版本在应用时使用日志下标，不需要编码
+func EncodeCMDSetReaction(reaction wkdb.Reaction) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(reaction.ChannelId)
+	encoder.WriteUint8(reaction.ChannelType)
+	encoder.WriteUint64(reaction.MessageSeq)
+	encoder.WriteString(reaction.Uid)
+	encoder.WriteString(reaction.Emoji)
+	encoder.WriteUint8(wkutil.BoolToUint8(reaction.IsDeleted))
+	encoder.WriteInt64(reaction.CreatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSetReaction() (reaction wkdb.Reaction, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if reaction.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if reaction.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if reaction.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.Emoji, err = decoder.String(); err != nil {
+		return
+	}
+	var deleted uint8
+	if deleted, err = decoder.Uint8(); err != nil {
+		return
+	}
+	reaction.IsDeleted = wkutil.Uint8ToBool(deleted)
+	reaction.CreatedAt, err = decoder.Int64()
+	return
+}
+
+func EncodeCMDAddReceipt(receipt wkdb.Receipt) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(receipt.ChannelId)
+	encoder.WriteUint8(receipt.ChannelType)
+	encoder.WriteString(receipt.Uid)
+	encoder.WriteUint64(receipt.ReadToSeq)
+	encoder.WriteInt64(receipt.ReadAt)
+	encoder.WriteUint32(uint32(len(receipt.Seqs)))
+	for _, seq := range receipt.Seqs {
+		encoder.WriteUint64(seq)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddReceipt() (receipt wkdb.Receipt, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if receipt.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if receipt.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if receipt.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if receipt.ReadToSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if receipt.ReadAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	receipt.Seqs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var seq uint64
+		if seq, err = decoder.Uint64(); err != nil {
+			return
+		}
+		receipt.Seqs = append(receipt.Seqs, seq)
+	}
+	return
+}
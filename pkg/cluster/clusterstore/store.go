@@ -123,6 +123,69 @@ func (s *Store) RemoveSystemUids(uids []string) error {
 	return err
 }
 
+func (s *Store) GetWebhookSubscriptions() ([]wkdb.WebhookSubscription, error) {
+	return s.wdb.GetWebhookSubscriptions()
+}
+
+// AddOrUpdateWebhookSubscription 添加或更新webhook订阅
+func (s *Store) AddOrUpdateWebhookSubscription(subscription wkdb.WebhookSubscription) error {
+	data, err := EncodeCMDAddOrUpdateWebhookSubscription(subscription)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDAddOrUpdateWebhookSubscription, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0 // webhook订阅默认存储在slot 0上
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// RemoveWebhookSubscription 移除webhook订阅
+func (s *Store) RemoveWebhookSubscription(id uint64) error {
+	data := EncodeCMDRemoveWebhookSubscription(id)
+	cmd := NewCMD(CMDRemoveWebhookSubscription, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0 // webhook订阅默认存储在slot 0上
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetSensitiveWords() ([]string, error) {
+	return s.wdb.GetSensitiveWords()
+}
+
+// AddSensitiveWords 添加敏感词
+func (s *Store) AddSensitiveWords(words []string) error {
+	data := EncodeCMDSensitiveWords(words)
+	cmd := NewCMD(CMDAddSensitiveWords, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0 // 敏感词默认存储在slot 0上
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// RemoveSensitiveWords 移除敏感词
+func (s *Store) RemoveSensitiveWords(words []string) error {
+	data := EncodeCMDSensitiveWords(words)
+	cmd := NewCMD(CMDRemoveSensitiveWords, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0 // 敏感词默认存储在slot 0上
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
 func (s *Store) GetIPBlacklist() ([]string, error) {
 	// return s.db.GetIPBlacklist()
 	return nil, nil
@@ -17,16 +17,37 @@ func (s *Store) OnMetaApply(slotId uint32, logs []replica.Log) error {
 	defer cancel()
 	requestGroup, _ := errgroup.WithContext(timeoutCtx)
 	requestGroup.SetLimit(400) // 同时应用的并发数
+	var orderedLogs []replica.Log
 	for _, lg := range logs {
+		if needApplyInOrder(lg) {
+			orderedLogs = append(orderedLogs, lg)
+			continue
+		}
 		requestGroup.Go(func(l replica.Log) func() error {
 			return func() error {
 				return s.onMetaApply(slotId, l)
 			}
 		}(lg))
 	}
+	// 结果依赖应用顺序的日志按日志顺序逐条应用，保证各副本一致
+	for _, lg := range orderedLogs {
+		if err := s.onMetaApply(slotId, lg); err != nil {
+			_ = requestGroup.Wait()
+			return err
+		}
+	}
 	return requestGroup.Wait()
 }
 
+// 已读回执依赖用户已回执的位置去重，需要按日志顺序应用
+func needApplyInOrder(log replica.Log) bool {
+	cmd := &CMD{}
+	if err := cmd.Unmarshal(log.Data); err != nil {
+		return false
+	}
+	return cmd.CmdType == CMDAddReceipt
+}
+
 func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 	cmd := &CMD{}
 	err := cmd.Unmarshal(log.Data)
@@ -42,7 +63,7 @@ func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 			s.Info("meta apply", zap.Duration("cost", end), zap.Uint32("slotId", slotId), zap.String("cmdType", cmd.CmdType.String()), zap.Int("dataLen", len(cmd.Data)))
 		}
 	}()
-	err = s.execCMD(cmd)
+	err = s.execCMD(cmd, log.Index)
 	if err != nil {
 		s.Error("exec cmd err", zap.Error(err), zap.String("cmdType", cmd.CmdType.String()), zap.Uint32("slotId", slotId), zap.Uint64("index", log.Index), zap.ByteString("data", log.Data))
 		return err
@@ -50,7 +71,7 @@ func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 	return nil
 }
 
-func (s *Store) execCMD(cmd *CMD) error {
+func (s *Store) execCMD(cmd *CMD, logIndex uint64) error {
 	switch cmd.CmdType {
 	case CMDAddSubscribers: // 添加订阅者
 		return s.handleAddSubscribers(cmd)
@@ -106,11 +127,147 @@ func (s *Store) execCMD(cmd *CMD) error {
 		return s.handleAddStreamMeta(cmd)
 	case CMDAddStreams: // 添加流
 		return s.handleAddStreams(cmd)
+	case CMDUpdateSubscribers: // 更新订阅者
+		return s.handleUpdateSubscribers(cmd)
+	case CMDAddPinnedMessage: // 置顶消息
+		return s.handleAddPinnedMessage(cmd)
+	case CMDRemovePinnedMessage: // 取消置顶消息
+		return s.handleRemovePinnedMessage(cmd)
+	case CMDAddScheduledMessage: // 添加定时消息
+		return s.handleAddScheduledMessage(cmd)
+	case CMDRemoveScheduledMessage: // 移除定时消息
+		return s.handleRemoveScheduledMessage(cmd)
+	case CMDUpdateConversationAttrs: // 修改会话属性
+		return s.handleUpdateConversationAttrs(cmd)
+	case CMDUpdateUserLastSeen: // 更新用户最后上线/离线时间
+		return s.handleUpdateUserLastSeen(cmd)
+	case CMDAddOrUpdateWebhookSubscription: // 添加或更新webhook订阅
+		return s.handleAddOrUpdateWebhookSubscription(cmd)
+	case CMDRemoveWebhookSubscription: // 移除webhook订阅
+		return s.handleRemoveWebhookSubscription(cmd)
+	case CMDAddSensitiveWords: // 添加敏感词
+		return s.handleAddSensitiveWords(cmd)
+	case CMDRemoveSensitiveWords: // 移除敏感词
+		return s.handleRemoveSensitiveWords(cmd)
+	case CMDSetReaction: // 添加或取消消息回应
+		return s.handleSetReaction(cmd, logIndex)
+	case CMDAddReceipt: // 添加已读回执
+		return s.handleAddReceipt(cmd)
 
 	}
 	return nil
 }
 
+// 日志下标在槽内递增，频道属于固定的槽，所以作为频道内的回应版本
+func (s *Store) handleSetReaction(cmd *CMD, logIndex uint64) error {
+	reaction, err := cmd.DecodeCMDSetReaction()
+	if err != nil {
+		s.Error("decode reaction err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	reaction.Seq = logIndex
+	return s.wdb.SetReaction(reaction)
+}
+
+func (s *Store) handleAddReceipt(cmd *CMD) error {
+	receipt, err := cmd.DecodeCMDAddReceipt()
+	if err != nil {
+		s.Error("decode receipt err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.AddReceipt(receipt)
+}
+
+func (s *Store) handleAddPinnedMessage(cmd *CMD) error {
+	pin, err := cmd.DecodeCMDAddPinnedMessage()
+	if err != nil {
+		s.Error("decode pinned message err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.AddPinnedMessage(pin)
+}
+
+func (s *Store) handleRemovePinnedMessage(cmd *CMD) error {
+	channelId, channelType, messageSeq, err := cmd.DecodeCMDRemovePinnedMessage()
+	if err != nil {
+		s.Error("decode remove pinned message err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.RemovePinnedMessage(channelId, channelType, messageSeq)
+}
+
+func (s *Store) handleAddScheduledMessage(cmd *CMD) error {
+	m, err := cmd.DecodeCMDAddScheduledMessage()
+	if err != nil {
+		s.Error("decode scheduled message err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.AddScheduledMessage(m)
+}
+
+func (s *Store) handleRemoveScheduledMessage(cmd *CMD) error {
+	channelId, channelType, id, err := cmd.DecodeCMDRemoveScheduledMessage()
+	if err != nil {
+		s.Error("decode remove scheduled message err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.RemoveScheduledMessage(channelId, channelType, id)
+}
+
+func (s *Store) handleUpdateConversationAttrs(cmd *CMD) error {
+	uid, id, attrs, err := cmd.DecodeCMDUpdateConversationAttrs()
+	if err != nil {
+		s.Error("decode update conversation attrs err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.UpdateConversationAttrs(uid, id, attrs)
+}
+
+func (s *Store) handleUpdateUserLastSeen(cmd *CMD) error {
+	lastSeen, err := cmd.DecodeCMDUpdateUserLastSeen()
+	if err != nil {
+		s.Error("decode user last seen err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.UpdateUserLastSeen(lastSeen)
+}
+
+func (s *Store) handleAddOrUpdateWebhookSubscription(cmd *CMD) error {
+	subscription, err := cmd.DecodeCMDAddOrUpdateWebhookSubscription()
+	if err != nil {
+		s.Error("decode webhook subscription err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.AddOrUpdateWebhookSubscription(subscription)
+}
+
+func (s *Store) handleRemoveWebhookSubscription(cmd *CMD) error {
+	id, err := cmd.DecodeCMDRemoveWebhookSubscription()
+	if err != nil {
+		s.Error("decode remove webhook subscription err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.RemoveWebhookSubscription(id)
+}
+
+func (s *Store) handleAddSensitiveWords(cmd *CMD) error {
+	words, err := cmd.DecodeCMDSensitiveWords()
+	if err != nil {
+		s.Error("decode add sensitive words err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.AddSensitiveWords(words)
+}
+
+func (s *Store) handleRemoveSensitiveWords(cmd *CMD) error {
+	words, err := cmd.DecodeCMDSensitiveWords()
+	if err != nil {
+		s.Error("decode remove sensitive words err", zap.Error(err), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.RemoveSensitiveWords(words)
+}
+
 func (s *Store) handleAddSubscribers(cmd *CMD) error {
 	channelId, channelType, members, err := cmd.DecodeMembers()
 	if err != nil {
@@ -120,6 +277,15 @@ func (s *Store) handleAddSubscribers(cmd *CMD) error {
 	return s.wdb.AddSubscribers(channelId, channelType, members)
 }
 
+func (s *Store) handleUpdateSubscribers(cmd *CMD) error {
+	channelId, channelType, members, err := cmd.DecodeMembers()
+	if err != nil {
+		s.Error("decode subscribers err", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType), zap.ByteString("data", cmd.Data))
+		return err
+	}
+	return s.wdb.UpdateSubscribers(channelId, channelType, members)
+}
+
 func (s *Store) handleRemoveSubscribers(cmd *CMD) error {
 	channelId, channelType, subscribers, err := cmd.DecodeChannelUids()
 	if err != nil {
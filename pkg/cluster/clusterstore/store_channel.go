@@ -22,6 +22,65 @@ func (s *Store) AddSubscribers(channelId string, channelType uint8, subscribers
 	return err
 }
 
+// UpdateSubscribers 更新订阅者的角色、禁言和扩展属性
+func (s *Store) UpdateSubscribers(channelId string, channelType uint8, subscribers []wkdb.Member) error {
+
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	data := EncodeMembers(channelId, channelType, subscribers)
+	cmd := NewCMD(CMDUpdateSubscribers, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetSubscriber 获取订阅者
+func (s *Store) GetSubscriber(channelId string, channelType uint8, uid string) (wkdb.Member, error) {
+	return s.wdb.GetSubscriber(channelId, channelType, uid)
+}
+
+// GetSubscribersByOffset 分页获取订阅者
+func (s *Store) GetSubscribersByOffset(channelId string, channelType uint8, offsetId uint64, limit int) ([]wkdb.Member, error) {
+	return s.wdb.GetSubscribersByOffset(channelId, channelType, offsetId, limit)
+}
+
+// AddPinnedMessage 置顶消息
+func (s *Store) AddPinnedMessage(pin wkdb.PinnedMessage) error {
+	data := EncodeCMDAddPinnedMessage(pin)
+	cmd := NewCMD(CMDAddPinnedMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(pin.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// RemovePinnedMessage 取消置顶消息
+func (s *Store) RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	data := EncodeCMDRemovePinnedMessage(channelId, channelType, messageSeq)
+	cmd := NewCMD(CMDRemovePinnedMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetPinnedMessages 获取频道的置顶消息
+func (s *Store) GetPinnedMessages(channelId string, channelType uint8) ([]wkdb.PinnedMessage, error) {
+	return s.wdb.GetPinnedMessages(channelId, channelType)
+}
+
 func (s *Store) ExistSubscriber(channelId string, channelType uint8, uid string) (bool, error) {
 	return s.wdb.ExistSubscriber(channelId, channelType, uid)
 }
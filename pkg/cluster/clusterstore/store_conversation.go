@@ -27,6 +27,24 @@ func (s *Store) AddOrUpdateConversations(uid string, conversations []wkdb.Conver
 	return err
 }
 
+// UpdateConversationAttrs 修改会话属性，会话不存在时新建
+func (s *Store) UpdateConversationAttrs(uid string, attrs wkdb.ConversationAttrs) error {
+	data := EncodeCMDUpdateConversationAttrs(uid, s.NextPrimaryKey(), attrs)
+	cmd := NewCMD(CMDUpdateConversationAttrs, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetConversationVersion 获取用户的会话版本
+func (s *Store) GetConversationVersion(uid string) (uint64, error) {
+	return s.wdb.GetConversationVersion(uid)
+}
+
 func (s *Store) DeleteConversation(uid string, channelID string, channelType uint8) error {
 	data := EncodeCMDDeleteConversation(uid, channelID, channelType)
 	cmd := NewCMD(CMDDeleteConversation, data)
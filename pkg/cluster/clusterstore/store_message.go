@@ -151,6 +151,83 @@ func (s *Store) SearchMessages(req wkdb.MessageSearchReq) ([]wkdb.Message, error
 	return s.wdb.SearchMessages(req)
 }
 
+// GetMessageExtra 获取消息的扩展数据（撤回/编辑状态）
+func (s *Store) GetMessageExtra(channelId string, channelType uint8, messageSeq uint64) (wkdb.MessageExtra, error) {
+	return s.wdb.GetMessageExtra(channelId, channelType, messageSeq)
+}
+
+// SetReaction 添加或取消消息回应，返回回应版本
+func (s *Store) SetReaction(reaction wkdb.Reaction) (uint64, error) {
+	data := EncodeCMDSetReaction(reaction)
+	cmd := NewCMD(CMDSetReaction, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	slotId := s.opts.GetSlotId(reaction.ChannelId)
+	result, err := s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	if err != nil {
+		return 0, err
+	}
+	if result == nil {
+		return 0, nil
+	}
+	return result.LogIndex(), nil
+}
+
+// AddReceipt 添加已读回执
+func (s *Store) AddReceipt(receipt wkdb.Receipt) error {
+	data := EncodeCMDAddReceipt(receipt)
+	cmd := NewCMD(CMDAddReceipt, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(receipt.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetMessageReactions 获取消息的回应
+func (s *Store) GetMessageReactions(channelId string, channelType uint8, messageSeq uint64) ([]wkdb.Reaction, error) {
+	return s.wdb.GetMessageReactions(channelId, channelType, messageSeq)
+}
+
+// SyncReactions 获取频道内版本大于startSeq的回应变更
+func (s *Store) SyncReactions(channelId string, channelType uint8, startSeq uint64, limit int) ([]wkdb.Reaction, error) {
+	return s.wdb.SyncReactions(channelId, channelType, startSeq, limit)
+}
+
+// GetReactionVersion 获取频道当前的回应版本
+func (s *Store) GetReactionVersion(channelId string, channelType uint8) (uint64, error) {
+	return s.wdb.GetReactionVersion(channelId, channelType)
+}
+
+// LoadThreadMsgs 加载父消息的回复
+func (s *Store) LoadThreadMsgs(channelId string, channelType uint8, parentSeq uint64, startSeq uint64, limit int) ([]wkdb.Message, error) {
+	return s.wdb.LoadThreadMsgs(channelId, channelType, parentSeq, startSeq, limit)
+}
+
+// GetThreadSummaries 获取父消息的回复概要
+func (s *Store) GetThreadSummaries(channelId string, channelType uint8, parentSeqs []uint64) ([]wkdb.ThreadSummary, error) {
+	return s.wdb.GetThreadSummaries(channelId, channelType, parentSeqs)
+}
+
+// GetMessageReceiptReaders 获取消息的已读用户
+func (s *Store) GetMessageReceiptReaders(channelId string, channelType uint8, messageSeq uint64, limit int) ([]wkdb.ReceiptReader, error) {
+	return s.wdb.GetMessageReceiptReaders(channelId, channelType, messageSeq, limit)
+}
+
+// GetMessageReceiptCount 获取消息的已读数量
+func (s *Store) GetMessageReceiptCount(channelId string, channelType uint8, messageSeq uint64) (uint32, error) {
+	return s.wdb.GetMessageReceiptCount(channelId, channelType, messageSeq)
+}
+
+// GetReceiptReadSeq 获取用户在频道内已回执到的消息seq
+func (s *Store) GetReceiptReadSeq(channelId string, channelType uint8, uid string) (uint64, error) {
+	return s.wdb.GetReceiptReadSeq(channelId, channelType, uid)
+}
+
 // 获取频道的槽id
 func (s *Store) getChannelSlotId(channelId string) uint32 {
 	return wkutil.GetSlotNum(int(s.opts.SlotCount), channelId)
@@ -367,3 +444,47 @@ func IsUserOwnChannel(channelId string, channelType uint8) bool {
 	}
 	return false
 }
+
+// AddScheduledMessage 添加定时消息，定时消息属于频道所在的槽
+func (s *Store) AddScheduledMessage(m wkdb.ScheduledMessage) error {
+	data, err := EncodeCMDAddScheduledMessage(m)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDAddScheduledMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(m.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// RemoveScheduledMessage 移除定时消息
+func (s *Store) RemoveScheduledMessage(channelId string, channelType uint8, id uint64) error {
+	data := EncodeCMDRemoveScheduledMessage(channelId, channelType, id)
+	cmd := NewCMD(CMDRemoveScheduledMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetScheduledMessage 获取定时消息
+func (s *Store) GetScheduledMessage(channelId string, channelType uint8, id uint64) (wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessage(channelId, channelType, id)
+}
+
+// GetScheduledMessages 获取频道待发送的定时消息
+func (s *Store) GetScheduledMessages(channelId string, channelType uint8) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessages(channelId, channelType)
+}
+
+// GetDueScheduledMessages 获取本节点上已到发送时间的定时消息
+func (s *Store) GetDueScheduledMessages(now int64, limit int, filter ...func(m wkdb.ScheduledMessage) bool) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetDueScheduledMessages(now, limit, filter...)
+}
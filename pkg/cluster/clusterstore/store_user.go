@@ -1,6 +1,8 @@
 package clusterstore
 
 import (
+	"context"
+
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/zap"
@@ -37,6 +39,27 @@ func (s *Store) UpdateUser(u wkdb.User) error {
 	return err
 }
 
+// UpdateUserLastSeen 更新用户的最后上线/离线时间
+func (s *Store) UpdateUserLastSeen(ctx context.Context, lastSeen wkdb.UserLastSeen) error {
+	data, err := EncodeCMDUpdateUserLastSeen(lastSeen)
+	if err != nil {
+		return err
+	}
+	cmd := NewCMD(CMDUpdateUserLastSeen, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(lastSeen.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetUserLastSeens(uids []string) ([]wkdb.UserLastSeen, error) {
+	return s.wdb.GetUserLastSeens(uids)
+}
+
 func (s *Store) UpdateDevice(d wkdb.Device) error {
 	data := EncodeCMDDevice(d)
 	cmd := NewCMD(CMDUpdateDevice, data)
@@ -1,4 +1,216 @@
 package mqtt
 
+import (
+	"bytes"
+	"io"
+)
+
+// ConnectPacket 连接报文
 type ConnectPacket struct {
+	ProtocolName    string          // MQTT（3.1为MQIsdp）
+	ProtocolVersion ProtocolVersion // 协议级别
+	CleanStart      bool            // 3.1.1中为Clean Session
+	KeepAlive       uint16          // 保持连接的时间间隔（单位秒），0表示关闭
+	Properties      *Properties     // 仅5.0
+
+	ClientID string
+
+	WillFlag       bool
+	WillQoS        QoS
+	WillRetain     bool
+	WillProperties *Properties // 仅5.0
+	WillTopic      string
+	WillPayload    []byte
+
+	UsernameFlag bool
+	Username     string
+	PasswordFlag bool
+	Password     []byte
+}
+
+func (c *ConnectPacket) Type() PacketType {
+	return CONNECT
+}
+
+func (c *ConnectPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	protocolName := c.ProtocolName
+	if protocolName == "" {
+		protocolName = "MQTT"
+		if c.ProtocolVersion == Version31 {
+			protocolName = "MQIsdp"
+		}
+	}
+	writeString(&buf, protocolName)
+	buf.WriteByte(byte(c.ProtocolVersion))
+
+	var flags byte
+	if c.UsernameFlag {
+		flags |= 0x80
+	}
+	if c.PasswordFlag {
+		flags |= 0x40
+	}
+	if c.WillFlag {
+		if c.WillRetain {
+			flags |= 0x20
+		}
+		flags |= byte(c.WillQoS&0x03) << 3
+		flags |= 0x04
+	}
+	if c.CleanStart {
+		flags |= 0x02
+	}
+	buf.WriteByte(flags)
+	writeUint16(&buf, c.KeepAlive)
+	if c.ProtocolVersion == Version5 {
+		c.Properties.encode(&buf)
+	}
+
+	writeString(&buf, c.ClientID)
+	if c.WillFlag {
+		if c.ProtocolVersion == Version5 {
+			c.WillProperties.encode(&buf)
+		}
+		writeString(&buf, c.WillTopic)
+		writeBinary(&buf, c.WillPayload)
+	}
+	if c.UsernameFlag {
+		writeString(&buf, c.Username)
+	}
+	if c.PasswordFlag {
+		writeBinary(&buf, c.Password)
+	}
+	return writePacket(w, CONNECT, 0, buf.Bytes())
+}
+
+// Decode 协议级别不支持时返回ErrUnsupportedVersion，服务端需要回复返回码为0x01的CONNACK后断开连接
+func (c *ConnectPacket) Decode(r io.Reader, _ FixedHeader) error {
+	var err error
+	if c.ProtocolName, err = readString(r); err != nil {
+		return err
+	}
+	version, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	c.ProtocolVersion = ProtocolVersion(version)
+	switch {
+	case c.ProtocolName == "MQTT" && (c.ProtocolVersion == Version311 || c.ProtocolVersion == Version5):
+	case c.ProtocolName == "MQIsdp" && c.ProtocolVersion == Version31:
+	case c.ProtocolName == "MQTT" || c.ProtocolName == "MQIsdp":
+		return ErrUnsupportedVersion
+	default:
+		return ErrProtocolViolation
+	}
+
+	flags, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	if flags&0x01 != 0 { // 保留位必须为0
+		return ErrMalformedPacket
+	}
+	c.UsernameFlag = flags&0x80 != 0
+	c.PasswordFlag = flags&0x40 != 0
+	c.WillRetain = flags&0x20 != 0
+	c.WillQoS = QoS(flags>>3) & 0x03
+	c.WillFlag = flags&0x04 != 0
+	c.CleanStart = flags&0x02 != 0
+	if c.WillQoS > QoS2 {
+		return ErrMalformedPacket
+	}
+	if !c.WillFlag && (c.WillQoS != QoS0 || c.WillRetain) {
+		return ErrMalformedPacket
+	}
+	if c.ProtocolVersion != Version5 && c.PasswordFlag && !c.UsernameFlag {
+		return ErrMalformedPacket
+	}
+
+	if c.KeepAlive, err = readUint16(r); err != nil {
+		return err
+	}
+	if c.ProtocolVersion == Version5 {
+		if c.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+	}
+
+	if c.ClientID, err = readString(r); err != nil {
+		return err
+	}
+	if c.WillFlag {
+		if c.ProtocolVersion == Version5 {
+			if c.WillProperties, err = decodeProperties(r); err != nil {
+				return err
+			}
+		}
+		if c.WillTopic, err = readString(r); err != nil {
+			return err
+		}
+		if c.WillPayload, err = readBinary(r); err != nil {
+			return err
+		}
+	}
+	if c.UsernameFlag {
+		if c.Username, err = readString(r); err != nil {
+			return err
+		}
+	}
+	if c.PasswordFlag {
+		if c.Password, err = readBinary(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnackPacket 连接确认报文
+type ConnackPacket struct {
+	ProtocolVersion ProtocolVersion
+	SessionPresent  bool
+	ReturnCode      ConnectReturnCode // 3.1.1
+	ReasonCode      ReasonCode        // 5.0
+	Properties      *Properties       // 仅5.0
+}
+
+func (c *ConnackPacket) Type() PacketType {
+	return CONNACK
+}
+
+func (c *ConnackPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	if c.SessionPresent {
+		buf.WriteByte(0x01)
+	} else {
+		buf.WriteByte(0x00)
+	}
+	if c.ProtocolVersion == Version5 {
+		buf.WriteByte(byte(c.ReasonCode))
+		c.Properties.encode(&buf)
+	} else {
+		buf.WriteByte(byte(c.ReturnCode))
+	}
+	return writePacket(w, CONNACK, 0, buf.Bytes())
+}
+
+func (c *ConnackPacket) Decode(r io.Reader, header FixedHeader) error {
+	flags, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	c.SessionPresent = flags&0x01 != 0
+	code, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	if c.ProtocolVersion == Version5 {
+		c.ReasonCode = ReasonCode(code)
+		if header.RemainingLength > 2 {
+			c.Properties, err = decodeProperties(r)
+		}
+		return err
+	}
+	c.ReturnCode = ConnectReturnCode(code)
+	return nil
 }
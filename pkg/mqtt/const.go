@@ -1,17 +1,122 @@
 package mqtt
 
+// PacketType 控制报文类型
+type PacketType byte
+
+const (
+	CONNECT     PacketType = 1
+	CONNACK     PacketType = 2
+	PUBLISH     PacketType = 3
+	PUBACK      PacketType = 4
+	PUBREC      PacketType = 5
+	PUBREL      PacketType = 6
+	PUBCOMP     PacketType = 7
+	SUBSCRIBE   PacketType = 8
+	SUBACK      PacketType = 9
+	UNSUBSCRIBE PacketType = 10
+	UNSUBACK    PacketType = 11
+	PINGREQ     PacketType = 12
+	PINGRESP    PacketType = 13
+	DISCONNECT  PacketType = 14
+	AUTH        PacketType = 15 // 仅5.0
+)
+
+func (p PacketType) String() string {
+	switch p {
+	case CONNECT:
+		return "CONNECT"
+	case CONNACK:
+		return "CONNACK"
+	case PUBLISH:
+		return "PUBLISH"
+	case PUBACK:
+		return "PUBACK"
+	case PUBREC:
+		return "PUBREC"
+	case PUBREL:
+		return "PUBREL"
+	case PUBCOMP:
+		return "PUBCOMP"
+	case SUBSCRIBE:
+		return "SUBSCRIBE"
+	case SUBACK:
+		return "SUBACK"
+	case UNSUBSCRIBE:
+		return "UNSUBSCRIBE"
+	case UNSUBACK:
+		return "UNSUBACK"
+	case PINGREQ:
+		return "PINGREQ"
+	case PINGRESP:
+		return "PINGRESP"
+	case DISCONNECT:
+		return "DISCONNECT"
+	case AUTH:
+		return "AUTH"
+	}
+	return "UNKNOWN"
+}
+
+// ProtocolVersion 协议级别（CONNECT报文中的Protocol Level）
+type ProtocolVersion byte
+
+const (
+	Version31  ProtocolVersion = 3 // MQTT 3.1（协议名为MQIsdp）
+	Version311 ProtocolVersion = 4 // MQTT 3.1.1
+	Version5   ProtocolVersion = 5 // MQTT 5.0
+)
+
+// QoS 服务质量等级
+type QoS byte
+
+const (
+	QoS0 QoS = 0 // 最多一次
+	QoS1 QoS = 1 // 至少一次
+	QoS2 QoS = 2 // 只有一次
+)
+
+// ConnectReturnCode 3.1.1 CONNACK的返回码，5.0使用ReasonCode
+type ConnectReturnCode byte
+
+const (
+	ConnAccepted                     ConnectReturnCode = 0x00 // 连接已接受
+	ConnRefusedProtocolVersion       ConnectReturnCode = 0x01 // 不支持的协议版本
+	ConnRefusedIdentifierRejected    ConnectReturnCode = 0x02 // 不合格的客户端标识符
+	ConnRefusedServerUnavailable     ConnectReturnCode = 0x03 // 服务端不可用
+	ConnRefusedBadUsernameOrPassword ConnectReturnCode = 0x04 // 无效的用户名或密码
+	ConnRefusedNotAuthorized         ConnectReturnCode = 0x05 // 未授权
+)
+
+// SubackFailure 3.1.1 SUBACK中订阅失败的返回码
+const SubackFailure byte = 0x80
+
+// ReasonCode 5.0的原因码
 type ReasonCode byte
 
 const (
 	Success                           ReasonCode = 0x00 // CONNACK, PUBACK, PUBREC, PUBREL, PUBCOMP, UNSUBACK, AUTH
+	NormalDisconnection               ReasonCode = 0x00 // DISCONNECT
+	GrantedQoS0                       ReasonCode = 0x00 // SUBACK
+	GrantedQoS1                       ReasonCode = 0x01 // SUBACK
+	GrantedQoS2                       ReasonCode = 0x02 // SUBACK
+	DisconnectWithWillMessage         ReasonCode = 0x04 // DISCONNECT
 	NoMatchingSubscribers             ReasonCode = 0x10 // PUBACK, PUBREC
+	NoSubscriptionExisted             ReasonCode = 0x11 // UNSUBACK
 	UnspecifiedError                  ReasonCode = 0x80 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
 	MalformedPacket                   ReasonCode = 0x81 // CONNACK, DISCONNECT
 	ProtocolError                     ReasonCode = 0x82 // CONNACK, DISCONNECT
 	ImplSpecificError                 ReasonCode = 0x83 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
+	UnsupportedProtocolVersion        ReasonCode = 0x84 // CONNACK
+	ClientIdentifierNotValid          ReasonCode = 0x85 // CONNACK
+	BadUserNameOrPassword             ReasonCode = 0x86 // CONNACK
 	NotAuthorized                     ReasonCode = 0x87 // CONNACK, PUBACK, PUBREC, SUBACK, UNSUBACK, DISCONNECT
+	ServerUnavailable                 ReasonCode = 0x88 // CONNACK
 	ServerBusy                        ReasonCode = 0x89 // CONNACK, DISCONNECT
+	Banned                            ReasonCode = 0x8A // CONNACK
+	ServerShuttingDown                ReasonCode = 0x8B // DISCONNECT
 	BadAuthMethod                     ReasonCode = 0x8C // CONNACK, DISCONNECT
+	KeepAliveTimeout                  ReasonCode = 0x8D // DISCONNECT
+	SessionTakenOver                  ReasonCode = 0x8E // DISCONNECT
 	TopicFilterInvalid                ReasonCode = 0x8F // SUBACK, UNSUBACK, DISCONNECT
 	TopicNameInvalid                  ReasonCode = 0x90 // CONNACK, PUBACK, PUBREC, DISCONNECT
 	PacketIdentifierInUse             ReasonCode = 0x91 // PUBACK, SUBACK, UNSUBACK
@@ -1,9 +1,182 @@
 package mqtt
 
-import "io"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
 
-// controlPacket MQTT control packet codec interface
+var (
+	ErrMalformedPacket    = errors.New("mqtt: malformed packet")
+	ErrProtocolViolation  = errors.New("mqtt: protocol violation")
+	ErrRemainingLength    = errors.New("mqtt: remaining length exceeds limit")
+	ErrUnknownPacketType  = errors.New("mqtt: unknown packet type")
+	ErrInvalidUTF8String  = errors.New("mqtt: invalid utf8 string")
+	ErrUnsupportedVersion = errors.New("mqtt: unsupported protocol version")
+)
+
+// MaxRemainingLength 剩余长度的最大值（4个字节的可变长度编码）
+const MaxRemainingLength = 268435455
+
+// FixedHeader 固定报头
+type FixedHeader struct {
+	Type            PacketType
+	Flags           byte // 低4位的标志位，只有PUBLISH的标志位有含义
+	RemainingLength uint32
+}
+
+// ControlPacket MQTT control packet codec interface
 type ControlPacket interface {
+	// Type 报文类型
+	Type() PacketType
+	// Encode 编码整个报文（包含固定报头）
 	Encode(w io.Writer) error
-	Decode(r io.Reader, remainingLen uint32) error
+	// Decode 解码固定报头之后的内容，r中只包含剩余长度的数据
+	Decode(r io.Reader, header FixedHeader) error
+}
+
+// flags 各报文固定报头中规定的标志位，PUBLISH除外
+func fixedFlags(t PacketType) byte {
+	switch t {
+	case PUBREL, SUBSCRIBE, UNSUBSCRIBE:
+		return 0x02
+	}
+	return 0
+}
+
+func writePacket(w io.Writer, t PacketType, flags byte, body []byte) error {
+	if len(body) > MaxRemainingLength {
+		return ErrRemainingLength
+	}
+	header := make([]byte, 0, 5)
+	header = append(header, byte(t)<<4|flags&0x0f)
+	header = appendVarInt(header, uint32(len(body)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// ---------------------- 编码 ----------------------
+
+func appendVarInt(b []byte, v uint32) []byte {
+	for {
+		digit := byte(v % 128)
+		v /= 128
+		if v > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func writeUint16(buf *bytes.Buffer, v uint16) {
+	buf.WriteByte(byte(v >> 8))
+	buf.WriteByte(byte(v))
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeVarInt(buf *bytes.Buffer, v uint32) {
+	buf.Write(appendVarInt(nil, v))
+}
+
+func writeBinary(buf *bytes.Buffer, v []byte) {
+	writeUint16(buf, uint16(len(v)))
+	buf.Write(v)
+}
+
+func writeString(buf *bytes.Buffer, v string) {
+	writeUint16(buf, uint16(len(v)))
+	buf.WriteString(v)
+}
+
+// ---------------------- 解码 ----------------------
+
+func readByte(r io.Reader) (byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return b[0], nil
+}
+
+func readUint16(r io.Reader) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+func readVarInt(r io.Reader) (uint32, error) {
+	var (
+		value      uint32
+		multiplier uint32 = 1
+	)
+	for i := 0; i < 4; i++ {
+		digit, err := readByte(r)
+		if err != nil {
+			return 0, err
+		}
+		value += uint32(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			return value, nil
+		}
+		multiplier *= 128
+	}
+	return 0, ErrMalformedPacket
+}
+
+func readBinary(r io.Reader) ([]byte, error) {
+	n, err := readUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b, nil
+}
+
+func readString(r io.Reader) (string, error) {
+	b, err := readBinary(r)
+	if err != nil {
+		return "", err
+	}
+	if !validUTF8String(b) {
+		return "", ErrInvalidUTF8String
+	}
+	return string(b), nil
+}
+
+// validUTF8String 规范要求字符串是合法的utf8且不能包含U+0000
+func validUTF8String(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
 }
@@ -0,0 +1,105 @@
+package mqtt
+
+import (
+	"bytes"
+	"io"
+)
+
+// PingreqPacket 心跳请求报文
+type PingreqPacket struct {
+}
+
+func (p *PingreqPacket) Type() PacketType {
+	return PINGREQ
+}
+
+func (p *PingreqPacket) Encode(w io.Writer) error {
+	return writePacket(w, PINGREQ, 0, nil)
+}
+
+func (p *PingreqPacket) Decode(_ io.Reader, _ FixedHeader) error {
+	return nil
+}
+
+// PingrespPacket 心跳响应报文
+type PingrespPacket struct {
+}
+
+func (p *PingrespPacket) Type() PacketType {
+	return PINGRESP
+}
+
+func (p *PingrespPacket) Encode(w io.Writer) error {
+	return writePacket(w, PINGRESP, 0, nil)
+}
+
+func (p *PingrespPacket) Decode(_ io.Reader, _ FixedHeader) error {
+	return nil
+}
+
+// DisconnectPacket 断开连接报文，3.1.1中只能由客户端发送
+type DisconnectPacket struct {
+	ProtocolVersion ProtocolVersion
+	ReasonCode      ReasonCode  // 仅5.0
+	Properties      *Properties // 仅5.0
+}
+
+func (d *DisconnectPacket) Type() PacketType {
+	return DISCONNECT
+}
+
+func (d *DisconnectPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	if d.ProtocolVersion == Version5 {
+		buf.WriteByte(byte(d.ReasonCode))
+		d.Properties.encode(&buf)
+	}
+	return writePacket(w, DISCONNECT, 0, buf.Bytes())
+}
+
+func (d *DisconnectPacket) Decode(r io.Reader, header FixedHeader) error {
+	if d.ProtocolVersion != Version5 || header.RemainingLength == 0 {
+		return nil
+	}
+	code, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	d.ReasonCode = ReasonCode(code)
+	if header.RemainingLength > 1 {
+		d.Properties, err = decodeProperties(r)
+	}
+	return err
+}
+
+// AuthPacket 认证交换报文，仅5.0
+type AuthPacket struct {
+	ReasonCode ReasonCode
+	Properties *Properties
+}
+
+func (a *AuthPacket) Type() PacketType {
+	return AUTH
+}
+
+func (a *AuthPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(a.ReasonCode))
+	a.Properties.encode(&buf)
+	return writePacket(w, AUTH, 0, buf.Bytes())
+}
+
+func (a *AuthPacket) Decode(r io.Reader, header FixedHeader) error {
+	if header.RemainingLength == 0 {
+		return nil
+	}
+	code, err := readByte(r)
+	if err != nil {
+		return err
+	}
+	a.ReasonCode = ReasonCode(code)
+	if header.RemainingLength > 1 {
+		a.Properties, err = decodeProperties(r)
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package mqtt
+
+import (
+	"bytes"
+	"io"
+)
+
+// 5.0的属性标识符
+const (
+	PropPayloadFormatIndicator          byte = 0x01
+	PropMessageExpiryInterval           byte = 0x02
+	PropContentType                     byte = 0x03
+	PropResponseTopic                   byte = 0x08
+	PropCorrelationData                 byte = 0x09
+	PropSubscriptionIdentifier          byte = 0x0B
+	PropSessionExpiryInterval           byte = 0x11
+	PropAssignedClientIdentifier        byte = 0x12
+	PropServerKeepAlive                 byte = 0x13
+	PropAuthenticationMethod            byte = 0x15
+	PropAuthenticationData              byte = 0x16
+	PropRequestProblemInformation       byte = 0x17
+	PropWillDelayInterval               byte = 0x18
+	PropRequestResponseInformation      byte = 0x19
+	PropResponseInformation             byte = 0x1A
+	PropServerReference                 byte = 0x1C
+	PropReasonString                    byte = 0x1F
+	PropReceiveMaximum                  byte = 0x21
+	PropTopicAliasMaximum               byte = 0x22
+	PropTopicAlias                      byte = 0x23
+	PropMaximumQoS                      byte = 0x24
+	PropRetainAvailable                 byte = 0x25
+	PropUserProperty                    byte = 0x26
+	PropMaximumPacketSize               byte = 0x27
+	PropWildcardSubscriptionAvailable   byte = 0x28
+	PropSubscriptionIdentifierAvailable byte = 0x29
+	PropSharedSubscriptionAvailable     byte = 0x2A
+)
+
+// UserProperty 用户属性
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+// Properties 5.0报文的属性，指针类型的字段为nil表示没有该属性
+type Properties struct {
+	PayloadFormatIndicator          *byte
+	MessageExpiryInterval           *uint32
+	ContentType                     string
+	ResponseTopic                   string
+	CorrelationData                 []byte
+	SubscriptionIdentifiers         []uint32
+	SessionExpiryInterval           *uint32
+	AssignedClientIdentifier        string
+	ServerKeepAlive                 *uint16
+	AuthenticationMethod            string
+	AuthenticationData              []byte
+	RequestProblemInformation       *byte
+	WillDelayInterval               *uint32
+	RequestResponseInformation      *byte
+	ResponseInformation             string
+	ServerReference                 string
+	ReasonString                    string
+	ReceiveMaximum                  *uint16
+	TopicAliasMaximum               *uint16
+	TopicAlias                      *uint16
+	MaximumQoS                      *byte
+	RetainAvailable                 *byte
+	UserProperties                  []UserProperty
+	MaximumPacketSize               *uint32
+	WildcardSubscriptionAvailable   *byte
+	SubscriptionIdentifierAvailable *byte
+	SharedSubscriptionAvailable     *byte
+}
+
+// encode 编码属性（包含属性长度）
+func (p *Properties) encode(buf *bytes.Buffer) {
+	if p == nil {
+		writeVarInt(buf, 0)
+		return
+	}
+	var b bytes.Buffer
+	writeByteProp := func(id byte, v *byte) {
+		if v != nil {
+			b.WriteByte(id)
+			b.WriteByte(*v)
+		}
+	}
+	writeUint16Prop := func(id byte, v *uint16) {
+		if v != nil {
+			b.WriteByte(id)
+			writeUint16(&b, *v)
+		}
+	}
+	writeUint32Prop := func(id byte, v *uint32) {
+		if v != nil {
+			b.WriteByte(id)
+			writeUint32(&b, *v)
+		}
+	}
+	writeStringProp := func(id byte, v string) {
+		if v != "" {
+			b.WriteByte(id)
+			writeString(&b, v)
+		}
+	}
+	writeBinaryProp := func(id byte, v []byte) {
+		if v != nil {
+			b.WriteByte(id)
+			writeBinary(&b, v)
+		}
+	}
+
+	writeByteProp(PropPayloadFormatIndicator, p.PayloadFormatIndicator)
+	writeUint32Prop(PropMessageExpiryInterval, p.MessageExpiryInterval)
+	writeStringProp(PropContentType, p.ContentType)
+	writeStringProp(PropResponseTopic, p.ResponseTopic)
+	writeBinaryProp(PropCorrelationData, p.CorrelationData)
+	for _, id := range p.SubscriptionIdentifiers {
+		b.WriteByte(PropSubscriptionIdentifier)
+		writeVarInt(&b, id)
+	}
+	writeUint32Prop(PropSessionExpiryInterval, p.SessionExpiryInterval)
+	writeStringProp(PropAssignedClientIdentifier, p.AssignedClientIdentifier)
+	writeUint16Prop(PropServerKeepAlive, p.ServerKeepAlive)
+	writeStringProp(PropAuthenticationMethod, p.AuthenticationMethod)
+	writeBinaryProp(PropAuthenticationData, p.AuthenticationData)
+	writeByteProp(PropRequestProblemInformation, p.RequestProblemInformation)
+	writeUint32Prop(PropWillDelayInterval, p.WillDelayInterval)
+	writeByteProp(PropRequestResponseInformation, p.RequestResponseInformation)
+	writeStringProp(PropResponseInformation, p.ResponseInformation)
+	writeStringProp(PropServerReference, p.ServerReference)
+	writeStringProp(PropReasonString, p.ReasonString)
+	writeUint16Prop(PropReceiveMaximum, p.ReceiveMaximum)
+	writeUint16Prop(PropTopicAliasMaximum, p.TopicAliasMaximum)
+	writeUint16Prop(PropTopicAlias, p.TopicAlias)
+	writeByteProp(PropMaximumQoS, p.MaximumQoS)
+	writeByteProp(PropRetainAvailable, p.RetainAvailable)
+	for _, up := range p.UserProperties {
+		b.WriteByte(PropUserProperty)
+		writeString(&b, up.Key)
+		writeString(&b, up.Value)
+	}
+	writeUint32Prop(PropMaximumPacketSize, p.MaximumPacketSize)
+	writeByteProp(PropWildcardSubscriptionAvailable, p.WildcardSubscriptionAvailable)
+	writeByteProp(PropSubscriptionIdentifierAvailable, p.SubscriptionIdentifierAvailable)
+	writeByteProp(PropSharedSubscriptionAvailable, p.SharedSubscriptionAvailable)
+
+	writeVarInt(buf, uint32(b.Len()))
+	buf.Write(b.Bytes())
+}
+
+// decodeProperties 解码属性（包含属性长度），同一个属性（用户属性和订阅标识符除外）出现多次视为协议错误
+func decodeProperties(r io.Reader) (*Properties, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &Properties{}
+	if length == 0 {
+		return p, nil
+	}
+	data := make([]byte, length)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	pr := bytes.NewReader(data)
+
+	seen := make(map[byte]bool)
+	readBytePtr := func() (*byte, error) {
+		v, err := readByte(pr)
+		return &v, err
+	}
+	readUint16Ptr := func() (*uint16, error) {
+		v, err := readUint16(pr)
+		return &v, err
+	}
+	readUint32Ptr := func() (*uint32, error) {
+		v, err := readUint32(pr)
+		return &v, err
+	}
+	for pr.Len() > 0 {
+		id, err := readVarInt(pr)
+		if err != nil {
+			return nil, err
+		}
+		propId := byte(id)
+		if id > 0x7f {
+			return nil, ErrMalformedPacket
+		}
+		if propId != PropUserProperty && propId != PropSubscriptionIdentifier {
+			if seen[propId] {
+				return nil, ErrProtocolViolation
+			}
+			seen[propId] = true
+		}
+		switch propId {
+		case PropPayloadFormatIndicator:
+			p.PayloadFormatIndicator, err = readBytePtr()
+		case PropMessageExpiryInterval:
+			p.MessageExpiryInterval, err = readUint32Ptr()
+		case PropContentType:
+			p.ContentType, err = readString(pr)
+		case PropResponseTopic:
+			p.ResponseTopic, err = readString(pr)
+		case PropCorrelationData:
+			p.CorrelationData, err = readBinary(pr)
+		case PropSubscriptionIdentifier:
+			var v uint32
+			if v, err = readVarInt(pr); err == nil {
+				p.SubscriptionIdentifiers = append(p.SubscriptionIdentifiers, v)
+			}
+		case PropSessionExpiryInterval:
+			p.SessionExpiryInterval, err = readUint32Ptr()
+		case PropAssignedClientIdentifier:
+			p.AssignedClientIdentifier, err = readString(pr)
+		case PropServerKeepAlive:
+			p.ServerKeepAlive, err = readUint16Ptr()
+		case PropAuthenticationMethod:
+			p.AuthenticationMethod, err = readString(pr)
+		case PropAuthenticationData:
+			p.AuthenticationData, err = readBinary(pr)
+		case PropRequestProblemInformation:
+			p.RequestProblemInformation, err = readBytePtr()
+		case PropWillDelayInterval:
+			p.WillDelayInterval, err = readUint32Ptr()
+		case PropRequestResponseInformation:
+			p.RequestResponseInformation, err = readBytePtr()
+		case PropResponseInformation:
+			p.ResponseInformation, err = readString(pr)
+		case PropServerReference:
+			p.ServerReference, err = readString(pr)
+		case PropReasonString:
+			p.ReasonString, err = readString(pr)
+		case PropReceiveMaximum:
+			p.ReceiveMaximum, err = readUint16Ptr()
+		case PropTopicAliasMaximum:
+			p.TopicAliasMaximum, err = readUint16Ptr()
+		case PropTopicAlias:
+			p.TopicAlias, err = readUint16Ptr()
+		case PropMaximumQoS:
+			p.MaximumQoS, err = readBytePtr()
+		case PropRetainAvailable:
+			p.RetainAvailable, err = readBytePtr()
+		case PropUserProperty:
+			var up UserProperty
+			if up.Key, err = readString(pr); err == nil {
+				if up.Value, err = readString(pr); err == nil {
+					p.UserProperties = append(p.UserProperties, up)
+				}
+			}
+		case PropMaximumPacketSize:
+			p.MaximumPacketSize, err = readUint32Ptr()
+		case PropWildcardSubscriptionAvailable:
+			p.WildcardSubscriptionAvailable, err = readBytePtr()
+		case PropSubscriptionIdentifierAvailable:
+			p.SubscriptionIdentifierAvailable, err = readBytePtr()
+		case PropSharedSubscriptionAvailable:
+			p.SharedSubscriptionAvailable, err = readBytePtr()
+		default:
+			return nil, ErrMalformedPacket
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ByteProp 返回字节属性值的指针，用于设置属性
+func ByteProp(v byte) *byte {
+	return &v
+}
+
+// Uint16Prop 返回两字节整数属性值的指针，用于设置属性
+func Uint16Prop(v uint16) *uint16 {
+	return &v
+}
+
+// Uint32Prop 返回四字节整数属性值的指针，用于设置属性
+func Uint32Prop(v uint32) *uint32 {
+	return &v
+}
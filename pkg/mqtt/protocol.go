@@ -1,8 +1,134 @@
 package mqtt
 
-import "io"
+import (
+	"bytes"
+	"io"
+)
 
-func ReadFrom(r io.Reader) (ControlPacket, error) {
+// NewPacket 根据报文类型创建报文，version为连接协商的协议级别（CONNECT报文自带协议级别，传0即可）
+func NewPacket(t PacketType, version ProtocolVersion) (ControlPacket, error) {
+	switch t {
+	case CONNECT:
+		return &ConnectPacket{}, nil
+	case CONNACK:
+		return &ConnackPacket{ProtocolVersion: version}, nil
+	case PUBLISH:
+		return &PublishPacket{ProtocolVersion: version}, nil
+	case PUBACK, PUBREC, PUBREL, PUBCOMP:
+		return &PubackPacket{PacketType: t, ProtocolVersion: version}, nil
+	case SUBSCRIBE:
+		return &SubscribePacket{ProtocolVersion: version}, nil
+	case SUBACK:
+		return &SubackPacket{ProtocolVersion: version}, nil
+	case UNSUBSCRIBE:
+		return &UnsubscribePacket{ProtocolVersion: version}, nil
+	case UNSUBACK:
+		return &UnsubackPacket{ProtocolVersion: version}, nil
+	case PINGREQ:
+		return &PingreqPacket{}, nil
+	case PINGRESP:
+		return &PingrespPacket{}, nil
+	case DISCONNECT:
+		return &DisconnectPacket{ProtocolVersion: version}, nil
+	case AUTH:
+		if version != Version5 {
+			return nil, ErrUnknownPacketType
+		}
+		return &AuthPacket{}, nil
+	}
+	return nil, ErrUnknownPacketType
+}
+
+// ReadFrom 从r中读取一个完整的报文
+func ReadFrom(r io.Reader, version ProtocolVersion) (ControlPacket, error) {
+	first, err := readByte(r)
+	if err != nil {
+		return nil, err
+	}
+	remainingLength, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	header := FixedHeader{
+		Type:            PacketType(first >> 4),
+		Flags:           first & 0x0f,
+		RemainingLength: remainingLength,
+	}
+	body := make([]byte, remainingLength)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return decodePacket(header, body, version)
+}
+
+// Decode 从data中解码一个报文，返回报文和报文占用的字节数
+// data中的数据不足一个完整的报文时返回(nil, 0, nil)，需要等待更多的数据
+func Decode(data []byte, version ProtocolVersion) (ControlPacket, int, error) {
+	header, headerLen, err := decodeFixedHeader(data)
+	if err != nil || headerLen == 0 {
+		return nil, 0, err
+	}
+	size := headerLen + int(header.RemainingLength)
+	if len(data) < size {
+		return nil, 0, nil
+	}
+	packet, err := decodePacket(header, data[headerLen:size], version)
+	if err != nil {
+		return nil, 0, err
+	}
+	return packet, size, nil
+}
+
+// Encode 编码报文
+func Encode(packet ControlPacket) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := packet.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeFixedHeader 解码固定报头，数据不足时headerLen返回0
+func decodeFixedHeader(data []byte) (FixedHeader, int, error) {
+	if len(data) < 2 {
+		return FixedHeader{}, 0, nil
+	}
+	var (
+		remainingLength uint32
+		multiplier      uint32 = 1
+	)
+	for i := 1; i <= 4; i++ {
+		if i >= len(data) {
+			return FixedHeader{}, 0, nil
+		}
+		digit := data[i]
+		remainingLength += uint32(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			return FixedHeader{
+				Type:            PacketType(data[0] >> 4),
+				Flags:           data[0] & 0x0f,
+				RemainingLength: remainingLength,
+			}, i + 1, nil
+		}
+		multiplier *= 128
+	}
+	return FixedHeader{}, 0, ErrMalformedPacket
+}
 
-	return nil, nil
+func decodePacket(header FixedHeader, body []byte, version ProtocolVersion) (ControlPacket, error) {
+	if header.Type != PUBLISH && header.Flags != fixedFlags(header.Type) {
+		return nil, ErrMalformedPacket
+	}
+	packet, err := NewPacket(header.Type, version)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(body)
+	if err = packet.Decode(r, header); err != nil {
+		return packet, err
+	}
+	if r.Len() > 0 { // 剩余长度与内容不符
+		return nil, ErrMalformedPacket
+	}
+	return packet, nil
 }
@@ -0,0 +1,180 @@
+package mqtt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeDecode(t *testing.T, packet ControlPacket, version ProtocolVersion) ControlPacket {
+	data, err := Encode(packet)
+	assert.NoError(t, err)
+
+	result, size, err := Decode(data, version)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), size)
+
+	// 数据不足一个报文时需要等待更多数据
+	if len(data) > 2 {
+		partial, size, err := Decode(data[:len(data)-1], version)
+		assert.NoError(t, err)
+		assert.Nil(t, partial)
+		assert.Equal(t, 0, size)
+	}
+
+	readResult, err := ReadFrom(bytes.NewReader(data), version)
+	assert.NoError(t, err)
+	assert.Equal(t, result, readResult)
+	return result
+}
+
+func TestConnect(t *testing.T) {
+	packet := &ConnectPacket{
+		ProtocolName:    "MQTT",
+		ProtocolVersion: Version311,
+		CleanStart:      true,
+		KeepAlive:       60,
+		ClientID:        "device1",
+		WillFlag:        true,
+		WillQoS:         QoS1,
+		WillTopic:       "will/topic",
+		WillPayload:     []byte("bye"),
+		UsernameFlag:    true,
+		Username:        "u1",
+		PasswordFlag:    true,
+		Password:        []byte("token"),
+	}
+	assert.Equal(t, packet, encodeDecode(t, packet, 0))
+
+	packet5 := &ConnectPacket{
+		ProtocolName:    "MQTT",
+		ProtocolVersion: Version5,
+		KeepAlive:       30,
+		Properties: &Properties{
+			SessionExpiryInterval: Uint32Prop(120),
+			ReceiveMaximum:        Uint16Prop(10),
+			UserProperties:        []UserProperty{{Key: "k", Value: "v"}},
+		},
+		ClientID:     "device2",
+		UsernameFlag: true,
+		Username:     "u2",
+	}
+	assert.Equal(t, packet5, encodeDecode(t, packet5, 0))
+}
+
+func TestConnectUnsupportedVersion(t *testing.T) {
+	data, err := Encode(&ConnectPacket{ProtocolName: "MQTT", ProtocolVersion: 6, ClientID: "c"})
+	assert.NoError(t, err)
+	_, _, err = Decode(data, 0)
+	assert.Equal(t, ErrUnsupportedVersion, err)
+}
+
+func TestConnack(t *testing.T) {
+	packet := &ConnackPacket{ProtocolVersion: Version311, SessionPresent: true, ReturnCode: ConnRefusedNotAuthorized}
+	assert.Equal(t, packet, encodeDecode(t, packet, Version311))
+
+	packet5 := &ConnackPacket{ProtocolVersion: Version5, ReasonCode: Success, Properties: &Properties{MaximumQoS: ByteProp(1), RetainAvailable: ByteProp(0)}}
+	assert.Equal(t, packet5, encodeDecode(t, packet5, Version5))
+}
+
+func TestPublish(t *testing.T) {
+	packet := &PublishPacket{ProtocolVersion: Version311, QoS: QoS1, Dup: true, TopicName: "channel/2/g1", PacketID: 10, Payload: []byte("hello")}
+	assert.Equal(t, packet, encodeDecode(t, packet, Version311))
+
+	packet0 := &PublishPacket{ProtocolVersion: Version311, TopicName: "a/b", Payload: []byte{}}
+	assert.Equal(t, packet0, encodeDecode(t, packet0, Version311))
+
+	packet5 := &PublishPacket{ProtocolVersion: Version5, QoS: QoS2, Retain: true, TopicName: "a/b", PacketID: 1, Properties: &Properties{ContentType: "text/plain"}, Payload: []byte("x")}
+	assert.Equal(t, packet5, encodeDecode(t, packet5, Version5))
+
+	// QoS为3是非法的
+	_, _, err := Decode([]byte{byte(PUBLISH)<<4 | 0x06, 0x05, 0x00, 0x01, 'a', 0x00, 0x01}, Version311)
+	assert.Equal(t, ErrMalformedPacket, err)
+}
+
+func TestPuback(t *testing.T) {
+	for _, packetType := range []PacketType{PUBACK, PUBREC, PUBREL, PUBCOMP} {
+		packet := &PubackPacket{PacketType: packetType, ProtocolVersion: Version311, PacketID: 7}
+		assert.Equal(t, packet, encodeDecode(t, packet, Version311))
+
+		packet5 := &PubackPacket{PacketType: packetType, ProtocolVersion: Version5, PacketID: 7, ReasonCode: NotAuthorized}
+		assert.Equal(t, packet5, encodeDecode(t, packet5, Version5))
+	}
+	// 5.0中原因码为0且没有属性时省略原因码
+	data, err := Encode(&PubackPacket{PacketType: PUBACK, ProtocolVersion: Version5, PacketID: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x40, 0x02, 0x00, 0x07}, data)
+}
+
+func TestSubscribe(t *testing.T) {
+	packet := &SubscribePacket{ProtocolVersion: Version311, PacketID: 3, Subscriptions: []Subscription{{TopicFilter: "channel/2/+", QoS: QoS1}, {TopicFilter: "#"}}}
+	assert.Equal(t, packet, encodeDecode(t, packet, Version311))
+
+	packet5 := &SubscribePacket{ProtocolVersion: Version5, PacketID: 3, Properties: &Properties{SubscriptionIdentifiers: []uint32{300}}, Subscriptions: []Subscription{{TopicFilter: "a/#", QoS: QoS2, NoLocal: true, RetainHandling: 2}}}
+	assert.Equal(t, packet5, encodeDecode(t, packet5, Version5))
+
+	suback := &SubackPacket{ProtocolVersion: Version311, PacketID: 3, ReasonCodes: []ReasonCode{GrantedQoS1, ReasonCode(SubackFailure)}}
+	assert.Equal(t, suback, encodeDecode(t, suback, Version311))
+
+	// 没有订阅的SUBSCRIBE是非法的
+	_, _, err := Decode([]byte{byte(SUBSCRIBE)<<4 | 0x02, 0x02, 0x00, 0x01}, Version311)
+	assert.Equal(t, ErrProtocolViolation, err)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	packet := &UnsubscribePacket{ProtocolVersion: Version311, PacketID: 4, TopicFilters: []string{"a/b", "c/#"}}
+	assert.Equal(t, packet, encodeDecode(t, packet, Version311))
+
+	unsuback := &UnsubackPacket{ProtocolVersion: Version5, PacketID: 4, Properties: &Properties{}, ReasonCodes: []ReasonCode{Success, NoSubscriptionExisted}}
+	assert.Equal(t, unsuback, encodeDecode(t, unsuback, Version5))
+}
+
+func TestPingAndDisconnect(t *testing.T) {
+	assert.Equal(t, &PingreqPacket{}, encodeDecode(t, &PingreqPacket{}, Version311))
+	assert.Equal(t, &PingrespPacket{}, encodeDecode(t, &PingrespPacket{}, Version311))
+
+	data, err := Encode(&DisconnectPacket{ProtocolVersion: Version311})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xe0, 0x00}, data)
+
+	disconnect := &DisconnectPacket{ProtocolVersion: Version5, ReasonCode: SessionTakenOver, Properties: &Properties{ReasonString: "kicked"}}
+	assert.Equal(t, disconnect, encodeDecode(t, disconnect, Version5))
+}
+
+func TestDecodeMultiplePackets(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, (&PingreqPacket{}).Encode(&buf))
+	assert.NoError(t, (&PublishPacket{ProtocolVersion: Version311, TopicName: "a", Payload: make([]byte, 200)}).Encode(&buf))
+	data := buf.Bytes()
+
+	packet, size, err := Decode(data, Version311)
+	assert.NoError(t, err)
+	assert.Equal(t, PINGREQ, packet.Type())
+
+	packet, size2, err := Decode(data[size:], Version311)
+	assert.NoError(t, err)
+	assert.Equal(t, PUBLISH, packet.Type())
+	assert.Equal(t, 200, len(packet.(*PublishPacket).Payload))
+	assert.Equal(t, len(data), size+size2)
+}
+
+func TestTopic(t *testing.T) {
+	assert.True(t, ValidTopicName("channel/2/g1"))
+	assert.False(t, ValidTopicName("channel/+/g1"))
+	assert.False(t, ValidTopicName(""))
+
+	assert.True(t, ValidTopicFilter("channel/+/g1"))
+	assert.True(t, ValidTopicFilter("channel/#"))
+	assert.True(t, ValidTopicFilter("#"))
+	assert.False(t, ValidTopicFilter("channel/#/g1"))
+	assert.False(t, ValidTopicFilter("channel/a+"))
+
+	assert.True(t, TopicMatch("channel/+/g1", "channel/2/g1"))
+	assert.True(t, TopicMatch("channel/#", "channel/2/g1"))
+	assert.True(t, TopicMatch("channel/#", "channel"))
+	assert.True(t, TopicMatch("#", "channel/2/g1"))
+	assert.False(t, TopicMatch("channel/+", "channel/2/g1"))
+	assert.False(t, TopicMatch("channel/2/g2", "channel/2/g1"))
+	assert.False(t, TopicMatch("#", "$SYS/uptime"))
+}
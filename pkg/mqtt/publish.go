@@ -0,0 +1,132 @@
+package mqtt
+
+import (
+	"bytes"
+	"io"
+)
+
+// PublishPacket 发布消息报文
+type PublishPacket struct {
+	ProtocolVersion ProtocolVersion
+	Dup             bool
+	QoS             QoS
+	Retain          bool
+	TopicName       string
+	PacketID        uint16      // QoS大于0时才有
+	Properties      *Properties // 仅5.0
+	Payload         []byte
+}
+
+func (p *PublishPacket) Type() PacketType {
+	return PUBLISH
+}
+
+func (p *PublishPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeString(&buf, p.TopicName)
+	if p.QoS > QoS0 {
+		writeUint16(&buf, p.PacketID)
+	}
+	if p.ProtocolVersion == Version5 {
+		p.Properties.encode(&buf)
+	}
+	buf.Write(p.Payload)
+
+	var flags byte
+	if p.Dup {
+		flags |= 0x08
+	}
+	flags |= byte(p.QoS&0x03) << 1
+	if p.Retain {
+		flags |= 0x01
+	}
+	return writePacket(w, PUBLISH, flags, buf.Bytes())
+}
+
+func (p *PublishPacket) Decode(r io.Reader, header FixedHeader) error {
+	p.Dup = header.Flags&0x08 != 0
+	p.QoS = QoS(header.Flags>>1) & 0x03
+	p.Retain = header.Flags&0x01 != 0
+	if p.QoS > QoS2 {
+		return ErrMalformedPacket
+	}
+	if p.QoS == QoS0 && p.Dup {
+		return ErrMalformedPacket
+	}
+	var err error
+	if p.TopicName, err = readString(r); err != nil {
+		return err
+	}
+	if p.QoS > QoS0 {
+		if p.PacketID, err = readUint16(r); err != nil {
+			return err
+		}
+		if p.PacketID == 0 {
+			return ErrMalformedPacket
+		}
+	}
+	if p.ProtocolVersion == Version5 {
+		if p.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+	}
+	if p.Payload, err = io.ReadAll(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PubackPacket 发布确认报文，PUBACK、PUBREC、PUBREL、PUBCOMP的格式相同
+type PubackPacket struct {
+	PacketType      PacketType // PUBACK、PUBREC、PUBREL、PUBCOMP
+	ProtocolVersion ProtocolVersion
+	PacketID        uint16
+	ReasonCode      ReasonCode  // 仅5.0
+	Properties      *Properties // 仅5.0
+}
+
+func (p *PubackPacket) Type() PacketType {
+	return p.PacketType
+}
+
+func (p *PubackPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeUint16(&buf, p.PacketID)
+	if p.ProtocolVersion == Version5 {
+		// 原因码为0且没有属性时可以省略
+		hasProperties := p.Properties != nil && !isEmptyProperties(p.Properties)
+		if p.ReasonCode != Success || hasProperties {
+			buf.WriteByte(byte(p.ReasonCode))
+			if hasProperties {
+				p.Properties.encode(&buf)
+			}
+		}
+	}
+	return writePacket(w, p.PacketType, fixedFlags(p.PacketType), buf.Bytes())
+}
+
+func (p *PubackPacket) Decode(r io.Reader, header FixedHeader) error {
+	var err error
+	if p.PacketID, err = readUint16(r); err != nil {
+		return err
+	}
+	if p.ProtocolVersion == Version5 && header.RemainingLength > 2 {
+		code, err := readByte(r)
+		if err != nil {
+			return err
+		}
+		p.ReasonCode = ReasonCode(code)
+		if header.RemainingLength > 3 {
+			if p.Properties, err = decodeProperties(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isEmptyProperties(p *Properties) bool {
+	var buf bytes.Buffer
+	p.encode(&buf)
+	return buf.Len() == 1
+}
@@ -0,0 +1,252 @@
+package mqtt
+
+import (
+	"bytes"
+	"io"
+)
+
+// Subscription 订阅的主题过滤器和订阅选项
+type Subscription struct {
+	TopicFilter       string
+	QoS               QoS
+	NoLocal           bool // 仅5.0
+	RetainAsPublished bool // 仅5.0
+	RetainHandling    byte // 仅5.0
+}
+
+// SubscribePacket 订阅报文
+type SubscribePacket struct {
+	ProtocolVersion ProtocolVersion
+	PacketID        uint16
+	Properties      *Properties // 仅5.0
+	Subscriptions   []Subscription
+}
+
+func (s *SubscribePacket) Type() PacketType {
+	return SUBSCRIBE
+}
+
+func (s *SubscribePacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeUint16(&buf, s.PacketID)
+	if s.ProtocolVersion == Version5 {
+		s.Properties.encode(&buf)
+	}
+	for _, sub := range s.Subscriptions {
+		writeString(&buf, sub.TopicFilter)
+		options := byte(sub.QoS & 0x03)
+		if s.ProtocolVersion == Version5 {
+			if sub.NoLocal {
+				options |= 0x04
+			}
+			if sub.RetainAsPublished {
+				options |= 0x08
+			}
+			options |= (sub.RetainHandling & 0x03) << 4
+		}
+		buf.WriteByte(options)
+	}
+	return writePacket(w, SUBSCRIBE, fixedFlags(SUBSCRIBE), buf.Bytes())
+}
+
+func (s *SubscribePacket) Decode(r io.Reader, header FixedHeader) error {
+	if header.Flags != fixedFlags(SUBSCRIBE) {
+		return ErrMalformedPacket
+	}
+	var err error
+	if s.PacketID, err = readUint16(r); err != nil {
+		return err
+	}
+	if s.PacketID == 0 {
+		return ErrMalformedPacket
+	}
+	if s.ProtocolVersion == Version5 {
+		if s.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 { // 至少要有一个订阅
+		return ErrProtocolViolation
+	}
+	pr := bytes.NewReader(payload)
+	for pr.Len() > 0 {
+		topicFilter, err := readString(pr)
+		if err != nil {
+			return err
+		}
+		options, err := readByte(pr)
+		if err != nil {
+			return err
+		}
+		sub := Subscription{
+			TopicFilter: topicFilter,
+			QoS:         QoS(options & 0x03),
+		}
+		if sub.QoS > QoS2 {
+			return ErrMalformedPacket
+		}
+		if s.ProtocolVersion == Version5 {
+			sub.NoLocal = options&0x04 != 0
+			sub.RetainAsPublished = options&0x08 != 0
+			sub.RetainHandling = (options >> 4) & 0x03
+			if sub.RetainHandling > 2 || options&0xc0 != 0 {
+				return ErrMalformedPacket
+			}
+		} else if options&0xfc != 0 {
+			return ErrMalformedPacket
+		}
+		s.Subscriptions = append(s.Subscriptions, sub)
+	}
+	return nil
+}
+
+// SubackPacket 订阅确认报文
+type SubackPacket struct {
+	ProtocolVersion ProtocolVersion
+	PacketID        uint16
+	Properties      *Properties // 仅5.0
+	// ReasonCodes 与订阅一一对应，3.1.1中为授予的QoS（0、1、2）或SubackFailure
+	ReasonCodes []ReasonCode
+}
+
+func (s *SubackPacket) Type() PacketType {
+	return SUBACK
+}
+
+func (s *SubackPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeUint16(&buf, s.PacketID)
+	if s.ProtocolVersion == Version5 {
+		s.Properties.encode(&buf)
+	}
+	for _, code := range s.ReasonCodes {
+		buf.WriteByte(byte(code))
+	}
+	return writePacket(w, SUBACK, 0, buf.Bytes())
+}
+
+func (s *SubackPacket) Decode(r io.Reader, _ FixedHeader) error {
+	var err error
+	if s.PacketID, err = readUint16(r); err != nil {
+		return err
+	}
+	if s.ProtocolVersion == Version5 {
+		if s.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+	}
+	codes, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		s.ReasonCodes = append(s.ReasonCodes, ReasonCode(code))
+	}
+	return nil
+}
+
+// UnsubscribePacket 取消订阅报文
+type UnsubscribePacket struct {
+	ProtocolVersion ProtocolVersion
+	PacketID        uint16
+	Properties      *Properties // 仅5.0
+	TopicFilters    []string
+}
+
+func (u *UnsubscribePacket) Type() PacketType {
+	return UNSUBSCRIBE
+}
+
+func (u *UnsubscribePacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeUint16(&buf, u.PacketID)
+	if u.ProtocolVersion == Version5 {
+		u.Properties.encode(&buf)
+	}
+	for _, topicFilter := range u.TopicFilters {
+		writeString(&buf, topicFilter)
+	}
+	return writePacket(w, UNSUBSCRIBE, fixedFlags(UNSUBSCRIBE), buf.Bytes())
+}
+
+func (u *UnsubscribePacket) Decode(r io.Reader, header FixedHeader) error {
+	if header.Flags != fixedFlags(UNSUBSCRIBE) {
+		return ErrMalformedPacket
+	}
+	var err error
+	if u.PacketID, err = readUint16(r); err != nil {
+		return err
+	}
+	if u.PacketID == 0 {
+		return ErrMalformedPacket
+	}
+	if u.ProtocolVersion == Version5 {
+		if u.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 { // 至少要有一个主题过滤器
+		return ErrProtocolViolation
+	}
+	pr := bytes.NewReader(payload)
+	for pr.Len() > 0 {
+		topicFilter, err := readString(pr)
+		if err != nil {
+			return err
+		}
+		u.TopicFilters = append(u.TopicFilters, topicFilter)
+	}
+	return nil
+}
+
+// UnsubackPacket 取消订阅确认报文
+type UnsubackPacket struct {
+	ProtocolVersion ProtocolVersion
+	PacketID        uint16
+	Properties      *Properties  // 仅5.0
+	ReasonCodes     []ReasonCode // 仅5.0，与取消的订阅一一对应
+}
+
+func (u *UnsubackPacket) Type() PacketType {
+	return UNSUBACK
+}
+
+func (u *UnsubackPacket) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+	writeUint16(&buf, u.PacketID)
+	if u.ProtocolVersion == Version5 {
+		u.Properties.encode(&buf)
+		for _, code := range u.ReasonCodes {
+			buf.WriteByte(byte(code))
+		}
+	}
+	return writePacket(w, UNSUBACK, 0, buf.Bytes())
+}
+
+func (u *UnsubackPacket) Decode(r io.Reader, _ FixedHeader) error {
+	var err error
+	if u.PacketID, err = readUint16(r); err != nil {
+		return err
+	}
+	if u.ProtocolVersion == Version5 {
+		if u.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+		codes, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		for _, code := range codes {
+			u.ReasonCodes = append(u.ReasonCodes, ReasonCode(code))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mqtt
+
+import "strings"
+
+const maxTopicLength = 65535
+
+// ValidTopicName 发布消息的主题名是否合法（不能为空，不能包含通配符）
+func ValidTopicName(topic string) bool {
+	if topic == "" || len(topic) > maxTopicLength {
+		return false
+	}
+	return !strings.ContainsAny(topic, "+#")
+}
+
+// ValidTopicFilter 订阅的主题过滤器是否合法
+// +只能单独占用一个层级，#只能单独占用最后一个层级
+func ValidTopicFilter(filter string) bool {
+	if filter == "" || len(filter) > maxTopicLength {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") {
+			if level != "#" || i != len(levels)-1 {
+				return false
+			}
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// TopicMatch 主题名是否匹配主题过滤器
+// 以$开头的主题不能被以通配符开头的过滤器匹配
+func TopicMatch(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true // #匹配父级和任意数量的子层级
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
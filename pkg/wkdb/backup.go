@@ -0,0 +1,19 @@
+package wkdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Checkpoint 将所有分区的快照写入到dir目录，目录结构与数据目录一致（dir/wukongimdb/shardxxx）
+// 每个分区的快照是一致的，同一个频道的数据都在同一个分区内
+func (wk *wukongDB) Checkpoint(dir string) error {
+	for i, db := range wk.dbs {
+		if err := db.Checkpoint(filepath.Join(dir, "wukongimdb", fmt.Sprintf("shard%03d", i)), pebble.WithFlushedWAL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
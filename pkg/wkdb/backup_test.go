@@ -0,0 +1,53 @@
+package wkdb_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   1,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  1,
+				Payload:     []byte("hello"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	err = d.Checkpoint(dir)
+	assert.NoError(t, err)
+
+	// 已存在的目录不能再次备份
+	err = d.Checkpoint(dir)
+	assert.Error(t, err)
+
+	restored := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err = restored.Open()
+	assert.NoError(t, err)
+	defer restored.Close()
+
+	msg, err := restored.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg.Payload)
+}
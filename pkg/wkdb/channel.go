@@ -425,6 +425,26 @@ func (wk *wukongDB) DeleteChannel(channelId string, channelType uint8) error {
 		return err
 	}
 
+	// 删除置顶消息
+	err = batch.DeleteRange(key.NewPinnedMessageKey(channelId, channelType, 0), key.NewPinnedMessageKey(channelId, channelType, math.MaxUint64), wk.noSync)
+	if err != nil {
+		return err
+	}
+
+	// 删除未发送的定时消息
+	scheduledMessages, err := wk.GetScheduledMessages(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	for _, m := range scheduledMessages {
+		if err = batch.Delete(key.NewScheduledMessageKey(channelId, channelType, m.Id), wk.noSync); err != nil {
+			return err
+		}
+		if err = batch.Delete(key.NewScheduledMessageSendAtKey(uint64(m.SendAt), channelId, channelType, m.Id), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	err = wk.IncChannelCount(-1)
 	if err != nil {
 		return err
@@ -536,6 +556,20 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 		return err
 	}
 
+	// mute
+	muteBytes := make([]byte, 1)
+	muteBytes[0] = wkutil.BoolToUint8(channelInfo.Mute)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.Mute), muteBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// muteUntil
+	muteUntilBytes := make([]byte, 8)
+	wk.endian.PutUint64(muteUntilBytes, uint64(channelInfo.MuteUntil))
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.MuteUntil), muteUntilBytes, wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if channelInfo.CreatedAt != nil {
 		ct := uint64(channelInfo.CreatedAt.UnixNano())
@@ -688,6 +722,10 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 			preChannelInfo.Large = wkutil.Uint8ToBool(iter.Value()[0])
 		case key.TableChannelInfo.Column.Disband:
 			preChannelInfo.Disband = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelInfo.Column.Mute:
+			preChannelInfo.Mute = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelInfo.Column.MuteUntil:
+			preChannelInfo.MuteUntil = int64(wk.endian.Uint64(iter.Value()))
 		case key.TableChannelInfo.Column.SubscriberCount:
 			preChannelInfo.SubscriberCount = int(wk.endian.Uint32(iter.Value()))
 		case key.TableChannelInfo.Column.AllowlistCount:
@@ -87,6 +87,46 @@ func TestUpdateChannel(t *testing.T) {
 	assert.Equal(t, channelInfo.UpdatedAt.Unix(), channelInfo2.UpdatedAt.Unix())
 }
 
+func TestChannelMute(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+	nw := time.Now()
+	channelInfo := wkdb.ChannelInfo{
+		ChannelId:   "channel1",
+		ChannelType: 2,
+		Mute:        true,
+		MuteUntil:   nw.Add(time.Hour).Unix(),
+		CreatedAt:   &nw,
+		UpdatedAt:   &nw,
+	}
+	_, err = d.AddChannel(channelInfo)
+	assert.NoError(t, err)
+
+	channelInfo2, err := d.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
+	assert.NoError(t, err)
+	assert.True(t, channelInfo2.Mute)
+	assert.Equal(t, channelInfo.MuteUntil, channelInfo2.MuteUntil)
+	assert.True(t, channelInfo2.IsMuted(nw.Unix()))
+	assert.False(t, channelInfo2.IsMuted(nw.Add(time.Hour*2).Unix()))
+
+	// 解除禁言
+	channelInfo.Mute = false
+	channelInfo.MuteUntil = 0
+	err = d.UpdateChannel(channelInfo)
+	assert.NoError(t, err)
+
+	channelInfo2, err = d.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
+	assert.NoError(t, err)
+	assert.False(t, channelInfo2.Mute)
+	assert.False(t, channelInfo2.IsMuted(nw.Unix()))
+}
+
 func TestExistChannel(t *testing.T) {
 	d := newTestDB(t)
 	err := d.Open()
@@ -41,6 +41,16 @@ func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Convers
 				return err
 			}
 			cn.Id = oldConversation.Id
+			// 清空记录的位置只能往后移
+			if oldConversation.ClearedToMsgSeq > cn.ClearedToMsgSeq {
+				cn.ClearedToMsgSeq = oldConversation.ClearedToMsgSeq
+			}
+			// 会话属性只能通过UpdateConversationAttrs修改
+			cn.Pinned = oldConversation.Pinned
+			cn.MutedUntil = oldConversation.MutedUntil
+			cn.Folder = oldConversation.Folder
+			cn.Draft = oldConversation.Draft
+			cn.Version = oldConversation.Version
 		}
 
 		if exist {
@@ -436,6 +446,16 @@ func (wk *wukongDB) writeConversation(conversation Conversation, w *Batch) error
 	wk.endian.PutUint64(msgSeqBytes, conversation.ReadToMsgSeq)
 	w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.ReadedToMsgSeq), msgSeqBytes)
 
+	// clearedToMsgSeq
+	if conversation.ClearedToMsgSeq > 0 {
+		var clearedSeqBytes = make([]byte, 8)
+		wk.endian.PutUint64(clearedSeqBytes, conversation.ClearedToMsgSeq)
+		w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.ClearedToMsgSeq), clearedSeqBytes)
+	}
+
+	// 会话属性
+	wk.writeConversationAttrs(conversation, w)
+
 	// createdAt
 	if conversation.CreatedAt != nil {
 		createdAtBytes := make([]byte, 8)
@@ -460,6 +480,51 @@ func (wk *wukongDB) writeConversation(conversation Conversation, w *Batch) error
 	return nil
 }
 
+// 写入会话属性，属性为空时删除对应的列
+func (wk *wukongDB) writeConversationAttrs(conversation Conversation, w *Batch) {
+	uid := conversation.Uid
+	id := conversation.Id
+
+	pinnedKey := key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Pinned)
+	if conversation.Pinned {
+		w.Set(pinnedKey, []byte{1})
+	} else {
+		w.Delete(pinnedKey)
+	}
+
+	mutedUntilKey := key.NewConversationColumnKey(uid, id, key.TableConversation.Column.MutedUntil)
+	if conversation.MutedUntil != 0 {
+		mutedUntilBytes := make([]byte, 8)
+		wk.endian.PutUint64(mutedUntilBytes, uint64(conversation.MutedUntil))
+		w.Set(mutedUntilKey, mutedUntilBytes)
+	} else {
+		w.Delete(mutedUntilKey)
+	}
+
+	folderKey := key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Folder)
+	if conversation.Folder != "" {
+		w.Set(folderKey, []byte(conversation.Folder))
+	} else {
+		w.Delete(folderKey)
+	}
+
+	draftKey := key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Draft)
+	if conversation.Draft != "" {
+		w.Set(draftKey, []byte(conversation.Draft))
+	} else {
+		w.Delete(draftKey)
+	}
+
+	versionKey := key.NewConversationColumnKey(uid, id, key.TableConversation.Column.Version)
+	if conversation.Version > 0 {
+		versionBytes := make([]byte, 8)
+		wk.endian.PutUint64(versionBytes, conversation.Version)
+		w.Set(versionKey, versionBytes)
+	} else {
+		w.Delete(versionKey)
+	}
+}
+
 func (wk *wukongDB) writeConversationIndex(conversation Conversation, w *Batch) error {
 
 	idBytes := make([]byte, 8)
@@ -544,6 +609,18 @@ func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conv
 			preConversation.UnreadCount = wk.endian.Uint32(iter.Value())
 		case key.TableConversation.Column.ReadedToMsgSeq:
 			preConversation.ReadToMsgSeq = wk.endian.Uint64(iter.Value())
+		case key.TableConversation.Column.ClearedToMsgSeq:
+			preConversation.ClearedToMsgSeq = wk.endian.Uint64(iter.Value())
+		case key.TableConversation.Column.Pinned:
+			preConversation.Pinned = iter.Value()[0] == 1
+		case key.TableConversation.Column.MutedUntil:
+			preConversation.MutedUntil = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableConversation.Column.Folder:
+			preConversation.Folder = string(iter.Value())
+		case key.TableConversation.Column.Draft:
+			preConversation.Draft = string(iter.Value())
+		case key.TableConversation.Column.Version:
+			preConversation.Version = wk.endian.Uint64(iter.Value())
 		case key.TableConversation.Column.CreatedAt:
 			tm := int64(wk.endian.Uint64(iter.Value()))
 			if tm > 0 {
@@ -616,3 +693,56 @@ func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conv
 
 // 	return conversations, nil
 // }
+
+// UpdateConversationAttrs 修改会话属性并递增用户的会话版本，会话不存在时使用id新建会话
+func (wk *wukongDB) UpdateConversationAttrs(uid string, id uint64, attrs ConversationAttrs) error {
+	conversation, err := wk.GetConversation(uid, attrs.ChannelId, attrs.ChannelType)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	version, err := wk.GetConversationVersion(uid)
+	if err != nil {
+		return err
+	}
+	version++
+
+	batch := wk.sharedBatchDB(uid).NewBatch()
+
+	exist := !IsEmptyConversation(conversation)
+	if !exist {
+		conversation = Conversation{
+			Id:          id,
+			Uid:         uid,
+			Type:        ConversationTypeChat,
+			ChannelId:   attrs.ChannelId,
+			ChannelType: attrs.ChannelType,
+		}
+	}
+	attrs.apply(&conversation)
+	conversation.Version = version
+
+	if exist {
+		wk.writeConversationAttrs(conversation, batch)
+	} else if err = wk.writeConversation(conversation, batch); err != nil {
+		return err
+	}
+
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, version)
+	batch.Set(key.NewConversationVersionKey(uid), versionBytes)
+
+	return batch.CommitWait()
+}
+
+// GetConversationVersion 获取用户的会话版本，每次修改会话属性都会递增
+func (wk *wukongDB) GetConversationVersion(uid string) (uint64, error) {
+	value, closer, err := wk.shardDB(uid).Get(key.NewConversationVersionKey(uid))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return wk.endian.Uint64(value), nil
+}
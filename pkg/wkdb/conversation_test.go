@@ -195,3 +195,140 @@ func TestDeleteConversation(t *testing.T) {
 // 	assert.Equal(t, conversations[0], conversations2[0])
 // 	assert.Equal(t, conversations[1], conversations2[1])
 // }
+
+func TestConversationClearedToMsgSeq(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	createdAt := time.Now()
+	updatedAt := time.Now()
+	conversation := wkdb.Conversation{
+		Id:              1,
+		Uid:             uid,
+		ChannelId:       "1234",
+		ChannelType:     2,
+		ReadToMsgSeq:    10,
+		ClearedToMsgSeq: 10,
+		CreatedAt:       &createdAt,
+		UpdatedAt:       &updatedAt,
+	}
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{conversation})
+	assert.NoError(t, err)
+
+	result, err := d.GetConversation(uid, conversation.ChannelId, conversation.ChannelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), result.ClearedToMsgSeq)
+
+	// 更新会话时不带清空位置，清空位置不能回退
+	conversation.ClearedToMsgSeq = 0
+	conversation.ReadToMsgSeq = 20
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{conversation})
+	assert.NoError(t, err)
+
+	result, err = d.GetConversation(uid, conversation.ChannelId, conversation.ChannelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), result.ReadToMsgSeq)
+	assert.Equal(t, uint64(10), result.ClearedToMsgSeq)
+
+	// 编解码
+	data, err := result.Marshal()
+	assert.NoError(t, err)
+	decoded := wkdb.Conversation{}
+	err = decoded.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), decoded.ClearedToMsgSeq)
+}
+
+func TestConversationAttrs(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	pinned := true
+	folder := "work"
+
+	// 会话不存在时新建
+	err = d.UpdateConversationAttrs(uid, 1, wkdb.ConversationAttrs{
+		ChannelId:   "1234",
+		ChannelType: 2,
+		Pinned:      &pinned,
+		Folder:      &folder,
+	})
+	assert.NoError(t, err)
+
+	result, err := d.GetConversation(uid, "1234", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Id)
+	assert.True(t, result.Pinned)
+	assert.Equal(t, "work", result.Folder)
+	assert.Equal(t, uint64(1), result.Version)
+
+	// 普通的会话更新不会覆盖属性
+	createdAt := time.Now()
+	updatedAt := time.Now()
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Id:           2,
+			Uid:          uid,
+			ChannelId:    "1234",
+			ChannelType:  2,
+			ReadToMsgSeq: 10,
+			CreatedAt:    &createdAt,
+			UpdatedAt:    &updatedAt,
+		},
+	})
+	assert.NoError(t, err)
+	result, err = d.GetConversation(uid, "1234", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), result.ReadToMsgSeq)
+	assert.True(t, result.Pinned)
+	assert.Equal(t, "work", result.Folder)
+
+	// 只修改传入的属性
+	pinned = false
+	mutedUntil := wkdb.ConversationMuteForever
+	draft := "hello"
+	err = d.UpdateConversationAttrs(uid, 3, wkdb.ConversationAttrs{
+		ChannelId:   "1234",
+		ChannelType: 2,
+		Pinned:      &pinned,
+		MutedUntil:  &mutedUntil,
+		Draft:       &draft,
+	})
+	assert.NoError(t, err)
+	result, err = d.GetConversation(uid, "1234", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), result.Id)
+	assert.False(t, result.Pinned)
+	assert.Equal(t, "work", result.Folder)
+	assert.Equal(t, "hello", result.Draft)
+	assert.True(t, result.IsMuted(time.Now().Unix()))
+	assert.Equal(t, uint64(2), result.Version)
+
+	version, err := d.GetConversationVersion(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+
+	// 编解码
+	data, err := result.Marshal()
+	assert.NoError(t, err)
+	decoded := wkdb.Conversation{}
+	err = decoded.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, result.MutedUntil, decoded.MutedUntil)
+	assert.Equal(t, result.Draft, decoded.Draft)
+	assert.Equal(t, result.Version, decoded.Version)
+}
@@ -5,6 +5,8 @@ type DB interface {
 	Close() error
 	// 获取下一个主键
 	NextPrimaryKey() uint64
+	// Checkpoint 将所有分区的快照写入到dir目录（在线备份）
+	Checkpoint(dir string) error
 	// 消息
 	MessageDB
 	// 用户
@@ -27,6 +29,10 @@ type DB interface {
 	SystemUidDB
 	// 流
 	StreamDB
+	// webhook死信和订阅
+	WebhookDB
+	// 敏感词
+	SensitiveWordDB
 }
 
 type MessageDB interface {
@@ -76,6 +82,50 @@ type MessageDB interface {
 
 	// 搜索消息
 	SearchMessages(req MessageSearchReq) ([]Message, error)
+
+	// GetMessageExtra 获取消息的扩展数据（撤回/编辑状态）
+	GetMessageExtra(channelId string, channelType uint8, messageSeq uint64) (MessageExtra, error)
+	// GetMessageEditHistory 获取消息的编辑历史（不包含原始内容）
+	GetMessageEditHistory(channelId string, channelType uint8, messageSeq uint64) ([]MessageEditHistory, error)
+	// SetReaction 添加或取消回应，版本不大于已有版本的变更会被忽略
+	SetReaction(reaction Reaction) error
+	// GetMessageReactions 获取消息的回应（不包含已取消的）
+	GetMessageReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error)
+	// SyncReactions 获取频道内回应版本大于startSeq的回应变更（包含已取消的），按版本升序
+	SyncReactions(channelId string, channelType uint8, startSeq uint64, limit int) ([]Reaction, error)
+	// GetReactionVersion 获取频道当前的回应版本
+	GetReactionVersion(channelId string, channelType uint8) (uint64, error)
+	// LoadThreadMsgs 加载父消息的回复，结果包含startSeq，按seq升序
+	LoadThreadMsgs(channelId string, channelType uint8, parentSeq uint64, startSeq uint64, limit int) ([]Message, error)
+	// GetThreadSummaries 获取父消息的回复数量和最新回复
+	GetThreadSummaries(channelId string, channelType uint8, parentSeqs []uint64) ([]ThreadSummary, error)
+	// AddPinnedMessage 置顶消息，已置顶的会更新置顶时间，超过MaxPinnedMessages时移除最早置顶的
+	AddPinnedMessage(pin PinnedMessage) error
+	// RemovePinnedMessage 取消置顶
+	RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error
+	// GetPinnedMessages 获取频道的置顶消息，按置顶时间倒序
+	GetPinnedMessages(channelId string, channelType uint8) ([]PinnedMessage, error)
+	// AddReceipt 添加已读回执，不大于用户已回执位置的消息不会重复计数
+	AddReceipt(receipt Receipt) error
+	// GetMessageReceiptReaders 获取消息的已读用户，最多保存MessageReceiptMaxReaders个
+	GetMessageReceiptReaders(channelId string, channelType uint8, messageSeq uint64, limit int) ([]ReceiptReader, error)
+	// GetMessageReceiptCount 获取消息的已读数量
+	GetMessageReceiptCount(channelId string, channelType uint8, messageSeq uint64) (uint32, error)
+	// GetReceiptReadSeq 获取用户在频道内已回执到的消息seq
+	GetReceiptReadSeq(channelId string, channelType uint8, uid string) (uint64, error)
+	// AddScheduledMessage 添加定时消息，相同id的会被覆盖
+	AddScheduledMessage(m ScheduledMessage) error
+	// RemoveScheduledMessage 移除定时消息，不存在时忽略
+	RemoveScheduledMessage(channelId string, channelType uint8, id uint64) error
+	// GetScheduledMessage 获取定时消息，不存在返回ErrNotFound
+	GetScheduledMessage(channelId string, channelType uint8, id uint64) (ScheduledMessage, error)
+	// GetScheduledMessages 获取频道待发送的定时消息，按发送时间升序
+	GetScheduledMessages(channelId string, channelType uint8) ([]ScheduledMessage, error)
+	// GetDueScheduledMessages 获取所有分区内发送时间点小于等于now的定时消息，limit为0时不限制数量，被filter过滤掉的不计入数量
+	GetDueScheduledMessages(now int64, limit int, filter ...func(m ScheduledMessage) bool) ([]ScheduledMessage, error)
+
+	// RebuildMessageSearchIndex 重建消息全文索引
+	RebuildMessageSearchIndex() error
 }
 
 type DeviceDB interface {
@@ -93,6 +143,9 @@ type DeviceDB interface {
 
 	// UpdateDevice 更新设备
 	UpdateDevice(device Device) error
+
+	// IterateDevices 遍历本节点的所有设备，iterFnc返回false时停止
+	IterateDevices(iterFnc func(d Device) bool) error
 }
 
 type UserDB interface {
@@ -113,6 +166,15 @@ type UserDB interface {
 
 	// UpdateUser 更新用户
 	UpdateUser(u User) error
+
+	// IterateUsers 遍历本节点的所有用户，iterFnc返回false时停止
+	IterateUsers(iterFnc func(u User) bool) error
+
+	// UpdateUserLastSeen 更新用户的最后上线/离线时间，与已有的记录合并，时间只会往后更新
+	UpdateUserLastSeen(lastSeen UserLastSeen) error
+
+	// GetUserLastSeens 批量获取用户的最后上线/离线时间，没有记录的用户不返回
+	GetUserLastSeens(uids []string) ([]UserLastSeen, error)
 }
 
 type ChannelDB interface {
@@ -131,6 +193,15 @@ type ChannelDB interface {
 	// GetSubscribers 获取订阅者
 	GetSubscribers(channelId string, channelType uint8) ([]Member, error)
 
+	// GetSubscribersByOffset 按id分页获取订阅者
+	GetSubscribersByOffset(channelId string, channelType uint8, offsetId uint64, limit int) ([]Member, error)
+
+	// GetSubscriber 获取订阅者，不存在返回ErrNotFound
+	GetSubscriber(channelId string, channelType uint8, uid string) (Member, error)
+
+	// UpdateSubscribers 更新订阅者（角色、禁言、扩展属性等）
+	UpdateSubscribers(channelId string, channelType uint8, members []Member) error
+
 	// AddOrUpdateChannel  添加或更新channel
 	AddChannel(channelInfo ChannelInfo) (uint64, error)
 	// UpdateChannel 更新channel
@@ -184,6 +255,9 @@ type ChannelDB interface {
 
 	// SearchChannels 搜索频道
 	SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
+
+	// IterateChannels 遍历本节点的所有频道信息，iterFnc返回false时停止
+	IterateChannels(iterFnc func(channelInfo ChannelInfo) bool) error
 }
 
 type ConversationDB interface {
@@ -215,6 +289,15 @@ type ConversationDB interface {
 
 	// SearchConversation 搜索最近会话
 	SearchConversation(req ConversationSearchReq) ([]Conversation, error)
+
+	// IterateConversations 遍历本节点的所有最近会话，iterFnc返回false时停止
+	IterateConversations(iterFnc func(conversation Conversation) bool) error
+
+	// UpdateConversationAttrs 修改会话的置顶、免打扰、分组、草稿等属性，会话不存在时使用id新建
+	UpdateConversationAttrs(uid string, id uint64, attrs ConversationAttrs) error
+
+	// GetConversationVersion 获取用户的会话版本
+	GetConversationVersion(uid string) (uint64, error)
 }
 
 type ChannelClusterConfigDB interface {
@@ -356,6 +439,35 @@ type StreamDB interface {
 	GetStreams(streamNo string) ([]*Stream, error)
 }
 
+type WebhookDB interface {
+	// AddWebhookDeadLetter 添加webhook死信，相同id的会被覆盖
+	AddWebhookDeadLetter(deadLetter WebhookDeadLetter) error
+	// GetWebhookDeadLetter 获取webhook死信，不存在返回ErrNotFound
+	GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error)
+	// GetWebhookDeadLetters 获取id大于startId的webhook死信，按id升序，limit为0时不限制数量
+	GetWebhookDeadLetters(startId uint64, limit int) ([]WebhookDeadLetter, error)
+	// RemoveWebhookDeadLetters 移除webhook死信
+	RemoveWebhookDeadLetters(ids []uint64) error
+	// RemoveAllWebhookDeadLetters 清空webhook死信
+	RemoveAllWebhookDeadLetters() error
+
+	// AddOrUpdateWebhookSubscription 添加或更新webhook订阅
+	AddOrUpdateWebhookSubscription(subscription WebhookSubscription) error
+	// RemoveWebhookSubscription 移除webhook订阅
+	RemoveWebhookSubscription(id uint64) error
+	// GetWebhookSubscriptions 获取所有webhook订阅，按id升序
+	GetWebhookSubscriptions() ([]WebhookSubscription, error)
+}
+
+type SensitiveWordDB interface {
+	// AddSensitiveWords 添加敏感词
+	AddSensitiveWords(words []string) error
+	// RemoveSensitiveWords 移除敏感词
+	RemoveSensitiveWords(words []string) error
+	// GetSensitiveWords 获取所有敏感词
+	GetSensitiveWords() ([]string, error)
+}
+
 type MessageSearchReq struct {
 	MessageId        int64
 	FromUid          string // 发送者uid
@@ -368,6 +480,10 @@ type MessageSearchReq struct {
 	Pre              bool   // 是否向前搜索
 
 	ClientMsgNo string // 客户端消息编号
+
+	Keyword   string // 搜索关键字，空格分隔的词是且的关系，双引号包含的内容按短语匹配，其他条件和分页游标同样生效，指定频道id时必须指定频道类型
+	StartTime int64  // 消息时间大于等于StartTime（单位秒，关键字搜索有效）
+	EndTime   int64  // 消息时间小于EndTime（单位秒，关键字搜索有效，可作为分页游标）
 }
 
 type ChannelSearchReq struct {
@@ -12,4 +12,6 @@ var (
 	ErrInvalidUserId   = errors.New("invalid user id")
 	ErrInvalidDeviceId = errors.New("invalid device id")
 	ErrAlreadyExist    = errors.New("already exist")
+	// 按频道搜索需要同时指定频道id和频道类型
+	ErrChannelTypeRequired = errors.New("channel type is required when channel id is set")
 )
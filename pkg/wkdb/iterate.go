@@ -0,0 +1,69 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) IterateUsers(iterFnc func(u User) bool) error {
+	return wk.iterateTable(key.TableUser.Id, func(iter *pebble.Iterator) (bool, error) {
+		next := true
+		err := wk.iteratorUser(iter, func(u User) bool {
+			next = iterFnc(u)
+			return next
+		})
+		return next, err
+	})
+}
+
+func (wk *wukongDB) IterateDevices(iterFnc func(d Device) bool) error {
+	return wk.iterateTable(key.TableDevice.Id, func(iter *pebble.Iterator) (bool, error) {
+		next := true
+		err := wk.iterDevice(iter, func(d Device) bool {
+			next = iterFnc(d)
+			return next
+		})
+		return next, err
+	})
+}
+
+func (wk *wukongDB) IterateChannels(iterFnc func(channelInfo ChannelInfo) bool) error {
+	return wk.iterateTable(key.TableChannelInfo.Id, func(iter *pebble.Iterator) (bool, error) {
+		next := true
+		err := wk.iterChannelInfo(iter, func(channelInfo ChannelInfo) bool {
+			next = iterFnc(channelInfo)
+			return next
+		})
+		return next, err
+	})
+}
+
+func (wk *wukongDB) IterateConversations(iterFnc func(conversation Conversation) bool) error {
+	return wk.iterateTable(key.TableConversation.Id, func(iter *pebble.Iterator) (bool, error) {
+		next := true
+		err := wk.iterateConversation(iter, func(conversation Conversation) bool {
+			next = iterFnc(conversation)
+			return next
+		})
+		return next, err
+	})
+}
+
+// 依次遍历每个分区的表数据，fnc返回false时停止遍历
+func (wk *wukongDB) iterateTable(tableId [2]byte, fnc func(iter *pebble.Iterator) (bool, error)) error {
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewTableLowKey(tableId),
+			UpperBound: key.NewTableHighKey(tableId),
+		})
+		next, err := fnc(iter)
+		iter.Close()
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+	return nil
+}
@@ -207,6 +207,20 @@ func NewMessageIndexTimestampKey(timestamp uint64, primaryKey [16]byte) []byte {
 
 }
 
+// NewMessageSecondIndexExpireKey 消息过期索引 expireAt为消息过期的时间点（单位秒）
+func NewMessageSecondIndexExpireKey(expireAt uint64, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessage.SecondIndexSize)
+	key[0] = TableMessage.Id[0]
+	key[1] = TableMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessage.SecondIndex.Expire[0]
+	key[5] = TableMessage.SecondIndex.Expire[1]
+	binary.BigEndian.PutUint64(key[6:], expireAt)
+	copy(key[14:], primaryKey[:])
+	return key
+}
+
 func ParseMessageSecondIndexKey(key []byte) (primaryKey [16]byte, err error) {
 	if len(key) != TableMessage.SecondIndexSize {
 		return [16]byte{}, fmt.Errorf("message: invalid index key length, keyLen: %d", len(key))
@@ -719,6 +733,17 @@ func NewConversationIndexChannelKey(uid string, channelId string, channelType ui
 	return key
 }
 
+// NewConversationVersionKey 用户最近会话属性的版本
+func NewConversationVersionKey(uid string) []byte {
+	key := make([]byte, 12)
+	key[0] = TableConversation.Id[0]
+	key[1] = TableConversation.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	return key
+}
+
 func NewConversationSecondIndexKey(uid string, indexName [2]byte, indexValue uint64, primaryKey uint64) []byte {
 	key := make([]byte, TableConversation.SecondIndexSize)
 	key[0] = TableConversation.Id[0]
@@ -925,3 +950,387 @@ func NewStreamMetaKey(streamNo string) []byte {
 	binary.BigEndian.PutUint64(key[4:], HashWithString(streamNo))
 	return key
 }
+
+// ---------------------- MessageExtra ----------------------
+
+func NewMessageExtraColumnKey(channelId string, channelType uint8, messageSeq uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageExtra.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableMessageExtra.Id[0]
+	key[1] = TableMessageExtra.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func NewMessageExtraPrimaryKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, 20)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableMessageExtra.Id[0]
+	key[1] = TableMessageExtra.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+func ParseMessageExtraColumnKey(key []byte) (messageSeq uint64, columnName [2]byte, err error) {
+	if len(key) != TableMessageExtra.Size {
+		err = fmt.Errorf("messageExtra: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// NewMessageEditHistoryKey 消息编辑历史的key
+func NewMessageEditHistoryKey(channelId string, channelType uint8, messageSeq uint64, version uint32) []byte {
+	key := make([]byte, TableMessageExtra.HistorySize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableMessageExtra.Id[0]
+	key[1] = TableMessageExtra.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint32(key[20:], version)
+	return key
+}
+
+func ParseMessageEditHistoryKey(key []byte) (messageSeq uint64, version uint32, err error) {
+	if len(key) != TableMessageExtra.HistorySize {
+		err = fmt.Errorf("messageEditHistory: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	version = binary.BigEndian.Uint32(key[20:])
+	return
+}
+
+// ---------------------- MessageSearch ----------------------
+
+func NewMessageSearchKey(term string, timestamp uint64, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessageSearch.Size)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(term))
+	binary.BigEndian.PutUint64(key[12:], timestamp)
+	copy(key[20:], primaryKey[:])
+	return key
+}
+
+func NewMessageSearchLowKey() []byte {
+	key := make([]byte, 4)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	return key
+}
+
+func NewMessageSearchHighKey() []byte {
+	key := make([]byte, 4)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 1
+	return key
+}
+
+func ParseMessageSearchKey(key []byte) (timestamp uint64, primaryKey [16]byte, err error) {
+	if len(key) != TableMessageSearch.Size {
+		err = fmt.Errorf("messageSearch: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	timestamp = binary.BigEndian.Uint64(key[12:])
+	copy(primaryKey[:], key[20:])
+	return
+}
+
+// NewTableLowKey 表数据的最小key
+func NewTableLowKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeTable, 0}
+}
+
+// NewTableHighKey 表数据的最大key（不包含）
+func NewTableHighKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeTable, 1}
+}
+
+// ---------------------- Reaction ----------------------
+
+func NewReactionKey(channelId string, channelType uint8, messageSeq uint64, uid string, emoji string) []byte {
+	key := make([]byte, TableReaction.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[28:], HashWithString(emoji))
+	return key
+}
+
+// NewReactionMessageKey 消息回应的前缀，用于获取某条消息的所有回应
+func NewReactionMessageKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableReaction.MessagePrefix)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+// NewReactionSeqIndexKey 回应版本索引
+func NewReactionSeqIndexKey(channelId string, channelType uint8, seq uint64) []byte {
+	key := make([]byte, TableReaction.SeqIndexSize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], seq)
+	return key
+}
+
+func ParseReactionSeqIndexKey(key []byte) (seq uint64, err error) {
+	if len(key) != TableReaction.SeqIndexSize {
+		err = fmt.Errorf("reactionSeqIndex: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	seq = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// ---------------------- Receipt ----------------------
+
+func NewReceiptKey(channelId string, channelType uint8, messageSeq uint64, uid string) []byte {
+	key := make([]byte, TableReceipt.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReceipt.Id[0]
+	key[1] = TableReceipt.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], HashWithString(uid))
+	return key
+}
+
+// NewReceiptMessageKey 消息已读回执的前缀，用于获取某条消息的已读用户
+func NewReceiptMessageKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableReceipt.MessagePrefix)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReceipt.Id[0]
+	key[1] = TableReceipt.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+// NewReceiptCountKey 消息已读数量
+func NewReceiptCountKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TableReceipt.CountSize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReceipt.Id[0]
+	key[1] = TableReceipt.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+// NewReceiptReadSeqKey 用户在频道内已回执到的消息seq
+func NewReceiptReadSeqKey(channelId string, channelType uint8, uid string) []byte {
+	key := make([]byte, TableReceipt.ReadSeqSize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableReceipt.Id[0]
+	key[1] = TableReceipt.Id[1]
+	key[2] = dataTypeIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], HashWithString(uid))
+	return key
+}
+
+// ---------------------- PinnedMessage ----------------------
+
+func NewPinnedMessageKey(channelId string, channelType uint8, messageSeq uint64) []byte {
+	key := make([]byte, TablePinnedMessage.Size)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TablePinnedMessage.Id[0]
+	key[1] = TablePinnedMessage.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	return key
+}
+
+// ---------------------- Thread ----------------------
+
+// NewThreadReplyKey 父消息的回复索引
+func NewThreadReplyKey(channelId string, channelType uint8, parentSeq uint64, replySeq uint64) []byte {
+	key := make([]byte, TableThread.ReplySize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableThread.Id[0]
+	key[1] = TableThread.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], parentSeq)
+	binary.BigEndian.PutUint64(key[20:], replySeq)
+	return key
+}
+
+func ParseThreadReplyKey(key []byte) (replySeq uint64, err error) {
+	if len(key) != TableThread.ReplySize {
+		err = fmt.Errorf("threadReply: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	replySeq = binary.BigEndian.Uint64(key[20:])
+	return
+}
+
+// NewThreadParentKey 回复所属父消息的索引
+func NewThreadParentKey(channelId string, channelType uint8, replySeq uint64) []byte {
+	key := make([]byte, TableThread.ParentSize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableThread.Id[0]
+	key[1] = TableThread.Id[1]
+	key[2] = dataTypeIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], replySeq)
+	return key
+}
+
+func ParseThreadParentKey(key []byte) (replySeq uint64, err error) {
+	if len(key) != TableThread.ParentSize {
+		err = fmt.Errorf("threadParent: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	replySeq = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// NewThreadCountKey 父消息的回复数量
+func NewThreadCountKey(channelId string, channelType uint8, parentSeq uint64) []byte {
+	key := make([]byte, TableThread.CountSize)
+	channelHash := channelIdToNum(channelId, channelType)
+	key[0] = TableThread.Id[0]
+	key[1] = TableThread.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], parentSeq)
+	return key
+}
+
+// ---------------------- ScheduledMessage ----------------------
+
+func NewScheduledMessageKey(channelId string, channelType uint8, id uint64) []byte {
+	return newScheduledMessageKeyWithHash(channelIdToNum(channelId, channelType), id)
+}
+
+func newScheduledMessageKeyWithHash(channelHash uint64, id uint64) []byte {
+	key := make([]byte, TableScheduledMessage.Size)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], id)
+	return key
+}
+
+// NewScheduledMessageSendAtKey 定时消息的到期索引 sendAt为发送时间点（单位秒）
+func NewScheduledMessageSendAtKey(sendAt uint64, channelId string, channelType uint8, id uint64) []byte {
+	key := make([]byte, TableScheduledMessage.SecondIndexSize)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], sendAt)
+	if channelId != "" {
+		binary.BigEndian.PutUint64(key[12:], channelIdToNum(channelId, channelType))
+	}
+	binary.BigEndian.PutUint64(key[20:], id)
+	return key
+}
+
+// ParseScheduledMessageSendAtKey 解析到期索引，返回定时消息的主键
+func ParseScheduledMessageSendAtKey(key []byte) (primaryKey []byte, err error) {
+	if len(key) != TableScheduledMessage.SecondIndexSize {
+		err = fmt.Errorf("scheduledMessage: invalid index key length, keyLen: %d", len(key))
+		return
+	}
+	primaryKey = newScheduledMessageKeyWithHash(binary.BigEndian.Uint64(key[12:]), binary.BigEndian.Uint64(key[20:]))
+	return
+}
+
+// ======================== UserLastSeen ========================
+
+func NewUserLastSeenKey(uid string) []byte {
+	key := make([]byte, TableUserLastSeen.Size)
+	key[0] = TableUserLastSeen.Id[0]
+	key[1] = TableUserLastSeen.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	return key
+}
+
+// ======================== WebhookDeadLetter ========================
+
+func NewWebhookDeadLetterKey(id uint64) []byte {
+	key := make([]byte, TableWebhookDeadLetter.Size)
+	key[0] = TableWebhookDeadLetter.Id[0]
+	key[1] = TableWebhookDeadLetter.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// ======================== WebhookSubscription ========================
+
+func NewWebhookSubscriptionKey(id uint64) []byte {
+	key := make([]byte, TableWebhookSubscription.Size)
+	key[0] = TableWebhookSubscription.Id[0]
+	key[1] = TableWebhookSubscription.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
+
+// ======================== SensitiveWord ========================
+
+func NewSensitiveWordKey(id uint64) []byte {
+	key := make([]byte, TableSensitiveWord.Size)
+	key[0] = TableSensitiveWord.Id[0]
+	key[1] = TableSensitiveWord.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	return key
+}
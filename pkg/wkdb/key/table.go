@@ -39,6 +39,9 @@ var TableMessage = struct {
 		FromUid     [2]byte
 		Payload     [2]byte
 		Term        [2]byte
+		Modify      [2]byte
+		ParentSeq   [2]byte
+		Sensitive   [2]byte
 	}
 	Index struct {
 		MessageId [2]byte
@@ -48,6 +51,7 @@ var TableMessage = struct {
 		ClientMsgNo [2]byte
 		Timestamp   [2]byte
 		Channel     [2]byte
+		Expire      [2]byte
 	}
 }{
 	Id:              [2]byte{0x01, 0x01},
@@ -68,6 +72,9 @@ var TableMessage = struct {
 		FromUid     [2]byte
 		Payload     [2]byte
 		Term        [2]byte
+		Modify      [2]byte
+		ParentSeq   [2]byte
+		Sensitive   [2]byte
 	}{
 		Header:      [2]byte{0x01, 0x01},
 		Setting:     [2]byte{0x01, 0x02},
@@ -82,6 +89,9 @@ var TableMessage = struct {
 		FromUid:     [2]byte{0x01, 0x0B},
 		Payload:     [2]byte{0x01, 0x0C},
 		Term:        [2]byte{0x01, 0x0D},
+		Modify:      [2]byte{0x01, 0x0E},
+		ParentSeq:   [2]byte{0x01, 0x0F},
+		Sensitive:   [2]byte{0x01, 0x10},
 	},
 	Index: struct {
 		MessageId [2]byte
@@ -93,11 +103,13 @@ var TableMessage = struct {
 		ClientMsgNo [2]byte
 		Timestamp   [2]byte
 		Channel     [2]byte
+		Expire      [2]byte
 	}{
 		FromUid:     [2]byte{0x01, 0x01},
 		ClientMsgNo: [2]byte{0x01, 0x02},
 		Timestamp:   [2]byte{0x01, 0x03},
 		Channel:     [2]byte{0x01, 0x04},
+		Expire:      [2]byte{0x01, 0x05},
 	},
 }
 
@@ -234,9 +246,13 @@ var TableSubscriber = struct {
 	IndexSize       int
 	SecondIndexSize int
 	Column          struct {
-		Uid       [2]byte
-		CreatedAt [2]byte
-		UpdatedAt [2]byte
+		Uid        [2]byte
+		CreatedAt  [2]byte
+		UpdatedAt  [2]byte
+		Role       [2]byte
+		MuteUntil  [2]byte
+		JoinedAt   [2]byte
+		Attributes [2]byte
 	}
 	Index struct {
 		Uid [2]byte
@@ -251,13 +267,21 @@ var TableSubscriber = struct {
 	IndexSize:       2 + 2 + 2 + 8 + 8,     // tableId + dataType + indexName + channel hash + columnHash
 	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + secondIndexName + channel hash +  columnValue + primaryKey
 	Column: struct {
-		Uid       [2]byte
-		CreatedAt [2]byte
-		UpdatedAt [2]byte
-	}{
-		Uid:       [2]byte{0x04, 0x01},
-		CreatedAt: [2]byte{0x04, 0x02},
-		UpdatedAt: [2]byte{0x04, 0x03},
+		Uid        [2]byte
+		CreatedAt  [2]byte
+		UpdatedAt  [2]byte
+		Role       [2]byte
+		MuteUntil  [2]byte
+		JoinedAt   [2]byte
+		Attributes [2]byte
+	}{
+		Uid:        [2]byte{0x04, 0x01},
+		CreatedAt:  [2]byte{0x04, 0x02},
+		UpdatedAt:  [2]byte{0x04, 0x03},
+		Role:       [2]byte{0x04, 0x04},
+		MuteUntil:  [2]byte{0x04, 0x05},
+		JoinedAt:   [2]byte{0x04, 0x06},
+		Attributes: [2]byte{0x04, 0x07},
 	},
 	Index: struct {
 		Uid [2]byte
@@ -313,6 +337,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte // 黑名单数量
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Mute            [2]byte // 全员禁言
+		MuteUntil       [2]byte // 全员禁言截止时间
 	}
 	Index struct {
 		Channel [2]byte
@@ -343,6 +369,8 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		Mute            [2]byte
+		MuteUntil       [2]byte
 	}{
 		Id:              [2]byte{0x06, 0x01},
 		ChannelId:       [2]byte{0x06, 0x02},
@@ -355,6 +383,8 @@ var TableChannelInfo = struct {
 		DenylistCount:   [2]byte{0x06, 0x09},
 		CreatedAt:       [2]byte{0x06, 0x0A},
 		UpdatedAt:       [2]byte{0x06, 0x0B},
+		Mute:            [2]byte{0x06, 0x0C},
+		MuteUntil:       [2]byte{0x06, 0x0D},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -482,14 +512,20 @@ var TableConversation = struct {
 	IndexSize       int
 	SecondIndexSize int
 	Column          struct {
-		Uid            [2]byte
-		ChannelId      [2]byte
-		ChannelType    [2]byte
-		Type           [2]byte
-		UnreadCount    [2]byte
-		ReadedToMsgSeq [2]byte
-		CreatedAt      [2]byte
-		UpdatedAt      [2]byte
+		Uid             [2]byte
+		ChannelId       [2]byte
+		ChannelType     [2]byte
+		Type            [2]byte
+		UnreadCount     [2]byte
+		ReadedToMsgSeq  [2]byte
+		CreatedAt       [2]byte
+		UpdatedAt       [2]byte
+		ClearedToMsgSeq [2]byte
+		Pinned          [2]byte
+		MutedUntil      [2]byte
+		Folder          [2]byte
+		Draft           [2]byte
+		Version         [2]byte
 	}
 	Index struct {
 		Channel [2]byte
@@ -505,23 +541,35 @@ var TableConversation = struct {
 	IndexSize:       2 + 2 + 2 + 8 + 8,     // tableId + dataType   + indexName + primaryKey + columnHash
 	SecondIndexSize: 2 + 2 + 8 + 2 + 8 + 8, // tableId + dataType + uid hash  + secondIndexName + columnValue + primaryKey
 	Column: struct {
-		Uid            [2]byte
-		ChannelId      [2]byte
-		ChannelType    [2]byte
-		Type           [2]byte
-		UnreadCount    [2]byte
-		ReadedToMsgSeq [2]byte
-		CreatedAt      [2]byte
-		UpdatedAt      [2]byte
-	}{
-		Uid:            [2]byte{0x09, 0x01},
-		ChannelId:      [2]byte{0x09, 0x02},
-		ChannelType:    [2]byte{0x09, 0x03},
-		Type:           [2]byte{0x09, 0x04},
-		UnreadCount:    [2]byte{0x09, 0x05},
-		ReadedToMsgSeq: [2]byte{0x09, 0x06},
-		CreatedAt:      [2]byte{0x09, 0x07},
-		UpdatedAt:      [2]byte{0x09, 0x08},
+		Uid             [2]byte
+		ChannelId       [2]byte
+		ChannelType     [2]byte
+		Type            [2]byte
+		UnreadCount     [2]byte
+		ReadedToMsgSeq  [2]byte
+		CreatedAt       [2]byte
+		UpdatedAt       [2]byte
+		ClearedToMsgSeq [2]byte
+		Pinned          [2]byte
+		MutedUntil      [2]byte
+		Folder          [2]byte
+		Draft           [2]byte
+		Version         [2]byte
+	}{
+		Uid:             [2]byte{0x09, 0x01},
+		ChannelId:       [2]byte{0x09, 0x02},
+		ChannelType:     [2]byte{0x09, 0x03},
+		Type:            [2]byte{0x09, 0x04},
+		UnreadCount:     [2]byte{0x09, 0x05},
+		ReadedToMsgSeq:  [2]byte{0x09, 0x06},
+		CreatedAt:       [2]byte{0x09, 0x07},
+		UpdatedAt:       [2]byte{0x09, 0x08},
+		ClearedToMsgSeq: [2]byte{0x09, 0x09},
+		Pinned:          [2]byte{0x09, 0x0A},
+		MutedUntil:      [2]byte{0x09, 0x0B},
+		Folder:          [2]byte{0x09, 0x0C},
+		Draft:           [2]byte{0x09, 0x0D},
+		Version:         [2]byte{0x09, 0x0E},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -759,3 +807,211 @@ var TableStreamMeta = struct {
 		StreamNo: [2]byte{0x12, 0x01},
 	},
 }
+
+// ======================== MessageExtra ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq   | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	   	|  8 字节	   | 2 字节		|
+// ---------------------
+// 编辑历史: tableID + dataTypeOther + channel hash + messageSeq + version(4字节)
+
+var TableMessageExtra = struct {
+	Id          [2]byte
+	Size        int
+	HistorySize int
+	Column      struct {
+		Revoke      [2]byte
+		Revoker     [2]byte
+		EditVersion [2]byte
+		EditedAt    [2]byte
+	}
+}{
+	Id:          [2]byte{0x13, 0x01},
+	Size:        2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + messageSeq + columnKey
+	HistorySize: 2 + 2 + 8 + 8 + 4, // tableId + dataType + channel hash + messageSeq + version
+	Column: struct {
+		Revoke      [2]byte
+		Revoker     [2]byte
+		EditVersion [2]byte
+		EditedAt    [2]byte
+	}{
+		Revoke:      [2]byte{0x13, 0x01},
+		Revoker:     [2]byte{0x13, 0x02},
+		EditVersion: [2]byte{0x13, 0x03},
+		EditedAt:    [2]byte{0x13, 0x04},
+	},
+}
+
+// ======================== MessageSearch ========================
+// 全文检索倒排索引
+// ---------------------
+// | tableID  | dataType	| term hash | timestamp | primaryKey(channel hash + messageSeq) |
+// | 2 byte   | 1 byte   	| 8 字节 	 |  8 字节	  | 16 字节		|
+// ---------------------
+
+var TableMessageSearch = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x14, 0x01},
+	Size: 2 + 2 + 8 + 8 + 16, // tableId + dataType + term hash + timestamp + primaryKey
+}
+
+// ======================== Reaction ========================
+// 消息回应
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq | uid hash | emoji hash |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	 | 8 字节	 | 8 字节	 |
+// ---------------------
+// 版本索引: tableID + dataTypeSecondIndex + channel hash + seq(回应版本)
+
+var TableReaction = struct {
+	Id            [2]byte
+	Size          int
+	SeqIndexSize  int
+	MessagePrefix int
+}{
+	Id:            [2]byte{0x15, 0x01},
+	Size:          2 + 2 + 8 + 8 + 8 + 8, // tableId + dataType + channel hash + messageSeq + uid hash + emoji hash
+	SeqIndexSize:  2 + 2 + 8 + 8,         // tableId + dataType + channel hash + seq
+	MessagePrefix: 2 + 2 + 8 + 8,         // tableId + dataType + channel hash + messageSeq
+}
+
+// ======================== Receipt ========================
+// 消息已读回执
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq | uid hash |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	 | 8 字节	 |
+// ---------------------
+// 已读数量: tableID + dataTypeOther + channel hash + messageSeq
+// 用户已回执到的位置: tableID + dataTypeIndex + channel hash + uid hash
+
+var TableReceipt = struct {
+	Id            [2]byte
+	Size          int
+	MessagePrefix int
+	CountSize     int
+	ReadSeqSize   int
+}{
+	Id:            [2]byte{0x16, 0x01},
+	Size:          2 + 2 + 8 + 8 + 8, // tableId + dataType + channel hash + messageSeq + uid hash
+	MessagePrefix: 2 + 2 + 8 + 8,     // tableId + dataType + channel hash + messageSeq
+	CountSize:     2 + 2 + 8 + 8,     // tableId + dataType + channel hash + messageSeq
+	ReadSeqSize:   2 + 2 + 8 + 8,     // tableId + dataType + channel hash + uid hash
+}
+
+// ======================== PinnedMessage ========================
+// 频道置顶消息
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节	 |
+// ---------------------
+
+var TablePinnedMessage = struct {
+	Id            [2]byte
+	Size          int
+	ChannelPrefix int
+}{
+	Id:            [2]byte{0x17, 0x01},
+	Size:          2 + 2 + 8 + 8, // tableId + dataType + channel hash + messageSeq
+	ChannelPrefix: 2 + 2 + 8,     // tableId + dataType + channel hash
+}
+
+// ======================== Thread ========================
+// 消息的回复（话题）索引
+// ---------------------
+// | tableID  | dataType	        | channel hash | parentSeq | replySeq |
+// | 2 byte   | 2 byte   	        | 8 字节 	   	|  8 字节	 | 8 字节	 |
+// ---------------------
+// 回复所属的父消息: tableID + dataTypeIndex + channel hash + replySeq，值为parentSeq
+// 父消息的回复数量: tableID + dataTypeOther + channel hash + parentSeq，值为未撤回的回复数量
+
+var TableThread = struct {
+	Id         [2]byte
+	ReplySize  int
+	ParentSize int
+	CountSize  int
+}{
+	Id:         [2]byte{0x18, 0x01},
+	ReplySize:  2 + 2 + 8 + 8 + 8, // tableId + dataType + channel hash + parentSeq + replySeq
+	ParentSize: 2 + 2 + 8 + 8,     // tableId + dataType + channel hash + replySeq
+	CountSize:  2 + 2 + 8 + 8,     // tableId + dataType + channel hash + parentSeq
+}
+
+// ======================== ScheduledMessage ========================
+// 定时消息
+// ---------------------
+// | tableID  | dataType	| channel hash | id     |
+// | 2 byte   | 2 byte   	| 8 字节 	   	|  8 字节 |
+// ---------------------
+// 到期索引: tableID + dataTypeSecondIndex + sendAt + channel hash + id
+
+var TableScheduledMessage = struct {
+	Id              [2]byte
+	Size            int
+	SecondIndexSize int
+}{
+	Id:              [2]byte{0x19, 0x01},
+	Size:            2 + 2 + 8 + 8,     // tableId + dataType + channel hash + id
+	SecondIndexSize: 2 + 2 + 8 + 8 + 8, // tableId + dataType + sendAt + channel hash + id
+}
+
+// ======================== UserLastSeen ========================
+// 用户及用户各设备的最后上线/离线时间
+// ---------------------
+// | tableID  | dataType	| uid hash |
+// | 2 byte   | 2 byte   	| 8 字节    |
+// ---------------------
+
+var TableUserLastSeen = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + uid hash
+}
+
+// ======================== WebhookDeadLetter ========================
+// 超过最大重试次数仍推送失败的webhook事件（节点本地数据）
+// ---------------------
+// | tableID  | dataType	| id      |
+// | 2 byte   | 2 byte   	| 8 字节   |
+// ---------------------
+
+var TableWebhookDeadLetter = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + id
+}
+
+// ======================== WebhookSubscription ========================
+// webhook订阅（集群数据，存储在slot 0）
+// ---------------------
+// | tableID  | dataType	| id      |
+// | 2 byte   | 2 byte   	| 8 字节   |
+// ---------------------
+
+var TableWebhookSubscription = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1C, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + id
+}
+
+// ======================== SensitiveWord ========================
+// 敏感词（集群数据，存储在slot 0）
+// ---------------------
+// | tableID  | dataType	| word hash |
+// | 2 byte   | 2 byte   	| 8 字节     |
+// ---------------------
+
+var TableSensitiveWord = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x1D, 0x01},
+	Size: 2 + 2 + 8, // tableId + dataType + word hash
+}
@@ -16,6 +16,7 @@ type dblock struct {
 	userLock               *userLock
 	addOrUpdateChannelLock *addOrUpdateChannelLock
 	conversationLock       *conversationLock
+	channelExtraLock       *channelExtraLock
 }
 
 func newDBLock() *dblock {
@@ -28,6 +29,7 @@ func newDBLock() *dblock {
 		totalLock:              newTotalLock(),
 		addOrUpdateChannelLock: newAddOrUpdateChannelLock(),
 		conversationLock:       newConversationLock(),
+		channelExtraLock:       newChannelExtraLock(),
 	}
 
 }
@@ -40,6 +42,7 @@ func (d *dblock) start() {
 	d.userLock.StartCleanLoop()
 	d.addOrUpdateChannelLock.StartCleanLoop()
 	d.conversationLock.StartCleanLoop()
+	d.channelExtraLock.StartCleanLoop()
 }
 
 func (d *dblock) stop() {
@@ -50,6 +53,7 @@ func (d *dblock) stop() {
 	d.userLock.StopCleanLoop()
 	d.addOrUpdateChannelLock.StopCleanLoop()
 	d.conversationLock.StopCleanLoop()
+	d.channelExtraLock.StopCleanLoop()
 }
 
 type channelClusterConfigLock struct {
@@ -248,3 +252,24 @@ func (c *conversationLock) lock(uid string) {
 func (c *conversationLock) unlock(uid string) {
 	c.Unlock(uid)
 }
+
+// 频道内回应、回执等读后写的数据的锁
+type channelExtraLock struct {
+	*keylock.KeyLock
+}
+
+func newChannelExtraLock() *channelExtraLock {
+	return &channelExtraLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (c *channelExtraLock) lockByChannel(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	c.Lock(key)
+}
+
+func (c *channelExtraLock) unlockByChannel(channelId string, channelType uint8) {
+	key := channelId + strconv.FormatInt(int64(channelType), 10)
+	c.Unlock(key)
+}
@@ -231,25 +231,8 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 		return nil, fmt.Errorf("end messageSeq[%d] must be less than start messageSeq[%d]", endMessageSeq, startMessageSeq)
 	}
 
-	var minSeq uint64
-	var maxSeq uint64
-
-	if endMessageSeq == 0 {
-		maxSeq = startMessageSeq + 1
-		if startMessageSeq < uint64(limit) {
-			minSeq = 1
-		} else {
-			minSeq = startMessageSeq - uint64(limit) + 1
-		}
-	} else {
-		maxSeq = startMessageSeq + 1
-		if startMessageSeq-endMessageSeq > uint64(limit) {
-			minSeq = startMessageSeq - uint64(limit) + 1
-		} else {
-			minSeq = endMessageSeq + 1
-		}
-
-	}
+	minSeq := endMessageSeq + 1
+	maxSeq := startMessageSeq + 1
 
 	// 获取频道的最大的messageSeq，超过这个的消息都视为无效
 	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
@@ -269,14 +252,27 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 	})
 	defer iter.Close()
 
+	now := time.Now().Unix()
 	msgs := make([]Message, 0)
-	err = wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
+
+	// 过期消息和修改日志不计入limit，所以从start往前取直到够数量
+	err = wk.iteratorChannelMessagesDirection(iter, 0, true, func(m Message) bool {
+		if m.IsExpired(now) || m.Modify != nil {
+			return true
+		}
 		msgs = append(msgs, m)
-		return true
+		return limit == 0 || len(msgs) < limit
 	})
 	if err != nil {
 		return nil, err
 	}
+	// 按messageSeq升序返回
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	if err = wk.fillMessageExtras(channelId, channelType, msgs); err != nil {
+		return nil, err
+	}
 	return msgs, nil
 }
 
@@ -308,15 +304,23 @@ func (wk *wukongDB) LoadNextRangeMsgs(channelId string, channelType uint8, start
 	})
 	defer iter.Close()
 
+	now := time.Now().Unix()
 	msgs := make([]Message, 0)
 
-	err = wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
+	// 过期消息和修改日志不计入limit，所以这里自己控制数量
+	err = wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if m.IsExpired(now) || m.Modify != nil {
+			return true
+		}
 		msgs = append(msgs, m)
-		return true
+		return limit == 0 || len(msgs) < limit
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err = wk.fillMessageExtras(channelId, channelType, msgs); err != nil {
+		return nil, err
+	}
 	return msgs, nil
 
 }
@@ -428,6 +432,22 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 
 	batch.DeleteRange(key.NewMessagePrimaryKey(channelId, channelType, messageSeq), key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64))
 
+	if err := wk.truncateExpireIndex(channelId, channelType, messageSeq, batch); err != nil {
+		return err
+	}
+
+	if err := wk.truncateThreadIndex(channelId, channelType, messageSeq, batch); err != nil {
+		return err
+	}
+
+	if err := wk.truncateMessageSearchIndex(channelId, channelType, messageSeq, batch); err != nil {
+		return err
+	}
+
+	if err := wk.truncateMessageModify(channelId, channelType, messageSeq, batch); err != nil {
+		return err
+	}
+
 	err := wk.setChannelLastMessageSeq(channelId, channelType, messageSeq-1, batch)
 	if err != nil {
 		return err
@@ -587,6 +607,10 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 
 	wk.metrics.SearchMessagesAdd(1)
 
+	if strings.TrimSpace(req.Keyword) != "" { // 关键字搜索走倒排索引
+		return wk.searchMessagesByKeyword(req)
+	}
+
 	if req.MessageId > 0 { // 如果指定了messageId，则直接查询messageId，这种情况要么没有要么只有一条
 		msg, err := wk.GetMessage(uint64(req.MessageId))
 		if err != nil {
@@ -595,12 +619,24 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 			}
 			return nil, err
 		}
-		return []Message{msg}, nil
+		if msg.IsExpired(time.Now().Unix()) || msg.Modify != nil {
+			return nil, nil
+		}
+		msgs := []Message{msg}
+		if err = wk.fillMessageExtras(msg.ChannelID, msg.ChannelType, msgs); err != nil {
+			return nil, err
+		}
+		return msgs, nil
 	}
 
+	now := time.Now().Unix()
 	iterFnc := func(msgs *[]Message) func(m Message) bool {
 		currSize := 0
 		return func(m Message) bool {
+			if m.IsExpired(now) || m.Modify != nil {
+				return true
+			}
+
 			if strings.TrimSpace(req.ChannelId) != "" && m.ChannelID != req.ChannelId {
 				return true
 			}
@@ -674,6 +710,9 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err = wk.fillMessageExtras(req.ChannelId, req.ChannelType, msgs); err != nil {
+			return nil, err
+		}
 
 		return msgs, nil
 
@@ -759,6 +798,12 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 
 	}
 
+	for i := range allMsgs {
+		if err := wk.fillMessageExtras(allMsgs[i].ChannelID, allMsgs[i].ChannelType, allMsgs[i:i+1]); err != nil {
+			return nil, err
+		}
+	}
+
 	return allMsgs, nil
 }
 
@@ -794,16 +839,7 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			return nil
 		}
 	}
-	for iter.Valid() {
-		if reverse {
-			if !iter.Prev() {
-				break
-			}
-		} else {
-			if !iter.Next() {
-				break
-			}
-		}
+	for ; iter.Valid(); wk.iterMove(iter, reverse) {
 		messageSeq, coulmnName, err := key.ParseMessageColumnKey(iter.Key())
 		if err != nil {
 			return err
@@ -857,7 +893,16 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
-
+		case key.TableMessage.Column.Modify:
+			modify := &MessageModify{}
+			if err := modify.Unmarshal(iter.Value()); err != nil {
+				return err
+			}
+			preMessage.Modify = modify
+		case key.TableMessage.Column.ParentSeq:
+			preMessage.ParentMessageSeq = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.Sensitive:
+			preMessage.Sensitive = len(iter.Value()) > 0 && iter.Value()[0] == 1
 		}
 		hasData = true
 	}
@@ -872,6 +917,13 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 
 }
 
+func (wk *wukongDB) iterMove(iter *pebble.Iterator, reverse bool) bool {
+	if reverse {
+		return iter.Prev()
+	}
+	return iter.Next()
+}
+
 func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, limitSize uint64) ([]Message, error) {
 	var (
 		msgs           = make([]Message, 0)
@@ -935,6 +987,16 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.Modify:
+			modify := &MessageModify{}
+			if err := modify.Unmarshal(iter.Value()); err != nil {
+				return nil, err
+			}
+			preMessage.Modify = modify
+		case key.TableMessage.Column.ParentSeq:
+			preMessage.ParentMessageSeq = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.Sensitive:
+			preMessage.Sensitive = len(iter.Value()) > 0 && iter.Value()[0] == 1
 		}
 	}
 
@@ -1018,5 +1080,48 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 	// index timestamp
 	w.Set(key.NewMessageIndexTimestampKey(uint64(msg.Timestamp), primaryValue), nil)
 
+	// index expire
+	if expireAt := msg.ExpireAt(); expireAt > 0 {
+		w.Set(key.NewMessageSecondIndexExpireKey(expireAt, primaryValue), nil)
+	}
+
+	// index full text
+	if wk.opts.FullTextIndex && msg.Modify == nil {
+		wk.writeMessageSearchIndex(msg.Payload, msg.FromUID, uint64(msg.Timestamp), primaryValue, w)
+	}
+
+	// parent seq
+	if msg.ParentMessageSeq > 0 {
+		parentSeqBytes := make([]byte, 8)
+		wk.endian.PutUint64(parentSeqBytes, msg.ParentMessageSeq)
+		w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.ParentSeq), parentSeqBytes)
+	}
+
+	// sensitive
+	if msg.Sensitive {
+		w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Sensitive), []byte{1})
+	}
+
+	// index thread
+	if err := wk.writeThreadIndex(channelId, channelType, msg, w); err != nil {
+		return err
+	}
+
+	// modify
+	if msg.Modify != nil {
+		modifyData, err := msg.Modify.Marshal()
+		if err != nil {
+			return err
+		}
+		w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Modify), append([]byte(nil), modifyData...))
+
+		if err := wk.writeMessageModify(channelId, channelType, msg, w); err != nil {
+			return err
+		}
+		if err := wk.writeMessageModifySearchIndex(channelId, channelType, msg.Modify, w); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
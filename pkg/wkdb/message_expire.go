@@ -0,0 +1,147 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+// 定时清理过期的消息
+func (wk *wukongDB) expireSweepLoop() {
+	if wk.opts.ExpireSweepInterval <= 0 {
+		return
+	}
+	tk := time.NewTicker(wk.opts.ExpireSweepInterval)
+	defer tk.Stop()
+
+	for {
+		select {
+		case <-tk.C:
+			wk.sweepExpiredMessages(time.Now().Unix())
+		case <-wk.cancelCtx.Done():
+			return
+		}
+	}
+}
+
+func (wk *wukongDB) sweepExpiredMessages(now int64) {
+	for i := 0; i < len(wk.dbs); i++ {
+		for {
+			select {
+			case <-wk.cancelCtx.Done():
+				return
+			default:
+			}
+			count, err := wk.deleteExpiredMessages(uint32(i), now, wk.opts.ExpireSweepBatchSize)
+			if err != nil {
+				wk.Error("deleteExpiredMessages failed", zap.Error(err), zap.Int("shard", i))
+				break
+			}
+			if count < wk.opts.ExpireSweepBatchSize { // 当前分区已清理完
+				break
+			}
+		}
+	}
+}
+
+// deleteExpiredMessages 删除指定分区内过期时间点小于等于now的消息，返回删除的数量
+func (wk *wukongDB) deleteExpiredMessages(shardId uint32, now int64, limit int) (int, error) {
+	db := wk.shardDBById(shardId)
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageSecondIndexExpireKey(0, minMessagePrimaryKey),
+		UpperBound: key.NewMessageSecondIndexExpireKey(uint64(now)+1, minMessagePrimaryKey),
+	})
+	defer iter.Close()
+
+	batch := wk.shardBatchDBById(shardId).NewBatch()
+	count := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		if limit > 0 && count >= limit {
+			break
+		}
+		primaryKey, err := key.ParseMessageSecondIndexKey(iter.Key())
+		if err != nil {
+			wk.Error("parse message expire index key failed", zap.Error(err))
+			continue
+		}
+
+		msg, err := wk.loadMessageByPrimaryKey(db, primaryKey)
+		if err != nil {
+			return 0, err
+		}
+
+		// 索引可能已失效（日志截断后同一位置写入了新消息），只删除索引
+		if IsEmptyMessage(msg) || !msg.IsExpired(now) {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+			count++
+			continue
+		}
+
+		// 删除消息数据
+		batch.DeleteRange(key.NewMessageColumnKeyWithPrimary(primaryKey, key.MinColumnKey), key.NewMessageColumnKeyWithPrimary(primaryKey, key.MaxColumnKey))
+
+		// 删除消息索引
+		batch.Delete(key.NewMessageSecondIndexFromUidKey(msg.FromUID, primaryKey))
+		batch.Delete(key.NewMessageIndexMessageIdKey(uint64(msg.MessageID)))
+		batch.Delete(key.NewMessageSecondIndexClientMsgNoKey(msg.ClientMsgNo, primaryKey))
+		batch.Delete(key.NewMessageIndexTimestampKey(uint64(msg.Timestamp), primaryKey))
+		if err = wk.deleteMessageAllSearchIndex(msg, primaryKey, batch); err != nil {
+			return 0, err
+		}
+		// 回复数量需要读取撤回状态，在删除扩展数据之前处理
+		if err = wk.deleteThreadIndex(msg.ChannelID, msg.ChannelType, msg, batch); err != nil {
+			return 0, err
+		}
+		wk.deleteMessageExtra(msg.ChannelID, msg.ChannelType, uint64(msg.MessageSeq), batch)
+		batch.Delete(append([]byte(nil), iter.Key()...))
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if err := batch.CommitWait(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (wk *wukongDB) loadMessageByPrimaryKey(db *pebble.DB, primaryKey [16]byte) (Message, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var msg Message
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		msg = m
+		return false
+	})
+	if err != nil {
+		return EmptyMessage, err
+	}
+	return msg, nil
+}
+
+// 删除startSeq及之后的消息的过期索引
+func (wk *wukongDB) truncateExpireIndex(channelId string, channelType uint8, startSeq uint64, w *Batch) error {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, startSeq),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var primaryKey [16]byte
+	wk.endian.PutUint64(primaryKey[:], key.ChannelIdToNum(channelId, channelType))
+	return wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if expireAt := m.ExpireAt(); expireAt > 0 {
+			wk.endian.PutUint64(primaryKey[8:], uint64(m.MessageSeq))
+			w.Delete(key.NewMessageSecondIndexExpireKey(expireAt, primaryKey))
+		}
+		return true
+	})
+}
@@ -0,0 +1,292 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) GetMessageExtra(channelId string, channelType uint8, messageSeq uint64) (MessageExtra, error) {
+	extras, err := wk.loadMessageExtras(channelId, channelType, messageSeq, messageSeq+1)
+	if err != nil {
+		return MessageExtra{}, err
+	}
+	if extra, ok := extras[messageSeq]; ok {
+		return extra, nil
+	}
+	return MessageExtra{MessageSeq: messageSeq}, nil
+}
+
+func (wk *wukongDB) GetMessageEditHistory(channelId string, channelType uint8, messageSeq uint64) ([]MessageEditHistory, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageEditHistoryKey(channelId, channelType, messageSeq, 0),
+		UpperBound: key.NewMessageEditHistoryKey(channelId, channelType, messageSeq+1, 0),
+	})
+	defer iter.Close()
+
+	histories := make([]MessageEditHistory, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, version, err := key.ParseMessageEditHistoryKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		payload := make([]byte, len(iter.Value()))
+		copy(payload, iter.Value())
+		histories = append(histories, MessageEditHistory{
+			Version: version,
+			Payload: payload,
+		})
+	}
+	return histories, nil
+}
+
+// 将消息修改写入扩展数据，各副本应用同一条日志得到的结果是一致的
+func (wk *wukongDB) writeMessageModify(channelId string, channelType uint8, msg Message, w *Batch) error {
+	modify := msg.Modify
+	switch modify.Type {
+	case MessageModifyTypeRevoke:
+		// 撤回的回复不再计入话题的回复数量，需要在写入撤回状态之前处理
+		if err := wk.revokeThreadReply(channelId, channelType, modify.MessageSeq, w); err != nil {
+			return err
+		}
+		w.Set(key.NewMessageExtraColumnKey(channelId, channelType, modify.MessageSeq, key.TableMessageExtra.Column.Revoke), []byte{1})
+		w.Set(key.NewMessageExtraColumnKey(channelId, channelType, modify.MessageSeq, key.TableMessageExtra.Column.Revoker), []byte(modify.Operator))
+	case MessageModifyTypeEdit:
+		// 每个版本的内容都保留
+		w.Set(key.NewMessageEditHistoryKey(channelId, channelType, modify.MessageSeq, modify.Version), modify.Payload)
+
+		// 同一批次内可能已经有更新的编辑，需要从批次内读取
+		versionKey := key.NewMessageExtraColumnKey(channelId, channelType, modify.MessageSeq, key.TableMessageExtra.Column.EditVersion)
+		value, err := wk.getValue(wk.channelDb(channelId, channelType), w, versionKey)
+		if err != nil {
+			return err
+		}
+		if value != nil && modify.Version < wk.endian.Uint32(value) { // 旧版本的编辑只保留历史
+			return nil
+		}
+		versionBytes := make([]byte, 4)
+		wk.endian.PutUint32(versionBytes, modify.Version)
+		w.Set(versionKey, versionBytes)
+
+		editedAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(editedAtBytes, uint64(msg.Timestamp))
+		w.Set(key.NewMessageExtraColumnKey(channelId, channelType, modify.MessageSeq, key.TableMessageExtra.Column.EditedAt), editedAtBytes)
+	}
+	return nil
+}
+
+// 删除消息的扩展数据
+func (wk *wukongDB) deleteMessageExtra(channelId string, channelType uint8, messageSeq uint64, w *Batch) {
+	w.DeleteRange(key.NewMessageExtraPrimaryKey(channelId, channelType, messageSeq), key.NewMessageExtraPrimaryKey(channelId, channelType, messageSeq+1))
+	w.DeleteRange(key.NewMessageEditHistoryKey(channelId, channelType, messageSeq, 0), key.NewMessageEditHistoryKey(channelId, channelType, messageSeq+1, 0))
+	// 回应的版本索引在同步时会因为找不到回应而被忽略
+	w.DeleteRange(key.NewReactionMessageKey(channelId, channelType, messageSeq), key.NewReactionMessageKey(channelId, channelType, messageSeq+1))
+	w.DeleteRange(key.NewReceiptMessageKey(channelId, channelType, messageSeq), key.NewReceiptMessageKey(channelId, channelType, messageSeq+1))
+	w.Delete(key.NewReceiptCountKey(channelId, channelType, messageSeq))
+}
+
+// 加载[startSeq,endSeq)范围内消息的扩展数据
+func (wk *wukongDB) loadMessageExtras(channelId string, channelType uint8, startSeq, endSeq uint64) (map[uint64]MessageExtra, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageExtraPrimaryKey(channelId, channelType, startSeq),
+		UpperBound: key.NewMessageExtraPrimaryKey(channelId, channelType, endSeq),
+	})
+	defer iter.Close()
+
+	extras := make(map[uint64]MessageExtra)
+	for iter.First(); iter.Valid(); iter.Next() {
+		messageSeq, columnName, err := key.ParseMessageExtraColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		extra := extras[messageSeq]
+		extra.MessageSeq = messageSeq
+		switch columnName {
+		case key.TableMessageExtra.Column.Revoke:
+			extra.Revoke = iter.Value()[0] == 1
+		case key.TableMessageExtra.Column.Revoker:
+			extra.Revoker = string(iter.Value())
+		case key.TableMessageExtra.Column.EditVersion:
+			extra.EditVersion = wk.endian.Uint32(iter.Value())
+		case key.TableMessageExtra.Column.EditedAt:
+			extra.EditedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		extras[messageSeq] = extra
+	}
+	return extras, nil
+}
+
+// 将扩展数据（撤回/编辑）填充到消息上，msgs需要是同一个频道的消息
+func (wk *wukongDB) fillMessageExtras(channelId string, channelType uint8, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	minSeq, maxSeq := uint64(msgs[0].MessageSeq), uint64(msgs[0].MessageSeq)
+	for _, m := range msgs {
+		if uint64(m.MessageSeq) < minSeq {
+			minSeq = uint64(m.MessageSeq)
+		}
+		if uint64(m.MessageSeq) > maxSeq {
+			maxSeq = uint64(m.MessageSeq)
+		}
+	}
+	extras, err := wk.loadMessageExtras(channelId, channelType, minSeq, maxSeq+1)
+	if err != nil {
+		return err
+	}
+	if len(extras) == 0 {
+		return nil
+	}
+	db := wk.channelDb(channelId, channelType)
+	for i, m := range msgs {
+		extra, ok := extras[uint64(m.MessageSeq)]
+		if !ok {
+			continue
+		}
+		m.Revoke = extra.Revoke
+		m.Revoker = extra.Revoker
+		m.EditVersion = extra.EditVersion
+		m.EditedAt = extra.EditedAt
+		if extra.EditVersion > 0 { // 返回最新编辑的内容
+			payload, closer, err := db.Get(key.NewMessageEditHistoryKey(channelId, channelType, uint64(m.MessageSeq), extra.EditVersion))
+			if err != nil && err != pebble.ErrNotFound {
+				return err
+			}
+			if err == nil {
+				m.Payload = append([]byte(nil), payload...)
+				closer.Close()
+			}
+		}
+		msgs[i] = m
+	}
+	return nil
+}
+
+// 回滚被截断的消息修改
+// 截断范围内消息自身的扩展数据直接删除；截断范围内的修改日志如果修改的是截断点之前的消息，
+// 则根据截断点之前剩余的修改日志重新计算这些消息的扩展数据（撤回、编辑版本、编辑历史、全文索引）
+func (wk *wukongDB) truncateMessageModify(channelId string, channelType uint8, startSeq uint64, w *Batch) error {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, startSeq),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	targets := make(map[uint64]struct{})
+	minTargetSeq := startSeq
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if m.Modify != nil && m.Modify.MessageSeq < startSeq {
+			targets[m.Modify.MessageSeq] = struct{}{}
+			if m.Modify.MessageSeq < minTargetSeq {
+				minTargetSeq = m.Modify.MessageSeq
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	w.DeleteRange(key.NewMessageExtraPrimaryKey(channelId, channelType, startSeq), key.NewMessageExtraPrimaryKey(channelId, channelType, math.MaxUint64))
+	w.DeleteRange(key.NewMessageEditHistoryKey(channelId, channelType, startSeq, 0), key.NewMessageEditHistoryKey(channelId, channelType, math.MaxUint64, 0))
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// 按日志顺序收集剩余的修改
+	modifies := make(map[uint64][]Message)
+	iter = db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, minTargetSeq+1),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, startSeq),
+	})
+	defer iter.Close()
+	err = wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if m.Modify != nil {
+			if _, ok := targets[m.Modify.MessageSeq]; ok {
+				modifies[m.Modify.MessageSeq] = append(modifies[m.Modify.MessageSeq], m)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for targetSeq := range targets {
+		if err := wk.rebuildMessageModify(channelId, channelType, targetSeq, modifies[targetSeq], w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 根据修改日志重新计算消息的扩展数据，modifies需要按日志顺序排列
+func (wk *wukongDB) rebuildMessageModify(channelId string, channelType uint8, messageSeq uint64, modifies []Message, w *Batch) error {
+	target, err := wk.LoadMsg(channelId, channelType, messageSeq)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	var primaryKey [16]byte
+	wk.endian.PutUint64(primaryKey[:], key.ChannelIdToNum(channelId, channelType))
+	wk.endian.PutUint64(primaryKey[8:], messageSeq)
+
+	if wk.opts.FullTextIndex {
+		if err := wk.deleteMessageAllSearchIndex(target, primaryKey, w); err != nil {
+			return err
+		}
+		wk.writeMessageSearchIndex(target.Payload, target.FromUID, uint64(target.Timestamp), primaryKey, w)
+	}
+	wasRevoked, err := wk.isMessageRevoked(channelId, channelType, messageSeq, w)
+	if err != nil {
+		return err
+	}
+	// 批次内先执行删除再执行写入，所以可以先删除再写入重新计算的结果
+	w.DeleteRange(key.NewMessageExtraPrimaryKey(channelId, channelType, messageSeq), key.NewMessageExtraPrimaryKey(channelId, channelType, messageSeq+1))
+	w.DeleteRange(key.NewMessageEditHistoryKey(channelId, channelType, messageSeq, 0), key.NewMessageEditHistoryKey(channelId, channelType, messageSeq+1, 0))
+
+	var (
+		editVersion uint32
+		editedAt    int64
+		revoked     bool
+	)
+	for _, m := range modifies {
+		modify := m.Modify
+		switch modify.Type {
+		case MessageModifyTypeRevoke:
+			revoked = true
+			w.Set(key.NewMessageExtraColumnKey(channelId, channelType, messageSeq, key.TableMessageExtra.Column.Revoke), []byte{1})
+			w.Set(key.NewMessageExtraColumnKey(channelId, channelType, messageSeq, key.TableMessageExtra.Column.Revoker), []byte(modify.Operator))
+		case MessageModifyTypeEdit:
+			w.Set(key.NewMessageEditHistoryKey(channelId, channelType, messageSeq, modify.Version), modify.Payload)
+			if modify.Version >= editVersion {
+				editVersion = modify.Version
+				editedAt = int64(m.Timestamp)
+			}
+			if wk.opts.FullTextIndex {
+				wk.writeMessageSearchIndex(modify.Payload, target.FromUID, uint64(target.Timestamp), primaryKey, w)
+			}
+		}
+	}
+	if editVersion > 0 {
+		versionBytes := make([]byte, 4)
+		wk.endian.PutUint32(versionBytes, editVersion)
+		w.Set(key.NewMessageExtraColumnKey(channelId, channelType, messageSeq, key.TableMessageExtra.Column.EditVersion), versionBytes)
+
+		editedAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(editedAtBytes, uint64(editedAt))
+		w.Set(key.NewMessageExtraColumnKey(channelId, channelType, messageSeq, key.TableMessageExtra.Column.EditedAt), editedAtBytes)
+	}
+	// 回复的撤回被回滚，重新计入话题的回复数量
+	if wasRevoked && !revoked {
+		return wk.restoreThreadReply(channelId, channelType, messageSeq, w)
+	}
+	return nil
+}
@@ -0,0 +1,495 @@
+package wkdb
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+// 重建索引时每批提交的消息数量
+const rebuildSearchIndexBatchSize = 1000
+
+// 写入消息内容的倒排索引，索引值为发送者uid的hash，用于按发送者过滤
+func (wk *wukongDB) writeMessageSearchIndex(payload []byte, fromUid string, timestamp uint64, primaryKey [16]byte, w *Batch) {
+	terms := groupTokens(tokenize(searchTextOfPayload(payload)))
+	if len(terms) == 0 {
+		return
+	}
+	fromUidHash := make([]byte, 8)
+	wk.endian.PutUint64(fromUidHash, key.HashWithString(fromUid))
+	for term := range terms {
+		w.Set(key.NewMessageSearchKey(term, timestamp, primaryKey), fromUidHash)
+	}
+}
+
+// 删除消息内容的倒排索引
+func (wk *wukongDB) deleteMessageSearchIndex(payload []byte, timestamp uint64, primaryKey [16]byte, w *Batch) {
+	terms := groupTokens(tokenize(searchTextOfPayload(payload)))
+	for term := range terms {
+		w.Delete(key.NewMessageSearchKey(term, timestamp, primaryKey))
+	}
+}
+
+// 消息编辑后为新内容建立索引，旧内容的索引在查询时通过校验过滤
+func (wk *wukongDB) writeMessageModifySearchIndex(channelId string, channelType uint8, modify *MessageModify, w *Batch) error {
+	if !wk.opts.FullTextIndex || modify.Type != MessageModifyTypeEdit {
+		return nil
+	}
+	// 被编辑的消息可能和编辑日志在同一批次里，还未提交
+	fromUid, err := wk.getMessageColumn(channelId, channelType, modify.MessageSeq, key.TableMessage.Column.FromUid, w)
+	if err != nil {
+		return err
+	}
+	timestampBytes, err := wk.getMessageColumn(channelId, channelType, modify.MessageSeq, key.TableMessage.Column.Timestamp, w)
+	if err != nil {
+		return err
+	}
+	if len(timestampBytes) < 4 { // 消息不存在
+		return nil
+	}
+	var primaryKey [16]byte
+	wk.endian.PutUint64(primaryKey[:], key.ChannelIdToNum(channelId, channelType))
+	wk.endian.PutUint64(primaryKey[8:], modify.MessageSeq)
+	wk.writeMessageSearchIndex(modify.Payload, string(fromUid), uint64(wk.endian.Uint32(timestampBytes)), primaryKey, w)
+	return nil
+}
+
+// 获取消息的某一列，优先从未提交的批次中获取
+func (wk *wukongDB) getMessageColumn(channelId string, channelType uint8, messageSeq uint64, columnName [2]byte, w *Batch) ([]byte, error) {
+	columnKey := key.NewMessageColumnKey(channelId, channelType, messageSeq, columnName)
+	if value, ok := w.get(columnKey); ok {
+		return value, nil
+	}
+	value, closer, err := wk.channelDb(channelId, channelType).Get(columnKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer closer.Close()
+	return append([]byte(nil), value...), nil
+}
+
+// 删除消息（包含所有编辑版本）的倒排索引
+func (wk *wukongDB) deleteMessageAllSearchIndex(msg Message, primaryKey [16]byte, w *Batch) error {
+	wk.deleteMessageSearchIndex(msg.Payload, uint64(msg.Timestamp), primaryKey, w)
+	histories, err := wk.GetMessageEditHistory(msg.ChannelID, msg.ChannelType, uint64(msg.MessageSeq))
+	if err != nil {
+		return err
+	}
+	for _, history := range histories {
+		wk.deleteMessageSearchIndex(history.Payload, uint64(msg.Timestamp), primaryKey, w)
+	}
+	return nil
+}
+
+// 删除截断范围内消息的倒排索引，避免同一位置重新追加的消息匹配到旧内容
+func (wk *wukongDB) truncateMessageSearchIndex(channelId string, channelType uint8, startSeq uint64, w *Batch) error {
+	if !wk.opts.FullTextIndex {
+		return nil
+	}
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, startSeq),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	var primaryKey [16]byte
+	wk.endian.PutUint64(primaryKey[:], key.ChannelIdToNum(channelId, channelType))
+	var deleteErr error
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if m.Modify != nil {
+			return true
+		}
+		wk.endian.PutUint64(primaryKey[8:], uint64(m.MessageSeq))
+		if deleteErr = wk.deleteMessageAllSearchIndex(m, primaryKey, w); deleteErr != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return deleteErr
+}
+
+type searchClause []string
+
+// 解析搜索关键字
+// 空格分隔的多个词是且的关系，双引号包含的内容作为一个短语，每个词或短语内的分词需要位置相邻
+func parseSearchKeyword(keyword string) []searchClause {
+	clauses := make([]searchClause, 0)
+	addClause := func(text string) {
+		tokens := tokenize(text)
+		if len(tokens) == 0 {
+			return
+		}
+		clause := make(searchClause, 0, len(tokens))
+		for _, t := range tokens {
+			if int(t.pos) < len(clause) { // 同位置的单字索引不参与查询
+				continue
+			}
+			clause = append(clause, t.term)
+		}
+		clauses = append(clauses, clause)
+	}
+	for i, part := range strings.Split(keyword, "\"") {
+		if i%2 == 1 {
+			addClause(part)
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			addClause(word)
+		}
+	}
+	return clauses
+}
+
+// 判断内容是否满足所有查询条件
+func matchSearchClauses(payload []byte, clauses []searchClause) bool {
+	terms := groupTokens(tokenize(searchTextOfPayload(payload)))
+	for _, clause := range clauses {
+		if !matchSearchClause(terms, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchSearchClause(terms map[string][]uint32, clause searchClause) bool {
+	for _, start := range terms[clause[0]] {
+		matched := true
+		for i := 1; i < len(clause); i++ {
+			if !containsPosition(terms[clause[i]], start+uint32(i)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPosition(positions []uint32, pos uint32) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// 通过倒排索引搜索消息，结果按时间倒序
+func (wk *wukongDB) searchMessagesByKeyword(req MessageSearchReq) ([]Message, error) {
+	if strings.TrimSpace(req.ChannelId) != "" && req.ChannelType == 0 {
+		return nil, ErrChannelTypeRequired
+	}
+	clauses := parseSearchKeyword(req.Keyword)
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	// 选择最长的词驱动查询，其他词通过点查过滤
+	termSet := make(map[string]struct{})
+	var driverTerm string
+	for _, clause := range clauses {
+		for _, term := range clause {
+			termSet[term] = struct{}{}
+			if len(term) > len(driverTerm) {
+				driverTerm = term
+			}
+		}
+	}
+	otherTerms := make([]string, 0, len(termSet))
+	for term := range termSet {
+		if term != driverTerm {
+			otherTerms = append(otherTerms, term)
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var (
+		allMsgs []Message
+		err     error
+	)
+	if strings.TrimSpace(req.ChannelId) != "" {
+		allMsgs, err = wk.searchMessagesByKeywordInShard(wk.channelDbIndex(req.ChannelId, req.ChannelType), req, clauses, driverTerm, otherTerms, limit)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i := 0; i < len(wk.dbs); i++ {
+			msgs, err := wk.searchMessagesByKeywordInShard(uint32(i), req, clauses, driverTerm, otherTerms, limit)
+			if err != nil {
+				return nil, err
+			}
+			allMsgs = append(allMsgs, msgs...)
+		}
+	}
+	sort.Slice(allMsgs, func(i, j int) bool {
+		if allMsgs[i].Timestamp == allMsgs[j].Timestamp {
+			return allMsgs[i].MessageID > allMsgs[j].MessageID
+		}
+		return allMsgs[i].Timestamp > allMsgs[j].Timestamp
+	})
+	if len(allMsgs) > limit {
+		if req.Pre { // 向前搜索时保留离游标最近的消息
+			allMsgs = allMsgs[len(allMsgs)-limit:]
+		} else {
+			allMsgs = allMsgs[:limit]
+		}
+	}
+	return allMsgs, nil
+}
+
+// 在指定分区内按关键字搜索，向前搜索（Pre）时按时间正序遍历，否则按时间倒序遍历
+func (wk *wukongDB) searchMessagesByKeywordInShard(shardId uint32, req MessageSearchReq, clauses []searchClause, driverTerm string, otherTerms []string, limit int) ([]Message, error) {
+	db := wk.shardDBById(shardId)
+
+	var startTime uint64
+	var endTime uint64 = math.MaxUint64
+	if req.StartTime > 0 {
+		startTime = uint64(req.StartTime)
+	}
+	if req.EndTime > 0 {
+		endTime = uint64(req.EndTime)
+	}
+
+	var channelHash uint64
+	filterChannel := strings.TrimSpace(req.ChannelId) != ""
+	if filterChannel {
+		channelHash = key.ChannelIdToNum(req.ChannelId, req.ChannelType)
+	}
+	var fromUidHash uint64
+	filterFromUid := strings.TrimSpace(req.FromUid) != ""
+	if filterFromUid {
+		fromUidHash = key.HashWithString(req.FromUid)
+	}
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageSearchKey(driverTerm, startTime, minMessagePrimaryKey),
+		UpperBound: key.NewMessageSearchKey(driverTerm, endTime, minMessagePrimaryKey),
+	})
+	defer iter.Close()
+
+	valid, step := iter.Last, iter.Prev
+	if req.Pre {
+		valid, step = iter.First, iter.Next
+	}
+
+	now := time.Now().Unix()
+	msgs := make([]Message, 0)
+	for valid(); iter.Valid() && len(msgs) < limit; step() {
+		timestamp, primaryKey, err := key.ParseMessageSearchKey(iter.Key())
+		if err != nil {
+			wk.Error("parse message search key failed", zap.Error(err))
+			continue
+		}
+		// 先通过索引里的hash过滤，加载消息后再精确校验
+		if filterChannel && wk.endian.Uint64(primaryKey[:8]) != channelHash {
+			continue
+		}
+		if filterFromUid && (len(iter.Value()) < 8 || wk.endian.Uint64(iter.Value()) != fromUidHash) {
+			continue
+		}
+		if req.OffsetMessageSeq > 0 && filterChannel && !matchSearchOffset(req.Pre, wk.endian.Uint64(primaryKey[8:]), req.OffsetMessageSeq) {
+			continue
+		}
+		has, err := wk.hasAllSearchTerms(db, otherTerms, timestamp, primaryKey)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			continue
+		}
+
+		msg, err := wk.loadMessageByPrimaryKey(db, primaryKey)
+		if err != nil {
+			return nil, err
+		}
+		if IsEmptyMessage(msg) || msg.IsExpired(now) || msg.Modify != nil {
+			continue
+		}
+		if !matchSearchReq(req, msg) {
+			continue
+		}
+		result := []Message{msg}
+		if err = wk.fillMessageExtras(msg.ChannelID, msg.ChannelType, result); err != nil {
+			return nil, err
+		}
+		msg = result[0]
+		if msg.Revoke {
+			continue
+		}
+		// 消息可能被编辑过，需要用最终内容校验
+		if !matchSearchClauses(msg.Payload, clauses) {
+			continue
+		}
+		if len(req.Payload) > 0 && !bytes.Contains(msg.Payload, req.Payload) {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// 校验消息是否满足关键字以外的搜索条件（与非关键字搜索的条件一致）
+func matchSearchReq(req MessageSearchReq, msg Message) bool {
+	if strings.TrimSpace(req.ChannelId) != "" && (msg.ChannelID != req.ChannelId || msg.ChannelType != req.ChannelType) {
+		return false
+	}
+	if req.ChannelType != 0 && msg.ChannelType != req.ChannelType {
+		return false
+	}
+	if strings.TrimSpace(req.FromUid) != "" && msg.FromUID != req.FromUid {
+		return false
+	}
+	if strings.TrimSpace(req.ClientMsgNo) != "" && msg.ClientMsgNo != req.ClientMsgNo {
+		return false
+	}
+	if req.MessageId > 0 && msg.MessageID != req.MessageId {
+		return false
+	}
+	if req.OffsetMessageId > 0 && !matchSearchOffset(req.Pre, uint64(msg.MessageID), uint64(req.OffsetMessageId)) {
+		return false
+	}
+	if req.OffsetMessageSeq > 0 && strings.TrimSpace(req.ChannelId) != "" && !matchSearchOffset(req.Pre, uint64(msg.MessageSeq), req.OffsetMessageSeq) {
+		return false
+	}
+	return true
+}
+
+// 分页游标，向前搜索取大于游标的消息，否则取小于游标的消息
+func matchSearchOffset(pre bool, value, offset uint64) bool {
+	if pre {
+		return value > offset
+	}
+	return value < offset
+}
+
+func (wk *wukongDB) hasAllSearchTerms(db *pebble.DB, terms []string, timestamp uint64, primaryKey [16]byte) (bool, error) {
+	for _, term := range terms {
+		_, closer, err := db.Get(key.NewMessageSearchKey(term, timestamp, primaryKey))
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		closer.Close()
+	}
+	return true, nil
+}
+
+func (wk *wukongDB) RebuildMessageSearchIndex() error {
+	for i := 0; i < len(wk.dbs); i++ {
+		if err := wk.rebuildMessageSearchIndex(uint32(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 重建指定分区的倒排索引
+func (wk *wukongDB) rebuildMessageSearchIndex(shardId uint32) error {
+	batchDb := wk.shardBatchDBById(shardId)
+
+	// 先清空旧索引
+	batch := batchDb.NewBatch()
+	batch.DeleteRange(key.NewMessageSearchLowKey(), key.NewMessageSearchHighKey())
+	if err := batch.CommitWait(); err != nil {
+		return err
+	}
+
+	db := wk.shardDBById(shardId)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary(minMessagePrimaryKey, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(maxMessagePrimaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		prePrimaryKey []byte
+		msg           Message
+		count         int
+	)
+	batch = batchDb.NewBatch()
+	flush := func() error {
+		if prePrimaryKey == nil || msg.Modify != nil {
+			return nil
+		}
+		var primaryKey [16]byte
+		copy(primaryKey[:], prePrimaryKey)
+		wk.writeMessageSearchIndex(msg.Payload, msg.FromUID, uint64(msg.Timestamp), primaryKey, batch)
+		histories, err := wk.GetMessageEditHistory(msg.ChannelID, msg.ChannelType, uint64(msg.MessageSeq))
+		if err != nil {
+			return err
+		}
+		for _, history := range histories {
+			wk.writeMessageSearchIndex(history.Payload, msg.FromUID, uint64(msg.Timestamp), primaryKey, batch)
+		}
+		count++
+		if count%rebuildSearchIndexBatchSize == 0 {
+			if err := batch.CommitWait(); err != nil {
+				return err
+			}
+			batch = batchDb.NewBatch()
+		}
+		return nil
+	}
+
+	// 按主键（频道+消息序号）聚合列数据
+	for iter.First(); iter.Valid(); iter.Next() {
+		k := iter.Key()
+		if len(k) != key.TableMessage.Size {
+			continue
+		}
+		if !bytes.Equal(prePrimaryKey, k[4:20]) {
+			if err := flush(); err != nil {
+				return err
+			}
+			prePrimaryKey = append(prePrimaryKey[:0], k[4:20]...)
+			msg = Message{}
+		}
+		messageSeq, columnName, err := key.ParseMessageColumnKey(k)
+		if err != nil {
+			return err
+		}
+		msg.MessageSeq = uint32(messageSeq)
+		value := iter.Value()
+		switch columnName {
+		case key.TableMessage.Column.ChannelId:
+			msg.ChannelID = string(value)
+		case key.TableMessage.Column.ChannelType:
+			msg.ChannelType = value[0]
+		case key.TableMessage.Column.FromUid:
+			msg.FromUID = string(value)
+		case key.TableMessage.Column.Timestamp:
+			msg.Timestamp = int32(wk.endian.Uint32(value))
+		case key.TableMessage.Column.Payload:
+			msg.Payload = append([]byte(nil), value...)
+		case key.TableMessage.Column.Modify:
+			msg.Modify = &MessageModify{}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if len(batch.setKvs) == 0 {
+		return nil
+	}
+	return batch.CommitWait()
+}
@@ -1,7 +1,9 @@
 package wkdb_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -210,3 +212,837 @@ func TestSearchMessages(t *testing.T) {
 	assert.Equal(t, 10, len(resultMessages))
 
 }
+
+func TestLoadMsgsSkipExpired(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	now := time.Now().Unix()
+	messages := []wkdb.Message{}
+	for i := 0; i < 10; i++ {
+		var expire uint32
+		if i%2 == 0 {
+			expire = 10 // 偶数seq的消息已过期
+		}
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Expire:      expire,
+				Timestamp:   int32(now - 60),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 3)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+	assert.Equal(t, uint32(2), resultMessages[0].MessageSeq)
+	assert.Equal(t, uint32(6), resultMessages[2].MessageSeq)
+
+	resultMessages, err = d.LoadPrevRangeMsgs(channelId, channelType, 10, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 5)
+
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Limit:       100,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 5)
+}
+
+func TestExpiredMessagesSweep(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithExpireSweepInterval(time.Millisecond*20)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   1,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  1,
+				Expire:      1,
+				Timestamp:   int32(time.Now().Unix() - 10),
+				Payload:     []byte("hello"),
+			},
+		},
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   2,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  2,
+				Timestamp:   int32(time.Now().Unix()),
+				Payload:     []byte("world"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	time.Sleep(time.Millisecond * 200)
+
+	// 过期的消息已被物理删除
+	resultMessages, err := d.LoadNextRangeMsgsForSize(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+	assert.Equal(t, uint32(2), resultMessages[0].MessageSeq)
+
+	_, err = d.GetMessage(1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
+
+func TestExpiredMessagesSweepSkipRewritten(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(1), wkdb.WithExpireSweepInterval(time.Millisecond*20)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	newMsg := func(messageId int64, seq uint32, expire uint32, timestamp int64) wkdb.Message {
+		return wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   messageId,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  seq,
+				Expire:      expire,
+				Timestamp:   int32(timestamp),
+				Payload:     []byte("hello"),
+			},
+		}
+	}
+
+	expireAt := time.Now().Unix() + 1
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		newMsg(1, 1, 1, expireAt-1),
+		newMsg(2, 2, 1, expireAt-1),
+	})
+	assert.NoError(t, err)
+
+	// 同一位置被新的未过期消息覆盖
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{newMsg(3, 1, 0, time.Now().Unix())})
+	assert.NoError(t, err)
+
+	// 截断后重新写入
+	err = d.TruncateLogTo(channelId, channelType, 2)
+	assert.NoError(t, err)
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{newMsg(4, 2, 0, time.Now().Unix())})
+	assert.NoError(t, err)
+
+	time.Sleep(time.Until(time.Unix(expireAt, 0)) + time.Millisecond*200)
+
+	resultMessages, err := d.LoadNextRangeMsgsForSize(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 2)
+
+	msg, err := d.GetMessage(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), msg.MessageSeq)
+	msg, err = d.GetMessage(4)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), msg.MessageSeq)
+}
+
+func TestMessageModify(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 3; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	// 撤回seq为1的消息，编辑seq为2的消息
+	messages = append(messages, wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:   4,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  4,
+		},
+		Modify: &wkdb.MessageModify{
+			Type:       wkdb.MessageModifyTypeRevoke,
+			MessageSeq: 1,
+			Operator:   "u1",
+		},
+	}, wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:   5,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  5,
+			Timestamp:   100,
+		},
+		Modify: &wkdb.MessageModify{
+			Type:       wkdb.MessageModifyTypeEdit,
+			MessageSeq: 2,
+			Operator:   "u1",
+			Version:    1,
+			Payload:    []byte("world"),
+		},
+	})
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+
+	assert.True(t, resultMessages[0].Revoke)
+	assert.Equal(t, "u1", resultMessages[0].Revoker)
+
+	assert.Equal(t, uint32(1), resultMessages[1].EditVersion)
+	assert.Equal(t, int64(100), resultMessages[1].EditedAt)
+	assert.Equal(t, []byte("world"), resultMessages[1].Payload)
+
+	assert.False(t, resultMessages[2].Revoke)
+	assert.Equal(t, uint32(0), resultMessages[2].EditVersion)
+
+	histories, err := d.GetMessageEditHistory(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Len(t, histories, 1)
+	assert.Equal(t, []byte("world"), histories[0].Payload)
+
+	// 修改日志需要原样提供给副本复制
+	logMessages, err := d.LoadNextRangeMsgsForSize(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, logMessages, 5)
+	assert.Equal(t, []byte("hello"), logMessages[1].Payload)
+	assert.NotNil(t, logMessages[4].Modify)
+	assert.Equal(t, uint32(1), logMessages[4].Modify.Version)
+}
+
+func TestTruncateLogToRollbackModify(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 3; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Timestamp:   int32(10 + i),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	modify := func(seq uint32, timestamp int32, m *wkdb.MessageModify) wkdb.Message {
+		return wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(seq),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  seq,
+				Timestamp:   timestamp,
+			},
+			Modify: m,
+		}
+	}
+	messages = append(messages,
+		modify(4, 50, &wkdb.MessageModify{Type: wkdb.MessageModifyTypeRevoke, MessageSeq: 1, Operator: "u1"}),
+		modify(5, 100, &wkdb.MessageModify{Type: wkdb.MessageModifyTypeEdit, MessageSeq: 2, Operator: "u1", Version: 1, Payload: []byte("world")}),
+		modify(6, 200, &wkdb.MessageModify{Type: wkdb.MessageModifyTypeEdit, MessageSeq: 2, Operator: "u1", Version: 2, Payload: []byte("again")}),
+		modify(7, 300, &wkdb.MessageModify{Type: wkdb.MessageModifyTypeRevoke, MessageSeq: 3, Operator: "u2"}),
+	)
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	search := func(keyword string) []int64 {
+		msgs, err := d.SearchMessages(wkdb.MessageSearchReq{Keyword: keyword, ChannelId: channelId, ChannelType: channelType, Limit: 10})
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(msgs))
+		for _, m := range msgs {
+			ids = append(ids, m.MessageID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{2}, search("again"))
+
+	// 截断未提交的第二次编辑和撤回，恢复到第一次编辑后的状态
+	err = d.TruncateLogTo(channelId, channelType, 6)
+	assert.NoError(t, err)
+
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 3)
+	assert.True(t, msgs[0].Revoke)
+	assert.Equal(t, "u1", msgs[0].Revoker)
+	assert.Equal(t, uint32(1), msgs[1].EditVersion)
+	assert.Equal(t, int64(100), msgs[1].EditedAt)
+	assert.Equal(t, []byte("world"), msgs[1].Payload)
+	assert.False(t, msgs[2].Revoke)
+	assert.Equal(t, "", msgs[2].Revoker)
+
+	histories, err := d.GetMessageEditHistory(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Len(t, histories, 1)
+	assert.Empty(t, search("again"))
+	assert.Equal(t, []int64{2}, search("world"))
+	assert.Equal(t, []int64{3}, search("hello"))
+
+	// 截断所有修改
+	err = d.TruncateLogTo(channelId, channelType, 4)
+	assert.NoError(t, err)
+
+	msgs, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 3)
+	assert.False(t, msgs[0].Revoke)
+	assert.Equal(t, uint32(0), msgs[1].EditVersion)
+	assert.Equal(t, int64(0), msgs[1].EditedAt)
+	assert.Equal(t, []byte("hello"), msgs[1].Payload)
+	histories, err = d.GetMessageEditHistory(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, histories)
+	assert.Empty(t, search("world"))
+	assert.Equal(t, []int64{3, 2, 1}, search("hello"))
+
+	// 同一位置重新追加的消息不能匹配到旧内容
+	err = d.TruncateLogTo(channelId, channelType, 2)
+	assert.NoError(t, err)
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   20,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  2,
+				Timestamp:   11,
+				Payload:     []byte("fresh"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, search("hello"))
+	assert.Equal(t, []int64{20}, search("fresh"))
+}
+
+func TestLoadPrevRangeMsgsSkipModify(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	// 末尾是两条修改日志，同一批次内新版本的编辑先于旧版本
+	messages = append(messages, wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:   6,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  6,
+		},
+		Modify: &wkdb.MessageModify{
+			Type:       wkdb.MessageModifyTypeEdit,
+			MessageSeq: 5,
+			Operator:   "u1",
+			Version:    2,
+			Payload:    []byte("v2"),
+		},
+	}, wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:   7,
+			ChannelID:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  7,
+		},
+		Modify: &wkdb.MessageModify{
+			Type:       wkdb.MessageModifyTypeEdit,
+			MessageSeq: 5,
+			Operator:   "u1",
+			Version:    1,
+			Payload:    []byte("v1"),
+		},
+	})
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	resultMessages, err := d.LoadPrevRangeMsgs(channelId, channelType, 7, 0, 3)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+	assert.Equal(t, uint32(3), resultMessages[0].MessageSeq)
+	assert.Equal(t, uint32(5), resultMessages[2].MessageSeq)
+
+	assert.Equal(t, uint32(2), resultMessages[2].EditVersion)
+	assert.Equal(t, []byte("v2"), resultMessages[2].Payload)
+
+	resultMessages, err = d.LoadLastMsgs(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 2)
+	assert.Equal(t, uint32(4), resultMessages[0].MessageSeq)
+	assert.Equal(t, uint32(5), resultMessages[1].MessageSeq)
+}
+
+func TestSearchMessagesByKeyword(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	payloads := []string{
+		`{"type":1,"content":"Hello World from WuKongIM"}`,
+		`{"type":1,"content":"world hello"}`,
+		`{"type":1,"content":"今天天气很好，我们去公园吧"}`,
+		`{"type":1,"content":"公园今天关门"}`,
+		`hello again`,
+	}
+	messages := []wkdb.Message{}
+	for i, payload := range payloads {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     fmt.Sprintf("u%d", i%2),
+				Timestamp:   int32(100 + i),
+				Payload:     []byte(payload),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	err = d.AppendMessages("other", channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   10,
+				ChannelID:   "other",
+				ChannelType: channelType,
+				MessageSeq:  1,
+				FromUID:     "u0",
+				Timestamp:   110,
+				Payload:     []byte("hello world"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	search := func(req wkdb.MessageSearchReq) []int64 {
+		req.Limit = 10
+		msgs, err := d.SearchMessages(req)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(msgs))
+		for _, m := range msgs {
+			ids = append(ids, m.MessageID)
+		}
+		return ids
+	}
+
+	// 且查询，大小写不敏感，结果按时间倒序
+	assert.Equal(t, []int64{10, 2, 1}, search(wkdb.MessageSearchReq{Keyword: "HELLO world"}))
+	// 短语查询
+	assert.Equal(t, []int64{10, 1}, search(wkdb.MessageSearchReq{Keyword: `"hello world"`}))
+	// 中文
+	assert.Equal(t, []int64{4, 3}, search(wkdb.MessageSearchReq{Keyword: "今天"}))
+	assert.Equal(t, []int64{3}, search(wkdb.MessageSearchReq{Keyword: "天气很好"}))
+	assert.Empty(t, search(wkdb.MessageSearchReq{Keyword: "天气公园"}))
+	// 单个字也能搜索到
+	assert.Equal(t, []int64{4, 3}, search(wkdb.MessageSearchReq{Keyword: "园"}))
+	assert.Equal(t, []int64{4}, search(wkdb.MessageSearchReq{Keyword: "门"}))
+	// 按频道、发送者、时间范围过滤
+	assert.Equal(t, []int64{5, 2, 1}, search(wkdb.MessageSearchReq{Keyword: "hello", ChannelId: channelId, ChannelType: channelType}))
+	assert.Equal(t, []int64{5, 1}, search(wkdb.MessageSearchReq{Keyword: "hello", ChannelId: channelId, ChannelType: channelType, FromUid: "u0"}))
+	assert.Equal(t, []int64{2}, search(wkdb.MessageSearchReq{Keyword: "hello", StartTime: 101, EndTime: 104}))
+	assert.Equal(t, []int64{2}, search(wkdb.MessageSearchReq{Keyword: "hello", MessageId: 2}))
+	assert.Equal(t, []int64{1}, search(wkdb.MessageSearchReq{Keyword: "hello", Payload: []byte("WuKongIM")}))
+	// 只指定频道id时不能返回其他频道的消息
+	_, err = d.SearchMessages(wkdb.MessageSearchReq{Keyword: "hello", ChannelId: channelId, Limit: 10})
+	assert.Equal(t, wkdb.ErrChannelTypeRequired, err)
+
+	// 分页
+	page := func(req wkdb.MessageSearchReq) []int64 {
+		req.Keyword = "hello"
+		req.Limit = 2
+		msgs, err := d.SearchMessages(req)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(msgs))
+		for _, m := range msgs {
+			ids = append(ids, m.MessageID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{10, 5}, page(wkdb.MessageSearchReq{}))
+	assert.Equal(t, []int64{2, 1}, page(wkdb.MessageSearchReq{OffsetMessageId: 5}))
+	assert.Empty(t, page(wkdb.MessageSearchReq{OffsetMessageId: 1}))
+	assert.Equal(t, []int64{5, 2}, page(wkdb.MessageSearchReq{OffsetMessageId: 1, Pre: true}))
+	assert.Equal(t, []int64{1}, page(wkdb.MessageSearchReq{ChannelId: channelId, ChannelType: channelType, OffsetMessageSeq: 2}))
+
+	// 编辑后按新内容搜索
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   6,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  6,
+				Timestamp:   200,
+			},
+			Modify: &wkdb.MessageModify{
+				Type:       wkdb.MessageModifyTypeEdit,
+				MessageSeq: 5,
+				Operator:   "u0",
+				Version:    1,
+				Payload:    []byte("goodbye"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, search(wkdb.MessageSearchReq{Keyword: "hello", ChannelId: channelId, ChannelType: channelType}))
+	assert.Equal(t, []int64{5}, search(wkdb.MessageSearchReq{Keyword: "goodbye"}))
+
+	// 重建索引
+	err = d.RebuildMessageSearchIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{10, 2, 1}, search(wkdb.MessageSearchReq{Keyword: "hello world"}))
+	assert.Equal(t, []int64{5}, search(wkdb.MessageSearchReq{Keyword: "goodbye"}))
+}
+
+func TestMessageReactions(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	setReaction := func(seq uint64, remove bool, uid, emoji string) {
+		err := d.SetReaction(wkdb.Reaction{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  1,
+			Uid:         uid,
+			Emoji:       emoji,
+			Seq:         seq,
+			IsDeleted:   remove,
+		})
+		assert.NoError(t, err)
+	}
+
+	setReaction(2, false, "u1", "👍")
+	setReaction(3, false, "u2", "👍")
+	setReaction(4, false, "u1", "❤️")
+	setReaction(5, true, "u1", "👍")
+
+	// 旧版本的变更会被忽略（副本的应用顺序可能不同）
+	setReaction(1, false, "u1", "👍")
+
+	// 回应不写入消息日志
+	lastSeq, _, err := d.GetChannelLastMessageSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), lastSeq)
+
+	reactions, err := d.GetMessageReactions(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+
+	version, err := d.GetReactionVersion(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), version)
+
+	// 增量同步包含取消的回应
+	reactions, err = d.SyncReactions(channelId, channelType, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 3)
+	assert.Equal(t, uint64(3), reactions[0].Seq)
+	assert.Equal(t, "u2", reactions[0].Uid)
+	assert.Equal(t, uint64(5), reactions[2].Seq)
+	assert.Equal(t, "u1", reactions[2].Uid)
+	assert.Equal(t, "👍", reactions[2].Emoji)
+	assert.True(t, reactions[2].IsDeleted)
+
+	reactions, err = d.SyncReactions(channelId, channelType, 4, 10)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+	assert.Equal(t, uint64(5), reactions[0].Seq)
+}
+
+func TestMessageReceipts(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	addReceipt := func(uid string, readToSeq uint64, readAt int64, seqs ...uint64) {
+		err := d.AddReceipt(wkdb.Receipt{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			Uid:         uid,
+			ReadToSeq:   readToSeq,
+			Seqs:        seqs,
+			ReadAt:      readAt,
+		})
+		assert.NoError(t, err)
+	}
+
+	addReceipt("u1", 2, 3, 1, 2)
+
+	// 重复的回执不会重复计数
+	addReceipt("u1", 2, 4, 1, 2)
+	addReceipt("u2", 1, 5, 1, 1)
+
+	// 回执不写入消息日志
+	lastSeq, _, err := d.GetChannelLastMessageSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), lastSeq)
+
+	count, err := d.GetMessageReceiptCount(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), count)
+	count, err = d.GetMessageReceiptCount(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), count)
+
+	readers, err := d.GetMessageReceiptReaders(channelId, channelType, 1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, readers, 2)
+	readers, err = d.GetMessageReceiptReaders(channelId, channelType, 2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, readers, 1)
+	assert.Equal(t, "u1", readers[0].Uid)
+	assert.Equal(t, int64(3), readers[0].ReadAt)
+
+	readSeq, err := d.GetReceiptReadSeq(channelId, channelType, "u2")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), readSeq)
+}
+
+func TestThreadMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	parentSeqs := []uint64{0, 1, 0, 1, 3, 6}
+	messages := make([]wkdb.Message, 0, len(parentSeqs))
+	for i, parentSeq := range parentSeqs {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte(`{"type":1,"content":"hello"}`),
+			},
+			ParentMessageSeq: parentSeq,
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	replies, err := d.LoadThreadMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 2)
+	assert.Equal(t, uint32(2), replies[0].MessageSeq)
+	assert.Equal(t, uint32(4), replies[1].MessageSeq)
+	assert.Equal(t, uint64(1), replies[0].ParentMessageSeq)
+
+	replies, err = d.LoadThreadMsgs(channelId, channelType, 1, 3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 1)
+	assert.Equal(t, uint32(4), replies[0].MessageSeq)
+
+	summaries, err := d.GetThreadSummaries(channelId, channelType, []uint64{1, 3, 6})
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.ThreadSummary{
+		{ParentMessageSeq: 1, ReplyCount: 2, LastReplySeq: 4},
+		{ParentMessageSeq: 3, ReplyCount: 1, LastReplySeq: 5},
+		{ParentMessageSeq: 6},
+	}, summaries)
+
+	// 撤回的回复不计入回复数量，重复撤回只减一次
+	revoke := func(messageSeq uint32) wkdb.Message {
+		return wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(messageSeq),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  messageSeq,
+			},
+			Modify: &wkdb.MessageModify{
+				Type:       wkdb.MessageModifyTypeRevoke,
+				MessageSeq: 4,
+				Operator:   "u1",
+			},
+		}
+	}
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{revoke(7)})
+	assert.NoError(t, err)
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{revoke(8)})
+	assert.NoError(t, err)
+	summaries, err = d.GetThreadSummaries(channelId, channelType, []uint64{1})
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.ThreadSummary{{ParentMessageSeq: 1, ReplyCount: 1, LastReplySeq: 2}}, summaries)
+
+	// 话题内仍然能看到撤回的回复
+	replies, err = d.LoadThreadMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 2)
+	assert.True(t, replies[1].Revoke)
+
+	// 撤回被截断后重新计入
+	err = d.TruncateLogTo(channelId, channelType, 7)
+	assert.NoError(t, err)
+	summaries, err = d.GetThreadSummaries(channelId, channelType, []uint64{1, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.ThreadSummary{
+		{ParentMessageSeq: 1, ReplyCount: 2, LastReplySeq: 4},
+		{ParentMessageSeq: 3, ReplyCount: 1, LastReplySeq: 5},
+	}, summaries)
+
+	// 截断日志后回复索引也被删除
+	err = d.TruncateLogTo(channelId, channelType, 4)
+	assert.NoError(t, err)
+	summaries, err = d.GetThreadSummaries(channelId, channelType, []uint64{1, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.ThreadSummary{
+		{ParentMessageSeq: 1, ReplyCount: 1, LastReplySeq: 2},
+		{ParentMessageSeq: 3},
+	}, summaries)
+}
+
+func TestMessageSensitive(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{MessageID: 1, ChannelID: channelId, ChannelType: channelType, MessageSeq: 1, Payload: []byte("hello")},
+			Sensitive:  true,
+		},
+		{
+			RecvPacket: wkproto.RecvPacket{MessageID: 2, ChannelID: channelId, ChannelType: channelType, MessageSeq: 2, Payload: []byte("hello")},
+		},
+	}
+
+	// 日志编码
+	data, err := messages[0].Marshal()
+	assert.NoError(t, err)
+	decoded := wkdb.Message{}
+	err = decoded.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.True(t, decoded.Sensitive)
+	assert.Equal(t, uint64(0), decoded.ParentMessageSeq)
+	assert.Nil(t, decoded.Modify)
+
+	// 存储
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	msg, err := d.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.True(t, msg.Sensitive)
+
+	msg, err = d.LoadMsg(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.False(t, msg.Sensitive)
+}
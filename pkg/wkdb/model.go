@@ -20,9 +20,36 @@ func IsEmptyMessage(m Message) bool {
 	return m.MessageID == 0 && m.MessageSeq == 0
 }
 
+// ExpireAt 消息过期的时间点（单位秒），0表示永不过期
+func (m *Message) ExpireAt() uint64 {
+	if m.Expire == 0 {
+		return 0
+	}
+	return uint64(m.Timestamp) + uint64(m.Expire)
+}
+
+// IsExpired 消息在now（单位秒）时是否已过期
+func (m *Message) IsExpired(now int64) bool {
+	expireAt := m.ExpireAt()
+	return expireAt > 0 && expireAt <= uint64(now)
+}
+
 type Message struct {
 	wkproto.RecvPacket
 	Term uint64 // raft term
+
+	// 消息修改（撤回/编辑），不为空表示这条日志是对频道内其他消息的修改，并不是一条普通消息
+	Modify *MessageModify
+	// 回复的父消息seq，0表示不是回复，由服务端在存储前从消息内容中解析
+	ParentMessageSeq uint64
+	// 是否含有敏感词（敏感词过滤策略为flag时由服务端标记）
+	Sensitive bool
+
+	// 以下字段来自消息扩展数据，不参与日志编码
+	Revoke      bool   // 是否已撤回
+	Revoker     string // 撤回者uid
+	EditVersion uint32 // 编辑版本，0表示未编辑
+	EditedAt    int64  // 最后编辑时间（单位秒）
 }
 
 func (m *Message) Unmarshal(data []byte) error {
@@ -51,6 +78,31 @@ func (m *Message) Unmarshal(data []byte) error {
 		return err
 	}
 
+	if dec.Len() > 0 {
+		modifyData, err := dec.Binary()
+		if err != nil {
+			return err
+		}
+		if len(modifyData) > 0 {
+			m.Modify = &MessageModify{}
+			if err = m.Modify.Unmarshal(modifyData); err != nil {
+				return err
+			}
+		}
+	}
+	if dec.Len() > 0 {
+		if m.ParentMessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+	if dec.Len() > 0 {
+		var sensitive uint8
+		if sensitive, err = dec.Uint8(); err != nil {
+			return err
+		}
+		m.Sensitive = sensitive == 1
+	}
+
 	return nil
 }
 
@@ -65,9 +117,314 @@ func (m *Message) Marshal() ([]byte, error) {
 	enc.WriteUint8(wkproto.LatestVersion)
 	enc.WriteBinary(data)
 	enc.WriteUint64(m.Term)
+	// 后面的字段是后加的，只在有值时写入，并且写入后面的字段时前面的字段也必须写入
+	if m.Modify != nil || m.ParentMessageSeq > 0 || m.Sensitive {
+		var modifyData []byte
+		if m.Modify != nil {
+			modifyData, err = m.Modify.Marshal()
+			if err != nil {
+				return nil, err
+			}
+		}
+		enc.WriteBinary(modifyData)
+	}
+	if m.ParentMessageSeq > 0 || m.Sensitive {
+		enc.WriteUint64(m.ParentMessageSeq)
+	}
+	if m.Sensitive {
+		enc.WriteUint8(1)
+	}
+	return enc.Bytes(), nil
+}
+
+// MessageModifyType 消息修改类型
+type MessageModifyType uint8
+
+const (
+	MessageModifyTypeUnknown MessageModifyType = iota
+	// MessageModifyTypeRevoke 撤回
+	MessageModifyTypeRevoke
+	// MessageModifyTypeEdit 编辑
+	MessageModifyTypeEdit
+)
+
+// MessageModify 消息修改，跟随频道日志复制到各个副本
+type MessageModify struct {
+	Type       MessageModifyType // 修改类型
+	MessageSeq uint64            // 被修改的消息seq
+	Operator   string            // 操作者uid
+	Version    uint32            // 编辑版本（编辑时有效）
+	Payload    []byte            // 编辑后的消息内容（编辑时有效）
+}
+
+func (m *MessageModify) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint8(uint8(m.Type))
+	enc.WriteUint64(m.MessageSeq)
+	enc.WriteString(m.Operator)
+	enc.WriteUint32(m.Version)
+	enc.WriteBinary(m.Payload)
+	return enc.Bytes(), nil
+}
+
+func (m *MessageModify) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	var tp uint8
+	if tp, err = dec.Uint8(); err != nil {
+		return err
+	}
+	m.Type = MessageModifyType(tp)
+	if m.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if m.Operator, err = dec.String(); err != nil {
+		return err
+	}
+	if m.Version, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if m.Payload, err = dec.Binary(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MessageExtra 消息扩展数据（撤回/编辑状态）
+type MessageExtra struct {
+	MessageSeq  uint64 `json:"message_seq"`
+	Revoke      bool   `json:"revoke,omitempty"`       // 是否已撤回
+	Revoker     string `json:"revoker,omitempty"`      // 撤回者uid
+	EditVersion uint32 `json:"edit_version,omitempty"` // 编辑版本
+	EditedAt    int64  `json:"edited_at,omitempty"`    // 最后编辑时间（单位秒）
+}
+
+// MessageReceiptMaxReaders 每条消息最多保存的已读用户数量，超过后只累计已读数量
+const MessageReceiptMaxReaders = 500
+
+// ReceiptReader 消息的已读用户
+type ReceiptReader struct {
+	Uid    string `json:"uid"`
+	ReadAt int64  `json:"read_at"` // 已读时间（单位秒）
+}
+
+func (r *ReceiptReader) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.Uid)
+	enc.WriteInt64(r.ReadAt)
+	return enc.Bytes(), nil
+}
+
+func (r *ReceiptReader) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if r.ReadAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Receipt 用户在频道内的一次已读回执
+type Receipt struct {
+	ChannelId   string   `json:"channel_id"`
+	ChannelType uint8    `json:"channel_type"`
+	Uid         string   `json:"uid"`          // 已读用户
+	ReadToSeq   uint64   `json:"read_to_seq"`  // 已回执到的位置，不大于已保存位置的消息不会重复计数
+	Seqs        []uint64 `json:"message_seqs"` // 需要计数的消息seq
+	ReadAt      int64    `json:"read_at"`      // 已读时间（单位秒）
+}
+
+// ThreadSummary 消息的回复概要
+type ThreadSummary struct {
+	ParentMessageSeq uint64 `json:"parent_message_seq"` // 父消息seq
+	ReplyCount       int    `json:"reply_count"`        // 回复数量
+	LastReplySeq     uint64 `json:"last_reply_seq"`     // 最新回复的消息seq
+}
+
+// MaxPinnedMessages 每个频道最多置顶的消息数量，超过后最早置顶的会被移除
+const MaxPinnedMessages = 50
+
+// PinnedMessage 频道置顶消息
+type PinnedMessage struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	MessageSeq  uint64 `json:"message_seq"` // 置顶的消息seq
+	MessageId   int64  `json:"message_id"`  // 置顶的消息id
+	PinnedBy    string `json:"pinned_by"`   // 置顶操作者uid
+	PinnedAt    int64  `json:"pinned_at"`   // 置顶时间（单位秒）
+}
+
+func (p *PinnedMessage) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(p.MessageSeq)
+	enc.WriteInt64(p.MessageId)
+	enc.WriteString(p.PinnedBy)
+	enc.WriteInt64(p.PinnedAt)
+	return enc.Bytes(), nil
+}
+
+func (p *PinnedMessage) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if p.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if p.MessageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if p.PinnedBy, err = dec.String(); err != nil {
+		return err
+	}
+	if p.PinnedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ScheduledMessage 定时消息，到达发送时间后由频道所在槽的领导节点发送
+type ScheduledMessage struct {
+	Id          uint64 `json:"id"`
+	ChannelId   string `json:"channel_id"` // 个人频道为fakeChannelId
+	ChannelType uint8  `json:"channel_type"`
+	FromUid     string `json:"from_uid"`
+	ClientMsgNo string `json:"client_msg_no"` // 发送时使用的客户端消息编号，重复发送时客户端可以据此去重
+	RedDot      bool   `json:"red_dot"`
+	NoPersist   bool   `json:"no_persist"`
+	SyncOnce    bool   `json:"sync_once"`
+	Expire      uint32 `json:"expire"`
+	Payload     []byte `json:"payload"`
+	SendAt      int64  `json:"send_at"`    // 发送时间点（单位秒）
+	CreatedAt   int64  `json:"created_at"` // 创建时间（单位秒）
+}
+
+var EmptyScheduledMessage = ScheduledMessage{}
+
+func (m *ScheduledMessage) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(m.Id)
+	enc.WriteString(m.ChannelId)
+	enc.WriteUint8(m.ChannelType)
+	enc.WriteString(m.FromUid)
+	enc.WriteString(m.ClientMsgNo)
+	enc.WriteUint8(wkutil.BoolToUint8(m.RedDot))
+	enc.WriteUint8(wkutil.BoolToUint8(m.NoPersist))
+	enc.WriteUint8(wkutil.BoolToUint8(m.SyncOnce))
+	enc.WriteUint32(m.Expire)
+	enc.WriteBinary(m.Payload)
+	enc.WriteInt64(m.SendAt)
+	enc.WriteInt64(m.CreatedAt)
+	return enc.Bytes(), nil
+}
+
+func (m *ScheduledMessage) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if m.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if m.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if m.FromUid, err = dec.String(); err != nil {
+		return err
+	}
+	if m.ClientMsgNo, err = dec.String(); err != nil {
+		return err
+	}
+	var flag uint8
+	if flag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	m.RedDot = wkutil.Uint8ToBool(flag)
+	if flag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	m.NoPersist = wkutil.Uint8ToBool(flag)
+	if flag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	m.SyncOnce = wkutil.Uint8ToBool(flag)
+	if m.Expire, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if m.Payload, err = dec.Binary(); err != nil {
+		return err
+	}
+	if m.SendAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if m.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Reaction 消息回应
+type Reaction struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	MessageSeq  uint64 `json:"message_seq"` // 被回应的消息seq
+	Uid         string `json:"uid"`         // 回应者uid
+	Emoji       string `json:"emoji"`       // 回应的表情
+	Seq         uint64 `json:"seq"`         // 回应版本（产生此次变更的槽日志下标），同一频道内递增
+	IsDeleted   bool   `json:"is_deleted"`  // 是否已取消
+	CreatedAt   int64  `json:"created_at"`  // 回应时间（单位秒）
+}
+
+func (r *Reaction) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(r.MessageSeq)
+	enc.WriteString(r.Uid)
+	enc.WriteString(r.Emoji)
+	enc.WriteUint64(r.Seq)
+	enc.WriteUint8(wkutil.BoolToUint8(r.IsDeleted))
+	enc.WriteInt64(r.CreatedAt)
 	return enc.Bytes(), nil
 }
 
+func (r *Reaction) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.MessageSeq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if r.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if r.Emoji, err = dec.String(); err != nil {
+		return err
+	}
+	if r.Seq, err = dec.Uint64(); err != nil {
+		return err
+	}
+	var deleted uint8
+	if deleted, err = dec.Uint8(); err != nil {
+		return err
+	}
+	r.IsDeleted = wkutil.Uint8ToBool(deleted)
+	if r.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MessageEditHistory 消息的某个编辑版本
+type MessageEditHistory struct {
+	Version uint32 `json:"version"` // 编辑版本
+	Payload []byte `json:"payload"` // 此版本的消息内容
+}
+
 var EmptyDevice = Device{}
 
 func IsEmptyDevice(d Device) bool {
@@ -109,6 +466,240 @@ type User struct {
 	UpdatedAt         *time.Time `json:"updated_at,omitempty"`          // 更新时间
 }
 
+// UserLastSeen 用户的最后上线/离线时间（单位秒）
+type UserLastSeen struct {
+	Uid         string           `json:"uid"`
+	LastOnline  int64            `json:"last_online"`  // 最后上线时间
+	LastOffline int64            `json:"last_offline"` // 最后离线时间
+	Devices     []DeviceLastSeen `json:"devices"`      // 各设备的最后上线/离线时间
+}
+
+// DeviceLastSeen 设备的最后上线/离线时间（单位秒）
+type DeviceLastSeen struct {
+	DeviceFlag  uint8 `json:"device_flag"`
+	LastOnline  int64 `json:"last_online"`
+	LastOffline int64 `json:"last_offline"`
+}
+
+// Merge 合并其他的记录，时间取较大的值
+func (u *UserLastSeen) Merge(other UserLastSeen) {
+	u.LastOnline = max(u.LastOnline, other.LastOnline)
+	u.LastOffline = max(u.LastOffline, other.LastOffline)
+	for _, od := range other.Devices {
+		exist := false
+		for i, d := range u.Devices {
+			if d.DeviceFlag == od.DeviceFlag {
+				u.Devices[i].LastOnline = max(d.LastOnline, od.LastOnline)
+				u.Devices[i].LastOffline = max(d.LastOffline, od.LastOffline)
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			u.Devices = append(u.Devices, od)
+		}
+	}
+}
+
+func (u *UserLastSeen) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(u.Uid)
+	enc.WriteInt64(u.LastOnline)
+	enc.WriteInt64(u.LastOffline)
+	enc.WriteUint16(uint16(len(u.Devices)))
+	for _, d := range u.Devices {
+		enc.WriteUint8(d.DeviceFlag)
+		enc.WriteInt64(d.LastOnline)
+		enc.WriteInt64(d.LastOffline)
+	}
+	return enc.Bytes(), nil
+}
+
+func (u *UserLastSeen) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if u.Uid, err = dec.String(); err != nil {
+		return err
+	}
+	if u.LastOnline, err = dec.Int64(); err != nil {
+		return err
+	}
+	if u.LastOffline, err = dec.Int64(); err != nil {
+		return err
+	}
+	var count uint16
+	if count, err = dec.Uint16(); err != nil {
+		return err
+	}
+	u.Devices = make([]DeviceLastSeen, 0, count)
+	for i := uint16(0); i < count; i++ {
+		var d DeviceLastSeen
+		if d.DeviceFlag, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if d.LastOnline, err = dec.Int64(); err != nil {
+			return err
+		}
+		if d.LastOffline, err = dec.Int64(); err != nil {
+			return err
+		}
+		u.Devices = append(u.Devices, d)
+	}
+	return nil
+}
+
+// WebhookDeadLetter 超过最大重试次数仍推送失败的webhook事件
+type WebhookDeadLetter struct {
+	Id         uint64 `json:"id"`
+	Event      string `json:"event"`       // 事件类型
+	Data       []byte `json:"data"`        // 事件数据（json）
+	RetryCount uint32 `json:"retry_count"` // 已重试次数
+	Error      string `json:"error"`       // 最后一次推送失败的原因
+	CreatedAt  int64  `json:"created_at"`  // 进入死信队列的时间（单位秒）
+	// 推送目标，为空表示全局配置的webhook地址
+	SubscriptionId uint64 `json:"subscription_id,omitempty"` // webhook订阅id
+	Target         string `json:"target,omitempty"`          // 推送地址（频道webhook的http地址）
+}
+
+func (w *WebhookDeadLetter) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(w.Id)
+	enc.WriteString(w.Event)
+	enc.WriteBinary(w.Data)
+	enc.WriteUint32(w.RetryCount)
+	enc.WriteString(w.Error)
+	enc.WriteInt64(w.CreatedAt)
+	enc.WriteUint64(w.SubscriptionId)
+	enc.WriteString(w.Target)
+	return enc.Bytes(), nil
+}
+
+func (w *WebhookDeadLetter) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if w.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if w.Event, err = dec.String(); err != nil {
+		return err
+	}
+	if w.Data, err = dec.Binary(); err != nil {
+		return err
+	}
+	if w.RetryCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	if w.Error, err = dec.String(); err != nil {
+		return err
+	}
+	if w.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if dec.Len() > 0 {
+		if w.SubscriptionId, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if w.Target, err = dec.String(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookSubscription webhook订阅，事件会推送给所有匹配的订阅
+type WebhookSubscription struct {
+	Id           uint64   `json:"id"`
+	URL          string   `json:"url,omitempty"`           // http推送地址
+	GRPCAddr     string   `json:"grpc_addr,omitempty"`     // grpc推送地址（与url二选一）
+	Events       []string `json:"events,omitempty"`        // 订阅的事件类型，为空表示订阅所有事件
+	ChannelTypes []uint8  `json:"channel_types,omitempty"` // 只推送指定频道类型的消息事件，为空表示不限制
+	ChannelIds   []string `json:"channel_ids,omitempty"`   // 只推送指定频道的消息事件，为空表示不限制
+	Secret       string   `json:"secret,omitempty"`        // 签名密钥
+	CreatedAt    int64    `json:"created_at"`              // 创建时间（单位秒）
+	UpdatedAt    int64    `json:"updated_at"`              // 更新时间（单位秒）
+}
+
+func (w *WebhookSubscription) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(w.Id)
+	enc.WriteString(w.URL)
+	enc.WriteString(w.GRPCAddr)
+	enc.WriteUint16(uint16(len(w.Events)))
+	for _, event := range w.Events {
+		enc.WriteString(event)
+	}
+	enc.WriteUint16(uint16(len(w.ChannelTypes)))
+	for _, channelType := range w.ChannelTypes {
+		enc.WriteUint8(channelType)
+	}
+	enc.WriteUint16(uint16(len(w.ChannelIds)))
+	for _, channelId := range w.ChannelIds {
+		enc.WriteString(channelId)
+	}
+	enc.WriteString(w.Secret)
+	enc.WriteInt64(w.CreatedAt)
+	enc.WriteInt64(w.UpdatedAt)
+	return enc.Bytes(), nil
+}
+
+func (w *WebhookSubscription) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if w.Id, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if w.URL, err = dec.String(); err != nil {
+		return err
+	}
+	if w.GRPCAddr, err = dec.String(); err != nil {
+		return err
+	}
+	var size uint16
+	if size, err = dec.Uint16(); err != nil {
+		return err
+	}
+	for i := 0; i < int(size); i++ {
+		event, err := dec.String()
+		if err != nil {
+			return err
+		}
+		w.Events = append(w.Events, event)
+	}
+	if size, err = dec.Uint16(); err != nil {
+		return err
+	}
+	for i := 0; i < int(size); i++ {
+		channelType, err := dec.Uint8()
+		if err != nil {
+			return err
+		}
+		w.ChannelTypes = append(w.ChannelTypes, channelType)
+	}
+	if size, err = dec.Uint16(); err != nil {
+		return err
+	}
+	for i := 0; i < int(size); i++ {
+		channelId, err := dec.String()
+		if err != nil {
+			return err
+		}
+		w.ChannelIds = append(w.ChannelIds, channelId)
+	}
+	if w.Secret, err = dec.String(); err != nil {
+		return err
+	}
+	if w.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if w.UpdatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
 var EmptyChannelInfo = ChannelInfo{}
 
 type ChannelInfo struct {
@@ -124,10 +715,20 @@ type ChannelInfo struct {
 	LastMsgSeq      uint64     `json:"last_msg_seq,omitempty"`     // 最新消息序号
 	LastMsgTime     uint64     `json:"last_msg_time,omitempty"`    // 最后一次消息时间
 	Webhook         string     `json:"webhook,omitempty"`          // webhook地址
+	Mute            bool       `json:"mute,omitempty"`             // 是否全员禁言（创建者和管理员除外）
+	MuteUntil       int64      `json:"mute_until,omitempty"`       // 全员禁言截止时间（单位秒），0表示一直禁言直到解除
 	CreatedAt       *time.Time `json:"created_at,omitempty"`       // 创建时间
 	UpdatedAt       *time.Time `json:"updated_at,omitempty"`       // 更新时间
 }
 
+// IsMuted 频道在now（单位秒）时是否处于全员禁言中
+func (c *ChannelInfo) IsMuted(now int64) bool {
+	if !c.Mute {
+		return false
+	}
+	return c.MuteUntil == 0 || c.MuteUntil > now
+}
+
 func NewChannelInfo(channelId string, channelType uint8) ChannelInfo {
 	return ChannelInfo{
 		ChannelId:   channelId,
@@ -281,18 +882,58 @@ const (
 
 // Conversation Conversation
 type Conversation struct {
-	Id           uint64           `json:"id,omitempty"`
-	Uid          string           `json:"uid,omitempty"`               // 用户uid
-	Type         ConversationType `json:"type,omitempty"`              // 会话类型
-	ChannelId    string           `json:"channel_id,omitempty"`        // 频道id
-	ChannelType  uint8            `json:"channel_type,omitempty"`      // 频道类型
-	UnreadCount  uint32           `json:"unread_count,omitempty"`      // 未读消息数量（这个可以用户自己设置）
-	ReadToMsgSeq uint64           `json:"readed_to_msg_seq,omitempty"` // 已经读至的消息序号
+	Id              uint64           `json:"id,omitempty"`
+	Uid             string           `json:"uid,omitempty"`                // 用户uid
+	Type            ConversationType `json:"type,omitempty"`               // 会话类型
+	ChannelId       string           `json:"channel_id,omitempty"`         // 频道id
+	ChannelType     uint8            `json:"channel_type,omitempty"`       // 频道类型
+	UnreadCount     uint32           `json:"unread_count,omitempty"`       // 未读消息数量（这个可以用户自己设置）
+	ReadToMsgSeq    uint64           `json:"readed_to_msg_seq,omitempty"`  // 已经读至的消息序号
+	ClearedToMsgSeq uint64           `json:"cleared_to_msg_seq,omitempty"` // 清空聊天记录至的消息序号（包含，只对此用户生效）
+	Pinned          bool             `json:"pinned,omitempty"`             // 是否置顶
+	MutedUntil      int64            `json:"muted_until,omitempty"`        // 免打扰截止时间（单位秒），ConversationMuteForever表示永久免打扰
+	Folder          string           `json:"folder,omitempty"`             // 所属的分组/标签
+	Draft           string           `json:"draft,omitempty"`              // 草稿
+	Version         uint64           `json:"version,omitempty"`            // 属性最后一次变更时用户的会话版本
 
 	CreatedAt *time.Time `json:"created_at,omitempty"` // 创建时间
 	UpdatedAt *time.Time `json:"updated_at,omitempty"` // 更新时间
 }
 
+// ConversationMuteForever 永久免打扰
+const ConversationMuteForever int64 = -1
+
+// IsMuted 在now时是否处于免打扰
+func (c Conversation) IsMuted(now int64) bool {
+	return c.MutedUntil == ConversationMuteForever || c.MutedUntil > now
+}
+
+// ConversationAttrs 会话属性的变更，为nil的属性不修改
+type ConversationAttrs struct {
+	ChannelId   string  `json:"channel_id"`
+	ChannelType uint8   `json:"channel_type"`
+	Pinned      *bool   `json:"pinned,omitempty"`
+	MutedUntil  *int64  `json:"muted_until,omitempty"`
+	Folder      *string `json:"folder,omitempty"`
+	Draft       *string `json:"draft,omitempty"`
+}
+
+// apply 将变更应用到会话上
+func (a ConversationAttrs) apply(c *Conversation) {
+	if a.Pinned != nil {
+		c.Pinned = *a.Pinned
+	}
+	if a.MutedUntil != nil {
+		c.MutedUntil = *a.MutedUntil
+	}
+	if a.Folder != nil {
+		c.Folder = *a.Folder
+	}
+	if a.Draft != nil {
+		c.Draft = *a.Draft
+	}
+}
+
 func (c *Conversation) Marshal() ([]byte, error) {
 	enc := wkproto.NewEncoder()
 	defer enc.End()
@@ -315,6 +956,12 @@ func (c *Conversation) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteUint64(c.ClearedToMsgSeq)
+	enc.WriteUint8(wkutil.BoolToUint8(c.Pinned))
+	enc.WriteInt64(c.MutedUntil)
+	enc.WriteString(c.Folder)
+	enc.WriteString(c.Draft)
+	enc.WriteUint64(c.Version)
 
 	return enc.Bytes(), nil
 }
@@ -369,6 +1016,32 @@ func (c *Conversation) Unmarshal(data []byte) error {
 		c.UpdatedAt = &ct
 	}
 
+	// 兼容旧数据
+	if dec.Len() > 0 {
+		if c.ClearedToMsgSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+	if dec.Len() > 0 {
+		var pinned uint8
+		if pinned, err = dec.Uint8(); err != nil {
+			return err
+		}
+		c.Pinned = wkutil.Uint8ToBool(pinned)
+		if c.MutedUntil, err = dec.Int64(); err != nil {
+			return err
+		}
+		if c.Folder, err = dec.String(); err != nil {
+			return err
+		}
+		if c.Draft, err = dec.String(); err != nil {
+			return err
+		}
+		if c.Version, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -725,15 +1398,50 @@ type Channel struct {
 	ChannelType uint8  `json:"channel_type,omitempty"`
 }
 
+// MemberRole 成员角色
+type MemberRole uint8
+
+const (
+	// MemberRoleMember 普通成员
+	MemberRoleMember MemberRole = iota
+	// MemberRoleAdmin 管理员
+	MemberRoleAdmin
+	// MemberRoleOwner 创建者
+	MemberRoleOwner
+)
+
+func (r MemberRole) String() string {
+	switch r {
+	case MemberRoleMember:
+		return "member"
+	case MemberRoleAdmin:
+		return "admin"
+	case MemberRoleOwner:
+		return "owner"
+	}
+	return fmt.Sprintf("unknown(%d)", r)
+}
+
 type Member struct {
 	Id        uint64     `json:"id"`
 	Uid       string     `json:"uid"`
 	CreatedAt *time.Time `json:"created_at,omitempty"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 
+	// 以下字段只有订阅者使用
+	Role       MemberRole        `json:"role,omitempty"`       // 成员角色
+	MuteUntil  int64             `json:"mute_until,omitempty"` // 禁言截止时间（单位秒），0表示没有禁言
+	JoinedAt   int64             `json:"joined_at,omitempty"`  // 加入时间（单位秒）
+	Attributes map[string]string `json:"attributes,omitempty"` // 成员扩展属性
+
 	version uint16 // 数据版本
 }
 
+// IsMuted 成员在now（单位秒）时是否处于禁言中
+func (m *Member) IsMuted(now int64) bool {
+	return m.MuteUntil > now
+}
+
 func (m *Member) Marshal() ([]byte, error) {
 	enc := wkproto.NewEncoder()
 	defer enc.End()
@@ -752,6 +1460,10 @@ func (m *Member) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteUint8(uint8(m.Role))
+	enc.WriteInt64(m.MuteUntil)
+	enc.WriteInt64(m.JoinedAt)
+	enc.WriteBinary(EncodeMemberAttributes(m.Attributes))
 	return enc.Bytes(), nil
 }
 
@@ -785,5 +1497,66 @@ func (m *Member) Unmarshal(data []byte) error {
 		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
 		m.UpdatedAt = &ct
 	}
+	// 兼容旧数据，旧数据没有以下字段
+	if dec.Len() > 0 {
+		var role uint8
+		if role, err = dec.Uint8(); err != nil {
+			return err
+		}
+		m.Role = MemberRole(role)
+		if m.MuteUntil, err = dec.Int64(); err != nil {
+			return err
+		}
+		if m.JoinedAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		var attrData []byte
+		if attrData, err = dec.Binary(); err != nil {
+			return err
+		}
+		if m.Attributes, err = DecodeMemberAttributes(attrData); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// EncodeMemberAttributes 编码成员扩展属性
+func EncodeMemberAttributes(attributes map[string]string) []byte {
+	if len(attributes) == 0 {
+		return nil
+	}
+	// 返回值会被外部持有，所以这里不归还编码缓存
+	enc := wkproto.NewEncoder()
+	enc.WriteUint16(uint16(len(attributes)))
+	for k, v := range attributes {
+		enc.WriteString(k)
+		enc.WriteString(v)
+	}
+	return enc.Bytes()
+}
+
+// DecodeMemberAttributes 解码成员扩展属性
+func DecodeMemberAttributes(data []byte) (map[string]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint16()
+	if err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]string, count)
+	for i := 0; i < int(count); i++ {
+		k, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		v, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		attributes[k] = v
+	}
+	return attributes, nil
+}
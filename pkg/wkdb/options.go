@@ -1,5 +1,7 @@
 package wkdb
 
+import "time"
+
 type Options struct {
 	NodeId            uint64
 	DataDir           string
@@ -10,6 +12,12 @@ type Options struct {
 	ShardNum     int               // 数据库分区数量，一但设置就不能修改
 	IsCmdChannel func(string) bool // 是否是cmd频道
 	MemTableSize int
+	// 过期消息清理的间隔
+	ExpireSweepInterval time.Duration
+	// 每个分区每轮最多清理的过期消息数量
+	ExpireSweepBatchSize int
+	// 是否开启消息全文索引
+	FullTextIndex bool
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -20,6 +28,10 @@ func NewOptions(opt ...Option) *Options {
 		EnableCost:        true,
 		ShardNum:          8,
 		MemTableSize:      16 * 1024 * 1024,
+
+		ExpireSweepInterval:  time.Minute,
+		ExpireSweepBatchSize: 1000,
+		FullTextIndex:        true,
 	}
 	for _, f := range opt {
 		f(o)
@@ -76,3 +88,21 @@ func WithMemTableSize(size int) Option {
 		o.MemTableSize = size
 	}
 }
+
+func WithExpireSweepInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.ExpireSweepInterval = interval
+	}
+}
+
+func WithExpireSweepBatchSize(size int) Option {
+	return func(o *Options) {
+		o.ExpireSweepBatchSize = size
+	}
+}
+
+func WithFullTextIndex(on bool) Option {
+	return func(o *Options) {
+		o.FullTextIndex = on
+	}
+}
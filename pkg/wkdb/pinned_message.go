@@ -0,0 +1,72 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddPinnedMessage(pin PinnedMessage) error {
+	pins, err := wk.GetPinnedMessages(pin.ChannelId, pin.ChannelType)
+	if err != nil {
+		return err
+	}
+	data, err := pin.Marshal()
+	if err != nil {
+		return err
+	}
+
+	batch := wk.channelDb(pin.ChannelId, pin.ChannelType).NewBatch()
+	defer batch.Close()
+	if err = batch.Set(key.NewPinnedMessageKey(pin.ChannelId, pin.ChannelType, pin.MessageSeq), data, wk.noSync); err != nil {
+		return err
+	}
+
+	// 超出数量限制时移除最早置顶的（pins按置顶时间倒序）
+	count := 1
+	for _, p := range pins {
+		if p.MessageSeq == pin.MessageSeq {
+			continue
+		}
+		count++
+		if count <= MaxPinnedMessages {
+			continue
+		}
+		if err = batch.Delete(key.NewPinnedMessageKey(pin.ChannelId, pin.ChannelType, p.MessageSeq), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemovePinnedMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	return wk.channelDb(channelId, channelType).Delete(key.NewPinnedMessageKey(channelId, channelType, messageSeq), wk.sync)
+}
+
+func (wk *wukongDB) GetPinnedMessages(channelId string, channelType uint8) ([]PinnedMessage, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewPinnedMessageKey(channelId, channelType, 0),
+		UpperBound: key.NewPinnedMessageKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	pins := make([]PinnedMessage, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		pin := PinnedMessage{}
+		if err := pin.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		pin.ChannelId = channelId
+		pin.ChannelType = channelType
+		pins = append(pins, pin)
+	}
+	sort.SliceStable(pins, func(i, j int) bool {
+		if pins[i].PinnedAt == pins[j].PinnedAt {
+			return pins[i].MessageSeq > pins[j].MessageSeq
+		}
+		return pins[i].PinnedAt > pins[j].PinnedAt
+	})
+	return pins, nil
+}
@@ -0,0 +1,50 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinnedMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	for i := 1; i <= wkdb.MaxPinnedMessages+2; i++ {
+		err = d.AddPinnedMessage(wkdb.PinnedMessage{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  uint64(i),
+			MessageId:   int64(i + 100),
+			PinnedBy:    "u1",
+			PinnedAt:    int64(i),
+		})
+		assert.NoError(t, err)
+	}
+
+	// 超出数量限制时最早置顶的被移除，按置顶时间倒序返回
+	pins, err := d.GetPinnedMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, pins, wkdb.MaxPinnedMessages)
+	assert.Equal(t, uint64(wkdb.MaxPinnedMessages+2), pins[0].MessageSeq)
+	assert.Equal(t, int64(wkdb.MaxPinnedMessages+102), pins[0].MessageId)
+	assert.Equal(t, "u1", pins[0].PinnedBy)
+	assert.Equal(t, channelId, pins[0].ChannelId)
+	assert.Equal(t, uint64(3), pins[len(pins)-1].MessageSeq)
+
+	err = d.RemovePinnedMessage(channelId, channelType, uint64(wkdb.MaxPinnedMessages+2))
+	assert.NoError(t, err)
+	pins, err = d.GetPinnedMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, pins, wkdb.MaxPinnedMessages-1)
+	assert.Equal(t, uint64(wkdb.MaxPinnedMessages+1), pins[0].MessageSeq)
+}
@@ -0,0 +1,129 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) GetMessageReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionMessageKey(channelId, channelType, messageSeq),
+		UpperBound: key.NewReactionMessageKey(channelId, channelType, messageSeq+1),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		reaction := Reaction{}
+		if err := reaction.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		if reaction.IsDeleted {
+			continue
+		}
+		reaction.ChannelId = channelId
+		reaction.ChannelType = channelType
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+func (wk *wukongDB) SyncReactions(channelId string, channelType uint8, startSeq uint64, limit int) ([]Reaction, error) {
+	if startSeq == math.MaxUint64 {
+		return nil, nil
+	}
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionSeqIndexKey(channelId, channelType, startSeq+1),
+		UpperBound: key.NewReactionSeqIndexKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	reactions := make([]Reaction, 0)
+	for iter.First(); iter.Valid() && (limit <= 0 || len(reactions) < limit); iter.Next() {
+		seq, err := key.ParseReactionSeqIndexKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		reaction, ok, err := wk.getReaction(db, iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		// 回应已被删除或者已有更新的版本
+		if !ok || reaction.Seq != seq {
+			continue
+		}
+		reaction.ChannelId = channelId
+		reaction.ChannelType = channelType
+		reactions = append(reactions, reaction)
+	}
+	return reactions, nil
+}
+
+func (wk *wukongDB) GetReactionVersion(channelId string, channelType uint8) (uint64, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionSeqIndexKey(channelId, channelType, 0),
+		UpperBound: key.NewReactionSeqIndexKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	if !iter.Last() {
+		return 0, nil
+	}
+	return key.ParseReactionSeqIndexKey(iter.Key())
+}
+
+func (wk *wukongDB) getReaction(db *pebble.DB, reactionKey []byte) (Reaction, bool, error) {
+	value, closer, err := db.Get(reactionKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return Reaction{}, false, nil
+		}
+		return Reaction{}, false, err
+	}
+	defer closer.Close()
+	reaction := Reaction{}
+	if err = reaction.Unmarshal(value); err != nil {
+		return Reaction{}, false, err
+	}
+	return reaction, true, nil
+}
+
+// SetReaction 添加或取消回应，reaction.Seq为回应版本，旧版本的索引会被删除
+// 版本不大于已有版本的变更会被忽略，取消不存在的回应也会记录下来，这样各副本以任意顺序应用得到的结果都是一致的
+func (wk *wukongDB) SetReaction(reaction Reaction) error {
+	channelId, channelType := reaction.ChannelId, reaction.ChannelType
+	wk.dblock.channelExtraLock.lockByChannel(channelId, channelType)
+	defer wk.dblock.channelExtraLock.unlockByChannel(channelId, channelType)
+
+	db := wk.channelDb(channelId, channelType)
+	reactionKey := key.NewReactionKey(channelId, channelType, reaction.MessageSeq, reaction.Uid, reaction.Emoji)
+	old, exist, err := wk.getReaction(db, reactionKey)
+	if err != nil {
+		return err
+	}
+	if exist && old.Seq >= reaction.Seq {
+		return nil
+	}
+
+	data, err := reaction.Marshal()
+	if err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	defer batch.Close()
+	if exist {
+		if err = batch.Delete(key.NewReactionSeqIndexKey(channelId, channelType, old.Seq), wk.noSync); err != nil {
+			return err
+		}
+	}
+	if err = batch.Set(reactionKey, data, wk.noSync); err != nil {
+		return err
+	}
+	if err = batch.Set(key.NewReactionSeqIndexKey(channelId, channelType, reaction.Seq), reactionKey, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
@@ -0,0 +1,128 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) GetMessageReceiptReaders(channelId string, channelType uint8, messageSeq uint64, limit int) ([]ReceiptReader, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReceiptMessageKey(channelId, channelType, messageSeq),
+		UpperBound: key.NewReceiptMessageKey(channelId, channelType, messageSeq+1),
+	})
+	defer iter.Close()
+
+	readers := make([]ReceiptReader, 0)
+	for iter.First(); iter.Valid() && (limit <= 0 || len(readers) < limit); iter.Next() {
+		reader := ReceiptReader{}
+		if err := reader.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
+}
+
+func (wk *wukongDB) GetMessageReceiptCount(channelId string, channelType uint8, messageSeq uint64) (uint32, error) {
+	value, err := wk.getValue(wk.channelDb(channelId, channelType), nil, key.NewReceiptCountKey(channelId, channelType, messageSeq))
+	if err != nil || value == nil {
+		return 0, err
+	}
+	return wk.endian.Uint32(value), nil
+}
+
+func (wk *wukongDB) GetReceiptReadSeq(channelId string, channelType uint8, uid string) (uint64, error) {
+	value, err := wk.getValue(wk.channelDb(channelId, channelType), nil, key.NewReceiptReadSeqKey(channelId, channelType, uid))
+	if err != nil || value == nil {
+		return 0, err
+	}
+	return wk.endian.Uint64(value), nil
+}
+
+// 优先从批次内获取还未提交的值，不存在返回nil
+func (wk *wukongDB) getValue(db *pebble.DB, w *Batch, k []byte) ([]byte, error) {
+	if w != nil {
+		if value, ok := w.get(k); ok {
+			return value, nil
+		}
+	}
+	value, closer, err := db.Get(k)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer closer.Close()
+	return append([]byte(nil), value...), nil
+}
+
+// AddReceipt 添加已读回执，receipt.ReadToSeq为用户已回执到的位置，不大于已保存位置的消息不会重复计数
+func (wk *wukongDB) AddReceipt(receipt Receipt) error {
+	channelId, channelType := receipt.ChannelId, receipt.ChannelType
+	wk.dblock.channelExtraLock.lockByChannel(channelId, channelType)
+	defer wk.dblock.channelExtraLock.unlockByChannel(channelId, channelType)
+
+	db := wk.channelDb(channelId, channelType)
+	readSeqKey := key.NewReceiptReadSeqKey(channelId, channelType, receipt.Uid)
+	value, err := wk.getValue(db, nil, readSeqKey)
+	if err != nil {
+		return err
+	}
+	var readSeq uint64
+	if value != nil {
+		readSeq = wk.endian.Uint64(value)
+	}
+	if receipt.ReadToSeq <= readSeq {
+		return nil
+	}
+
+	reader := &ReceiptReader{
+		Uid:    receipt.Uid,
+		ReadAt: receipt.ReadAt,
+	}
+	readerData, err := reader.Marshal()
+	if err != nil {
+		return err
+	}
+	readerData = append([]byte(nil), readerData...)
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	counted := make(map[uint64]struct{}, len(receipt.Seqs))
+	for _, seq := range receipt.Seqs {
+		if seq <= readSeq || seq > receipt.ReadToSeq {
+			continue
+		}
+		if _, ok := counted[seq]; ok {
+			continue
+		}
+		counted[seq] = struct{}{}
+		countKey := key.NewReceiptCountKey(channelId, channelType, seq)
+		value, err := wk.getValue(db, nil, countKey)
+		if err != nil {
+			return err
+		}
+		var count uint32
+		if value != nil {
+			count = wk.endian.Uint32(value)
+		}
+		if count < MessageReceiptMaxReaders {
+			if err = batch.Set(key.NewReceiptKey(channelId, channelType, seq, receipt.Uid), readerData, wk.noSync); err != nil {
+				return err
+			}
+		}
+		countBytes := make([]byte, 4)
+		wk.endian.PutUint32(countBytes, count+1)
+		if err = batch.Set(countKey, countBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	readSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(readSeqBytes, receipt.ReadToSeq)
+	if err = batch.Set(readSeqKey, readSeqBytes, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
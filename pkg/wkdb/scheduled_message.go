@@ -0,0 +1,170 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddScheduledMessage(m ScheduledMessage) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	db := wk.channelDb(m.ChannelId, m.ChannelType)
+
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 重复添加时需要移除旧的到期索引
+	old, err := wk.getScheduledMessage(db, m.ChannelId, m.ChannelType, m.Id)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == nil && old.SendAt != m.SendAt {
+		if err = batch.Delete(key.NewScheduledMessageSendAtKey(uint64(old.SendAt), m.ChannelId, m.ChannelType, m.Id), wk.noSync); err != nil {
+			return err
+		}
+	}
+	if err = batch.Set(key.NewScheduledMessageKey(m.ChannelId, m.ChannelType, m.Id), data, wk.noSync); err != nil {
+		return err
+	}
+	if err = batch.Set(key.NewScheduledMessageSendAtKey(uint64(m.SendAt), m.ChannelId, m.ChannelType, m.Id), nil, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveScheduledMessage(channelId string, channelType uint8, id uint64) error {
+	db := wk.channelDb(channelId, channelType)
+	m, err := wk.getScheduledMessage(db, channelId, channelType, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	if err = batch.Delete(key.NewScheduledMessageKey(channelId, channelType, id), wk.noSync); err != nil {
+		return err
+	}
+	if err = batch.Delete(key.NewScheduledMessageSendAtKey(uint64(m.SendAt), channelId, channelType, id), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetScheduledMessage(channelId string, channelType uint8, id uint64) (ScheduledMessage, error) {
+	return wk.getScheduledMessage(wk.channelDb(channelId, channelType), channelId, channelType, id)
+}
+
+func (wk *wukongDB) GetScheduledMessages(channelId string, channelType uint8) ([]ScheduledMessage, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageKey(channelId, channelType, 0),
+		UpperBound: key.NewScheduledMessageKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	messages := make([]ScheduledMessage, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		m := ScheduledMessage{}
+		if err := m.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		if messages[i].SendAt == messages[j].SendAt {
+			return messages[i].Id < messages[j].Id
+		}
+		return messages[i].SendAt < messages[j].SendAt
+	})
+	return messages, nil
+}
+
+func (wk *wukongDB) GetDueScheduledMessages(now int64, limit int, filter ...func(m ScheduledMessage) bool) ([]ScheduledMessage, error) {
+	messages := make([]ScheduledMessage, 0)
+	for i := 0; i < len(wk.dbs); i++ {
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+		shardLimit := 0
+		if limit > 0 {
+			shardLimit = limit - len(messages)
+		}
+		shardMessages, err := wk.getDueScheduledMessages(wk.shardDBById(uint32(i)), now, shardLimit, filter...)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, shardMessages...)
+	}
+	return messages, nil
+}
+
+// 获取分区内发送时间点小于等于now的定时消息，limit为0时不限制数量，被filter过滤掉的不计入数量
+func (wk *wukongDB) getDueScheduledMessages(db *pebble.DB, now int64, limit int, filter ...func(m ScheduledMessage) bool) ([]ScheduledMessage, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageSendAtKey(0, "", 0, 0),
+		UpperBound: key.NewScheduledMessageSendAtKey(uint64(now)+1, "", 0, 0),
+	})
+	defer iter.Close()
+
+	messages := make([]ScheduledMessage, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, err := key.ParseScheduledMessageSendAtKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, closer, err := db.Get(primaryKey)
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		m := ScheduledMessage{}
+		err = m.Unmarshal(value)
+		closer.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !matchScheduledMessage(m, filter) {
+			continue
+		}
+		messages = append(messages, m)
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+func matchScheduledMessage(m ScheduledMessage, filter []func(m ScheduledMessage) bool) bool {
+	for _, fnc := range filter {
+		if !fnc(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (wk *wukongDB) getScheduledMessage(db *pebble.DB, channelId string, channelType uint8, id uint64) (ScheduledMessage, error) {
+	value, closer, err := db.Get(key.NewScheduledMessageKey(channelId, channelType, id))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return EmptyScheduledMessage, ErrNotFound
+		}
+		return EmptyScheduledMessage, err
+	}
+	defer closer.Close()
+
+	m := ScheduledMessage{}
+	if err = m.Unmarshal(value); err != nil {
+		return EmptyScheduledMessage, err
+	}
+	return m, nil
+}
@@ -0,0 +1,95 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	for i := 1; i <= 3; i++ {
+		err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+			Id:          uint64(i),
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			FromUid:     "u1",
+			ClientMsgNo: "no",
+			RedDot:      true,
+			Payload:     []byte("hello"),
+			SendAt:      int64(400 - i*100), // 300 200 100
+		})
+		assert.NoError(t, err)
+	}
+	err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+		Id:          4,
+		ChannelId:   "other",
+		ChannelType: channelType,
+		Payload:     []byte("hi"),
+		SendAt:      1000,
+	})
+	assert.NoError(t, err)
+
+	// 按发送时间升序
+	messages, err := d.GetScheduledMessages(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, uint64(3), messages[0].Id)
+	assert.Equal(t, "u1", messages[0].FromUid)
+	assert.True(t, messages[0].RedDot)
+	assert.Equal(t, []byte("hello"), messages[0].Payload)
+
+	due, err := d.GetDueScheduledMessages(200, 0)
+	assert.NoError(t, err)
+	assert.Len(t, due, 2)
+
+	due, err = d.GetDueScheduledMessages(1000, 1)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// 被过滤掉的定时消息不占用数量
+	due, err = d.GetDueScheduledMessages(1000, 1, func(m wkdb.ScheduledMessage) bool {
+		return m.Id != 3
+	})
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, uint64(2), due[0].Id)
+
+	// 修改发送时间后旧的到期索引失效
+	err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+		Id:          3,
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Payload:     []byte("hello"),
+		SendAt:      500,
+	})
+	assert.NoError(t, err)
+	due, err = d.GetDueScheduledMessages(200, 0)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, uint64(2), due[0].Id)
+
+	err = d.RemoveScheduledMessage(channelId, channelType, 2)
+	assert.NoError(t, err)
+	_, err = d.GetScheduledMessage(channelId, channelType, 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+	due, err = d.GetDueScheduledMessages(300, 0)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, uint64(1), due[0].Id)
+
+	// 移除不存在的定时消息
+	err = d.RemoveScheduledMessage(channelId, channelType, 2)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,44 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddSensitiveWords(words []string) error {
+	w := wk.defaultShardDB().NewBatch()
+	defer w.Close()
+	for _, word := range words {
+		if err := w.Set(key.NewSensitiveWordKey(key.HashWithString(word)), []byte(word), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return w.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveSensitiveWords(words []string) error {
+	w := wk.defaultShardDB().NewBatch()
+	defer w.Close()
+	for _, word := range words {
+		if err := w.Delete(key.NewSensitiveWordKey(key.HashWithString(word)), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return w.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetSensitiveWords() ([]string, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSensitiveWordKey(0),
+		UpperBound: key.NewSensitiveWordKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	words := make([]string, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		words = append(words, string(iter.Value()))
+	}
+	return words, nil
+}
@@ -0,0 +1,32 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndRemoveSensitiveWords(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddSensitiveWords([]string{"word1", "word2", "word3"})
+	assert.NoError(t, err)
+
+	words, err := d.GetSensitiveWords()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"word1", "word2", "word3"}, words)
+
+	err = d.RemoveSensitiveWords([]string{"word2"})
+	assert.NoError(t, err)
+
+	words, err = d.GetSensitiveWords()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"word1", "word3"}, words)
+}
@@ -65,6 +65,81 @@ func (wk *wukongDB) GetSubscribers(channelId string, channelType uint8) ([]Membe
 	return members, nil
 }
 
+// GetSubscribersByOffset 按id分页获取订阅者，返回id大于offsetId的limit个订阅者
+func (wk *wukongDB) GetSubscribersByOffset(channelId string, channelType uint8, offsetId uint64, limit int) ([]Member, error) {
+	if offsetId == math.MaxUint64 {
+		return nil, nil
+	}
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSubscriberColumnKey(channelId, channelType, offsetId+1, key.MinColumnKey),
+		UpperBound: key.NewSubscriberColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	members := make([]Member, 0, limit)
+	err := wk.iterateSubscriber(iter, func(member Member) bool {
+		if len(members) >= limit {
+			return false
+		}
+		members = append(members, member)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetSubscriber 获取频道内的某个订阅者，不存在返回ErrNotFound
+func (wk *wukongDB) GetSubscriber(channelId string, channelType uint8, uid string) (Member, error) {
+	members, err := wk.getSubscribersByUids(channelId, channelType, []string{uid})
+	if err != nil {
+		return Member{}, err
+	}
+	if len(members) == 0 {
+		return Member{}, ErrNotFound
+	}
+	return members[0], nil
+}
+
+// UpdateSubscribers 更新订阅者的角色、禁言和扩展属性等信息，不存在的订阅者会被忽略
+// 同一个uid出现多次时以最后一次为准
+func (wk *wukongDB) UpdateSubscribers(channelId string, channelType uint8, members []Member) error {
+	lastIndex := make(map[string]int, len(members))
+	for i, member := range members {
+		lastIndex[member.Uid] = i
+	}
+
+	db := wk.channelDb(channelId, channelType)
+	w := db.NewIndexedBatch()
+	defer w.Close()
+	for i, member := range members {
+		if lastIndex[member.Uid] != i { // 去重复
+			continue
+		}
+		oldMembers, err := wk.getSubscribersByUids(channelId, channelType, []string{member.Uid})
+		if err != nil {
+			return err
+		}
+		if len(oldMembers) == 0 {
+			continue
+		}
+		oldMember := oldMembers[0]
+		if err = wk.removeSubscriber(channelId, channelType, oldMember, w); err != nil {
+			return err
+		}
+		member.Id = oldMember.Id
+		member.CreatedAt = oldMember.CreatedAt // 不允许更新创建时间
+		if member.JoinedAt == 0 {
+			member.JoinedAt = oldMember.JoinedAt
+		}
+		if err = wk.writeSubscriber(channelId, channelType, member, w); err != nil {
+			return err
+		}
+	}
+	return w.Commit(wk.sync)
+}
+
 func (wk *wukongDB) RemoveSubscribers(channelId string, channelType uint8, subscribers []string) error {
 
 	wk.metrics.RemoveSubscribersAdd(1)
@@ -259,6 +334,17 @@ func (wk *wukongDB) iterateSubscriber(iter *pebble.Iterator, iterFnc func(member
 				t := time.Unix(tm/1e9, tm%1e9)
 				preMember.UpdatedAt = &t
 			}
+		case key.TableSubscriber.Column.Role:
+			preMember.Role = MemberRole(iter.Value()[0])
+		case key.TableSubscriber.Column.MuteUntil:
+			preMember.MuteUntil = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableSubscriber.Column.JoinedAt:
+			preMember.JoinedAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableSubscriber.Column.Attributes:
+			preMember.Attributes, err = DecodeMemberAttributes(iter.Value())
+			if err != nil {
+				return err
+			}
 		}
 		hasData = true
 	}
@@ -317,6 +403,38 @@ func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member
 		}
 	}
 
+	// role
+	if member.Role != MemberRoleMember {
+		if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.Role), []byte{uint8(member.Role)}, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// muteUntil
+	if member.MuteUntil > 0 {
+		muteUntil := make([]byte, 8)
+		wk.endian.PutUint64(muteUntil, uint64(member.MuteUntil))
+		if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.MuteUntil), muteUntil, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// joinedAt
+	if member.JoinedAt > 0 {
+		joinedAt := make([]byte, 8)
+		wk.endian.PutUint64(joinedAt, uint64(member.JoinedAt))
+		if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.JoinedAt), joinedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// attributes
+	if len(member.Attributes) > 0 {
+		if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.Attributes), EncodeMemberAttributes(member.Attributes), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
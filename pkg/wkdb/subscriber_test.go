@@ -234,3 +234,149 @@ func TestRemoveAllSubscriber(t *testing.T) {
 
 	assert.Equal(t, 0, len(subscribers2))
 }
+
+func TestUpdateSubscribers(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	channelId := "channel1"
+	channelType := uint8(2)
+	err = d.AddSubscribers(channelId, channelType, []wkdb.Member{
+		{
+			Uid:       "uid1",
+			CreatedAt: &createdAt,
+			Role:      wkdb.MemberRoleOwner,
+			JoinedAt:  createdAt.Unix(),
+		},
+		{
+			Uid:       "uid2",
+			CreatedAt: &createdAt,
+			JoinedAt:  createdAt.Unix(),
+		},
+	})
+	assert.NoError(t, err)
+
+	owner, err := d.GetSubscriber(channelId, channelType, "uid1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.MemberRoleOwner, owner.Role)
+	assert.Equal(t, createdAt.Unix(), owner.JoinedAt)
+
+	muteUntil := time.Now().Add(time.Hour).Unix()
+	err = d.UpdateSubscribers(channelId, channelType, []wkdb.Member{
+		{
+			Uid:        "uid2",
+			Role:       wkdb.MemberRoleAdmin,
+			MuteUntil:  muteUntil,
+			Attributes: map[string]string{"nickname": "n2"},
+		},
+		{
+			Uid:  "uid3", // 不存在的订阅者会被忽略
+			Role: wkdb.MemberRoleAdmin,
+		},
+	})
+	assert.NoError(t, err)
+
+	member, err := d.GetSubscriber(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.MemberRoleAdmin, member.Role)
+	assert.Equal(t, muteUntil, member.MuteUntil)
+	assert.Equal(t, createdAt.Unix(), member.JoinedAt)
+	assert.Equal(t, "n2", member.Attributes["nickname"])
+	assert.Equal(t, createdAt.UnixNano(), member.CreatedAt.UnixNano())
+	assert.True(t, member.IsMuted(time.Now().Unix()))
+
+	_, err = d.GetSubscriber(channelId, channelType, "uid3")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// 解除禁言
+	member.MuteUntil = 0
+	err = d.UpdateSubscribers(channelId, channelType, []wkdb.Member{member})
+	assert.NoError(t, err)
+	member, err = d.GetSubscriber(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), member.MuteUntil)
+	assert.Equal(t, wkdb.MemberRoleAdmin, member.Role)
+
+	// 重复的uid以最后一次为准
+	err = d.UpdateSubscribers(channelId, channelType, []wkdb.Member{
+		{Uid: "uid2", Role: wkdb.MemberRoleMember},
+		{Uid: "uid2", Role: wkdb.MemberRoleAdmin, Attributes: map[string]string{"nickname": "n3"}},
+	})
+	assert.NoError(t, err)
+	member, err = d.GetSubscriber(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.MemberRoleAdmin, member.Role)
+	assert.Equal(t, "n3", member.Attributes["nickname"])
+
+	members, err := d.GetSubscribers(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(members))
+}
+
+func TestGetSubscribersByOffset(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	subscribers := make([]wkdb.Member, 0, 5)
+	for _, uid := range []string{"uid1", "uid2", "uid3", "uid4", "uid5"} {
+		subscribers = append(subscribers, wkdb.Member{Uid: uid})
+	}
+	err = d.AddSubscribers(channelId, channelType, subscribers)
+	assert.NoError(t, err)
+
+	uids := make([]string, 0, len(subscribers))
+	var offsetId uint64
+	for {
+		members, err := d.GetSubscribersByOffset(channelId, channelType, offsetId, 2)
+		assert.NoError(t, err)
+		if len(members) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(members), 2)
+		for _, member := range members {
+			uids = append(uids, member.Uid)
+		}
+		offsetId = members[len(members)-1].Id
+	}
+	sort.Strings(uids)
+	assert.Equal(t, []string{"uid1", "uid2", "uid3", "uid4", "uid5"}, uids)
+}
+
+func TestMemberMarshal(t *testing.T) {
+	createdAt := time.Unix(100, 0)
+	m := wkdb.Member{
+		Id:         1,
+		Uid:        "uid1",
+		CreatedAt:  &createdAt,
+		Role:       wkdb.MemberRoleAdmin,
+		MuteUntil:  200,
+		JoinedAt:   100,
+		Attributes: map[string]string{"k": "v"},
+	}
+	data, err := m.Marshal()
+	assert.NoError(t, err)
+
+	m2 := wkdb.Member{}
+	err = m2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m.Uid, m2.Uid)
+	assert.Equal(t, m.Role, m2.Role)
+	assert.Equal(t, m.MuteUntil, m2.MuteUntil)
+	assert.Equal(t, m.JoinedAt, m2.JoinedAt)
+	assert.Equal(t, m.Attributes, m2.Attributes)
+}
@@ -0,0 +1,223 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+// threadParentOfMessage 获取回复消息的父消息seq，不是回复返回0
+// 父消息seq由服务端在存储前设置，只能回复之前的消息
+func threadParentOfMessage(msg Message) uint64 {
+	if msg.Modify != nil || msg.ParentMessageSeq >= uint64(msg.MessageSeq) {
+		return 0
+	}
+	return msg.ParentMessageSeq
+}
+
+// 父消息的回复数量只统计未撤回的回复，随回复索引和撤回状态一起维护
+func (wk *wukongDB) writeThreadIndex(channelId string, channelType uint8, msg Message, w *Batch) error {
+	parentSeq := threadParentOfMessage(msg)
+	if parentSeq == 0 {
+		return nil
+	}
+	parentSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(parentSeqBytes, parentSeq)
+	w.Set(key.NewThreadReplyKey(channelId, channelType, parentSeq, uint64(msg.MessageSeq)), nil)
+	w.Set(key.NewThreadParentKey(channelId, channelType, uint64(msg.MessageSeq)), parentSeqBytes)
+	return wk.incThreadReplyCount(channelId, channelType, parentSeq, 1, w)
+}
+
+func (wk *wukongDB) deleteThreadIndex(channelId string, channelType uint8, msg Message, w *Batch) error {
+	parentSeq := threadParentOfMessage(msg)
+	if parentSeq == 0 {
+		return nil
+	}
+	w.Delete(key.NewThreadReplyKey(channelId, channelType, parentSeq, uint64(msg.MessageSeq)))
+	w.Delete(key.NewThreadParentKey(channelId, channelType, uint64(msg.MessageSeq)))
+
+	// 撤回的回复已经不在回复数量里
+	revoked, err := wk.isMessageRevoked(channelId, channelType, uint64(msg.MessageSeq), w)
+	if err != nil || revoked {
+		return err
+	}
+	return wk.incThreadReplyCount(channelId, channelType, parentSeq, -1, w)
+}
+
+// 回复被撤回，不再计入父消息的回复数量（回复索引保留，话题内仍然能看到撤回的回复）
+func (wk *wukongDB) revokeThreadReply(channelId string, channelType uint8, replySeq uint64, w *Batch) error {
+	parentSeq, err := wk.getThreadParentSeq(channelId, channelType, replySeq, w)
+	if err != nil || parentSeq == 0 {
+		return err
+	}
+	revoked, err := wk.isMessageRevoked(channelId, channelType, replySeq, w)
+	if err != nil || revoked {
+		return err
+	}
+	return wk.incThreadReplyCount(channelId, channelType, parentSeq, -1, w)
+}
+
+// 回复的撤回被回滚，重新计入父消息的回复数量
+func (wk *wukongDB) restoreThreadReply(channelId string, channelType uint8, replySeq uint64, w *Batch) error {
+	parentSeq, err := wk.getThreadParentSeq(channelId, channelType, replySeq, w)
+	if err != nil || parentSeq == 0 {
+		return err
+	}
+	return wk.incThreadReplyCount(channelId, channelType, parentSeq, 1, w)
+}
+
+// 删除回复seq大于等于startSeq的回复索引（截断日志时使用）
+func (wk *wukongDB) truncateThreadIndex(channelId string, channelType uint8, startSeq uint64, w *Batch) error {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewThreadParentKey(channelId, channelType, startSeq),
+		UpperBound: key.NewThreadParentKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		replySeq, err := key.ParseThreadParentKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		parentSeq := wk.endian.Uint64(iter.Value())
+		w.Delete(key.NewThreadReplyKey(channelId, channelType, parentSeq, replySeq))
+
+		revoked, err := wk.isMessageRevoked(channelId, channelType, replySeq, w)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			if err := wk.incThreadReplyCount(channelId, channelType, parentSeq, -1, w); err != nil {
+				return err
+			}
+		}
+	}
+	w.DeleteRange(key.NewThreadParentKey(channelId, channelType, startSeq), key.NewThreadParentKey(channelId, channelType, math.MaxUint64))
+	return nil
+}
+
+// 获取回复的父消息seq，不是回复（或回复已删除）返回0
+func (wk *wukongDB) getThreadParentSeq(channelId string, channelType uint8, replySeq uint64, w *Batch) (uint64, error) {
+	value, err := wk.getValue(wk.channelDb(channelId, channelType), w, key.NewThreadParentKey(channelId, channelType, replySeq))
+	if err != nil || len(value) != 8 {
+		return 0, err
+	}
+	return wk.endian.Uint64(value), nil
+}
+
+func (wk *wukongDB) isMessageRevoked(channelId string, channelType uint8, messageSeq uint64, w *Batch) (bool, error) {
+	value, err := wk.getValue(wk.channelDb(channelId, channelType), w, key.NewMessageExtraColumnKey(channelId, channelType, messageSeq, key.TableMessageExtra.Column.Revoke))
+	if err != nil {
+		return false, err
+	}
+	return len(value) > 0 && value[0] == 1, nil
+}
+
+// 修改父消息的回复数量，同一批次内的修改会累加
+func (wk *wukongDB) incThreadReplyCount(channelId string, channelType uint8, parentSeq uint64, delta int, w *Batch) error {
+	wk.dblock.channelExtraLock.lockByChannel(channelId, channelType)
+	defer wk.dblock.channelExtraLock.unlockByChannel(channelId, channelType)
+
+	countKey := key.NewThreadCountKey(channelId, channelType, parentSeq)
+	value, err := wk.getValue(wk.channelDb(channelId, channelType), w, countKey)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if len(value) == 4 {
+		count = int64(wk.endian.Uint32(value))
+	}
+	count += int64(delta)
+	if count < 0 {
+		count = 0
+	}
+	// 数量为0时也写入，不能删除，批次内只能读到写入的值
+	countBytes := make([]byte, 4)
+	wk.endian.PutUint32(countBytes, uint32(count))
+	w.Set(countKey, countBytes)
+	return nil
+}
+
+func (wk *wukongDB) LoadThreadMsgs(channelId string, channelType uint8, parentSeq uint64, startSeq uint64, limit int) ([]Message, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewThreadReplyKey(channelId, channelType, parentSeq, startSeq),
+		UpperBound: key.NewThreadReplyKey(channelId, channelType, parentSeq, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	now := time.Now().Unix()
+	msgs := make([]Message, 0)
+	for iter.First(); iter.Valid() && (limit <= 0 || len(msgs) < limit); iter.Next() {
+		replySeq, err := key.ParseThreadReplyKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		msg, err := wk.LoadMsg(channelId, channelType, replySeq)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if msg.IsExpired(now) {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := wk.fillMessageExtras(channelId, channelType, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (wk *wukongDB) GetThreadSummaries(channelId string, channelType uint8, parentSeqs []uint64) ([]ThreadSummary, error) {
+	db := wk.channelDb(channelId, channelType)
+	summaries := make([]ThreadSummary, 0, len(parentSeqs))
+	for _, parentSeq := range parentSeqs {
+		summary, err := wk.getThreadSummary(db, channelId, channelType, parentSeq)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (wk *wukongDB) getThreadSummary(db *pebble.DB, channelId string, channelType uint8, parentSeq uint64) (ThreadSummary, error) {
+	summary := ThreadSummary{
+		ParentMessageSeq: parentSeq,
+	}
+	value, err := wk.getValue(db, nil, key.NewThreadCountKey(channelId, channelType, parentSeq))
+	if err != nil {
+		return summary, err
+	}
+	if len(value) == 4 {
+		summary.ReplyCount = int(wk.endian.Uint32(value))
+	}
+	if summary.ReplyCount == 0 {
+		return summary, nil
+	}
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewThreadReplyKey(channelId, channelType, parentSeq, 0),
+		UpperBound: key.NewThreadReplyKey(channelId, channelType, parentSeq, math.MaxUint64),
+	})
+	defer iter.Close()
+	// 从最新的回复往前找到第一条未撤回的回复
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		replySeq, err := key.ParseThreadReplyKey(iter.Key())
+		if err != nil {
+			return summary, err
+		}
+		revoked, err := wk.isMessageRevoked(channelId, channelType, replySeq, nil)
+		if err != nil {
+			return summary, err
+		}
+		if !revoked {
+			summary.LastReplySeq = replySeq
+			break
+		}
+	}
+	return summary, nil
+}
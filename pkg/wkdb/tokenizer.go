@@ -0,0 +1,116 @@
+package wkdb
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 单条消息最多建立索引的词数量
+const maxMessageTokens = 1000
+
+type searchToken struct {
+	term string
+	pos  uint32
+}
+
+// 提取消息中需要建立索引的文本，json消息取content字段，否则取整个payload
+func searchTextOfPayload(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	if payload[0] == '{' {
+		var v struct {
+			Content *string `json:"content"`
+		}
+		if err := json.Unmarshal(payload, &v); err == nil {
+			if v.Content != nil {
+				return *v.Content
+			}
+			return ""
+		}
+	}
+	if !utf8.Valid(payload) {
+		return ""
+	}
+	return string(payload)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// tokenize 分词
+// 拉丁字母和数字按单词切分并转小写，中日韩文字按二元组(bigram)切分，单个字单独成词
+// 连续的中日韩文字同时建立单字索引，单字与以它开头的二元组位置相同（最后一个字与最后一个二元组位置相同），这样单字也能被搜索到
+// 词的位置是连续递增的，短语查询依赖位置相邻判断
+func tokenize(text string) []searchToken {
+	tokens := make([]searchToken, 0)
+	var pos uint32
+	addAt := func(term string, p uint32) bool {
+		if len(tokens) >= maxMessageTokens {
+			return false
+		}
+		tokens = append(tokens, searchToken{term: term, pos: p})
+		return true
+	}
+	add := func(term string) bool {
+		if !addAt(term, pos) {
+			return false
+		}
+		pos++
+		return true
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isCJK(r):
+			j := i
+			for j < len(runes) && isCJK(runes[j]) {
+				j++
+			}
+			if j-i == 1 {
+				if !add(string(runes[i])) {
+					return tokens
+				}
+			} else {
+				for k := i; k+1 < j; k++ {
+					if !add(string(runes[k:k+2])) || !addAt(string(runes[k]), pos-1) {
+						return tokens
+					}
+				}
+				if !addAt(string(runes[j-1]), pos-1) {
+					return tokens
+				}
+			}
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) && !isCJK(runes[j]) {
+				j++
+			}
+			if !add(strings.ToLower(string(runes[i:j]))) {
+				return tokens
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// 按词聚合位置
+func groupTokens(tokens []searchToken) map[string][]uint32 {
+	terms := make(map[string][]uint32, len(tokens))
+	for _, t := range tokens {
+		terms[t.term] = append(terms[t.term], t.pos)
+	}
+	return terms
+}
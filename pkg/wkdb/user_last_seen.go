@@ -0,0 +1,59 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) UpdateUserLastSeen(lastSeen UserLastSeen) error {
+	db := wk.shardDB(lastSeen.Uid)
+	old, err := wk.getUserLastSeen(db, lastSeen.Uid)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == nil {
+		old.Merge(lastSeen)
+		lastSeen = old
+	}
+	data, err := lastSeen.Marshal()
+	if err != nil {
+		return err
+	}
+	return db.Set(key.NewUserLastSeenKey(lastSeen.Uid), data, wk.sync)
+}
+
+func (wk *wukongDB) GetUserLastSeens(uids []string) ([]UserLastSeen, error) {
+	lastSeens := make([]UserLastSeen, 0, len(uids))
+	for _, uid := range uids {
+		lastSeen, err := wk.getUserLastSeen(wk.shardDB(uid), uid)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		lastSeens = append(lastSeens, lastSeen)
+	}
+	return lastSeens, nil
+}
+
+func (wk *wukongDB) getUserLastSeen(db *pebble.DB, uid string) (UserLastSeen, error) {
+	value, closer, err := db.Get(key.NewUserLastSeenKey(uid))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return UserLastSeen{}, ErrNotFound
+		}
+		return UserLastSeen{}, err
+	}
+	defer closer.Close()
+
+	lastSeen := UserLastSeen{}
+	if err = lastSeen.Unmarshal(value); err != nil {
+		return UserLastSeen{}, err
+	}
+	// uid的hash冲突
+	if lastSeen.Uid != uid {
+		return UserLastSeen{}, ErrNotFound
+	}
+	return lastSeen, nil
+}
@@ -125,3 +125,85 @@ func TestExistUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, exist)
 }
+
+func TestIterateUsers(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	tn := time.Now()
+	uids := []string{"test1", "test2", "test3"}
+	for _, uid := range uids {
+		err = d.AddUser(wkdb.User{
+			Uid:       uid,
+			CreatedAt: &tn,
+			UpdatedAt: &tn,
+		})
+		assert.NoError(t, err)
+	}
+
+	results := make([]string, 0)
+	err = d.IterateUsers(func(u wkdb.User) bool {
+		results = append(results, u.Uid)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, uids, results)
+
+	// 返回false时停止遍历
+	count := 0
+	err = d.IterateUsers(func(u wkdb.User) bool {
+		count++
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestUserLastSeen(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.UpdateUserLastSeen(wkdb.UserLastSeen{
+		Uid:         "test",
+		LastOnline:  100,
+		LastOffline: 200,
+		Devices: []wkdb.DeviceLastSeen{
+			{DeviceFlag: 0, LastOnline: 100, LastOffline: 200},
+		},
+	})
+	assert.NoError(t, err)
+
+	// 合并记录，时间只会往后更新
+	err = d.UpdateUserLastSeen(wkdb.UserLastSeen{
+		Uid:         "test",
+		LastOnline:  50,
+		LastOffline: 300,
+		Devices: []wkdb.DeviceLastSeen{
+			{DeviceFlag: 0, LastOnline: 50, LastOffline: 150},
+			{DeviceFlag: 1, LastOnline: 250, LastOffline: 300},
+		},
+	})
+	assert.NoError(t, err)
+
+	lastSeens, err := d.GetUserLastSeens([]string{"test", "notexist"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(lastSeens))
+	assert.Equal(t, "test", lastSeens[0].Uid)
+	assert.Equal(t, int64(100), lastSeens[0].LastOnline)
+	assert.Equal(t, int64(300), lastSeens[0].LastOffline)
+	assert.Equal(t, 2, len(lastSeens[0].Devices))
+	assert.Equal(t, wkdb.DeviceLastSeen{DeviceFlag: 0, LastOnline: 100, LastOffline: 200}, lastSeens[0].Devices[0])
+	assert.Equal(t, wkdb.DeviceLastSeen{DeviceFlag: 1, LastOnline: 250, LastOffline: 300}, lastSeens[0].Devices[1])
+}
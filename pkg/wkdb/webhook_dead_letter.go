@@ -0,0 +1,69 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddWebhookDeadLetter(deadLetter WebhookDeadLetter) error {
+	data, err := deadLetter.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.defaultShardDB().Set(key.NewWebhookDeadLetterKey(deadLetter.Id), data, wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookDeadLetter(id uint64) (WebhookDeadLetter, error) {
+	value, closer, err := wk.defaultShardDB().Get(key.NewWebhookDeadLetterKey(id))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return WebhookDeadLetter{}, ErrNotFound
+		}
+		return WebhookDeadLetter{}, err
+	}
+	defer closer.Close()
+
+	var deadLetter WebhookDeadLetter
+	if err = deadLetter.Unmarshal(value); err != nil {
+		return WebhookDeadLetter{}, err
+	}
+	return deadLetter, nil
+}
+
+func (wk *wukongDB) GetWebhookDeadLetters(startId uint64, limit int) ([]WebhookDeadLetter, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookDeadLetterKey(startId + 1),
+		UpperBound: key.NewWebhookDeadLetterKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	deadLetters := make([]WebhookDeadLetter, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var deadLetter WebhookDeadLetter
+		if err := deadLetter.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+		if limit > 0 && len(deadLetters) >= limit {
+			break
+		}
+	}
+	return deadLetters, nil
+}
+
+func (wk *wukongDB) RemoveWebhookDeadLetters(ids []uint64) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, id := range ids {
+		if err := batch.Delete(key.NewWebhookDeadLetterKey(id), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveAllWebhookDeadLetters() error {
+	return wk.defaultShardDB().DeleteRange(key.NewWebhookDeadLetterKey(0), key.NewWebhookDeadLetterKey(math.MaxUint64), wk.sync)
+}
@@ -0,0 +1,59 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeadLetter(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for i := uint64(1); i <= 3; i++ {
+		err = d.AddWebhookDeadLetter(wkdb.WebhookDeadLetter{
+			Id:         i,
+			Event:      "msg.offline",
+			Data:       []byte(`{"message_id":1}`),
+			RetryCount: 5,
+			Error:      "timeout",
+			CreatedAt:  100,
+		})
+		assert.NoError(t, err)
+	}
+
+	deadLetter, err := d.GetWebhookDeadLetter(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "msg.offline", deadLetter.Event)
+	assert.Equal(t, []byte(`{"message_id":1}`), deadLetter.Data)
+	assert.Equal(t, uint32(5), deadLetter.RetryCount)
+	assert.Equal(t, "timeout", deadLetter.Error)
+
+	deadLetters, err := d.GetWebhookDeadLetters(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(deadLetters))
+	assert.Equal(t, uint64(2), deadLetters[0].Id)
+
+	deadLetters, err = d.GetWebhookDeadLetters(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deadLetters))
+	assert.Equal(t, uint64(1), deadLetters[0].Id)
+
+	err = d.RemoveWebhookDeadLetters([]uint64{1})
+	assert.NoError(t, err)
+	_, err = d.GetWebhookDeadLetter(1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.RemoveAllWebhookDeadLetters()
+	assert.NoError(t, err)
+	deadLetters, err = d.GetWebhookDeadLetters(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(deadLetters))
+}
@@ -0,0 +1,38 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateWebhookSubscription(subscription WebhookSubscription) error {
+	data, err := subscription.Marshal()
+	if err != nil {
+		return err
+	}
+	return wk.defaultShardDB().Set(key.NewWebhookSubscriptionKey(subscription.Id), data, wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookSubscription(id uint64) error {
+	return wk.defaultShardDB().Delete(key.NewWebhookSubscriptionKey(id), wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookSubscriptions() ([]WebhookSubscription, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookSubscriptionKey(0),
+		UpperBound: key.NewWebhookSubscriptionKey(math.MaxUint64),
+	})
+	defer iter.Close()
+
+	subscriptions := make([]WebhookSubscription, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var subscription WebhookSubscription
+		if err := subscription.Unmarshal(iter.Value()); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
@@ -0,0 +1,63 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscription(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateWebhookSubscription(wkdb.WebhookSubscription{
+		Id:           1,
+		URL:          "http://127.0.0.1:8080/webhook",
+		Events:       []string{"msg.notify", "msg.offline"},
+		ChannelTypes: []uint8{2},
+		ChannelIds:   []string{"g1", "g2"},
+		Secret:       "secret",
+		CreatedAt:    100,
+		UpdatedAt:    100,
+	})
+	assert.NoError(t, err)
+	err = d.AddOrUpdateWebhookSubscription(wkdb.WebhookSubscription{
+		Id:       2,
+		GRPCAddr: "127.0.0.1:6979",
+	})
+	assert.NoError(t, err)
+
+	subscriptions, err := d.GetWebhookSubscriptions()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(subscriptions))
+	assert.Equal(t, "http://127.0.0.1:8080/webhook", subscriptions[0].URL)
+	assert.Equal(t, []string{"msg.notify", "msg.offline"}, subscriptions[0].Events)
+	assert.Equal(t, []uint8{2}, subscriptions[0].ChannelTypes)
+	assert.Equal(t, []string{"g1", "g2"}, subscriptions[0].ChannelIds)
+	assert.Equal(t, "secret", subscriptions[0].Secret)
+	assert.Equal(t, "127.0.0.1:6979", subscriptions[1].GRPCAddr)
+	assert.Equal(t, 0, len(subscriptions[1].Events))
+
+	err = d.AddOrUpdateWebhookSubscription(wkdb.WebhookSubscription{
+		Id:     1,
+		URL:    "http://127.0.0.1:8081/webhook",
+		Events: []string{"user.onlinestatus"},
+	})
+	assert.NoError(t, err)
+
+	err = d.RemoveWebhookSubscription(2)
+	assert.NoError(t, err)
+
+	subscriptions, err = d.GetWebhookSubscriptions()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(subscriptions))
+	assert.Equal(t, "http://127.0.0.1:8081/webhook", subscriptions[0].URL)
+	assert.Equal(t, []string{"user.onlinestatus"}, subscriptions[0].Events)
+}
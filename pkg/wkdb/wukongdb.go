@@ -1,12 +1,14 @@
 package wkdb
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
 	"hash"
 	"hash/fnv"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -33,6 +35,7 @@ type wukongDB struct {
 	dblock       *dblock
 	cancelCtx    context.Context
 	cancelFunc   context.CancelFunc
+	wg           sync.WaitGroup // 等待后台任务退出
 
 	metrics trace.IDBMetrics
 
@@ -117,11 +120,18 @@ func (wk *wukongDB) Open() error {
 
 	// go wk.collectMetricsLoop()
 
+	wk.wg.Add(1)
+	go func() {
+		defer wk.wg.Done()
+		wk.expireSweepLoop()
+	}()
+
 	return nil
 }
 
 func (wk *wukongDB) Close() error {
 	wk.cancelFunc()
+	wk.wg.Wait()
 	for _, db := range wk.dbs {
 		if err := db.Close(); err != nil {
 			wk.Error("close db error", zap.Error(err))
@@ -420,6 +430,16 @@ func (b *Batch) DeleteRange(start, end []byte) {
 	})
 }
 
+// 获取批次中待写入的值
+func (b *Batch) get(key []byte) ([]byte, bool) {
+	for i := len(b.setKvs) - 1; i >= 0; i-- {
+		if bytes.Equal(b.setKvs[i].key, key) {
+			return b.setKvs[i].val, true
+		}
+	}
+	return nil, false
+}
+
 func (b *Batch) Commit() error {
 	b.db.batchChan <- b
 	return nil
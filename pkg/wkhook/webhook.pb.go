@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.27.1
+// 	protoc-gen-go v1.34.2
 // 	protoc        v3.18.1
 // source: pkg/wkhook/webhook.proto
 
@@ -176,6 +176,352 @@ func (x *EventResp) GetData() []byte {
 	return nil
 }
 
+type BeforeSendMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MessageId   int64  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	ClientMsgNo string `protobuf:"bytes,2,opt,name=client_msg_no,json=clientMsgNo,proto3" json:"client_msg_no,omitempty"`
+	FromUid     string `protobuf:"bytes,3,opt,name=from_uid,json=fromUid,proto3" json:"from_uid,omitempty"`
+	ChannelId   string `protobuf:"bytes,4,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	ChannelType uint32 `protobuf:"varint,5,opt,name=channel_type,json=channelType,proto3" json:"channel_type,omitempty"`
+	Payload     []byte `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
+}
+
+func (x *BeforeSendMessage) Reset() {
+	*x = BeforeSendMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BeforeSendMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeforeSendMessage) ProtoMessage() {}
+
+func (x *BeforeSendMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeforeSendMessage.ProtoReflect.Descriptor instead.
+func (*BeforeSendMessage) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BeforeSendMessage) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *BeforeSendMessage) GetClientMsgNo() string {
+	if x != nil {
+		return x.ClientMsgNo
+	}
+	return ""
+}
+
+func (x *BeforeSendMessage) GetFromUid() string {
+	if x != nil {
+		return x.FromUid
+	}
+	return ""
+}
+
+func (x *BeforeSendMessage) GetChannelId() string {
+	if x != nil {
+		return x.ChannelId
+	}
+	return ""
+}
+
+func (x *BeforeSendMessage) GetChannelType() uint32 {
+	if x != nil {
+		return x.ChannelType
+	}
+	return 0
+}
+
+func (x *BeforeSendMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type BeforeSendReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Messages []*BeforeSendMessage `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+}
+
+func (x *BeforeSendReq) Reset() {
+	*x = BeforeSendReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BeforeSendReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeforeSendReq) ProtoMessage() {}
+
+func (x *BeforeSendReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeforeSendReq.ProtoReflect.Descriptor instead.
+func (*BeforeSendReq) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BeforeSendReq) GetMessages() []*BeforeSendMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type BeforeSendResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MessageId  int64  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	ReasonCode uint32 `protobuf:"varint,2,opt,name=reason_code,json=reasonCode,proto3" json:"reason_code,omitempty"`
+	Payload    []byte `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+}
+
+func (x *BeforeSendResult) Reset() {
+	*x = BeforeSendResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BeforeSendResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeforeSendResult) ProtoMessage() {}
+
+func (x *BeforeSendResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeforeSendResult.ProtoReflect.Descriptor instead.
+func (*BeforeSendResult) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BeforeSendResult) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *BeforeSendResult) GetReasonCode() uint32 {
+	if x != nil {
+		return x.ReasonCode
+	}
+	return 0
+}
+
+func (x *BeforeSendResult) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type BeforeSendResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*BeforeSendResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+}
+
+func (x *BeforeSendResp) Reset() {
+	*x = BeforeSendResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BeforeSendResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeforeSendResp) ProtoMessage() {}
+
+func (x *BeforeSendResp) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeforeSendResp.ProtoReflect.Descriptor instead.
+func (*BeforeSendResp) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BeforeSendResp) GetResults() []*BeforeSendResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type DatasourceReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cmd  string `protobuf:"bytes,1,opt,name=cmd,proto3" json:"cmd,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *DatasourceReq) Reset() {
+	*x = DatasourceReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DatasourceReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatasourceReq) ProtoMessage() {}
+
+func (x *DatasourceReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatasourceReq.ProtoReflect.Descriptor instead.
+func (*DatasourceReq) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DatasourceReq) GetCmd() string {
+	if x != nil {
+		return x.Cmd
+	}
+	return ""
+}
+
+func (x *DatasourceReq) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type DatasourceResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *DatasourceResp) Reset() {
+	*x = DatasourceResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_wkhook_webhook_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DatasourceResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatasourceResp) ProtoMessage() {}
+
+func (x *DatasourceResp) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_wkhook_webhook_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatasourceResp.ProtoReflect.Descriptor instead.
+func (*DatasourceResp) Descriptor() ([]byte, []int) {
+	return file_pkg_wkhook_webhook_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DatasourceResp) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 var File_pkg_wkhook_webhook_proto protoreflect.FileDescriptor
 
 var file_pkg_wkhook_webhook_proto_rawDesc = []byte{
@@ -189,15 +535,58 @@ var file_pkg_wkhook_webhook_proto_rawDesc = []byte{
 	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x45,
 	0x76, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
 	0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
-	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x2a, 0x25, 0x0a, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x00,
-	0x12, 0x0b, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x10, 0x01, 0x32, 0x44, 0x0a,
-	0x0e, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
-	0x32, 0x0a, 0x0b, 0x53, 0x65, 0x6e, 0x64, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x12, 0x10,
-	0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71,
-	0x1a, 0x11, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52,
-	0x65, 0x73, 0x70, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xcd, 0x01, 0x0a, 0x11, 0x42, 0x65, 0x66, 0x6f, 0x72,
+	0x65, 0x53, 0x65, 0x6e, 0x64, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1d, 0x0a, 0x0a,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49, 0x64, 0x12, 0x22, 0x0a, 0x0d, 0x63,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x67, 0x5f, 0x6e, 0x6f, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x4d, 0x73, 0x67, 0x4e, 0x6f, 0x12,
+	0x19, 0x0a, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x75, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x66, 0x72, 0x6f, 0x6d, 0x55, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68,
+	0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61,
+	0x6e, 0x6e, 0x65, 0x6c, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0b, 0x63, 0x68, 0x61, 0x6e, 0x6e, 0x65, 0x6c, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x46, 0x0a, 0x0d, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65,
+	0x53, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x12, 0x35, 0x0a, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x77, 0x6b, 0x68, 0x6f,
+	0x6f, 0x6b, 0x2e, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x52, 0x08, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x22, 0x6c,
+	0x0a, 0x10, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x49,
+	0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x43, 0x6f,
+	0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x44, 0x0a, 0x0e,
+	0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x12, 0x32,
+	0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53,
+	0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x73, 0x22, 0x35, 0x0a, 0x0d, 0x44, 0x61, 0x74, 0x61, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x52, 0x65, 0x71, 0x12, 0x10, 0x0a, 0x03, 0x63, 0x6d, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x63, 0x6d, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x24, 0x0a, 0x0e, 0x44, 0x61, 0x74,
+	0x61, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x64,
+	0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x2a,
+	0x25, 0x0a, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x09,
+	0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x75, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x10, 0x01, 0x32, 0x81, 0x01, 0x0a, 0x0e, 0x57, 0x65, 0x62, 0x68, 0x6f,
+	0x6f, 0x6b, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x32, 0x0a, 0x0b, 0x53, 0x65, 0x6e,
+	0x64, 0x57, 0x65, 0x62, 0x68, 0x6f, 0x6f, 0x6b, 0x12, 0x10, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f,
+	0x6b, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x1a, 0x11, 0x2e, 0x77, 0x6b, 0x68,
+	0x6f, 0x6f, 0x6b, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x12, 0x3b, 0x0a,
+	0x0a, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x12, 0x15, 0x2e, 0x77, 0x6b,
+	0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x52,
+	0x65, 0x71, 0x1a, 0x16, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x42, 0x65, 0x66, 0x6f,
+	0x72, 0x65, 0x53, 0x65, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x70, 0x32, 0x4d, 0x0a, 0x11, 0x44, 0x61,
+	0x74, 0x61, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x38, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x15, 0x2e, 0x77, 0x6b, 0x68,
+	0x6f, 0x6f, 0x6b, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65,
+	0x71, 0x1a, 0x16, 0x2e, 0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f, 0x3b,
+	0x77, 0x6b, 0x68, 0x6f, 0x6f, 0x6b, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -213,21 +602,33 @@ func file_pkg_wkhook_webhook_proto_rawDescGZIP() []byte {
 }
 
 var file_pkg_wkhook_webhook_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pkg_wkhook_webhook_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_pkg_wkhook_webhook_proto_goTypes = []interface{}{
-	(EventStatus)(0),  // 0: wkhook.EventStatus
-	(*EventReq)(nil),  // 1: wkhook.EventReq
-	(*EventResp)(nil), // 2: wkhook.EventResp
+var file_pkg_wkhook_webhook_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_pkg_wkhook_webhook_proto_goTypes = []any{
+	(EventStatus)(0),          // 0: wkhook.EventStatus
+	(*EventReq)(nil),          // 1: wkhook.EventReq
+	(*EventResp)(nil),         // 2: wkhook.EventResp
+	(*BeforeSendMessage)(nil), // 3: wkhook.BeforeSendMessage
+	(*BeforeSendReq)(nil),     // 4: wkhook.BeforeSendReq
+	(*BeforeSendResult)(nil),  // 5: wkhook.BeforeSendResult
+	(*BeforeSendResp)(nil),    // 6: wkhook.BeforeSendResp
+	(*DatasourceReq)(nil),     // 7: wkhook.DatasourceReq
+	(*DatasourceResp)(nil),    // 8: wkhook.DatasourceResp
 }
 var file_pkg_wkhook_webhook_proto_depIdxs = []int32{
 	0, // 0: wkhook.EventResp.status:type_name -> wkhook.EventStatus
-	1, // 1: wkhook.WebhookService.SendWebhook:input_type -> wkhook.EventReq
-	2, // 2: wkhook.WebhookService.SendWebhook:output_type -> wkhook.EventResp
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3, // 1: wkhook.BeforeSendReq.messages:type_name -> wkhook.BeforeSendMessage
+	5, // 2: wkhook.BeforeSendResp.results:type_name -> wkhook.BeforeSendResult
+	1, // 3: wkhook.WebhookService.SendWebhook:input_type -> wkhook.EventReq
+	4, // 4: wkhook.WebhookService.BeforeSend:input_type -> wkhook.BeforeSendReq
+	7, // 5: wkhook.DatasourceService.Request:input_type -> wkhook.DatasourceReq
+	2, // 6: wkhook.WebhookService.SendWebhook:output_type -> wkhook.EventResp
+	6, // 7: wkhook.WebhookService.BeforeSend:output_type -> wkhook.BeforeSendResp
+	8, // 8: wkhook.DatasourceService.Request:output_type -> wkhook.DatasourceResp
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_pkg_wkhook_webhook_proto_init() }
@@ -236,7 +637,7 @@ func file_pkg_wkhook_webhook_proto_init() {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
-		file_pkg_wkhook_webhook_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_wkhook_webhook_proto_msgTypes[0].Exporter = func(v any, i int) any {
 			switch v := v.(*EventReq); i {
 			case 0:
 				return &v.state
@@ -248,7 +649,7 @@ func file_pkg_wkhook_webhook_proto_init() {
 				return nil
 			}
 		}
-		file_pkg_wkhook_webhook_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_wkhook_webhook_proto_msgTypes[1].Exporter = func(v any, i int) any {
 			switch v := v.(*EventResp); i {
 			case 0:
 				return &v.state
@@ -260,6 +661,78 @@ func file_pkg_wkhook_webhook_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_wkhook_webhook_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*BeforeSendMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_wkhook_webhook_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*BeforeSendReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_wkhook_webhook_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*BeforeSendResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_wkhook_webhook_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*BeforeSendResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_wkhook_webhook_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*DatasourceReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_wkhook_webhook_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*DatasourceResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -267,9 +740,9 @@ func file_pkg_wkhook_webhook_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_wkhook_webhook_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   2,
+			NumMessages:   8,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_pkg_wkhook_webhook_proto_goTypes,
 		DependencyIndexes: file_pkg_wkhook_webhook_proto_depIdxs,
@@ -24,6 +24,8 @@ const _ = grpc.SupportPackageIsVersion7
 type WebhookServiceClient interface {
 	// 发送webhook事件
 	SendWebhook(ctx context.Context, in *EventReq, opts ...grpc.CallOption) (*EventResp, error)
+	// 消息发送前的审核（同步调用），可以拒绝消息或替换消息内容
+	BeforeSend(ctx context.Context, in *BeforeSendReq, opts ...grpc.CallOption) (*BeforeSendResp, error)
 }
 
 type webhookServiceClient struct {
@@ -43,12 +45,23 @@ func (c *webhookServiceClient) SendWebhook(ctx context.Context, in *EventReq, op
 	return out, nil
 }
 
+func (c *webhookServiceClient) BeforeSend(ctx context.Context, in *BeforeSendReq, opts ...grpc.CallOption) (*BeforeSendResp, error) {
+	out := new(BeforeSendResp)
+	err := c.cc.Invoke(ctx, "/wkhook.WebhookService/BeforeSend", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WebhookServiceServer is the server API for WebhookService service.
 // All implementations must embed UnimplementedWebhookServiceServer
 // for forward compatibility
 type WebhookServiceServer interface {
 	// 发送webhook事件
 	SendWebhook(context.Context, *EventReq) (*EventResp, error)
+	// 消息发送前的审核（同步调用），可以拒绝消息或替换消息内容
+	BeforeSend(context.Context, *BeforeSendReq) (*BeforeSendResp, error)
 	mustEmbedUnimplementedWebhookServiceServer()
 }
 
@@ -59,6 +72,9 @@ type UnimplementedWebhookServiceServer struct {
 func (UnimplementedWebhookServiceServer) SendWebhook(context.Context, *EventReq) (*EventResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendWebhook not implemented")
 }
+func (UnimplementedWebhookServiceServer) BeforeSend(context.Context, *BeforeSendReq) (*BeforeSendResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeforeSend not implemented")
+}
 func (UnimplementedWebhookServiceServer) mustEmbedUnimplementedWebhookServiceServer() {}
 
 // UnsafeWebhookServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -90,6 +106,24 @@ func _WebhookService_SendWebhook_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WebhookService_BeforeSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeforeSendReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebhookServiceServer).BeforeSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wkhook.WebhookService/BeforeSend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebhookServiceServer).BeforeSend(ctx, req.(*BeforeSendReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WebhookService_ServiceDesc is the grpc.ServiceDesc for WebhookService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -101,6 +135,98 @@ var WebhookService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendWebhook",
 			Handler:    _WebhookService_SendWebhook_Handler,
 		},
+		{
+			MethodName: "BeforeSend",
+			Handler:    _WebhookService_BeforeSend_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/wkhook/webhook.proto",
+}
+
+// DatasourceServiceClient is the client API for DatasourceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DatasourceServiceClient interface {
+	// 数据源请求，cmd和data与http数据源相同
+	Request(ctx context.Context, in *DatasourceReq, opts ...grpc.CallOption) (*DatasourceResp, error)
+}
+
+type datasourceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatasourceServiceClient(cc grpc.ClientConnInterface) DatasourceServiceClient {
+	return &datasourceServiceClient{cc}
+}
+
+func (c *datasourceServiceClient) Request(ctx context.Context, in *DatasourceReq, opts ...grpc.CallOption) (*DatasourceResp, error) {
+	out := new(DatasourceResp)
+	err := c.cc.Invoke(ctx, "/wkhook.DatasourceService/Request", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DatasourceServiceServer is the server API for DatasourceService service.
+// All implementations must embed UnimplementedDatasourceServiceServer
+// for forward compatibility
+type DatasourceServiceServer interface {
+	// 数据源请求，cmd和data与http数据源相同
+	Request(context.Context, *DatasourceReq) (*DatasourceResp, error)
+	mustEmbedUnimplementedDatasourceServiceServer()
+}
+
+// UnimplementedDatasourceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDatasourceServiceServer struct {
+}
+
+func (UnimplementedDatasourceServiceServer) Request(context.Context, *DatasourceReq) (*DatasourceResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Request not implemented")
+}
+func (UnimplementedDatasourceServiceServer) mustEmbedUnimplementedDatasourceServiceServer() {}
+
+// UnsafeDatasourceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatasourceServiceServer will
+// result in compilation errors.
+type UnsafeDatasourceServiceServer interface {
+	mustEmbedUnimplementedDatasourceServiceServer()
+}
+
+func RegisterDatasourceServiceServer(s grpc.ServiceRegistrar, srv DatasourceServiceServer) {
+	s.RegisterService(&DatasourceService_ServiceDesc, srv)
+}
+
+func _DatasourceService_Request_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DatasourceReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatasourceServiceServer).Request(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wkhook.DatasourceService/Request",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatasourceServiceServer).Request(ctx, req.(*DatasourceReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DatasourceService_ServiceDesc is the grpc.ServiceDesc for DatasourceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DatasourceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wkhook.DatasourceService",
+	HandlerType: (*DatasourceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Request",
+			Handler:    _DatasourceService_Request_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/wkhook/webhook.proto",
@@ -0,0 +1,28 @@
+package wknet
+
+import "net"
+
+// IMQTTConn mqtt连接，连接上的数据是mqtt协议的报文
+type IMQTTConn interface {
+	IsMQTT() bool
+}
+
+func CreateMQTTConn(id int64, connFd NetFd, localAddr, remoteAddr net.Addr, eg *Engine, reactorSub *ReactorSub) (Conn, error) {
+	defaultConn := GetDefaultConn(id, connFd, localAddr, remoteAddr, eg, reactorSub)
+	return NewMQTTConn(defaultConn), nil
+}
+
+// MQTTConn mqtt连接，读写与tcp连接相同，报文的编解码由上层处理
+type MQTTConn struct {
+	*DefaultConn
+}
+
+func NewMQTTConn(d *DefaultConn) *MQTTConn {
+	return &MQTTConn{
+		DefaultConn: d,
+	}
+}
+
+func (m *MQTTConn) IsMQTT() bool {
+	return true
+}
@@ -3,6 +3,7 @@ package wkutil
 import (
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // CopyFile CopyFile
@@ -20,6 +21,25 @@ func CopyFile(dstName, srcName string) (written int64, err error) {
 	return io.Copy(dst, src)
 }
 
+// CopyDir 递归复制目录
+func CopyDir(dstDir, srcDir string) error {
+	return filepath.Walk(srcDir, func(srcPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, srcPath)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dstDir, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, info.Mode().Perm())
+		}
+		_, err = CopyFile(dstPath, srcPath)
+		return err
+	})
+}
+
 func WriteFile(filename string, data []byte) error {
 	return os.WriteFile(filename, data, 0644)
 }